@@ -22,6 +22,7 @@ import (
 	"github.com/provideplatform/nchain/token"
 	"github.com/provideplatform/nchain/tx"
 	"github.com/provideplatform/nchain/wallet"
+	"github.com/provideplatform/nchain/webhook"
 
 	pgputil "github.com/kthomas/go-pgputil"
 	redisutil "github.com/kthomas/go-redisutil"
@@ -139,6 +140,7 @@ func runAPI() {
 	tx.InstallTransactionsAPI(r)
 	wallet.InstallAccountsAPI(r)
 	wallet.InstallWalletsAPI(r)
+	webhook.InstallWebhooksAPI(r)
 
 	srv = &http.Server{
 		Addr:    util.ListenAddr,
@@ -56,6 +56,14 @@ var (
 	// ConsumeNATSStreamingSubscriptions is a flag the indicates if the nchain instance is running in API or consumer mode
 	ConsumeNATSStreamingSubscriptions bool
 
+	// AllowInsecureWebhooks is a flag that indicates if webhooks may use plain http and target loopback, private or
+	// link-local addresses; it should only be set in development
+	AllowInsecureWebhooks bool
+
+	// AllowInsecureRemoteSigners is a flag that indicates if remote signers may use plain http and target loopback,
+	// private or link-local addresses; it should only be set in development
+	AllowInsecureRemoteSigners bool
+
 	// DefaultDockerhubOrganization is the default public Dockerhub organization to leverage when resolving repository names
 	DefaultDockerhubOrganization *string
 
@@ -73,6 +81,12 @@ var (
 
 	// DefaultInfrastructureUsesSelfSignedCertificate is a flag that indicates if various managed infrastructure (i.e., load balancers) should use a self-signed cert
 	DefaultInfrastructureUsesSelfSignedCertificate bool
+
+	// DefaultKeystorePath is the directory from which the geth JSON keystores of keystore accounts may be read
+	DefaultKeystorePath string
+
+	// DefaultPKCS11ModulePath is the PKCS#11 module (i.e., libsofthsm2.so) used to sign txs on behalf of pkcs11 accounts
+	DefaultPKCS11ModulePath string
 )
 
 func init() {
@@ -89,6 +103,8 @@ func init() {
 
 	DefaultAWSConfig = awsconf.GetConfig()
 	ConsumeNATSStreamingSubscriptions = strings.ToLower(os.Getenv("CONSUME_NATS_STREAMING_SUBSCRIPTIONS")) == "true"
+	AllowInsecureWebhooks = strings.ToLower(os.Getenv("ALLOW_INSECURE_WEBHOOKS")) == "true"
+	AllowInsecureRemoteSigners = strings.ToLower(os.Getenv("ALLOW_INSECURE_REMOTE_SIGNERS")) == "true"
 }
 
 func RequireInfrastructureSupport() {
@@ -135,6 +151,14 @@ func RequireInfrastructureSupport() {
 		}
 	}
 
+	if os.Getenv("KEYSTORE_PATH") != "" {
+		DefaultKeystorePath = os.Getenv("KEYSTORE_PATH")
+	}
+
+	if os.Getenv("PKCS11_MODULE_PATH") != "" {
+		DefaultPKCS11ModulePath = os.Getenv("PKCS11_MODULE_PATH")
+	}
+
 	DefaultInfrastructureUsesSelfSignedCertificate = !(DefaultInfrastructureDomain != "" && DefaultInfrastructureRoute53HostedZoneID != "" && DefaultInfrastructureAWSConfig != nil && DefaultInfrastructureAWSConfig.DefaultCertificateArn != nil && *DefaultInfrastructureAWSConfig.DefaultCertificateArn != "")
 }
 
@@ -0,0 +1,104 @@
+package common
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"syscall"
+	"time"
+)
+
+// restrictedNetworks are the address ranges which outbound requests to user-provided urls, i.e. webhooks
+// and remote signers, may not target; loopback, link-local and multicast addresses are also restricted
+var restrictedNetworks = parseCIDRs(
+	"0.0.0.0/8",
+	"10.0.0.0/8",
+	"100.64.0.0/10",
+	"172.16.0.0/12",
+	"192.168.0.0/16",
+	"198.18.0.0/15",
+	"fc00::/7",
+)
+
+// ValidatePublicURL returns an error if the given url does not use https, or if its host does not resolve
+// or resolves to a restricted address; when insecure, plain http and restricted addresses are permitted
+func ValidatePublicURL(rawURL string, insecure bool) error {
+	_url, err := url.Parse(rawURL)
+	if err != nil || _url.Hostname() == "" {
+		return errors.New("invalid url")
+	}
+
+	if _url.Scheme != "https" && (_url.Scheme != "http" || !insecure) {
+		return errors.New("url scheme must be https")
+	}
+
+	if insecure {
+		return nil
+	}
+
+	ips, err := net.LookupIP(_url.Hostname())
+	if err != nil || len(ips) == 0 {
+		return fmt.Errorf("host could not be resolved: %s", _url.Hostname())
+	}
+
+	for _, ip := range ips {
+		if IsRestrictedIP(ip) {
+			return fmt.Errorf("host resolves to a restricted address: %s", _url.Hostname())
+		}
+	}
+
+	return nil
+}
+
+// PublicDialContext returns a dial func which refuses connections to restricted addresses when the resolved
+// address is dialed, so a host which resolved to a public address when its url was validated, or a redirect,
+// cannot be used to reach internal services; when insecure, connections to restricted addresses are permitted
+func PublicDialContext(timeout time.Duration, insecure bool) func(ctx context.Context, network, address string) (net.Conn, error) {
+	dialer := &net.Dialer{
+		Timeout: timeout,
+		Control: func(network, address string, conn syscall.RawConn) error {
+			if insecure {
+				return nil
+			}
+
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+
+			if ip := net.ParseIP(host); ip == nil || IsRestrictedIP(ip) {
+				return fmt.Errorf("connection to restricted address refused: %s", host)
+			}
+			return nil
+		},
+	}
+	return dialer.DialContext
+}
+
+// IsRestrictedIP returns true if the given address is loopback, private, link-local, multicast or unspecified
+func IsRestrictedIP(ip net.IP) bool {
+	if ip.IsLoopback() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() || ip.IsMulticast() || ip.IsUnspecified() {
+		return true
+	}
+
+	for _, restricted := range restrictedNetworks {
+		if restricted.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+func parseCIDRs(cidrs ...string) []*net.IPNet {
+	networks := make([]*net.IPNet, 0)
+	for _, cidr := range cidrs {
+		_, network, err := net.ParseCIDR(cidr)
+		if err != nil {
+			panic(err)
+		}
+		networks = append(networks, network)
+	}
+	return networks
+}
@@ -26,6 +26,8 @@ const resolveTokenTickerTimeout = time.Minute * 1
 
 const natsTxCreateSubject = "nchain.tx.create"
 
+const abiEntryTypeError = "error"
+
 // Contract instances must be associated with an application identifier.
 type Contract struct {
 	provide.Model
@@ -172,7 +174,67 @@ func (c *Contract) ExecuteEthereumContract(
 
 // ReadEthereumContractAbi is called from token
 func (c *Contract) ReadEthereumContractAbi() (*abi.ABI, error) {
-	var _abi *abi.ABI
+	entries, err := c.readEthereumContractAbiEntries()
+	if err != nil {
+		return nil, err
+	}
+
+	// custom error entries are not supported by the abi parser and are read via ReadEthereumContractErrors
+	abiEntries := make([]map[string]interface{}, 0)
+	for _, entry := range entries {
+		if typ, typOk := entry["type"].(string); !typOk || typ != abiEntryTypeError {
+			abiEntries = append(abiEntries, entry)
+		}
+	}
+
+	abistr, err := json.Marshal(abiEntries)
+	if err != nil {
+		common.Log.Warningf("Failed to marshal ABI from contract params to json; %s", err.Error())
+		return nil, err
+	}
+
+	abival, err := abi.JSON(strings.NewReader(string(abistr)))
+	if err != nil {
+		common.Log.Warningf("Failed to initialize ABI from contract params to json; %s", err.Error())
+		return nil, err
+	}
+
+	return &abival, nil
+}
+
+// ReadEthereumContractErrors returns the solidity custom errors declared in the contract ABI;
+// each error is represented as a method so its selector and arguments can be used to decode revert data
+func (c *Contract) ReadEthereumContractErrors() ([]abi.Method, error) {
+	entries, err := c.readEthereumContractAbiEntries()
+	if err != nil {
+		return nil, err
+	}
+
+	customErrors := make([]abi.Method, 0)
+	for _, entry := range entries {
+		if typ, typOk := entry["type"].(string); !typOk || typ != abiEntryTypeError {
+			continue
+		}
+
+		raw, _ := json.Marshal(entry)
+		var customError struct {
+			Name   string         `json:"name"`
+			Inputs []abi.Argument `json:"inputs"`
+		}
+		err := json.Unmarshal(raw, &customError)
+		if err != nil {
+			common.Log.Warningf("Failed to parse custom error from contract ABI; %s", err.Error())
+			return nil, err
+		}
+
+		customErrors = append(customErrors, abi.NewMethod(customError.Name, customError.Name, abi.Function, "", false, false, customError.Inputs, nil))
+	}
+
+	return customErrors, nil
+}
+
+// readEthereumContractAbiEntries reads the raw ABI entries from the contract params or compiled artifact
+func (c *Contract) readEthereumContractAbiEntries() ([]map[string]interface{}, error) {
 	params := c.ParseParams()
 	contractAbi, contractAbiOk := params["abi"]
 	if !contractAbiOk {
@@ -182,24 +244,24 @@ func (c *Contract) ReadEthereumContractAbi() (*abi.ABI, error) {
 		}
 	}
 
-	if contractAbi != nil {
-		abistr, err := json.Marshal(contractAbi)
-		if err != nil {
-			common.Log.Warningf("Failed to marshal ABI from contract params to json; %s", err.Error())
-			return nil, err
-		}
+	if contractAbi == nil {
+		return nil, fmt.Errorf("Failed to read ABI from params for contract: %s", c.ID)
+	}
 
-		abival, err := abi.JSON(strings.NewReader(string(abistr)))
-		if err != nil {
-			common.Log.Warningf("Failed to initialize ABI from contract params to json; %s", err.Error())
-			return nil, err
-		}
+	abistr, err := json.Marshal(contractAbi)
+	if err != nil {
+		common.Log.Warningf("Failed to marshal ABI from contract params to json; %s", err.Error())
+		return nil, err
+	}
 
-		_abi = &abival
-	} else {
-		return nil, fmt.Errorf("Failed to read ABI from params for contract: %s", c.ID)
+	var entries []map[string]interface{}
+	err = json.Unmarshal(abistr, &entries)
+	if err != nil {
+		common.Log.Warningf("Failed to initialize ABI from contract params to json; %s", err.Error())
+		return nil, err
 	}
-	return _abi, nil
+
+	return entries, nil
 }
 
 // ResolveCompiledDependencyArtifact returns the compiled artifact if matched to the given descriptor;
@@ -430,6 +492,8 @@ func (c *Contract) ExecuteFromTx(
 	// params := execution.Params
 	gas := execution.Gas
 	gasPrice := execution.GasPrice
+	maxFeePerGas := execution.MaxFeePerGas
+	maxPriorityFeePerGas := execution.MaxPriorityFeePerGas
 	nonce := execution.Nonce
 
 	//xxx add path to params
@@ -457,6 +521,18 @@ func (c *Contract) ExecuteFromTx(
 		txParams["gas_price"] = gasPrice
 	}
 
+	if maxFeePerGas != nil {
+		txParams["max_fee_per_gas"] = maxFeePerGas
+	}
+
+	if maxPriorityFeePerGas != nil {
+		txParams["max_priority_fee_per_gas"] = maxPriorityFeePerGas
+	}
+
+	if execution.AccessList != nil {
+		txParams["access_list"] = execution.AccessList
+	}
+
 	if nonce != nil {
 		txParams["nonce"] = *nonce
 	}
@@ -0,0 +1,184 @@
+package contract
+
+import (
+	"fmt"
+	"math/big"
+	"reflect"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	ethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+)
+
+// DecodedInput is calldata decoded using the ABI of the contract which was called
+type DecodedInput struct {
+	Method    string                 `json:"method"`
+	Signature string                 `json:"signature"`
+	Selector  string                 `json:"selector"`
+	Args      map[string]interface{} `json:"args"`
+}
+
+// DecodedLog is a log decoded into a named event using the ABI of the contract which emitted it
+type DecodedLog struct {
+	Address   string                 `json:"address"`
+	Event     string                 `json:"event"`
+	Signature string                 `json:"signature"`
+	Topic     string                 `json:"topic"`
+	LogIndex  *uint                  `json:"log_index,omitempty"`
+	Args      map[string]interface{} `json:"args"`
+}
+
+// DecodeInput decodes the given calldata into the called method and its named arguments
+func DecodeInput(_abi *abi.ABI, data []byte) (*DecodedInput, error) {
+	if len(data) < 4 {
+		return nil, fmt.Errorf("unable to decode %d-byte calldata; no method selector", len(data))
+	}
+
+	method, err := _abi.MethodById(data[:4])
+	if err != nil {
+		return nil, err
+	}
+
+	args := map[string]interface{}{}
+	err = method.Inputs.UnpackIntoMap(args, data[4:])
+	if err != nil {
+		return nil, fmt.Errorf("failed to unpack arguments of method %s; %s", method.Sig, err.Error())
+	}
+
+	return &DecodedInput{
+		Method:    method.RawName,
+		Signature: method.Sig,
+		Selector:  hexutil.Encode(method.ID),
+		Args:      normalizeDecodedArgs(args),
+	}, nil
+}
+
+// DecodeLog decodes the given log topics and data into the emitted event and its named arguments;
+// indexed arguments of dynamic types are decoded as the hash of their value
+func DecodeLog(_abi *abi.ABI, address string, topics []ethcommon.Hash, data []byte) (*DecodedLog, error) {
+	if len(topics) == 0 {
+		return nil, fmt.Errorf("unable to decode anonymous log emitted by %s", address)
+	}
+
+	event, err := _abi.EventByID(topics[0])
+	if err != nil {
+		return nil, err
+	}
+
+	args := map[string]interface{}{}
+	err = event.Inputs.UnpackIntoMap(args, data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unpack arguments of event %s; %s", event.Sig, err.Error())
+	}
+
+	indexed := make(abi.Arguments, 0)
+	for _, input := range event.Inputs {
+		if input.Indexed {
+			indexed = append(indexed, input)
+		}
+	}
+
+	err = abi.ParseTopicsIntoMap(args, indexed, topics[1:])
+	if err != nil {
+		return nil, fmt.Errorf("failed to unpack indexed arguments of event %s; %s", event.Sig, err.Error())
+	}
+
+	return &DecodedLog{
+		Address:   ethcommon.HexToAddress(address).Hex(),
+		Event:     event.RawName,
+		Signature: event.Sig,
+		Topic:     topics[0].Hex(),
+		Args:      normalizeDecodedArgs(args),
+	}, nil
+}
+
+// DecodeInput decodes the given calldata using the contract ABI
+func (c *Contract) DecodeInput(data []byte) (*DecodedInput, error) {
+	_abi, err := c.ReadEthereumContractAbi()
+	if err != nil {
+		return nil, err
+	}
+	return DecodeInput(_abi, data)
+}
+
+// DecodeLog decodes the given log using the contract ABI
+func (c *Contract) DecodeLog(topics []ethcommon.Hash, data []byte) (*DecodedLog, error) {
+	_abi, err := c.ReadEthereumContractAbi()
+	if err != nil {
+		return nil, err
+	}
+
+	address := ""
+	if c.Address != nil {
+		address = *c.Address
+	}
+	return DecodeLog(_abi, address, topics, data)
+}
+
+// normalizeDecodedArgs renders the given decoded argument values as JSON-friendly values;
+// integers are rendered as decimal strings and byte arrays as hex
+func normalizeDecodedArgs(args map[string]interface{}) map[string]interface{} {
+	for name, val := range args {
+		args[name] = normalizeDecodedValue(reflect.ValueOf(val))
+	}
+	return args
+}
+
+// normalizeDecodedValue renders the given decoded value as a JSON-friendly value
+func normalizeDecodedValue(val reflect.Value) interface{} {
+	if !val.IsValid() {
+		return nil
+	}
+
+	switch v := val.Interface().(type) {
+	case ethcommon.Address:
+		return v.Hex()
+	case ethcommon.Hash:
+		return v.Hex()
+	case []byte:
+		return hexutil.Encode(v)
+	case *big.Int:
+		if v == nil {
+			return nil
+		}
+		return v.String()
+	}
+
+	switch val.Kind() {
+	case reflect.Ptr:
+		if val.IsNil() {
+			return nil
+		}
+		return normalizeDecodedValue(val.Elem())
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return fmt.Sprintf("%d", val.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return fmt.Sprintf("%d", val.Uint())
+	case reflect.Array:
+		if val.Type().Elem().Kind() == reflect.Uint8 {
+			buf := make([]byte, val.Len())
+			reflect.Copy(reflect.ValueOf(buf), val)
+			return hexutil.Encode(buf)
+		}
+		fallthrough
+	case reflect.Slice:
+		vals := make([]interface{}, val.Len())
+		for i := 0; i < val.Len(); i++ {
+			vals[i] = normalizeDecodedValue(val.Index(i))
+		}
+		return vals
+	case reflect.Struct:
+		fields := map[string]interface{}{}
+		for i := 0; i < val.NumField(); i++ {
+			field := val.Type().Field(i)
+			if tag := field.Tag.Get("json"); tag != "" {
+				fields[tag] = normalizeDecodedValue(val.Field(i))
+			} else {
+				fields[field.Name] = normalizeDecodedValue(val.Field(i))
+			}
+		}
+		return fields
+	}
+
+	return val.Interface()
+}
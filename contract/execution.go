@@ -32,15 +32,24 @@ type Execution struct {
 	HDPath   *string     `json:"hd_derivation_path"`
 
 	// Tx params
-	Gas       *float64      `json:"gas"`
-	GasPrice  *float64      `json:"gas_price"`
-	Nonce     *uint64       `json:"nonce"`
-	Method    string        `json:"method"`
-	Params    []interface{} `json:"params"`
-	Subsidize bool          `json:"subsidize"`
-	Value     *big.Int      `json:"value"`
+	Gas                  *float64      `json:"gas"`
+	GasPrice             *float64      `json:"gas_price"`
+	MaxFeePerGas         *float64      `json:"max_fee_per_gas"`
+	MaxPriorityFeePerGas *float64      `json:"max_priority_fee_per_gas"`
+	AccessList           interface{}   `json:"access_list,omitempty"` // EIP-2930 access list
+	Nonce                *uint64       `json:"nonce"`
+	Method               string        `json:"method"`
+	Params               []interface{} `json:"params"`
+	Subsidize            bool          `json:"subsidize"` // deprecated and ignored; use relay or the network faucet
+	Relay                bool          `json:"relay"`     // submit as an EIP-2771 meta-tx using the relayer of the application
+	Value                *big.Int      `json:"value"`
+
+	// Scheduling; when either is set, the execution is parked until the given time and/or block height
+	ScheduledAt    *time.Time `json:"scheduled_at,omitempty"`
+	NotBeforeBlock *uint64    `json:"not_before_block,omitempty"`
 
 	// Tx metadata/instrumentation
+	BatchID     *uuid.UUID `json:"batch_id,omitempty"`
 	Ref         *string    `json:"ref"`
 	PublishedAt *time.Time `json:"published_at"`
 }
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"strings"
 
+	ethcommon "github.com/ethereum/go-ethereum/common"
 	"github.com/gin-gonic/gin"
 	dbconf "github.com/kthomas/go-db-config"
 	uuid "github.com/kthomas/go.uuid"
@@ -21,6 +22,7 @@ func InstallContractsAPI(r *gin.Engine) {
 	r.GET("/api/v1/contracts/:id", contractDetailsHandler)
 	r.POST("/api/v1/contracts", createContractHandler)
 	r.POST("/api/v1/contracts/:id/subscriptions", createContractSubscriptionTokenHandler)
+	r.POST("/api/v1/contracts/:id/decode", contractDecodeHandler)
 
 	r.GET("/api/v1/networks/:id/contracts", networkContractsListHandler)
 	r.GET("/api/v1/networks/:id/contracts/:contractId", networkContractDetailsHandler)
@@ -399,3 +401,108 @@ func networkContractDetailsHandler(c *gin.Context) {
 
 	provide.Render(contract, 200, c)
 }
+
+// contractDecodeHandler decodes the given calldata and/or logs using the contract ABI
+func contractDecodeHandler(c *gin.Context) {
+	appID := util.AuthorizedSubjectID(c, "application")
+	userID := util.AuthorizedSubjectID(c, "user")
+	orgID := util.AuthorizedSubjectID(c, "organization")
+	if appID == nil && userID == nil && orgID == nil {
+		provide.RenderError("unauthorized", 401, c)
+		return
+	}
+
+	buf, err := c.GetRawData()
+	if err != nil {
+		provide.RenderError(err.Error(), 400, c)
+		return
+	}
+
+	params := &struct {
+		Data *string `json:"data"`
+		Logs []*struct {
+			Address *string  `json:"address"`
+			Topics  []string `json:"topics"`
+			Data    *string  `json:"data"`
+		} `json:"logs"`
+	}{}
+	err = json.Unmarshal(buf, params)
+	if err != nil {
+		provide.RenderError(err.Error(), 400, c)
+		return
+	}
+
+	if params.Data == nil && len(params.Logs) == 0 {
+		provide.RenderError("data or logs required", 422, c)
+		return
+	}
+
+	db := dbconf.DatabaseConnection()
+	contract := &Contract{}
+
+	query := db.Where("id = ?", c.Param("id"))
+	if appID != nil {
+		query = query.Where("contracts.application_id = ?", appID)
+	}
+	if orgID != nil {
+		query = query.Where("contracts.organization_id = ?", orgID)
+	}
+	if userID != nil {
+		query = query.Where("contracts.application_id IS NULL")
+	}
+	query.Find(&contract)
+
+	if contract == nil || contract.ID == uuid.Nil {
+		provide.RenderError("contract not found", 404, c)
+		return
+	}
+
+	_abi, err := contract.ReadEthereumContractAbi()
+	if err != nil {
+		provide.RenderError(fmt.Sprintf("failed to read contract abi; %s", err.Error()), 422, c)
+		return
+	}
+
+	resp := map[string]interface{}{}
+
+	if params.Data != nil {
+		input, err := DecodeInput(_abi, ethcommon.FromHex(*params.Data))
+		if err != nil {
+			provide.RenderError(fmt.Sprintf("failed to decode calldata; %s", err.Error()), 422, c)
+			return
+		}
+		resp["decoded_input"] = input
+	}
+
+	if len(params.Logs) > 0 {
+		logs := make([]*DecodedLog, 0)
+		for i, log := range params.Logs {
+			address := ""
+			if log.Address != nil {
+				address = *log.Address
+			} else if contract.Address != nil {
+				address = *contract.Address
+			}
+
+			topics := make([]ethcommon.Hash, 0)
+			for _, topic := range log.Topics {
+				topics = append(topics, ethcommon.HexToHash(topic))
+			}
+
+			var data []byte
+			if log.Data != nil {
+				data = ethcommon.FromHex(*log.Data)
+			}
+
+			decoded, err := DecodeLog(_abi, address, topics, data)
+			if err != nil {
+				provide.RenderError(fmt.Sprintf("failed to decode log %d; %s", i, err.Error()), 422, c)
+				return
+			}
+			logs = append(logs, decoded)
+		}
+		resp["decoded_logs"] = logs
+	}
+
+	provide.Render(resp, 200, c)
+}
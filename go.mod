@@ -10,6 +10,7 @@ require (
 	github.com/aws/aws-sdk-go v1.31.8
 	github.com/btcsuite/btcd v0.21.0-beta
 	github.com/btcsuite/btcutil v1.0.2
+	github.com/btcsuite/btcutil/psbt v1.0.2
 	github.com/cmars/basen v0.0.0-20150613233007-fe3947df716e // indirect
 	github.com/deckarep/golang-set v1.7.2-0.20180927150649-699df6a3acf6 // indirect
 	github.com/ethereum/go-ethereum v1.9.22
@@ -39,6 +40,7 @@ require (
 	github.com/libp2p/go-libp2p-metrics v0.1.0 // indirect
 	github.com/libp2p/go-libp2p-peer v0.2.0 // indirect
 	github.com/mattn/go-colorable v0.1.2 // indirect
+	github.com/miekg/pkcs11 v1.1.1
 	github.com/miguelmota/go-ethereum-hdwallet v0.0.0-20200123000308-a60dcd172b4c
 	github.com/minio/sha256-simd v0.1.2-0.20190917233721-f675151bb5e1 // indirect
 	github.com/multiformats/go-multiaddr-net v0.1.1 // indirect
@@ -53,5 +55,6 @@ require (
 	github.com/spaolacci/murmur3 v1.1.1-0.20190317074736-539464a789e9 // indirect
 	github.com/status-im/keycard-go v0.0.0-20191119114148-6dd40a46baa0 // indirect
 	go.mongodb.org/mongo-driver v1.3.3
+	golang.org/x/crypto v0.0.0-20210314154223-e6e6c4f2bb5b
 	launchpad.net/gocheck v0.0.0-20140225173054-000000000087 // indirect
 )
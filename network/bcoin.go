@@ -0,0 +1,183 @@
+package network
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/btcsuite/btcd/btcec"
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcutil"
+	"github.com/btcsuite/btcutil/bech32"
+	"github.com/provideplatform/nchain/common"
+	providecrypto "github.com/provideplatform/provide-go/crypto"
+	"golang.org/x/crypto/blake2b"
+)
+
+// bcoinHTTPTimeout is the timeout of requests to the bcoin or hsd node HTTP API
+const bcoinHTTPTimeout = time.Second * 30
+
+// handshakeAddressHRPs are the bech32 human-readable parts of handshake addresses, keyed by chain
+var handshakeAddressHRPs = map[string]string{
+	"main":    "hs",
+	"testnet": "ts",
+	"regtest": "rs",
+	"simnet":  "ss",
+}
+
+// BcoinCoin is an unspent output indexed by a bcoin or hsd node for an address
+type BcoinCoin struct {
+	Version  int    `json:"version"`
+	Height   int64  `json:"height"`
+	Value    int64  `json:"value"`
+	Script   string `json:"script,omitempty"`
+	Address  string `json:"address"`
+	Coinbase bool   `json:"coinbase"`
+	Hash     string `json:"hash"`
+	Index    uint32 `json:"index"`
+	Covenant *struct {
+		Type   int    `json:"type"`
+		Action string `json:"action"`
+	} `json:"covenant,omitempty"`
+}
+
+// bcoinJSONRPCResponse is the JSON-RPC envelope returned by a bcoin or hsd node
+type bcoinJSONRPCResponse struct {
+	Result json.RawMessage `json:"result"`
+	Error  *struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+// IsUTXONetwork returns true if the network is a bcoin-based UTXO network, i.e. bitcoin or handshake
+func (n *Network) IsUTXONetwork() bool {
+	return n.IsBcoinNetwork() || n.IsHandshakeNetwork()
+}
+
+// rpcAPICredentials returns the configured credentials of the bcoin or hsd node API
+func (n *Network) rpcAPICredentials() (string, string) {
+	cfg := n.ParseConfig()
+	rpcAPIUser, _ := cfg[networkConfigRPCAPIUser].(string)
+	rpcAPIKey, _ := cfg[networkConfigRPCAPIKey].(string)
+	return rpcAPIUser, rpcAPIKey
+}
+
+// InvokeBcoinJSONRPC invokes the given JSON-RPC method on the bcoin or hsd node and unmarshals its result into the given response
+func (n *Network) InvokeBcoinJSONRPC(method string, params []interface{}, response interface{}) error {
+	if !n.IsUTXONetwork() {
+		return fmt.Errorf("bcoin JSON-RPC invocation not supported by network %s", n.ID)
+	}
+
+	rpcAPIUser, rpcAPIKey := n.rpcAPICredentials()
+	var resp bcoinJSONRPCResponse
+	err := providecrypto.BcoinInvokeJsonRpcClient(n.ID.String(), n.RPCURL(), rpcAPIUser, rpcAPIKey, method, params, &resp)
+	if err != nil {
+		return err
+	}
+
+	if resp.Error != nil {
+		return fmt.Errorf("JSON-RPC method %s failed; %s", method, resp.Error.Message)
+	}
+
+	if response != nil && len(resp.Result) > 0 {
+		return json.Unmarshal(resp.Result, response)
+	}
+	return nil
+}
+
+// BcoinCoins returns the unspent outputs of the given address using the coin index of the bcoin or hsd node;
+// the node must be run with address indexing enabled
+func (n *Network) BcoinCoins(address string) ([]*BcoinCoin, error) {
+	if !n.IsUTXONetwork() {
+		return nil, fmt.Errorf("coin index not supported by network %s", n.ID)
+	}
+
+	url := fmt.Sprintf("%s/coin/address/%s", strings.TrimSuffix(n.RPCURL(), "/"), address)
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	rpcAPIUser, rpcAPIKey := n.rpcAPICredentials()
+	if rpcAPIKey != "" {
+		req.SetBasicAuth(rpcAPIUser, rpcAPIKey)
+	}
+
+	client := &http.Client{Timeout: bcoinHTTPTimeout}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch coins of address %s; %s", address, err.Error())
+	}
+	defer resp.Body.Close()
+
+	body, _ := ioutil.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to fetch coins of address %s; status: %d; %s", address, resp.StatusCode, string(body))
+	}
+
+	coins := make([]*BcoinCoin, 0)
+	err = json.Unmarshal(body, &coins)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unmarshal coins of address %s; %s", address, err.Error())
+	}
+
+	common.Log.Debugf("fetched %d coin(s) of address %s on network: %s", len(coins), address, n.ID)
+	return coins, nil
+}
+
+// BcoinChainParams returns the bitcoin chain params of the configured chain of the bcoin network
+func (n *Network) BcoinChainParams() *chaincfg.Params {
+	chain, _ := n.ParseConfig()[networkConfigChain].(string)
+	switch chain {
+	case "testnet", "testnet3":
+		return &chaincfg.TestNet3Params
+	case "regtest":
+		return &chaincfg.RegressionNetParams
+	case "simnet":
+		return &chaincfg.SimNetParams
+	}
+	return &chaincfg.MainNetParams
+}
+
+// HandshakeAddressHRP returns the bech32 human-readable part of addresses on the configured chain of the handshake network
+func (n *Network) HandshakeAddressHRP() string {
+	chain, _ := n.ParseConfig()[networkConfigChain].(string)
+	if hrp, hrpOk := handshakeAddressHRPs[chain]; hrpOk {
+		return hrp
+	}
+	return handshakeAddressHRPs["main"]
+}
+
+// BcoinAddress returns the version 0 witness pubkey hash address of the given secp256k1 public key on the network;
+// bitcoin addresses commit to the hash160 of the compressed public key, and handshake addresses to its blake2b-160
+func (n *Network) BcoinAddress(publicKey []byte) (string, error) {
+	pubkey, err := btcec.ParsePubKey(publicKey, btcec.S256())
+	if err != nil {
+		return "", fmt.Errorf("invalid secp256k1 public key; %s", err.Error())
+	}
+
+	if n.IsHandshakeNetwork() {
+		hasher, _ := blake2b.New(20, nil)
+		hasher.Write(pubkey.SerializeCompressed())
+
+		program, err := bech32.ConvertBits(hasher.Sum(nil), 8, 5, true)
+		if err != nil {
+			return "", err
+		}
+		return bech32.Encode(n.HandshakeAddressHRP(), append([]byte{0x00}, program...))
+	}
+
+	if n.IsBcoinNetwork() {
+		addr, err := btcutil.NewAddressWitnessPubKeyHash(btcutil.Hash160(pubkey.SerializeCompressed()), n.BcoinChainParams())
+		if err != nil {
+			return "", err
+		}
+		return addr.EncodeAddress(), nil
+	}
+
+	return "", fmt.Errorf("unable to derive address for unsupported network: %s", n.ID)
+}
@@ -182,6 +182,21 @@ func consumeBlockFinalizedMsg(msg *nats.Msg) {
 						blockTimestamp := time.Unix(int64(blockFinalizedMsg.Timestamp/1000), 0)
 						finalizedAt := time.Now()
 
+						// detect chain reorganizations before the finalized block is saved
+						parentHash, _ := result["parentHash"].(string)
+						orphaned, err := network.detectReorg(db, blockFinalizedMsg.Block, *blockFinalizedMsg.BlockHash, parentHash)
+						if err != nil {
+							common.Log.Warningf("failed to detect chain reorganization for block %d on network: %s; %s", blockFinalizedMsg.Block, network.ID.String(), err.Error())
+						}
+						if len(orphaned) > 0 {
+							err = network.handleReorg(db, orphaned)
+							if err != nil {
+								common.Log.Warningf("failed to handle block finalized message; %s", err.Error())
+								msg.Nak()
+								return
+							}
+						}
+
 						// save the finalized block to the db
 						var minedBlock Block
 						minedBlock.NetworkID = network.ID
@@ -213,6 +228,11 @@ func consumeBlockFinalizedMsg(msg *nats.Msg) {
 								}
 							}
 						}
+
+						err = network.confirmTxs(blockFinalizedMsg.Block)
+						if err != nil {
+							common.Log.Warningf("failed to handle block finalized message; %s", err.Error())
+						}
 					}
 				}
 			} else {
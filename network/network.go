@@ -30,20 +30,30 @@ const hostReachabilityInterval = time.Millisecond * 2500
 const networkStateGenesis = "genesis"
 const natsNetworkContractCreateInvocationSubject = "nchain.contract.persist"
 
+const defaultConfirmationDepth = uint64(1)
+const defaultGasEstimateMultiplier = float64(1.2)
+const defaultMulticall3Address = "0xcA11bde05977b3631167028862bE2a173976CA11" // deterministic deployment address on most EVM networks
+const defaultPendingTxTimeout = time.Minute * 10
+
 const loadBalancerTypeRPC = "rpc"
 const loadBalancerTypeIPFS = "ipfs"
 
+const networkConfigAccessListGeneration = "access_list_generation"
 const networkConfigBootnodes = "bootnodes"
 const networkConfigChain = "chain"
 const networkConfigChainspec = "chainspec"
 const networkConfigChainspecURL = "chainspec_url"
 const networkConfigChainspecABI = "chainspec_abi"
 const networkConfigChainspecABIURL = "chainspec_abi_url"
+const networkConfigConfirmationDepth = "confirmation_depth"
 const networkConfigEnv = "env"
+const networkConfigGasEstimateMultiplier = "gas_estimate_multiplier"
 const networkConfigJSONRPCURL = "json_rpc_url"
+const networkConfigMulticall3Address = "multicall3_address"
 const networkConfigJSONRPCPort = "json_rpc_port"
 const networkConfigNativeCurrency = "native_currency"
 const networkConfigNetworkID = "network_id"
+const networkConfigPendingTxTimeout = "pending_tx_timeout"
 const networkConfigPlatform = "platform"
 const networkConfigRPCAPIUser = "rpc_api_user"
 const networkConfigRPCAPIKey = "rpc_api_key"
@@ -678,6 +688,63 @@ func (n *Network) WebsocketURL() string {
 	return ""
 }
 
+// GasEstimateMultiplier returns the safety margin applied to gas estimates for txs broadcast to the network
+func (n *Network) GasEstimateMultiplier() float64 {
+	cfg := n.ParseConfig()
+	if multiplier, ok := cfg[networkConfigGasEstimateMultiplier].(float64); ok && multiplier >= 1 {
+		return multiplier
+	}
+	return defaultGasEstimateMultiplier
+}
+
+// AccessListGenerationEnabled returns true if EIP-2930 access lists should be generated via eth_createAccessList
+// for txs broadcast to the network which do not provide one
+func (n *Network) AccessListGenerationEnabled() bool {
+	cfg := n.ParseConfig()
+	if enabled, ok := cfg[networkConfigAccessListGeneration].(bool); ok {
+		return enabled
+	}
+	return false
+}
+
+// NativeCurrency returns the symbol of the native currency of the network, i.e. ETH
+func (n *Network) NativeCurrency() string {
+	cfg := n.ParseConfig()
+	if symbol, ok := cfg[networkConfigNativeCurrency].(string); ok {
+		return symbol
+	}
+	return ""
+}
+
+// ConfirmationDepth returns the number of blocks, including the block in which a tx was mined, which must be appended
+// to the chain before the tx is considered final
+func (n *Network) ConfirmationDepth() uint64 {
+	cfg := n.ParseConfig()
+	if depth, ok := cfg[networkConfigConfirmationDepth].(float64); ok && depth >= 1 {
+		return uint64(depth)
+	}
+	return defaultConfirmationDepth
+}
+
+// Multicall3Address returns the address of the Multicall3 contract used to aggregate reads into a single eth_call
+func (n *Network) Multicall3Address() string {
+	cfg := n.ParseConfig()
+	if addr, ok := cfg[networkConfigMulticall3Address].(string); ok && addr != "" {
+		return addr
+	}
+	return defaultMulticall3Address
+}
+
+// PendingTxTimeout returns the duration after which a tx broadcast to the network without a receipt is considered stuck;
+// configured in seconds
+func (n *Network) PendingTxTimeout() time.Duration {
+	cfg := n.ParseConfig()
+	if timeout, ok := cfg[networkConfigPendingTxTimeout].(float64); ok && timeout > 0 {
+		return time.Duration(timeout) * time.Second
+	}
+	return defaultPendingTxTimeout
+}
+
 // addPeer adds the given peer url to the network topology and notifies other peers of the new peer's existence
 func (n *Network) addPeer(peerURL string) error {
 	// FIXME: batch this so networks with lots of nodes still perform well
@@ -0,0 +1,157 @@
+package p2p
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/provideplatform/nchain/common"
+	provide "github.com/provideplatform/provide-go/api/nchain"
+	providecrypto "github.com/provideplatform/provide-go/crypto"
+)
+
+// TxCallTreeTracer is implemented by p2p providers which support tracing the call tree of a mined tx
+type TxCallTreeTracer interface {
+	TraceTxCallTree(hash string) (*CallFrame, error)
+}
+
+// evmTraceTxCallTree traces the call tree of the given tx using the geth callTracer
+func evmTraceTxCallTree(rpcClientKey, rpcURL, hash string) (*CallFrame, error) {
+	client, err := providecrypto.EVMResolveJsonRpcClient(rpcClientKey, rpcURL)
+	if err != nil {
+		return nil, err
+	}
+
+	var frame *CallFrame
+	err = client.CallContext(context.TODO(), &frame, "debug_traceTransaction", hash, map[string]interface{}{
+		"tracer": "callTracer",
+		"tracerConfig": map[string]interface{}{
+			"withLog": true,
+		},
+	})
+	if err != nil {
+		common.Log.Debugf("failed to trace call tree of tx %s; %s", hash, err.Error())
+		return nil, err
+	}
+
+	if frame == nil {
+		return nil, fmt.Errorf("no call tree traced for tx %s", hash)
+	}
+	return frame, nil
+}
+
+// evmParityTxCallTree traces the call tree of the given tx using the parity trace module
+func evmParityTxCallTree(rpcClientKey, rpcURL, hash string) (*CallFrame, error) {
+	traces, err := evmFetchTxTraces(rpcClientKey, rpcURL, hash)
+	if err != nil {
+		return nil, err
+	}
+
+	prvdTraces := &provide.TxTrace{}
+	rawTraces, _ := json.Marshal(traces)
+	json.Unmarshal(rawTraces, &prvdTraces)
+
+	return ParityCallTree(prvdTraces)
+}
+
+// ParityCallTree builds the call tree described by the given flat parity-style traces, in which the
+// position of each frame within the tree is given by its trace address
+func ParityCallTree(traces *provide.TxTrace) (*CallFrame, error) {
+	if traces == nil || len(traces.Result) == 0 {
+		return nil, fmt.Errorf("no traces to build call tree")
+	}
+
+	var root *CallFrame
+	for i, trace := range traces.Result {
+		frame := &CallFrame{
+			Type:   "CALL",
+			From:   stringOrEmpty(trace.Action.From),
+			To:     stringOrEmpty(trace.Action.To),
+			Output: decodeHexOrNil(trace.Result.Output),
+			Error:  stringOrEmpty(trace.Error),
+		}
+
+		if trace.Type != nil {
+			switch *trace.Type {
+			case "create":
+				frame.Type = "CREATE"
+				frame.To = stringOrEmpty(trace.Result.Address)
+				frame.Input = decodeHexOrNil(trace.Action.Init)
+				frame.Output = decodeHexOrNil(trace.Result.Code)
+			case "suicide":
+				frame.Type = "SELFDESTRUCT"
+			default:
+				if trace.Action.CallType != nil {
+					frame.Type = strings.ToUpper(*trace.Action.CallType)
+				}
+				frame.Input = decodeHexOrNil(trace.Action.Input)
+			}
+		}
+
+		if trace.Action.Value != nil {
+			if value, err := hexutil.DecodeBig(*trace.Action.Value); err == nil {
+				frame.Value = (*hexutil.Big)(value)
+			}
+		}
+		if trace.Action.Gas != nil {
+			if gas, err := hexutil.DecodeUint64(*trace.Action.Gas); err == nil {
+				frame.Gas = hexutil.Uint64(gas)
+			}
+		}
+		if trace.Result.GasUsed != nil {
+			if gasUsed, err := hexutil.DecodeUint64(*trace.Result.GasUsed); err == nil {
+				frame.GasUsed = hexutil.Uint64(gasUsed)
+			}
+		}
+
+		if len(trace.TraceAddress) == 0 {
+			if root != nil {
+				return nil, fmt.Errorf("multiple root frames in traces; trace %d", i)
+			}
+			root = frame
+			continue
+		}
+
+		if root == nil {
+			return nil, fmt.Errorf("trace %d precedes the root frame", i)
+		}
+
+		// traces are ordered depth-first, so the parent of each frame has been placed in the tree
+		parent := root
+		for _, idx := range trace.TraceAddress[:len(trace.TraceAddress)-1] {
+			position, positionOk := idx.(float64)
+			if !positionOk || int(position) < 0 || int(position) >= len(parent.Calls) {
+				return nil, fmt.Errorf("invalid trace address of trace %d", i)
+			}
+			parent = parent.Calls[int(position)]
+		}
+		parent.Calls = append(parent.Calls, frame)
+	}
+
+	if root == nil {
+		return nil, fmt.Errorf("no root frame in traces")
+	}
+	return root, nil
+}
+
+// stringOrEmpty dereferences the given string, or returns the empty string if it is nil
+func stringOrEmpty(str *string) string {
+	if str == nil {
+		return ""
+	}
+	return *str
+}
+
+// decodeHexOrNil decodes the given hex string, or returns nil if it is nil or invalid
+func decodeHexOrNil(str *string) hexutil.Bytes {
+	if str == nil {
+		return nil
+	}
+	data, err := hexutil.Decode(*str)
+	if err != nil {
+		return nil
+	}
+	return data
+}
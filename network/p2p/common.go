@@ -11,6 +11,7 @@ import (
 	"github.com/ethereum/go-ethereum"
 	"github.com/ethereum/go-ethereum/accounts/abi"
 	ethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/jinzhu/gorm"
 	uuid "github.com/kthomas/go.uuid"
@@ -83,6 +84,33 @@ type API interface {
 	EnrichStartCommand(bootnodes []string) []string
 }
 
+// CallTracer is implemented by p2p providers which support tracing ephemeral calls (i.e., debug_traceCall)
+type CallTracer interface {
+	TraceCall(call map[string]interface{}, block string) (*CallFrame, error)
+}
+
+// CallFrame is a single frame of the call tree produced by the geth callTracer
+type CallFrame struct {
+	Type    string         `json:"type"`
+	From    string         `json:"from"`
+	To      string         `json:"to,omitempty"`
+	Value   *hexutil.Big   `json:"value,omitempty"`
+	Gas     hexutil.Uint64 `json:"gas"`
+	GasUsed hexutil.Uint64 `json:"gasUsed"`
+	Input   hexutil.Bytes  `json:"input"`
+	Output  hexutil.Bytes  `json:"output,omitempty"`
+	Error   string         `json:"error,omitempty"`
+	Logs    []*CallLog     `json:"logs,omitempty"`
+	Calls   []*CallFrame   `json:"calls,omitempty"`
+}
+
+// CallLog is a log emitted within a call frame
+type CallLog struct {
+	Address ethcommon.Address `json:"address"`
+	Topics  []ethcommon.Hash  `json:"topics"`
+	Data    hexutil.Bytes     `json:"data"`
+}
+
 func evmFetchTxReceipt(rpcClientKey, rpcURL, signerAddress, hash string) (*types.Receipt, error) {
 	receipt, err := providecrypto.EVMGetTxReceipt(rpcClientKey, rpcURL, hash, signerAddress)
 	if err != nil {
@@ -101,6 +129,27 @@ func evmFetchTxTraces(rpcClientKey, rpcURL, hash string) (*provide.EthereumTxTra
 	return traces.(*provide.EthereumTxTraceResponse), nil
 }
 
+func evmTraceCall(rpcClientKey, rpcURL string, call map[string]interface{}, block string) (*CallFrame, error) {
+	client, err := providecrypto.EVMResolveJsonRpcClient(rpcClientKey, rpcURL)
+	if err != nil {
+		return nil, err
+	}
+
+	var frame *CallFrame
+	err = client.CallContext(context.TODO(), &frame, "debug_traceCall", call, block, map[string]interface{}{
+		"tracer": "callTracer",
+		"tracerConfig": map[string]interface{}{
+			"withLog": true,
+		},
+	})
+	if err != nil {
+		common.Log.Debugf("failed to trace call; %s", err.Error())
+		return nil, err
+	}
+
+	return frame, nil
+}
+
 func evmResolveTokenContract(
 	rpcClientKey, rpcURL string,
 	artifact *provide.CompiledArtifact,
@@ -137,6 +137,11 @@ func (p *GethP2PProvider) FetchTxTraces(hash string) (*provide.TxTrace, error) {
 	return prvdTraces, nil
 }
 
+// TraceTxCallTree traces the call tree of the given mined tx using the callTracer
+func (p *GethP2PProvider) TraceTxCallTree(hash string) (*CallFrame, error) {
+	return evmTraceTxCallTree(p.networkID, *p.rpcURL, hash)
+}
+
 // AcceptNonReservedPeers allows non-reserved peers to connect
 func (p *GethP2PProvider) AcceptNonReservedPeers() error {
 	return errors.New("geth does not implement AcceptNonReservedPeers()")
@@ -153,6 +158,11 @@ func (p *GethP2PProvider) AddPeer(peerURL string) error {
 	return providecrypto.EVMInvokeJsonRpcClient(*p.rpcClientKey, *p.rpcURL, "admin_addPeer", []interface{}{peerURL}, &resp)
 }
 
+// TraceCall traces the given ephemeral call at the given block using the callTracer
+func (p *GethP2PProvider) TraceCall(call map[string]interface{}, block string) (*CallFrame, error) {
+	return evmTraceCall(p.networkID, *p.rpcURL, call, block)
+}
+
 // FormatBootnodes formats the given peer urls as a valid bootnodes param
 func (p *GethP2PProvider) FormatBootnodes(bootnodes []string) string {
 	return strings.Join(bootnodes, ",")
@@ -84,6 +84,11 @@ func (p *NethermindP2PProvider) FetchTxTraces(hash string) (*provide.TxTrace, er
 	return prvdTraces, nil
 }
 
+// TraceTxCallTree traces the call tree of the given mined tx using the trace module
+func (p *NethermindP2PProvider) TraceTxCallTree(hash string) (*CallFrame, error) {
+	return evmParityTxCallTree(p.networkID, *p.rpcURL, hash)
+}
+
 // AcceptNonReservedPeers allows non-reserved peers to connect
 func (p *NethermindP2PProvider) AcceptNonReservedPeers() error {
 	return errors.New("nethermind p2p client does not impl AcceptNonReservedPeers()")
@@ -104,6 +104,11 @@ func (p *ParityP2PProvider) EnrichStartCommand(bootnodes []string) []string {
 	return cmd
 }
 
+// TraceTxCallTree traces the call tree of the given mined tx using the trace module
+func (p *ParityP2PProvider) TraceTxCallTree(hash string) (*CallFrame, error) {
+	return evmParityTxCallTree(p.networkID, *p.rpcURL, hash)
+}
+
 // AcceptNonReservedPeers allows non-reserved peers to connect
 func (p *ParityP2PProvider) AcceptNonReservedPeers() error {
 	var resp interface{}
@@ -153,12 +153,22 @@ func (p *QuorumP2PProvider) FetchTxTraces(hash string) (*provide.TxTrace, error)
 	return prvdTraces, nil
 }
 
+// TraceTxCallTree traces the call tree of the given mined tx using the callTracer
+func (p *QuorumP2PProvider) TraceTxCallTree(hash string) (*CallFrame, error) {
+	return evmTraceTxCallTree(p.networkID, *p.rpcURL, hash)
+}
+
 // AddPeer adds a peer by its peer url
 func (p *QuorumP2PProvider) AddPeer(peerURL string) error {
 	var resp interface{}
 	return providecrypto.EVMInvokeJsonRpcClient(*p.rpcClientKey, *p.rpcURL, "admin_addPeer", []interface{}{peerURL}, &resp)
 }
 
+// TraceCall traces the given ephemeral call at the given block using the callTracer
+func (p *QuorumP2PProvider) TraceCall(call map[string]interface{}, block string) (*CallFrame, error) {
+	return evmTraceCall(p.networkID, *p.rpcURL, call, block)
+}
+
 // FormatBootnodes formats the given peer urls as a valid bootnodes param
 func (p *QuorumP2PProvider) FormatBootnodes(bootnodes []string) string {
 	return strings.Join(bootnodes, ",")
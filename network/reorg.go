@@ -0,0 +1,111 @@
+package network
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/jinzhu/gorm"
+	natsutil "github.com/kthomas/go-natsutil"
+	"github.com/provideplatform/nchain/common"
+	provide "github.com/provideplatform/provide-go/crypto"
+)
+
+// maxReorgDepth is the maximum number of ancestors of a finalized block which are checked for a chain reorganization
+const maxReorgDepth = 64
+
+const natsTxConfirmSubject = "nchain.tx.confirm"
+const natsTxReorgSubject = "nchain.tx.reorg"
+
+// detectReorg compares the given canonical block and its ancestry against the blocks previously finalized
+// on the network; previously-finalized blocks which are no longer canonical are returned, highest first
+func (n *Network) detectReorg(db *gorm.DB, number uint64, hash, parentHash string) ([]*Block, error) {
+	orphaned := make([]*Block, 0)
+
+	height := number
+	canonicalHash := hash
+	canonicalParentHash := parentHash
+
+	for depth := 0; depth < maxReorgDepth; depth++ {
+		var blocks []*Block
+		db.Where("network_id = ? AND block = ? AND hash != ?", n.ID, height, canonicalHash).Find(&blocks)
+		if len(blocks) == 0 && depth > 0 {
+			break // the ancestor is canonical or was never finalized
+		}
+		orphaned = append(orphaned, blocks...)
+
+		if depth > 0 {
+			// the ancestor was orphaned; resolve its canonical parent to continue walking the ancestry
+			_parentHash, err := n.canonicalParentHash(height)
+			if err != nil {
+				return orphaned, err
+			}
+			canonicalParentHash = _parentHash
+		}
+
+		if height == 0 || canonicalParentHash == "" {
+			break
+		}
+
+		height--
+		canonicalHash = canonicalParentHash
+	}
+
+	return orphaned, nil
+}
+
+// canonicalParentHash returns the parent hash of the canonical block at the given height
+func (n *Network) canonicalParentHash(height uint64) (string, error) {
+	block, err := provide.EVMGetBlockByNumber(n.ID.String(), n.RPCURL(), height)
+	if err != nil {
+		return "", fmt.Errorf("failed to fetch block %d; %s", height, err.Error())
+	}
+
+	if result, resultOk := block.Result.(map[string]interface{}); resultOk {
+		if parentHash, parentHashOk := result["parentHash"].(string); parentHashOk {
+			return parentHash, nil
+		}
+	}
+
+	return "", nil
+}
+
+// handleReorg removes the orphaned blocks and notifies the tx package so the txs mined in the orphaned
+// blocks are reverted to pending and re-resolved against the canonical chain
+func (n *Network) handleReorg(db *gorm.DB, orphaned []*Block) error {
+	for _, block := range orphaned {
+		common.Log.Warningf("chain reorganization detected on network: %s; orphaned block %d (hash: %s)", n.ID, block.Block, block.Hash)
+
+		payload, _ := json.Marshal(map[string]interface{}{
+			"network_id": n.ID.String(),
+			"block":      block.Block,
+			"hash":       block.Hash,
+		})
+		_, err := natsutil.NatsJetstreamPublish(natsTxReorgSubject, payload)
+		if err != nil {
+			return fmt.Errorf("failed to publish orphaned block %d on subject %s; %s", block.Block, natsTxReorgSubject, err.Error())
+		}
+
+		db.Delete(block)
+	}
+
+	return nil
+}
+
+// confirmTxs notifies the tx package to finalize the txs which have the number of confirmations
+// required by the network now that the given block has been finalized
+func (n *Network) confirmTxs(number uint64) error {
+	if n.ConfirmationDepth() <= 1 {
+		return nil // txs are finalized as soon as the block in which they were mined is finalized
+	}
+
+	payload, _ := json.Marshal(map[string]interface{}{
+		"network_id": n.ID.String(),
+		"block":      number,
+	})
+	_, err := natsutil.NatsJetstreamPublish(natsTxConfirmSubject, payload)
+	if err != nil {
+		return fmt.Errorf("failed to publish confirmed block %d on subject %s; %s", number, natsTxConfirmSubject, err.Error())
+	}
+
+	return nil
+}
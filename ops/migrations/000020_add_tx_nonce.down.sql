@@ -0,0 +1,2 @@
+DROP INDEX idx_transactions_network_id_nonce;
+ALTER TABLE ONLY transactions DROP COLUMN nonce;
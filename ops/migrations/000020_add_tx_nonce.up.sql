@@ -0,0 +1,2 @@
+ALTER TABLE ONLY transactions ADD COLUMN nonce int8;
+CREATE INDEX idx_transactions_network_id_nonce ON transactions USING btree (network_id, nonce);
@@ -0,0 +1,3 @@
+ALTER TABLE ONLY transactions DROP CONSTRAINT transactions_replaced_by_id_transactions_id_foreign;
+DROP INDEX idx_transactions_replaced_by_id;
+ALTER TABLE ONLY transactions DROP COLUMN replaced_by_id;
@@ -0,0 +1,5 @@
+ALTER TABLE ONLY transactions ADD COLUMN replaced_by_id uuid;
+CREATE INDEX idx_transactions_replaced_by_id ON transactions USING btree (replaced_by_id);
+
+ALTER TABLE ONLY transactions
+    ADD CONSTRAINT transactions_replaced_by_id_transactions_id_foreign FOREIGN KEY (replaced_by_id) REFERENCES transactions(id) ON UPDATE CASCADE ON DELETE SET NULL;
@@ -0,0 +1 @@
+ALTER TABLE ONLY transactions DROP COLUMN failure_reason;
@@ -0,0 +1 @@
+ALTER TABLE ONLY transactions ADD COLUMN failure_reason json;
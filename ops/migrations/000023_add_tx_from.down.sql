@@ -0,0 +1,2 @@
+DROP INDEX idx_transactions_from;
+ALTER TABLE ONLY transactions DROP COLUMN "from";
@@ -0,0 +1,2 @@
+ALTER TABLE ONLY transactions ADD COLUMN "from" varchar(64);
+CREATE INDEX idx_transactions_from ON transactions USING btree ("from");
@@ -0,0 +1,3 @@
+DROP INDEX idx_transactions_user_id_ref;
+DROP INDEX idx_transactions_organization_id_ref;
+DROP INDEX idx_transactions_application_id_ref;
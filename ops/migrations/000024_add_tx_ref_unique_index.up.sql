@@ -0,0 +1,25 @@
+DO $$
+DECLARE
+  duplicates bigint;
+BEGIN
+  SELECT count(*) INTO duplicates FROM (
+    SELECT application_id, ref FROM transactions WHERE application_id IS NOT NULL AND ref IS NOT NULL
+    GROUP BY application_id, ref HAVING count(*) > 1
+    UNION ALL
+    SELECT organization_id, ref FROM transactions WHERE application_id IS NULL AND organization_id IS NOT NULL AND ref IS NOT NULL
+    GROUP BY organization_id, ref HAVING count(*) > 1
+    UNION ALL
+    SELECT user_id, ref FROM transactions WHERE application_id IS NULL AND organization_id IS NULL AND user_id IS NOT NULL AND ref IS NOT NULL
+    GROUP BY user_id, ref HAVING count(*) > 1
+  ) AS dup;
+
+  IF duplicates > 0 THEN
+    RAISE EXCEPTION 'unable to enforce unique tx refs; % ref(s) are shared by more than one tx of the same application, organization or user', duplicates
+      USING HINT = 'resolve the duplicate refs of the transactions table, i.e. by clearing or renaming the refs of all but one tx sharing each ref, and rerun the migration';
+  END IF;
+END
+$$;
+
+CREATE UNIQUE INDEX idx_transactions_application_id_ref ON transactions USING btree (application_id, ref) WHERE application_id IS NOT NULL AND ref IS NOT NULL;
+CREATE UNIQUE INDEX idx_transactions_organization_id_ref ON transactions USING btree (organization_id, ref) WHERE application_id IS NULL AND organization_id IS NOT NULL AND ref IS NOT NULL;
+CREATE UNIQUE INDEX idx_transactions_user_id_ref ON transactions USING btree (user_id, ref) WHERE application_id IS NULL AND organization_id IS NULL AND user_id IS NOT NULL AND ref IS NOT NULL;
@@ -0,0 +1 @@
+ALTER TABLE ONLY transactions ADD COLUMN raw text;
@@ -0,0 +1,43 @@
+CREATE TABLE public.webhooks (
+    id uuid DEFAULT public.uuid_generate_v4() NOT NULL,
+    created_at timestamp with time zone DEFAULT now() NOT NULL,
+    application_id uuid,
+    organization_id uuid,
+    url text NOT NULL,
+    secret text NOT NULL,
+    events json,
+    enabled boolean DEFAULT true NOT NULL
+);
+
+ALTER TABLE public.webhooks OWNER TO current_user;
+
+ALTER TABLE ONLY public.webhooks
+    ADD CONSTRAINT webhooks_pkey PRIMARY KEY (id);
+
+CREATE INDEX idx_webhooks_application_id ON public.webhooks USING btree (application_id);
+CREATE INDEX idx_webhooks_organization_id ON public.webhooks USING btree (organization_id);
+
+CREATE TABLE public.webhook_deliveries (
+    id uuid DEFAULT public.uuid_generate_v4() NOT NULL,
+    created_at timestamp with time zone DEFAULT now() NOT NULL,
+    webhook_id uuid NOT NULL,
+    event_id uuid NOT NULL,
+    event text NOT NULL,
+    attempt integer NOT NULL,
+    payload json,
+    status_code integer,
+    error text,
+    duration bigint,
+    delivered_at timestamp with time zone
+);
+
+ALTER TABLE public.webhook_deliveries OWNER TO current_user;
+
+ALTER TABLE ONLY public.webhook_deliveries
+    ADD CONSTRAINT webhook_deliveries_pkey PRIMARY KEY (id);
+
+CREATE INDEX idx_webhook_deliveries_webhook_id ON public.webhook_deliveries USING btree (webhook_id);
+CREATE INDEX idx_webhook_deliveries_event_id ON public.webhook_deliveries USING btree (event_id);
+
+ALTER TABLE ONLY public.webhook_deliveries
+    ADD CONSTRAINT webhook_deliveries_webhook_id_webhooks_id_foreign FOREIGN KEY (webhook_id) REFERENCES public.webhooks(id) ON UPDATE CASCADE ON DELETE CASCADE;
@@ -0,0 +1,2 @@
+ALTER TABLE ONLY transactions DROP COLUMN batch_id;
+DROP TABLE public.transaction_batches;
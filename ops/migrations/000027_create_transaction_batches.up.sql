@@ -0,0 +1,28 @@
+CREATE TABLE public.transaction_batches (
+    id uuid DEFAULT public.uuid_generate_v4() NOT NULL,
+    created_at timestamp with time zone DEFAULT now() NOT NULL,
+    application_id uuid,
+    organization_id uuid,
+    user_id uuid,
+    contract_id uuid,
+    size integer NOT NULL,
+    refs json
+);
+
+ALTER TABLE public.transaction_batches OWNER TO current_user;
+
+ALTER TABLE ONLY public.transaction_batches
+    ADD CONSTRAINT transaction_batches_pkey PRIMARY KEY (id);
+
+CREATE INDEX idx_transaction_batches_application_id ON public.transaction_batches USING btree (application_id);
+CREATE INDEX idx_transaction_batches_organization_id ON public.transaction_batches USING btree (organization_id);
+CREATE INDEX idx_transaction_batches_user_id ON public.transaction_batches USING btree (user_id);
+
+ALTER TABLE ONLY public.transaction_batches
+    ADD CONSTRAINT transaction_batches_contract_id_contracts_id_foreign FOREIGN KEY (contract_id) REFERENCES public.contracts(id) ON UPDATE CASCADE ON DELETE SET NULL;
+
+ALTER TABLE ONLY transactions ADD COLUMN batch_id uuid;
+CREATE INDEX idx_transactions_batch_id ON transactions USING btree (batch_id);
+
+ALTER TABLE ONLY transactions
+    ADD CONSTRAINT transactions_batch_id_transaction_batches_id_foreign FOREIGN KEY (batch_id) REFERENCES transaction_batches(id) ON UPDATE CASCADE ON DELETE SET NULL;
@@ -0,0 +1 @@
+DROP TABLE public.scheduled_transactions;
@@ -0,0 +1,33 @@
+CREATE TABLE public.scheduled_transactions (
+    id uuid DEFAULT public.uuid_generate_v4() NOT NULL,
+    created_at timestamp with time zone DEFAULT now() NOT NULL,
+    application_id uuid,
+    organization_id uuid,
+    user_id uuid,
+    network_id uuid NOT NULL,
+    contract_id uuid,
+    ref text NOT NULL,
+    status varchar(32) DEFAULT 'scheduled' NOT NULL,
+    scheduled_at timestamp with time zone,
+    not_before_block bigint,
+    payload json,
+    published_at timestamp with time zone,
+    canceled_at timestamp with time zone
+);
+
+ALTER TABLE public.scheduled_transactions OWNER TO current_user;
+
+ALTER TABLE ONLY public.scheduled_transactions
+    ADD CONSTRAINT scheduled_transactions_pkey PRIMARY KEY (id);
+
+CREATE INDEX idx_scheduled_transactions_application_id ON public.scheduled_transactions USING btree (application_id);
+CREATE INDEX idx_scheduled_transactions_organization_id ON public.scheduled_transactions USING btree (organization_id);
+CREATE INDEX idx_scheduled_transactions_user_id ON public.scheduled_transactions USING btree (user_id);
+CREATE INDEX idx_scheduled_transactions_network_id_ref ON public.scheduled_transactions USING btree (network_id, ref);
+CREATE INDEX idx_scheduled_transactions_status_scheduled_at ON public.scheduled_transactions USING btree (status, scheduled_at);
+
+ALTER TABLE ONLY public.scheduled_transactions
+    ADD CONSTRAINT scheduled_transactions_network_id_networks_id_foreign FOREIGN KEY (network_id) REFERENCES public.networks(id) ON UPDATE CASCADE ON DELETE CASCADE;
+
+ALTER TABLE ONLY public.scheduled_transactions
+    ADD CONSTRAINT scheduled_transactions_contract_id_contracts_id_foreign FOREIGN KEY (contract_id) REFERENCES public.contracts(id) ON UPDATE CASCADE ON DELETE SET NULL;
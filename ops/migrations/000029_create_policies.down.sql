@@ -0,0 +1,3 @@
+DROP INDEX idx_transactions_wallet_id_created_at;
+DROP INDEX idx_transactions_account_id_created_at;
+DROP TABLE public.policies;
@@ -0,0 +1,34 @@
+CREATE TABLE public.policies (
+    id uuid DEFAULT public.uuid_generate_v4() NOT NULL,
+    created_at timestamp with time zone DEFAULT now() NOT NULL,
+    account_id uuid,
+    wallet_id uuid,
+    name text,
+    enabled boolean DEFAULT true NOT NULL,
+    max_value text,
+    daily_limit text,
+    allowed_addresses json,
+    allowed_methods json,
+    window_start varchar(5),
+    window_end varchar(5),
+    timezone text,
+    approval_threshold text,
+    required_approvals integer
+);
+
+ALTER TABLE public.policies OWNER TO current_user;
+
+ALTER TABLE ONLY public.policies
+    ADD CONSTRAINT policies_pkey PRIMARY KEY (id);
+
+CREATE INDEX idx_policies_account_id ON public.policies USING btree (account_id);
+CREATE INDEX idx_policies_wallet_id ON public.policies USING btree (wallet_id);
+
+ALTER TABLE ONLY public.policies
+    ADD CONSTRAINT policies_account_id_accounts_id_foreign FOREIGN KEY (account_id) REFERENCES public.accounts(id) ON UPDATE CASCADE ON DELETE CASCADE;
+
+ALTER TABLE ONLY public.policies
+    ADD CONSTRAINT policies_wallet_id_wallets_id_foreign FOREIGN KEY (wallet_id) REFERENCES public.wallets(id) ON UPDATE CASCADE ON DELETE CASCADE;
+
+CREATE INDEX idx_transactions_account_id_created_at ON public.transactions USING btree (account_id, created_at);
+CREATE INDEX idx_transactions_wallet_id_created_at ON public.transactions USING btree (wallet_id, created_at);
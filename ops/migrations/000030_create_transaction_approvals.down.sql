@@ -0,0 +1,8 @@
+DROP TABLE public.transaction_approvals;
+
+DROP INDEX idx_transactions_status_approval_expires_at;
+ALTER TABLE ONLY transactions DROP COLUMN approval_expires_at;
+ALTER TABLE ONLY transactions DROP COLUMN approval_requirements;
+
+ALTER TABLE ONLY policies DROP COLUMN approval_timeout;
+ALTER TABLE ONLY policies DROP COLUMN approvers;
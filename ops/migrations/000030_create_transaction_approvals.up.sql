@@ -0,0 +1,25 @@
+ALTER TABLE ONLY policies ADD COLUMN approvers json;
+ALTER TABLE ONLY policies ADD COLUMN approval_timeout bigint;
+
+ALTER TABLE ONLY transactions ADD COLUMN approval_requirements json;
+ALTER TABLE ONLY transactions ADD COLUMN approval_expires_at timestamp with time zone;
+CREATE INDEX idx_transactions_status_approval_expires_at ON transactions USING btree (status, approval_expires_at);
+
+CREATE TABLE public.transaction_approvals (
+    id uuid DEFAULT public.uuid_generate_v4() NOT NULL,
+    created_at timestamp with time zone DEFAULT now() NOT NULL,
+    transaction_id uuid NOT NULL,
+    user_id uuid NOT NULL,
+    approved boolean NOT NULL,
+    comment text
+);
+
+ALTER TABLE public.transaction_approvals OWNER TO current_user;
+
+ALTER TABLE ONLY public.transaction_approvals
+    ADD CONSTRAINT transaction_approvals_pkey PRIMARY KEY (id);
+
+CREATE UNIQUE INDEX idx_transaction_approvals_transaction_id_user_id ON public.transaction_approvals USING btree (transaction_id, user_id);
+
+ALTER TABLE ONLY public.transaction_approvals
+    ADD CONSTRAINT transaction_approvals_transaction_id_transactions_id_foreign FOREIGN KEY (transaction_id) REFERENCES public.transactions(id) ON UPDATE CASCADE ON DELETE CASCADE;
@@ -0,0 +1,5 @@
+ALTER TABLE ONLY accounts ALTER COLUMN key_id SET NOT NULL;
+ALTER TABLE ONLY accounts ALTER COLUMN vault_id SET NOT NULL;
+
+ALTER TABLE ONLY accounts DROP COLUMN encrypted_config;
+ALTER TABLE ONLY accounts DROP COLUMN config;
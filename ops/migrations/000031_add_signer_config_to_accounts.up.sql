@@ -0,0 +1,5 @@
+ALTER TABLE ONLY accounts ADD COLUMN config json;
+ALTER TABLE ONLY accounts ADD COLUMN encrypted_config bytea;
+
+ALTER TABLE ONLY accounts ALTER COLUMN vault_id DROP NOT NULL;
+ALTER TABLE ONLY accounts ALTER COLUMN key_id DROP NOT NULL;
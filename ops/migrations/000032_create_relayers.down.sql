@@ -0,0 +1,7 @@
+ALTER TABLE ONLY transactions DROP CONSTRAINT transactions_relayer_id_relayers_id_foreign;
+DROP INDEX idx_transactions_relayer_id;
+ALTER TABLE ONLY transactions DROP COLUMN relayed_at;
+ALTER TABLE ONLY transactions DROP COLUMN relayer_gas_reserved;
+ALTER TABLE ONLY transactions DROP COLUMN relayer_id;
+
+DROP TABLE public.relayers;
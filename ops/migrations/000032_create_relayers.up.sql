@@ -0,0 +1,37 @@
+CREATE TABLE public.relayers (
+    id uuid DEFAULT public.uuid_generate_v4() NOT NULL,
+    created_at timestamp with time zone DEFAULT now() NOT NULL,
+    application_id uuid NOT NULL,
+    network_id uuid NOT NULL,
+    account_id uuid NOT NULL,
+    name text,
+    enabled boolean NOT NULL,
+    forwarder_address text NOT NULL,
+    forwarder_name text,
+    forwarder_version text,
+    gas_budget bigint,
+    budget_period text,
+    gas_used bigint DEFAULT 0 NOT NULL,
+    period_started_at timestamp with time zone
+);
+
+ALTER TABLE public.relayers OWNER TO current_user;
+
+ALTER TABLE ONLY public.relayers
+    ADD CONSTRAINT relayers_pkey PRIMARY KEY (id);
+
+CREATE INDEX idx_relayers_application_id_network_id ON public.relayers USING btree (application_id, network_id);
+
+ALTER TABLE ONLY public.relayers
+    ADD CONSTRAINT relayers_network_id_networks_id_foreign FOREIGN KEY (network_id) REFERENCES public.networks(id) ON UPDATE CASCADE ON DELETE CASCADE;
+
+ALTER TABLE ONLY public.relayers
+    ADD CONSTRAINT relayers_account_id_accounts_id_foreign FOREIGN KEY (account_id) REFERENCES public.accounts(id) ON UPDATE CASCADE ON DELETE CASCADE;
+
+ALTER TABLE ONLY transactions ADD COLUMN relayer_id uuid;
+ALTER TABLE ONLY transactions ADD COLUMN relayer_gas_reserved bigint;
+ALTER TABLE ONLY transactions ADD COLUMN relayed_at timestamp with time zone;
+CREATE INDEX idx_transactions_relayer_id ON transactions USING btree (relayer_id);
+
+ALTER TABLE ONLY transactions
+    ADD CONSTRAINT transactions_relayer_id_relayers_id_foreign FOREIGN KEY (relayer_id) REFERENCES public.relayers(id) ON UPDATE CASCADE ON DELETE SET NULL;
@@ -0,0 +1 @@
+DROP TABLE public.transaction_internal_transfers;
@@ -0,0 +1,28 @@
+CREATE TABLE public.transaction_internal_transfers (
+    id uuid DEFAULT public.uuid_generate_v4() NOT NULL,
+    created_at timestamp with time zone DEFAULT now() NOT NULL,
+    transaction_id uuid NOT NULL,
+    network_id uuid NOT NULL,
+    block bigint,
+    type varchar(32) NOT NULL,
+    from_address varchar(64) NOT NULL,
+    to_address varchar(64) NOT NULL,
+    value numeric NOT NULL,
+    depth integer NOT NULL,
+    trace_address text NOT NULL
+);
+
+ALTER TABLE public.transaction_internal_transfers OWNER TO current_user;
+
+ALTER TABLE ONLY public.transaction_internal_transfers
+    ADD CONSTRAINT transaction_internal_transfers_pkey PRIMARY KEY (id);
+
+CREATE INDEX idx_transaction_internal_transfers_transaction_id ON public.transaction_internal_transfers USING btree (transaction_id);
+CREATE INDEX idx_transaction_internal_transfers_network_id_from_address ON public.transaction_internal_transfers USING btree (network_id, lower(from_address));
+CREATE INDEX idx_transaction_internal_transfers_network_id_to_address ON public.transaction_internal_transfers USING btree (network_id, lower(to_address));
+
+ALTER TABLE ONLY public.transaction_internal_transfers
+    ADD CONSTRAINT transaction_internal_transfers_transaction_id_transactions_id_foreign FOREIGN KEY (transaction_id) REFERENCES public.transactions(id) ON UPDATE CASCADE ON DELETE CASCADE;
+
+ALTER TABLE ONLY public.transaction_internal_transfers
+    ADD CONSTRAINT transaction_internal_transfers_network_id_networks_id_foreign FOREIGN KEY (network_id) REFERENCES public.networks(id) ON UPDATE CASCADE ON DELETE CASCADE;
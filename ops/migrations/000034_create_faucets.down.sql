@@ -0,0 +1,2 @@
+DROP TABLE public.faucet_drips;
+DROP TABLE public.faucets;
@@ -0,0 +1,56 @@
+CREATE TABLE public.faucets (
+    id uuid DEFAULT public.uuid_generate_v4() NOT NULL,
+    created_at timestamp with time zone DEFAULT now() NOT NULL,
+    network_id uuid NOT NULL,
+    user_id uuid,
+    account_id uuid NOT NULL,
+    enabled boolean NOT NULL,
+    drip_value numeric NOT NULL,
+    address_cooldown bigint,
+    application_cooldown bigint,
+    address_daily_cap bigint,
+    application_daily_cap bigint
+);
+
+ALTER TABLE public.faucets OWNER TO current_user;
+
+ALTER TABLE ONLY public.faucets
+    ADD CONSTRAINT faucets_pkey PRIMARY KEY (id);
+
+CREATE UNIQUE INDEX idx_faucets_network_id ON public.faucets USING btree (network_id);
+
+ALTER TABLE ONLY public.faucets
+    ADD CONSTRAINT faucets_network_id_networks_id_foreign FOREIGN KEY (network_id) REFERENCES public.networks(id) ON UPDATE CASCADE ON DELETE CASCADE;
+
+ALTER TABLE ONLY public.faucets
+    ADD CONSTRAINT faucets_account_id_accounts_id_foreign FOREIGN KEY (account_id) REFERENCES public.accounts(id) ON UPDATE CASCADE ON DELETE CASCADE;
+
+CREATE TABLE public.faucet_drips (
+    id uuid DEFAULT public.uuid_generate_v4() NOT NULL,
+    created_at timestamp with time zone DEFAULT now() NOT NULL,
+    faucet_id uuid NOT NULL,
+    network_id uuid NOT NULL,
+    application_id uuid,
+    organization_id uuid,
+    user_id uuid,
+    transaction_id uuid,
+    beneficiary text NOT NULL,
+    value numeric NOT NULL,
+    status text NOT NULL,
+    description text
+);
+
+ALTER TABLE public.faucet_drips OWNER TO current_user;
+
+ALTER TABLE ONLY public.faucet_drips
+    ADD CONSTRAINT faucet_drips_pkey PRIMARY KEY (id);
+
+CREATE INDEX idx_faucet_drips_network_id_created_at ON public.faucet_drips USING btree (network_id, created_at);
+CREATE INDEX idx_faucet_drips_application_id ON public.faucet_drips USING btree (application_id);
+CREATE INDEX idx_faucet_drips_beneficiary ON public.faucet_drips USING btree (lower(beneficiary));
+
+ALTER TABLE ONLY public.faucet_drips
+    ADD CONSTRAINT faucet_drips_faucet_id_faucets_id_foreign FOREIGN KEY (faucet_id) REFERENCES public.faucets(id) ON UPDATE CASCADE ON DELETE CASCADE;
+
+ALTER TABLE ONLY public.faucet_drips
+    ADD CONSTRAINT faucet_drips_transaction_id_transactions_id_foreign FOREIGN KEY (transaction_id) REFERENCES public.transactions(id) ON UPDATE CASCADE ON DELETE SET NULL;
@@ -0,0 +1,5 @@
+DROP TABLE public.gas_spend_daily;
+
+ALTER TABLE ONLY transactions DROP COLUMN fee_paid;
+ALTER TABLE ONLY transactions DROP COLUMN effective_gas_price;
+ALTER TABLE ONLY transactions DROP COLUMN gas_used;
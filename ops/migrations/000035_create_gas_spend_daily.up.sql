@@ -0,0 +1,30 @@
+ALTER TABLE ONLY transactions ADD COLUMN gas_used bigint;
+ALTER TABLE ONLY transactions ADD COLUMN effective_gas_price numeric;
+ALTER TABLE ONLY transactions ADD COLUMN fee_paid numeric;
+
+CREATE TABLE public.gas_spend_daily (
+    id uuid DEFAULT public.uuid_generate_v4() NOT NULL,
+    created_at timestamp with time zone DEFAULT now() NOT NULL,
+    day date NOT NULL,
+    network_id uuid NOT NULL,
+    application_id uuid NOT NULL,
+    organization_id uuid NOT NULL,
+    account_id uuid NOT NULL,
+    tx_count bigint DEFAULT 0 NOT NULL,
+    gas_used numeric DEFAULT 0 NOT NULL,
+    fee_paid numeric DEFAULT 0 NOT NULL
+);
+
+ALTER TABLE public.gas_spend_daily OWNER TO current_user;
+
+ALTER TABLE ONLY public.gas_spend_daily
+    ADD CONSTRAINT gas_spend_daily_pkey PRIMARY KEY (id);
+
+-- application, organization and account ids are the nil uuid when the tx was not associated with one
+CREATE UNIQUE INDEX idx_gas_spend_daily_day_network_id_application_id_organization_id_account_id ON public.gas_spend_daily USING btree (day, network_id, application_id, organization_id, account_id);
+CREATE INDEX idx_gas_spend_daily_network_id_day ON public.gas_spend_daily USING btree (network_id, day);
+CREATE INDEX idx_gas_spend_daily_application_id_day ON public.gas_spend_daily USING btree (application_id, day);
+CREATE INDEX idx_gas_spend_daily_organization_id_day ON public.gas_spend_daily USING btree (organization_id, day);
+
+ALTER TABLE ONLY public.gas_spend_daily
+    ADD CONSTRAINT gas_spend_daily_network_id_networks_id_foreign FOREIGN KEY (network_id) REFERENCES public.networks(id) ON UPDATE CASCADE ON DELETE CASCADE;
@@ -0,0 +1 @@
+ALTER TABLE ONLY transactions ADD COLUMN access_list json;
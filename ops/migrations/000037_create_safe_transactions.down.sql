@@ -0,0 +1,2 @@
+DROP TABLE public.safe_transaction_confirmations;
+DROP TABLE public.safe_transactions;
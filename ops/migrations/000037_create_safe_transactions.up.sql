@@ -0,0 +1,67 @@
+CREATE TABLE public.safe_transactions (
+    id uuid DEFAULT public.uuid_generate_v4() NOT NULL,
+    created_at timestamp with time zone DEFAULT now() NOT NULL,
+    network_id uuid NOT NULL,
+    application_id uuid,
+    organization_id uuid,
+    user_id uuid,
+    contract_id uuid,
+    account_id uuid,
+    safe_address text NOT NULL,
+    "to" text NOT NULL,
+    value numeric NOT NULL,
+    data text,
+    operation smallint NOT NULL,
+    safe_tx_gas bigint NOT NULL,
+    base_gas bigint NOT NULL,
+    gas_price numeric NOT NULL,
+    gas_token text NOT NULL,
+    refund_receiver text NOT NULL,
+    safe_nonce bigint NOT NULL,
+    safe_tx_hash text NOT NULL,
+    threshold bigint NOT NULL,
+    confirmations bigint DEFAULT 0 NOT NULL,
+    status text NOT NULL,
+    description text,
+    transaction_id uuid
+);
+
+ALTER TABLE public.safe_transactions OWNER TO current_user;
+
+ALTER TABLE ONLY public.safe_transactions
+    ADD CONSTRAINT safe_transactions_pkey PRIMARY KEY (id);
+
+CREATE INDEX idx_safe_transactions_network_id_safe_address_safe_nonce ON public.safe_transactions USING btree (network_id, lower(safe_address), safe_nonce);
+CREATE INDEX idx_safe_transactions_application_id ON public.safe_transactions USING btree (application_id);
+CREATE INDEX idx_safe_transactions_organization_id ON public.safe_transactions USING btree (organization_id);
+CREATE INDEX idx_safe_transactions_user_id ON public.safe_transactions USING btree (user_id);
+CREATE INDEX idx_safe_transactions_transaction_id ON public.safe_transactions USING btree (transaction_id);
+
+ALTER TABLE ONLY public.safe_transactions
+    ADD CONSTRAINT safe_transactions_network_id_networks_id_foreign FOREIGN KEY (network_id) REFERENCES public.networks(id) ON UPDATE CASCADE ON DELETE CASCADE;
+
+ALTER TABLE ONLY public.safe_transactions
+    ADD CONSTRAINT safe_transactions_account_id_accounts_id_foreign FOREIGN KEY (account_id) REFERENCES public.accounts(id) ON UPDATE CASCADE ON DELETE SET NULL;
+
+ALTER TABLE ONLY public.safe_transactions
+    ADD CONSTRAINT safe_transactions_transaction_id_transactions_id_foreign FOREIGN KEY (transaction_id) REFERENCES public.transactions(id) ON UPDATE CASCADE ON DELETE SET NULL;
+
+CREATE TABLE public.safe_transaction_confirmations (
+    id uuid DEFAULT public.uuid_generate_v4() NOT NULL,
+    created_at timestamp with time zone DEFAULT now() NOT NULL,
+    safe_transaction_id uuid NOT NULL,
+    owner text NOT NULL,
+    signature text NOT NULL,
+    account_id uuid,
+    user_id uuid
+);
+
+ALTER TABLE public.safe_transaction_confirmations OWNER TO current_user;
+
+ALTER TABLE ONLY public.safe_transaction_confirmations
+    ADD CONSTRAINT safe_transaction_confirmations_pkey PRIMARY KEY (id);
+
+CREATE UNIQUE INDEX idx_safe_transaction_confirmations_safe_transaction_id_owner ON public.safe_transaction_confirmations USING btree (safe_transaction_id, lower(owner));
+
+ALTER TABLE ONLY public.safe_transaction_confirmations
+    ADD CONSTRAINT safe_transaction_confirmations_safe_transaction_id_safe_transactions_id_foreign FOREIGN KEY (safe_transaction_id) REFERENCES public.safe_transactions(id) ON UPDATE CASCADE ON DELETE CASCADE;
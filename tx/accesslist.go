@@ -0,0 +1,114 @@
+package tx
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	ethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/provideplatform/nchain/common"
+	"github.com/provideplatform/nchain/network"
+	providecrypto "github.com/provideplatform/provide-go/crypto"
+)
+
+// accessListArgs are the eth_createAccessList call args
+type accessListArgs struct {
+	From  ethcommon.Address  `json:"from"`
+	To    *ethcommon.Address `json:"to,omitempty"`
+	Gas   *hexutil.Uint64    `json:"gas,omitempty"`
+	Value *hexutil.Big       `json:"value,omitempty"`
+	Data  *hexutil.Bytes     `json:"data,omitempty"`
+}
+
+// accessListResult is the eth_createAccessList response
+type accessListResult struct {
+	AccessList AccessList     `json:"accessList"`
+	GasUsed    hexutil.Uint64 `json:"gasUsed"`
+	Error      string         `json:"error,omitempty"`
+}
+
+// parseAccessList parses the given access_list param, which uses the JSON-RPC encoding of access lists,
+// i.e. [{"address": "0x...", "storageKeys": ["0x..."]}]
+func parseAccessList(param interface{}) (AccessList, error) {
+	raw, err := json.Marshal(param)
+	if err != nil {
+		return nil, err
+	}
+
+	var accessList AccessList
+	err = json.Unmarshal(raw, &accessList)
+	if err != nil {
+		return nil, fmt.Errorf("invalid access_list; %s", err.Error())
+	}
+	return accessList, nil
+}
+
+// createAccessList generates the access list of the tx when sent from the given address via eth_createAccessList
+func (t *Transaction) createAccessList(ntwrk *network.Network, address string, gas uint64) (AccessList, error) {
+	client, err := providecrypto.EVMResolveJsonRpcClient(ntwrk.ID.String(), ntwrk.RPCURL())
+	if err != nil {
+		return nil, err
+	}
+
+	args := &accessListArgs{
+		From:  ethcommon.HexToAddress(address),
+		Value: (*hexutil.Big)(t.Value.BigInt()),
+	}
+	if t.To != nil {
+		to := ethcommon.HexToAddress(*t.To)
+		args.To = &to
+	}
+	if t.Data != nil {
+		data := hexutil.Bytes(ethcommon.FromHex(*t.Data))
+		args.Data = &data
+	}
+	if gas > 0 {
+		_gas := hexutil.Uint64(gas)
+		args.Gas = &_gas
+	}
+
+	var result accessListResult
+	err = client.CallContext(context.TODO(), &result, "eth_createAccessList", args, "pending")
+	if err != nil {
+		return nil, err
+	}
+	if result.Error != "" {
+		return nil, fmt.Errorf("tx execution failed while generating access list; %s", result.Error)
+	}
+
+	common.Log.Debugf("generated access list for tx from %s on network: %s; %d address(es); gas used: %d", address, ntwrk.ID, len(result.AccessList), uint64(result.GasUsed))
+	return result.AccessList, nil
+}
+
+// resolveAccessList resolves the EIP-2930 access list with which the tx is signed; the list is read from the
+// access_list param or, when the network enables access list generation, generated for calls which do not
+// provide one. An explicitly empty access_list disables generation. The resolved list is recorded on the tx.
+func (t *Transaction) resolveAccessList(ntwrk *network.Network, address string, gas uint64) (AccessList, error) {
+	var accessList AccessList
+
+	if param, paramOk := t.ParseParams()["access_list"]; paramOk && param != nil {
+		parsed, err := parseAccessList(param)
+		if err != nil {
+			return nil, err
+		}
+		accessList = parsed
+	} else if ntwrk.AccessListGenerationEnabled() && t.To != nil {
+		generated, err := t.createAccessList(ntwrk, address, gas)
+		if err != nil {
+			// generation is an optimization, so the tx is signed without an access list if it fails
+			common.Log.Debugf("failed to generate access list for tx from %s on network: %s; %s", address, ntwrk.ID, err.Error())
+			return nil, nil
+		}
+		accessList = generated
+	}
+
+	if len(accessList) == 0 {
+		return nil, nil
+	}
+
+	raw, _ := json.Marshal(accessList)
+	rawAccessList := json.RawMessage(raw)
+	t.AccessList = &rawAccessList
+	return accessList, nil
+}
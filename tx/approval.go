@@ -0,0 +1,290 @@
+package tx
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/jinzhu/gorm"
+	uuid "github.com/kthomas/go.uuid"
+	"github.com/provideplatform/nchain/common"
+	"github.com/provideplatform/nchain/wallet"
+	"github.com/provideplatform/nchain/webhook"
+	provide "github.com/provideplatform/provide-go/api"
+)
+
+// txStatusAwaitingApproval is the status of a tx which is persisted, but not signed, until its approval quorum is reached
+const txStatusAwaitingApproval = "awaiting_approval"
+
+// txApprovalExpiryBatchSize is the maximum number of txs awaiting approval which are expired on each sweep
+const txApprovalExpiryBatchSize = 256
+
+// Approval is the decision of a designated approver on a tx awaiting approval
+type Approval struct {
+	provide.Model
+	TransactionID uuid.UUID `sql:"not null;type:uuid" json:"transaction_id"`
+	UserID        uuid.UUID `sql:"not null;type:uuid" json:"user_id"`
+	Approved      bool      `sql:"not null" json:"approved"`
+	Comment       *string   `json:"comment,omitempty"`
+}
+
+// TableName returns the table in which tx approvals are persisted
+func (a *Approval) TableName() string {
+	return "transaction_approvals"
+}
+
+// approvalRequirement is the quorum of designated approvers required by a single spending policy
+type approvalRequirement struct {
+	PolicyID          uuid.UUID     `json:"policy_id"`
+	RequiredApprovals int           `json:"required_approvals"`
+	Approvers         []uuid.UUID   `json:"approvers"`
+	Timeout           time.Duration `json:"-"`
+}
+
+// approvalRequirementFactory returns the approval quorum required by the given policy
+func approvalRequirementFactory(policy *wallet.Policy) (*approvalRequirement, error) {
+	approvers, err := policy.ApproverIDs()
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve approvers of spending policy %s; %s", policy.ID, err.Error())
+	}
+
+	if policy.RequiredApprovals == nil || len(approvers) < *policy.RequiredApprovals {
+		return nil, fmt.Errorf("spending policy %s designates too few approvers to reach its approval quorum", policy.ID)
+	}
+
+	return &approvalRequirement{
+		PolicyID:          policy.ID,
+		RequiredApprovals: *policy.RequiredApprovals,
+		Approvers:         approvers,
+		Timeout:           policy.ApprovalExpiry(),
+	}, nil
+}
+
+// awaitApproval persists the unsigned tx until the given approval requirements are satisfied; the tx
+// expires when the shortest approval timeout of the requirements elapses
+func (t *Transaction) awaitApproval(db *gorm.DB, requirements []*approvalRequirement) bool {
+	timeout := requirements[0].Timeout
+	for _, requirement := range requirements {
+		if requirement.Timeout < timeout {
+			timeout = requirement.Timeout
+		}
+	}
+
+	rawRequirements, _ := json.Marshal(requirements)
+	_rawRequirements := json.RawMessage(rawRequirements)
+	expiresAt := time.Now().Add(timeout)
+
+	if t.AccountID != nil && *t.AccountID == uuid.Nil {
+		t.AccountID = nil
+	}
+	if t.WalletID != nil && *t.WalletID == uuid.Nil {
+		t.WalletID = nil
+	}
+
+	t.Status = common.StringOrNil(txStatusAwaitingApproval)
+	t.ApprovalRequirements = &_rawRequirements
+	t.ApprovalExpiresAt = &expiresAt
+
+	result := db.Create(&t)
+	errors := result.GetErrors()
+	if len(errors) > 0 {
+		for _, err := range errors {
+			t.Errors = append(t.Errors, &provide.Error{
+				Message: common.StringOrNil(err.Error()),
+			})
+		}
+		return false
+	}
+
+	common.Log.Debugf("tx %s awaiting %d approval requirement(s) until %s", t.ID, len(requirements), expiresAt.Format(time.RFC3339))
+	return true
+}
+
+// requirements returns the approval requirements of the tx
+func (t *Transaction) requirements() []*approvalRequirement {
+	requirements := make([]*approvalRequirement, 0)
+	if t.ApprovalRequirements != nil {
+		json.Unmarshal(*t.ApprovalRequirements, &requirements)
+	}
+	return requirements
+}
+
+// isApprover returns true if the given user is a designated approver of the tx
+func (t *Transaction) isApprover(userID uuid.UUID) bool {
+	for _, requirement := range t.requirements() {
+		for _, approver := range requirement.Approvers {
+			if approver == userID {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// approvals returns the decisions of the approvers of the tx
+func (t *Transaction) approvals(db *gorm.DB) []*Approval {
+	approvals := make([]*Approval, 0)
+	db.Where("transaction_id = ?", t.ID).Order("created_at ASC").Find(&approvals)
+	return approvals
+}
+
+// quorum returns true if every approval requirement of the tx is satisfied by the given approvals
+func (t *Transaction) quorum(approvals []*Approval) bool {
+	approved := map[uuid.UUID]bool{}
+	for _, approval := range approvals {
+		if approval.Approved {
+			approved[approval.UserID] = true
+		}
+	}
+
+	for _, requirement := range t.requirements() {
+		count := 0
+		for _, approver := range requirement.Approvers {
+			if approved[approver] {
+				count++
+			}
+		}
+		if count < requirement.RequiredApprovals {
+			return false
+		}
+	}
+	return true
+}
+
+// submitApproval records the decision of a designated approver; the tx fails when it is rejected, and is
+// signed and broadcast when its approval quorum is reached
+func (t *Transaction) submitApproval(db *gorm.DB, approval *Approval) error {
+	if t.Status == nil || *t.Status != txStatusAwaitingApproval {
+		return fmt.Errorf("tx %s is not awaiting approval", t.ID)
+	}
+
+	if t.ApprovalExpiresAt != nil && time.Now().After(*t.ApprovalExpiresAt) {
+		t.failAwaitingApproval(db, fmt.Sprintf("approval expired at %s", t.ApprovalExpiresAt.Format(time.RFC3339)))
+		return fmt.Errorf("approval of tx %s expired", t.ID)
+	}
+
+	for _, existing := range t.approvals(db) {
+		if existing.UserID == approval.UserID {
+			return fmt.Errorf("user %s has already submitted a decision on tx %s", approval.UserID, t.ID)
+		}
+	}
+
+	approval.TransactionID = t.ID
+	result := db.Create(&approval)
+	errors := result.GetErrors()
+	if len(errors) > 0 {
+		return fmt.Errorf("failed to persist approval of tx %s; %s", t.ID, errors[0].Error())
+	}
+
+	if !approval.Approved {
+		desc := fmt.Sprintf("rejected by approver %s", approval.UserID)
+		if approval.Comment != nil {
+			desc = fmt.Sprintf("%s; %s", desc, *approval.Comment)
+		}
+		t.failAwaitingApproval(db, desc)
+		return nil
+	}
+
+	if t.quorum(t.approvals(db)) {
+		t.resumeApproved(db)
+	}
+
+	return nil
+}
+
+// resumeApproved signs and broadcasts the tx once its approval quorum is reached; the tx is claimed
+// so it is only signed once when approvals are submitted concurrently
+func (t *Transaction) resumeApproved(db *gorm.DB) bool {
+	result := db.Model(&Transaction{}).
+		Where("id = ? AND status = ?", t.ID, txStatusAwaitingApproval).
+		Update("status", "pending")
+	if result.RowsAffected == 0 {
+		return false
+	}
+	t.Status = common.StringOrNil("pending")
+
+	common.Log.Debugf("approval quorum reached for tx %s; signing tx", t.ID)
+
+	signer, err := t.signerFactory(db)
+	if err != nil {
+		desc := err.Error()
+		t.updateStatus(db, "failed", &desc)
+		return false
+	}
+
+	if t.shouldRelay() && t.RelayerID == nil {
+		signer, err = t.relay(db, signer)
+		if err != nil {
+			desc := err.Error()
+			t.updateStatus(db, "failed", &desc)
+			return false
+		}
+	}
+
+	signingErr := t.sign(db, signer)
+	return t.broadcastSigned(db, signer, signingErr)
+}
+
+// failAwaitingApproval marks the tx awaiting approval as failed and releases its batch-allocated nonce, if any
+func (t *Transaction) failAwaitingApproval(db *gorm.DB, desc string) {
+	result := db.Model(&Transaction{}).
+		Where("id = ? AND status = ?", t.ID, txStatusAwaitingApproval).
+		Updates(map[string]interface{}{
+			"status":      "failed",
+			"description": desc,
+		})
+	if result.RowsAffected == 0 {
+		return
+	}
+
+	t.Status = common.StringOrNil("failed")
+	t.Description = common.StringOrNil(desc)
+	common.Log.Debugf("tx %s awaiting approval failed; %s", t.ID, desc)
+
+	t.releaseBatchNonce(db)
+	t.dispatchWebhook(db, webhook.EventTransactionFailed)
+}
+
+// reclaimBatchNonce releases the batch-allocated nonce of a tx which will never be signed; the nonce of a tx
+// which was signed is released by broadcast, which knows whether the network consumed it
+func (t *Transaction) reclaimBatchNonce(signer *TransactionSigner) {
+	if t.BatchID == nil || t.SignedTx != nil || signer == nil {
+		return
+	}
+	params := t.ParseParams()
+	if nonce, nonceOk := params["nonce"].(float64); nonceOk {
+		nonceManagerFactory(signer.Network, signer.Address()).Reclaim(uint64(nonce))
+
+		// the nonce may be reissued as soon as it is reclaimed, so it must never be reclaimed twice
+		delete(params, "nonce")
+		t.setParams(params)
+	}
+}
+
+// releaseBatchNonce resolves the signer of the tx, which failed before it was signed, and releases its
+// batch-allocated nonce so the subsequent txs of the signer are not stalled by the gap
+func (t *Transaction) releaseBatchNonce(db *gorm.DB) {
+	if t.BatchID == nil || t.SignedTx != nil {
+		return
+	}
+	if signer, err := t.signerFactory(db); err == nil {
+		t.reclaimBatchNonce(signer)
+	}
+}
+
+// expireAwaitingApprovalTxs fails the txs whose approval quorum was not reached before their approval expired
+func expireAwaitingApprovalTxs(db *gorm.DB) {
+	var txs []*Transaction
+	db.Where("status = ? AND approval_expires_at < ?", txStatusAwaitingApproval, time.Now()).
+		Order("approval_expires_at ASC").
+		Limit(txApprovalExpiryBatchSize).
+		Find(&txs)
+
+	for _, tx := range txs {
+		tx.failAwaitingApproval(db, fmt.Sprintf("approval expired at %s", tx.ApprovalExpiresAt.Format(time.RFC3339)))
+	}
+
+	if len(txs) > 0 {
+		common.Log.Debugf("expired %d tx(s) awaiting approval", len(txs))
+	}
+}
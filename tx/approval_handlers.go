@@ -0,0 +1,110 @@
+package tx
+
+import (
+	"encoding/json"
+
+	"github.com/gin-gonic/gin"
+	dbconf "github.com/kthomas/go-db-config"
+	uuid "github.com/kthomas/go.uuid"
+	"github.com/provideplatform/nchain/common"
+	provide "github.com/provideplatform/provide-go/common"
+	util "github.com/provideplatform/provide-go/common/util"
+)
+
+// installTransactionApprovalsAPI installs the tx approval handlers using the given gin Engine
+func installTransactionApprovalsAPI(r *gin.Engine) {
+	r.GET("/api/v1/transactions/:id/approvals", transactionApprovalsListHandler)
+	r.POST("/api/v1/transactions/:id/approvals", createTransactionApprovalHandler)
+}
+
+func transactionApprovalsListHandler(c *gin.Context) {
+	appID := util.AuthorizedSubjectID(c, "application")
+	orgID := util.AuthorizedSubjectID(c, "organization")
+	userID := util.AuthorizedSubjectID(c, "user")
+	if appID == nil && orgID == nil && userID == nil {
+		provide.RenderError("unauthorized", 401, c)
+		return
+	}
+
+	db := dbconf.DatabaseConnection()
+
+	var tx = &Transaction{}
+	db.Where("id = ?", c.Param("id")).Find(&tx)
+	if tx == nil || tx.ID == uuid.Nil {
+		provide.RenderError("transaction not found", 404, c)
+		return
+	}
+
+	validApp := appID != nil && (tx.ApplicationID != nil && *tx.ApplicationID == *appID)
+	validOrg := orgID != nil && (tx.OrganizationID != nil && *tx.OrganizationID == *orgID)
+	validUser := userID != nil && (tx.UserID != nil && *tx.UserID == *userID)
+	validApprover := userID != nil && tx.isApprover(*userID)
+
+	if !validApp && !validOrg && !validUser && !validApprover {
+		provide.RenderError("forbidden", 403, c)
+		return
+	}
+
+	provide.Render(tx.approvals(db), 200, c)
+}
+
+// createTransactionApprovalHandler records the decision of a designated approver on a tx awaiting approval
+func createTransactionApprovalHandler(c *gin.Context) {
+	userID := util.AuthorizedSubjectID(c, "user")
+	if userID == nil {
+		provide.RenderError("unauthorized", 401, c)
+		return
+	}
+
+	buf, err := c.GetRawData()
+	if err != nil {
+		provide.RenderError(err.Error(), 400, c)
+		return
+	}
+
+	params := map[string]interface{}{}
+	err = json.Unmarshal(buf, &params)
+	if err != nil {
+		provide.RenderError(err.Error(), 400, c)
+		return
+	}
+
+	approved, approvedOk := params["approved"].(bool)
+	if !approvedOk {
+		provide.RenderError("approved must be true or false", 422, c)
+		return
+	}
+
+	db := dbconf.DatabaseConnection()
+
+	var tx = &Transaction{}
+	db.Where("id = ?", c.Param("id")).Find(&tx)
+	if tx == nil || tx.ID == uuid.Nil {
+		provide.RenderError("transaction not found", 404, c)
+		return
+	}
+
+	if !tx.isApprover(*userID) {
+		provide.RenderError("forbidden", 403, c)
+		return
+	} else if tx.UserID != nil && *tx.UserID == *userID {
+		provide.RenderError("tx may not be approved by the user on whose behalf it was created", 403, c)
+		return
+	}
+
+	approval := &Approval{
+		UserID:   *userID,
+		Approved: approved,
+	}
+	if comment, commentOk := params["comment"].(string); commentOk && comment != "" {
+		approval.Comment = common.StringOrNil(comment)
+	}
+
+	err = tx.submitApproval(db, approval)
+	if err != nil {
+		provide.RenderError(err.Error(), 422, c)
+		return
+	}
+
+	provide.Render(tx, 201, c)
+}
@@ -0,0 +1,144 @@
+// +build unit
+
+package tx
+
+import (
+	"encoding/json"
+	"testing"
+
+	uuid "github.com/kthomas/go.uuid"
+	"github.com/provideplatform/nchain/common"
+	"github.com/provideplatform/nchain/wallet"
+)
+
+// approvalTxFactory returns a tx awaiting the given approval requirements
+func approvalTxFactory(requirements ...*approvalRequirement) *Transaction {
+	raw, _ := json.Marshal(requirements)
+	rawRequirements := json.RawMessage(raw)
+	return &Transaction{
+		Status:               common.StringOrNil(txStatusAwaitingApproval),
+		ApprovalRequirements: &rawRequirements,
+	}
+}
+
+func approverIDs(n int) []uuid.UUID {
+	ids := make([]uuid.UUID, 0)
+	for i := 0; i < n; i++ {
+		id, _ := uuid.NewV4()
+		ids = append(ids, id)
+	}
+	return ids
+}
+
+func approvalFactory(userID uuid.UUID, approved bool) *Approval {
+	return &Approval{
+		UserID:   userID,
+		Approved: approved,
+	}
+}
+
+func TestApprovalQuorum(t *testing.T) {
+	approvers := approverIDs(3)
+	tx := approvalTxFactory(&approvalRequirement{
+		RequiredApprovals: 2,
+		Approvers:         approvers,
+	})
+
+	if tx.quorum([]*Approval{}) {
+		t.Errorf("expected no quorum without approvals")
+	}
+	if tx.quorum([]*Approval{approvalFactory(approvers[0], true)}) {
+		t.Errorf("expected no quorum with 1 of 2 required approvals")
+	}
+	if !tx.quorum([]*Approval{approvalFactory(approvers[0], true), approvalFactory(approvers[2], true)}) {
+		t.Errorf("expected quorum with 2 of 2 required approvals")
+	}
+}
+
+func TestApprovalQuorumCountsEachApproverOnce(t *testing.T) {
+	approvers := approverIDs(2)
+	tx := approvalTxFactory(&approvalRequirement{
+		RequiredApprovals: 2,
+		Approvers:         approvers,
+	})
+
+	if tx.quorum([]*Approval{approvalFactory(approvers[0], true), approvalFactory(approvers[0], true)}) {
+		t.Errorf("expected repeated approvals of the same approver to be counted once")
+	}
+}
+
+func TestApprovalQuorumIgnoresRejectionsAndOtherUsers(t *testing.T) {
+	approvers := approverIDs(2)
+	outsider := approverIDs(1)[0]
+	tx := approvalTxFactory(&approvalRequirement{
+		RequiredApprovals: 1,
+		Approvers:         approvers,
+	})
+
+	if tx.quorum([]*Approval{approvalFactory(approvers[0], false)}) {
+		t.Errorf("expected rejection not to count toward quorum")
+	}
+	if tx.quorum([]*Approval{approvalFactory(outsider, true)}) {
+		t.Errorf("expected approval by a user who is not a designated approver not to count toward quorum")
+	}
+}
+
+func TestApprovalQuorumRequiresEveryRequirement(t *testing.T) {
+	accountApprovers := approverIDs(2)
+	walletApprovers := approverIDs(2)
+	tx := approvalTxFactory(
+		&approvalRequirement{RequiredApprovals: 1, Approvers: accountApprovers},
+		&approvalRequirement{RequiredApprovals: 2, Approvers: walletApprovers},
+	)
+
+	approvals := []*Approval{
+		approvalFactory(accountApprovers[1], true),
+		approvalFactory(walletApprovers[0], true),
+	}
+	if tx.quorum(approvals) {
+		t.Errorf("expected no quorum until every requirement is satisfied")
+	}
+
+	approvals = append(approvals, approvalFactory(walletApprovers[1], true))
+	if !tx.quorum(approvals) {
+		t.Errorf("expected quorum once every requirement is satisfied")
+	}
+}
+
+func TestIsApprover(t *testing.T) {
+	approvers := approverIDs(2)
+	tx := approvalTxFactory(&approvalRequirement{RequiredApprovals: 1, Approvers: approvers})
+
+	if !tx.isApprover(approvers[1]) {
+		t.Errorf("expected designated approver to be an approver of the tx")
+	}
+	if tx.isApprover(approverIDs(1)[0]) {
+		t.Errorf("expected other user not to be an approver of the tx")
+	}
+}
+
+func TestApprovalRequirementFactory(t *testing.T) {
+	approvers := approverIDs(2)
+	raw, _ := json.Marshal(append(approvers, approvers[0]))
+	rawApprovers := json.RawMessage(raw)
+
+	requiredApprovals := 2
+	policy := &wallet.Policy{
+		RequiredApprovals: &requiredApprovals,
+		Approvers:         &rawApprovers,
+	}
+
+	requirement, err := approvalRequirementFactory(policy)
+	if err != nil {
+		t.Fatalf("failed to resolve approval requirement; %s", err.Error())
+	}
+	if requirement.RequiredApprovals != 2 || len(requirement.Approvers) != 2 {
+		t.Errorf("expected 2 of 2 distinct approvers to be required; got %d of %d", requirement.RequiredApprovals, len(requirement.Approvers))
+	}
+
+	// duplicate approvers must not allow a quorum to be reached by fewer distinct approvers
+	requiredApprovals = 3
+	if _, err := approvalRequirementFactory(policy); err == nil {
+		t.Errorf("expected policy requiring more approvals than it has distinct approvers to be rejected")
+	}
+}
@@ -0,0 +1,368 @@
+package tx
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	ethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/jinzhu/gorm"
+	natsutil "github.com/kthomas/go-natsutil"
+	uuid "github.com/kthomas/go.uuid"
+	"github.com/provideplatform/nchain/common"
+	"github.com/provideplatform/nchain/contract"
+	"github.com/provideplatform/nchain/network"
+	provide "github.com/provideplatform/provide-go/api"
+	providecrypto "github.com/provideplatform/provide-go/crypto"
+)
+
+// maxBatchSize is the maximum number of txs or contract executions which may be submitted in a single batch
+const maxBatchSize = 256
+
+// Aggregated batch statuses
+const (
+	batchStatusQueued  = "queued"  // no tx in the batch has been created
+	batchStatusPending = "pending" // at least one tx in the batch is queued or pending
+	batchStatusSuccess = "success" // every tx in the batch succeeded
+	batchStatusFailed  = "failed"  // no tx in the batch succeeded
+	batchStatusPartial = "partial" // the batch is settled; some txs succeeded and some did not
+)
+
+// Batch is a set of txs or contract executions which were submitted together; the txs signed by the
+// same signer are allocated sequential nonces and published to nchain.tx in the order submitted
+type Batch struct {
+	provide.Model
+	ApplicationID  *uuid.UUID       `sql:"type:uuid" json:"application_id,omitempty"`
+	OrganizationID *uuid.UUID       `sql:"type:uuid" json:"organization_id,omitempty"`
+	UserID         *uuid.UUID       `sql:"type:uuid" json:"user_id,omitempty"`
+	ContractID     *uuid.UUID       `sql:"type:uuid" json:"contract_id,omitempty"` // set when the batch is a batch of contract executions
+	Size           int              `sql:"not null" json:"size"`
+	Refs           *json.RawMessage `sql:"type:json" json:"-"` // ordered refs of the txs in the batch
+
+	// Ephemeral aggregated status and per-item details
+	Status *string      `sql:"-" json:"status"`
+	Items  []*BatchItem `sql:"-" json:"items"`
+}
+
+// BatchItem is the status of a single tx, or the result of a single read, submitted in a batch
+type BatchItem struct {
+	Ref           *string     `json:"ref"`
+	Nonce         *uint64     `json:"nonce,omitempty"`
+	TransactionID *uuid.UUID  `json:"transaction_id,omitempty"`
+	Hash          *string     `json:"hash,omitempty"`
+	Status        *string     `json:"status"`
+	Description   *string     `json:"description,omitempty"`
+	Response      interface{} `json:"response,omitempty"` // result of a read, when the item is a view method
+	View          bool        `json:"view,omitempty"`
+}
+
+// batchEntry is a tx, and the contract execution from which it was built, if any, pending submission in a batch
+type batchEntry struct {
+	tx        *Transaction
+	execution *contract.Execution
+	item      *BatchItem
+}
+
+// batchRead is a contract execution of a view method submitted in a batch; reads are executed synchronously
+type batchRead struct {
+	execution *contract.Execution
+	method    *abi.Method
+	item      *BatchItem
+}
+
+// TableName returns the table in which batches are persisted
+func (b *Batch) TableName() string {
+	return "transaction_batches"
+}
+
+// submit allocates sequential nonces to the given entries, grouped by signer, persists the batch and publishes
+// each entry to nchain.tx in the order given
+func (b *Batch) submit(db *gorm.DB, entries []*batchEntry) error {
+	type signerGroup struct {
+		manager *NonceManager
+		entries []*batchEntry
+		nonces  []uint64
+	}
+
+	groups := make([]*signerGroup, 0)
+	groupsByKey := map[string]*signerGroup{}
+
+	for i, entry := range entries {
+		if entry.tx.shouldRelay() {
+			return fmt.Errorf("unable to relay batch item %d; relayed txs cannot be submitted in a batch", i)
+		}
+
+		signer, err := entry.tx.signerFactory(db)
+		if err != nil {
+			return fmt.Errorf("failed to resolve signer of batch item %d; %s", i, err.Error())
+		}
+
+		address := signer.Address()
+		if address == "" {
+			return fmt.Errorf("failed to resolve signer address of batch item %d", i)
+		}
+
+		key := NonceKey(signer.Network.ID, address)
+		group, groupOk := groupsByKey[key]
+		if !groupOk {
+			group = &signerGroup{
+				manager: nonceManagerFactory(signer.Network, address),
+				entries: make([]*batchEntry, 0),
+			}
+			groups = append(groups, group)
+			groupsByKey[key] = group
+		}
+		group.entries = append(group.entries, entry)
+	}
+
+	// reclaim releases the allocated nonces of the entries which were not published
+	reclaim := func() {
+		for _, group := range groups {
+			for i, nonce := range group.nonces {
+				if group.entries[i].item.Status == nil {
+					group.manager.Reclaim(nonce)
+				}
+			}
+		}
+	}
+
+	for _, group := range groups {
+		nonces, err := group.manager.AllocateN(len(group.entries))
+		if err != nil {
+			reclaim()
+			return fmt.Errorf("failed to allocate %d nonce(s) for batch signer %s; %s", len(group.entries), group.manager.Address, err.Error())
+		}
+		group.nonces = nonces
+		for i, entry := range group.entries {
+			nonce := nonces[i]
+			entry.item.Nonce = &nonce
+		}
+	}
+
+	refs := make([]string, 0)
+	for _, entry := range entries {
+		refs = append(refs, *entry.item.Ref)
+	}
+	rawRefs, _ := json.Marshal(refs)
+	_rawRefs := json.RawMessage(rawRefs)
+	b.Refs = &_rawRefs
+	b.Size = len(entries)
+
+	result := db.Create(&b)
+	errors := result.GetErrors()
+	if len(errors) > 0 {
+		reclaim()
+		for _, err := range errors {
+			b.Errors = append(b.Errors, &provide.Error{
+				Message: common.StringOrNil(err.Error()),
+			})
+		}
+		return fmt.Errorf("failed to persist batch; %s", errors[0].Error())
+	}
+
+	for i, entry := range entries {
+		publishedAt := time.Now()
+
+		var payload []byte
+		if entry.execution != nil {
+			entry.execution.BatchID = &b.ID
+			entry.execution.Nonce = entry.item.Nonce
+			entry.execution.PublishedAt = &publishedAt
+			payload, _ = json.Marshal(entry.execution)
+		} else {
+			params := entry.tx.ParseParams()
+			params["nonce"] = *entry.item.Nonce
+			entry.tx.setParams(params)
+			entry.tx.BatchID = &b.ID
+			entry.tx.PublishedAt = &publishedAt
+			payload, _ = json.Marshal(&txMessage{
+				Transaction: entry.tx,
+			})
+		}
+
+		_, err := natsutil.NatsJetstreamPublish(natsTxSubject, payload)
+		if err != nil {
+			reclaim()
+			return fmt.Errorf("failed to publish batch item %d of batch %s; %s", i, b.ID, err.Error())
+		}
+		entry.item.Status = common.StringOrNil(batchStatusQueued)
+	}
+
+	b.Items = make([]*BatchItem, 0)
+	for _, entry := range entries {
+		b.Items = append(b.Items, entry.item)
+	}
+	b.aggregateStatus()
+
+	common.Log.Debugf("published %d tx(s) in batch %s using %d signer(s)", len(entries), b.ID, len(groups))
+	return nil
+}
+
+// refs returns the ordered refs of the txs in the batch
+func (b *Batch) refs() []string {
+	refs := make([]string, 0)
+	if b.Refs != nil {
+		json.Unmarshal(*b.Refs, &refs)
+	}
+	return refs
+}
+
+// populateItems resolves the current status of each tx in the batch and the aggregated batch status
+func (b *Batch) populateItems(db *gorm.DB) {
+	var txs []*Transaction
+	db.Where("batch_id = ?", b.ID).Find(&txs)
+
+	txsByRef := map[string]*Transaction{}
+	for _, tx := range txs {
+		if tx.Ref != nil {
+			txsByRef[*tx.Ref] = tx
+		}
+	}
+
+	b.Items = make([]*BatchItem, 0)
+	for _, ref := range b.refs() {
+		item := &BatchItem{
+			Ref:    common.StringOrNil(ref),
+			Status: common.StringOrNil(batchStatusQueued),
+		}
+
+		if tx, txOk := txsByRef[ref]; txOk {
+			item.TransactionID = &tx.ID
+			item.Nonce = tx.Nonce
+			item.Hash = tx.Hash
+			item.Status = tx.Status
+			item.Description = tx.Description
+		}
+
+		b.Items = append(b.Items, item)
+	}
+
+	b.aggregateStatus()
+}
+
+// aggregateStatus sets the batch status from the statuses of its items
+func (b *Batch) aggregateStatus() {
+	queued := 0
+	pending := 0
+	succeeded := 0
+
+	for _, item := range b.Items {
+		status := batchStatusQueued
+		if item.Status != nil {
+			status = *item.Status
+		}
+
+		switch status {
+		case batchStatusQueued:
+			queued++
+		case "pending", txStatusAwaitingApproval:
+			pending++
+		case "success":
+			succeeded++
+		}
+	}
+
+	status := batchStatusPartial
+	if len(b.Items) > 0 && queued == len(b.Items) {
+		status = batchStatusQueued
+	} else if queued > 0 || pending > 0 {
+		status = batchStatusPending
+	} else if succeeded == len(b.Items) {
+		status = batchStatusSuccess
+	} else if succeeded == 0 {
+		status = batchStatusFailed
+	}
+
+	b.Status = common.StringOrNil(status)
+}
+
+// executeBatchReads executes the given reads against the contract; when aggregate is true, the reads are packed
+// into a single eth_call using the Multicall3 contract configured for the network
+func executeBatchReads(c *contract.Contract, ntwrk *network.Network, reads []*batchRead, aggregate bool) error {
+	if !aggregate || len(reads) < 2 {
+		for _, read := range reads {
+			resp, err := executeTransaction(c, read.execution)
+			if err != nil {
+				read.item.Status = common.StringOrNil("failed")
+				read.item.Description = common.StringOrNil(err.Error())
+				continue
+			}
+			read.item.Status = common.StringOrNil("success")
+			read.item.Response = resp.Response
+		}
+		return nil
+	}
+
+	if c.Address == nil {
+		return fmt.Errorf("unable to aggregate reads of contract %s without an address", c.ID)
+	}
+
+	calls := make([]multicall3Call, 0)
+	for i, read := range reads {
+		data, err := providecrypto.EVMEncodeABI(read.method, read.execution.Params...)
+		if err != nil {
+			return fmt.Errorf("failed to encode %d parameters of read %d (method %s); %s", len(read.execution.Params), i, read.method.Name, err.Error())
+		}
+		calls = append(calls, multicall3Call{
+			Target:       ethcommon.HexToAddress(*c.Address),
+			AllowFailure: true,
+			CallData:     data,
+		})
+	}
+
+	results, err := multicall(ntwrk, "", calls)
+	if err != nil {
+		return err
+	}
+
+	for i, result := range results {
+		read := reads[i]
+		if !result.Success {
+			read.item.Status = common.StringOrNil("failed")
+			read.item.Description = common.StringOrNil(fmt.Sprintf("read of method %s reverted", read.method.Name))
+			continue
+		}
+
+		out, err := read.method.Outputs.UnpackValues(result.ReturnData)
+		if err != nil {
+			read.item.Status = common.StringOrNil("failed")
+			read.item.Description = common.StringOrNil(fmt.Sprintf("failed to unpack response of method %s; %s", read.method.Name, err.Error()))
+			continue
+		}
+
+		read.item.Status = common.StringOrNil("success")
+		if len(out) == 1 {
+			read.item.Response = out[0]
+		} else {
+			read.item.Response = out
+		}
+	}
+
+	common.Log.Debugf("executed %d aggregated read(s) of contract %s using Multicall3", len(reads), c.ID)
+	return nil
+}
+
+// validateBatchRefs assigns a ref to each item in the batch which was submitted without one and
+// ensures the refs are unique within the batch
+func validateBatchRefs(entries []*batchEntry) error {
+	refs := map[string]bool{}
+	for i, entry := range entries {
+		if entry.item.Ref == nil || strings.TrimSpace(*entry.item.Ref) == "" {
+			ref, _ := uuid.NewV4()
+			entry.item.Ref = common.StringOrNil(ref.String())
+		}
+
+		if refs[*entry.item.Ref] {
+			return fmt.Errorf("duplicate ref %s for batch item %d", *entry.item.Ref, i)
+		}
+		refs[*entry.item.Ref] = true
+
+		entry.tx.Ref = entry.item.Ref
+		if entry.execution != nil {
+			entry.execution.Ref = entry.item.Ref
+		}
+	}
+	return nil
+}
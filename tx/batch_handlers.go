@@ -0,0 +1,283 @@
+package tx
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jinzhu/gorm"
+	dbconf "github.com/kthomas/go-db-config"
+	uuid "github.com/kthomas/go.uuid"
+	"github.com/provideplatform/nchain/common"
+	"github.com/provideplatform/nchain/contract"
+	provide "github.com/provideplatform/provide-go/common"
+	util "github.com/provideplatform/provide-go/common/util"
+)
+
+// installTransactionBatchesAPI installs the batch handlers using the given gin Engine
+func installTransactionBatchesAPI(r *gin.Engine) {
+	r.POST("/api/v1/transactions/batch", createTransactionBatchHandler)
+	r.GET("/api/v1/transactions/batch/:id", transactionBatchDetailsHandler)
+	r.POST("/api/v1/contracts/:id/execute/batch", contractExecutionBatchHandler)
+}
+
+// createTransactionBatchHandler submits the given txs as a batch; txs signed by the same signer are allocated
+// sequential nonces and published in the order given
+func createTransactionBatchHandler(c *gin.Context) {
+	appID := util.AuthorizedSubjectID(c, "application")
+	orgID := util.AuthorizedSubjectID(c, "organization")
+	userID := util.AuthorizedSubjectID(c, "user")
+	if appID == nil && orgID == nil && userID == nil {
+		provide.RenderError("unauthorized", 401, c)
+		return
+	}
+
+	buf, err := c.GetRawData()
+	if err != nil {
+		provide.RenderError(err.Error(), 400, c)
+		return
+	}
+
+	var txs []*Transaction
+	err = json.Unmarshal(buf, &txs)
+	if err != nil {
+		provide.RenderError(err.Error(), 422, c)
+		return
+	}
+
+	if len(txs) == 0 || len(txs) > maxBatchSize {
+		provide.RenderError(fmt.Sprintf("batch must contain between 1 and %d transactions", maxBatchSize), 422, c)
+		return
+	}
+
+	db := dbconf.DatabaseConnection()
+
+	entries := make([]*batchEntry, 0)
+	for i, tx := range txs {
+		tx.ApplicationID = appID
+		tx.OrganizationID = orgID
+		tx.UserID = userID
+
+		if tx.Raw != nil {
+			provide.RenderError(fmt.Sprintf("batch item %d is a pre-signed raw tx; raw txs cannot be submitted in a batch", i), 422, c)
+			return
+		}
+
+		if tx.AccountID == nil && tx.WalletID == nil {
+			provide.RenderError(fmt.Sprintf("batch item %d requires an account_id or wallet_id", i), 422, c)
+			return
+		}
+
+		if _, nonceOk := tx.ParseParams()["nonce"]; nonceOk {
+			provide.RenderError(fmt.Sprintf("batch item %d specifies a nonce; batch nonces are allocated sequentially", i), 422, c)
+			return
+		}
+
+		if tx.ScheduledAt != nil || tx.NotBeforeBlock != nil {
+			provide.RenderError(fmt.Sprintf("batch item %d specifies scheduling options; batch items cannot be scheduled", i), 422, c)
+			return
+		}
+
+		if !tx.Validate() {
+			obj := map[string]interface{}{}
+			obj["errors"] = tx.Errors
+			obj["index"] = i
+			provide.Render(obj, 422, c)
+			return
+		}
+
+		entries = append(entries, &batchEntry{
+			tx:   tx,
+			item: &BatchItem{Ref: tx.Ref},
+		})
+	}
+
+	submitBatch(c, db, &Batch{
+		ApplicationID:  appID,
+		OrganizationID: orgID,
+		UserID:         userID,
+	}, entries, nil)
+}
+
+// transactionBatchDetailsHandler renders the aggregated status of a batch and the status of each of its txs
+func transactionBatchDetailsHandler(c *gin.Context) {
+	appID := util.AuthorizedSubjectID(c, "application")
+	orgID := util.AuthorizedSubjectID(c, "organization")
+	userID := util.AuthorizedSubjectID(c, "user")
+	if appID == nil && orgID == nil && userID == nil {
+		provide.RenderError("unauthorized", 401, c)
+		return
+	}
+
+	db := dbconf.DatabaseConnection()
+
+	batch := &Batch{}
+	db.Where("id = ?", c.Param("id")).Find(&batch)
+	if batch == nil || batch.ID == uuid.Nil {
+		provide.RenderError("batch not found", 404, c)
+		return
+	}
+
+	validApp := appID != nil && (batch.ApplicationID != nil && *batch.ApplicationID == *appID)
+	validOrg := orgID != nil && (batch.OrganizationID != nil && *batch.OrganizationID == *orgID)
+	validUser := userID != nil && (batch.UserID != nil && *batch.UserID == *userID)
+
+	if !validApp && !validOrg && !validUser {
+		provide.RenderError("forbidden", 403, c)
+		return
+	}
+
+	batch.populateItems(db)
+	provide.Render(batch, 200, c)
+}
+
+// contractExecutionBatchHandler executes the given methods on the contract as a batch; txs signed by the same
+// signer are allocated sequential nonces and published in the order given. View methods are read synchronously
+// and, when the multicall query param is true, aggregated into a single eth_call using Multicall3
+func contractExecutionBatchHandler(c *gin.Context) {
+	appID := util.AuthorizedSubjectID(c, "application")
+	orgID := util.AuthorizedSubjectID(c, "organization")
+	userID := util.AuthorizedSubjectID(c, "user")
+	if appID == nil && orgID == nil && userID == nil {
+		provide.RenderError("unauthorized", 401, c)
+		return
+	}
+
+	buf, err := c.GetRawData()
+	if err != nil {
+		provide.RenderError(err.Error(), 400, c)
+		return
+	}
+
+	var executions []*contract.Execution
+	err = json.Unmarshal(buf, &executions)
+	if err != nil {
+		provide.RenderError(err.Error(), 422, c)
+		return
+	}
+
+	if len(executions) == 0 || len(executions) > maxBatchSize {
+		provide.RenderError(fmt.Sprintf("batch must contain between 1 and %d executions", maxBatchSize), 422, c)
+		return
+	}
+
+	db := dbconf.DatabaseConnection()
+
+	contractObj := resolveExecutionContract(c, db, c.Param("id"), appID, orgID, userID)
+	if contractObj == nil {
+		return
+	}
+
+	_abi, err := contractObj.ReadEthereumContractAbi()
+	if err != nil {
+		provide.RenderError(fmt.Sprintf("failed to execute batch on contract: %s; no ABI resolved: %s", contractObj.ID, err.Error()), 422, c)
+		return
+	}
+
+	ntwrk, err := contractObj.GetNetwork()
+	if err != nil {
+		provide.RenderError(err.Error(), 422, c)
+		return
+	}
+
+	items := make([]*BatchItem, 0)
+	entries := make([]*batchEntry, 0)
+	reads := make([]*batchRead, 0)
+
+	for i, execution := range executions {
+		execution.Contract = contractObj
+		execution.ContractID = &contractObj.ID
+
+		mthd, mthdOk := _abi.Methods[execution.Method]
+		if !mthdOk {
+			provide.RenderError(fmt.Sprintf("batch item %d: method %s not found in ABI", i, execution.Method), 422, c)
+			return
+		}
+
+		item := &BatchItem{Ref: execution.Ref}
+		items = append(items, item)
+
+		if mthd.IsConstant() {
+			item.View = true
+			reads = append(reads, &batchRead{
+				execution: execution,
+				method:    &mthd,
+				item:      item,
+			})
+			continue
+		}
+
+		if execution.Nonce != nil {
+			provide.RenderError(fmt.Sprintf("batch item %d specifies a nonce; batch nonces are allocated sequentially", i), 422, c)
+			return
+		}
+
+		if execution.ScheduledAt != nil || execution.NotBeforeBlock != nil {
+			provide.RenderError(fmt.Sprintf("batch item %d specifies scheduling options; batch items cannot be scheduled", i), 422, c)
+			return
+		}
+
+		if (execution.AccountID == nil || *execution.AccountID == uuid.Nil) && (execution.WalletID == nil || *execution.WalletID == uuid.Nil) {
+			provide.RenderError(fmt.Sprintf("batch item %d requires an account_id or wallet_id", i), 422, c)
+			return
+		}
+
+		entries = append(entries, &batchEntry{
+			tx:        executionTransactionFactory(contractObj, execution),
+			execution: execution,
+			item:      item,
+		})
+	}
+
+	err = executeBatchReads(contractObj, ntwrk, reads, strings.ToLower(c.Query("multicall")) == "true")
+	if err != nil {
+		provide.RenderError(err.Error(), 422, c)
+		return
+	}
+
+	submitBatch(c, db, &Batch{
+		ApplicationID:  appID,
+		OrganizationID: orgID,
+		UserID:         userID,
+		ContractID:     &contractObj.ID,
+	}, entries, items)
+}
+
+// submitBatch submits the given entries as a batch and renders the batch; items, if given, are the ordered
+// items of the batch including any reads, otherwise the items are those of the submitted entries
+func submitBatch(c *gin.Context, db *gorm.DB, batch *Batch, entries []*batchEntry, items []*BatchItem) {
+	if len(entries) > 0 {
+		err := validateBatchRefs(entries)
+		if err != nil {
+			provide.RenderError(err.Error(), 422, c)
+			return
+		}
+
+		for i, entry := range entries {
+			if existing := entry.tx.findByRef(db); existing != nil {
+				provide.RenderError(fmt.Sprintf("batch item %d: ref %s was previously used by tx: %s", i, *entry.item.Ref, existing.ID), 422, c)
+				return
+			}
+		}
+
+		err = batch.submit(db, entries)
+		if err != nil {
+			common.Log.Warningf("failed to submit batch of %d tx(s); %s", len(entries), err.Error())
+			provide.RenderError(err.Error(), 500, c)
+			return
+		}
+	}
+
+	if items != nil {
+		batch.Items = items
+		batch.aggregateStatus()
+	}
+
+	if len(entries) == 0 {
+		provide.Render(batch, 200, c) // the batch consisted only of reads
+		return
+	}
+
+	provide.Render(batch, 202, c)
+}
@@ -0,0 +1,115 @@
+// +build unit
+
+package tx
+
+import (
+	"encoding/json"
+	"testing"
+
+	dbconf "github.com/kthomas/go-db-config"
+	uuid "github.com/kthomas/go.uuid"
+	"github.com/nats-io/nats.go"
+	"github.com/provideplatform/nchain/wallet"
+)
+
+func TestBatchNonceReclaimedWhenEntryFailsBeforeSigning(t *testing.T) {
+	pending := uint64(7)
+	ntwrk, srv := jsonRPCStubFactory(t, map[string]jsonRPCHandler{
+		"eth_getTransactionCount": pendingNonceStub(&pending),
+	})
+	defer srv.Close()
+
+	signer := &TransactionSigner{
+		Network: ntwrk,
+		Account: &wallet.Account{Address: "0x8C8d1BDB3f1e9A9e1a6d2A4b5f4c1a2B3C4d5E6f"},
+	}
+	manager := nonceManagerFactory(ntwrk, signer.Address())
+
+	nonces, err := manager.AllocateN(3)
+	if err != nil {
+		t.Fatalf("failed to allocate batch nonces; %s", err.Error())
+	}
+	if nonces[0] != 7 || nonces[1] != 8 || nonces[2] != 9 {
+		t.Fatalf("expected batch nonces 7, 8 and 9; got %v", nonces)
+	}
+
+	// the second entry in the batch fails, i.e. gas estimation reverts, before it is signed
+	batchID, _ := uuid.NewV4()
+	params, _ := json.Marshal(map[string]interface{}{"nonce": nonces[1]})
+	rawParams := json.RawMessage(params)
+	failed := &Transaction{
+		BatchID: &batchID,
+		Params:  &rawParams,
+	}
+	failed.reclaimBatchNonce(signer)
+	failed.reclaimBatchNonce(signer) // the nonce must not be reclaimed twice
+
+	nonce, err := manager.Allocate()
+	if err != nil {
+		t.Fatalf("failed to allocate nonce; %s", err.Error())
+	}
+	if *nonce != 8 {
+		t.Errorf("expected the reclaimed gap 8 to be reissued; got %d", *nonce)
+	}
+
+	nonce, err = manager.Allocate()
+	if err != nil {
+		t.Fatalf("failed to allocate nonce; %s", err.Error())
+	}
+	if *nonce != 10 {
+		t.Errorf("expected nonce 10 after the reissued gap; got %d", *nonce)
+	}
+}
+
+func TestBatchNonceNotReclaimedWhenEntryWasSigned(t *testing.T) {
+	pending := uint64(3)
+	ntwrk, srv := jsonRPCStubFactory(t, map[string]jsonRPCHandler{
+		"eth_getTransactionCount": pendingNonceStub(&pending),
+	})
+	defer srv.Close()
+
+	signer := &TransactionSigner{
+		Network: ntwrk,
+		Account: &wallet.Account{Address: "0x1C8d1BDB3f1e9A9e1a6d2A4b5f4c1a2B3C4d5E6f"},
+	}
+	manager := nonceManagerFactory(ntwrk, signer.Address())
+
+	nonces, err := manager.AllocateN(2)
+	if err != nil {
+		t.Fatalf("failed to allocate batch nonces; %s", err.Error())
+	}
+
+	// the nonce of a signed tx is released by the broadcast, which knows whether the network consumed it
+	batchID, _ := uuid.NewV4()
+	params, _ := json.Marshal(map[string]interface{}{"nonce": nonces[0]})
+	rawParams := json.RawMessage(params)
+	signed := &Transaction{
+		BatchID:  &batchID,
+		Params:   &rawParams,
+		SignedTx: &TypedTransaction{},
+	}
+	signed.reclaimBatchNonce(signer)
+
+	nonce, err := manager.Allocate()
+	if err != nil {
+		t.Fatalf("failed to allocate nonce; %s", err.Error())
+	}
+	if *nonce != 5 {
+		t.Errorf("expected nonce 5; got %d", *nonce)
+	}
+}
+
+func TestTxMsgWithoutRefIsDropped(t *testing.T) {
+	networkID, _ := uuid.NewV4()
+	msg := &nats.Msg{
+		Subject: "nchain.tx",
+		Data:    []byte(`{"transaction":{}}`),
+	}
+
+	// a tx message without a ref cannot be deduplicated, so it must be dropped before it is logged or created
+	tx := &Transaction{NetworkID: networkID}
+	consumeTxMsg(dbconf.DatabaseConnection(), msg, tx)
+	if tx.ID != uuid.Nil {
+		t.Errorf("expected tx message without a ref not to be created")
+	}
+}
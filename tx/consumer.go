@@ -4,7 +4,6 @@ import (
 	"encoding/json"
 	"fmt"
 	"math/big"
-	"strings"
 	"sync"
 	"time"
 
@@ -15,11 +14,10 @@ import (
 	"github.com/nats-io/nats.go"
 	"github.com/provideplatform/nchain/common"
 	"github.com/provideplatform/nchain/contract"
+	"github.com/provideplatform/nchain/network"
 	"github.com/provideplatform/nchain/wallet"
 	api "github.com/provideplatform/provide-go/api"
-	bookie "github.com/provideplatform/provide-go/api/bookie"
 	provide "github.com/provideplatform/provide-go/api/nchain"
-	util "github.com/provideplatform/provide-go/common/util"
 )
 
 const defaultNatsStream = "nchain"
@@ -46,8 +44,24 @@ const natsTxReceiptMaxInFlight = 1024 * 30
 const natsTxReceiptMsgMaxDeliveries = 100
 const txReceiptAckWait = time.Second * 5
 
+const natsTxConfirmSubject = "nchain.tx.confirm"
+const natsTxConfirmMaxInFlight = 1024
+const natsTxConfirmMsgMaxDeliveries = 10
+const txConfirmAckWait = time.Second * 30
+
+const natsTxReorgSubject = "nchain.tx.reorg"
+const natsTxReorgMaxInFlight = 1024
+const natsTxReorgMsgMaxDeliveries = 10
+const txReorgAckWait = time.Second * 30
+
 var waitGroup sync.WaitGroup
 
+// txMessage is the nchain.tx message which creates a single tx, as opposed to a contract execution; txs
+// submitted in a batch, and scheduled txs once they are due, are published using this message
+type txMessage struct {
+	Transaction *Transaction `json:"transaction"`
+}
+
 func init() {
 	if !common.ConsumeNATSStreamingSubscriptions {
 		common.Log.Debug("Tx package consumer configured to skip NATS streaming subscription setup")
@@ -63,6 +77,11 @@ func init() {
 	createNatsTxCreateSubscriptions(&waitGroup)
 	createNatsTxFinalizeSubscriptions(&waitGroup)
 	createNatsTxReceiptSubscriptions(&waitGroup)
+	createNatsTxConfirmSubscriptions(&waitGroup)
+	createNatsTxReorgSubscriptions(&waitGroup)
+
+	requireTxSweeper()
+	requireTxScheduler()
 }
 
 func createNatsTxSubscriptions(wg *sync.WaitGroup) {
@@ -129,6 +148,38 @@ func createNatsTxReceiptSubscriptions(wg *sync.WaitGroup) {
 	}
 }
 
+func createNatsTxConfirmSubscriptions(wg *sync.WaitGroup) {
+	for i := uint64(0); i < natsutil.GetNatsConsumerConcurrency(); i++ {
+		natsutil.RequireNatsJetstreamSubscription(wg,
+			txConfirmAckWait,
+			natsTxConfirmSubject,
+			natsTxConfirmSubject,
+			natsTxConfirmSubject,
+			consumeTxConfirmMsg,
+			txConfirmAckWait,
+			natsTxConfirmMaxInFlight,
+			natsTxConfirmMsgMaxDeliveries,
+			nil,
+		)
+	}
+}
+
+func createNatsTxReorgSubscriptions(wg *sync.WaitGroup) {
+	for i := uint64(0); i < natsutil.GetNatsConsumerConcurrency(); i++ {
+		natsutil.RequireNatsJetstreamSubscription(wg,
+			txReorgAckWait,
+			natsTxReorgSubject,
+			natsTxReorgSubject,
+			natsTxReorgSubject,
+			consumeTxReorgMsg,
+			txReorgAckWait,
+			natsTxReorgMaxInFlight,
+			natsTxReorgMsgMaxDeliveries,
+			nil,
+		)
+	}
+}
+
 func consumeTxCreateMsg(msg *nats.Msg) {
 	common.Log.Debugf("consuming %d-byte NATS tx message on subject: %s", len(msg.Data), msg.Subject)
 
@@ -241,60 +292,23 @@ func consumeTxCreateMsg(msg *nats.Msg) {
 			errmsg = fmt.Sprintf("%s\n\t%s", errmsg, *err.Message)
 		}
 
-		faucetSubsidyEligible := strings.Contains(strings.ToLower(errmsg), "insufficient funds") && tx.shouldSubsidize()
-		if faucetSubsidyEligible {
-			common.Log.Debugf("transaction execution failed due to insufficient funds but faucet subsidy exists for network: %s; requesting subsidized tx funding", tx.NetworkID)
-			faucetBeneficiary, _ := tx.signerFactory(db)
-			faucetBeneficiaryAddress := faucetBeneficiary.Address()
-
-			params := tx.ParseParams()
-			gas, gasOk := params["gas"].(float64)
-			if !gasOk {
-				gas = float64(210000 * 2) // FIXME-- parameterize
-			}
-			networkSubsidyFaucetDripValue := int64(100000000000000000) // FIXME-- configurable
-
-			err = subsidize(
-				db,
-				tx.NetworkID,
-				faucetBeneficiaryAddress,
-				networkSubsidyFaucetDripValue,
-				int64(gas),
-			)
-			if err == nil {
-				db.Delete(&tx) // Drop tx that had insufficient funds so its hash can be rebroadcast...
-				common.Log.Debugf("faucet subsidy transaction broadcast; beneficiary: %s", faucetBeneficiaryAddress)
-				contract.TransactionID = &tx.ID
-				db.Save(&contract)
-				msg.Ack()
-				return
-			}
-		} else {
-			common.Log.Warning(errmsg)
-			msg.Nak()
-		}
-	}
-}
-
-// subsidize the given beneficiary with a drip equal to the given val
-func subsidize(db *gorm.DB, networkID uuid.UUID, beneficiary string, val, gas int64) error {
-	payment, err := bookie.CreatePayment(util.DefaultVaultAccessJWT, map[string]interface{}{
-		"to":   beneficiary,
-		"data": common.StringOrNil("0x"),
-	})
-	if err != nil {
-		return err
+		common.Log.Warning(errmsg)
+		msg.Nak()
 	}
-
-	common.Log.Debugf("subsidized transaction using api.providepayments.com; beneficiary: %s; tx hash: %s", beneficiary, payment.Params["result"].(string))
-	return nil
 }
 
 func consumeTxExecutionMsg(msg *nats.Msg) {
 	common.Log.Debugf("consuming %d-byte NATS tx message on subject: %s", len(msg.Data), msg.Subject)
 
+	txMsg := &txMessage{}
+	err := json.Unmarshal(msg.Data, txMsg)
+	if err == nil && txMsg.Transaction != nil {
+		consumeTxMsg(dbconf.DatabaseConnection(), msg, txMsg.Transaction)
+		return
+	}
+
 	execution := &contract.Execution{}
-	err := json.Unmarshal(msg.Data, execution)
+	err = json.Unmarshal(msg.Data, execution)
 	if err != nil {
 		common.Log.Warningf("failed to unmarshal contract execution during NATS tx message handling")
 		msg.Nak()
@@ -360,38 +374,28 @@ func consumeTxExecutionMsg(msg *nats.Msg) {
 		return
 	}
 
+	if execution.ScheduledAt != nil || execution.NotBeforeBlock != nil {
+		scheduled, err := scheduleExecution(db, cntract, execution)
+		if err != nil {
+			common.Log.Warningf("failed to schedule contract execution; %s", err.Error())
+			msg.Nak()
+			return
+		}
+		common.Log.Debugf("parked contract execution with ref %s as scheduled tx: %s", *scheduled.Ref, scheduled.ID)
+		msg.Ack()
+		return
+	}
+
+	// NATS may redeliver the tx message up to natsTxMsgMaxDeliveries times; never execute the same ref twice
+	if existing := executionTransactionFactory(cntract, execution).findByRef(db); existing != nil {
+		common.Log.Debugf("contract execution with ref %s already resulted in tx: %s; status: %s", *execution.Ref, existing.ID, *existing.Status)
+		msg.Ack()
+		return
+	}
+
 	executionResponse, err := executeTransaction(cntract, execution)
 	if err != nil {
 		common.Log.Debugf("contract execution failed; %s", err.Error())
-
-		// CHECKME - this functionality is now in bookie, and shouldn't be replicated here
-		// if execution.AccountAddress != nil {
-		// 	networkSubsidyFaucetDripValue := int64(100000000000000000) // FIXME-- configurable
-		// 	_subsidize := strings.Contains(strings.ToLower(err.Error()), "insufficient funds") && tx.shouldSubsidize()
-
-		// 	if _subsidize {
-		// 		common.Log.Debugf("contract execution failed due to insufficient funds but tx subsidize flag is set; requesting subsidized tx funding for target network: %s", cntract.NetworkID)
-		// 		faucetBeneficiaryAddress := *execution.AccountAddress
-		// 		err = subsidize(
-		// 			db,
-		// 			cntract.NetworkID,
-		// 			faucetBeneficiaryAddress,
-		// 			networkSubsidyFaucetDripValue,
-		// 			int64(210000*2),
-		// 		)
-		// 		if err == nil {
-		// 			db.Where("ref = ?", execution.Ref).Find(&tx)
-		// 			if tx != nil && tx.ID != uuid.Nil {
-		// 				db.Delete(&tx) // Drop tx that had insufficient funds so its hash can be rebroadcast...
-		// 			}
-		// 			common.Log.Debugf("faucet subsidy transaction broadcast; beneficiary: %s", faucetBeneficiaryAddress)
-		// 		}
-		// 	}
-
-		// } else {
-		// 	common.Log.Warningf("Failed to execute contract; %s", err.Error())
-		// }
-
 		msg.Nak()
 	} else {
 		logmsg := fmt.Sprintf("executed contract: %s", *cntract.Address)
@@ -491,7 +495,15 @@ func consumeTxFinalizeMsg(msg *nats.Msg) {
 		tx.NetworkLatency = &networkLatency
 	}
 
-	tx.updateStatus(db, "success", nil)
+	ntwrk := &network.Network{}
+	db.Model(tx).Related(&ntwrk)
+	if ntwrk == nil || ntwrk.ID == uuid.Nil {
+		nack(msg, fmt.Sprintf("failed to finalize tx: %s; network not resolved", hash), true)
+		return
+	}
+
+	// txs which reverted on-chain remain failed once finalized; others remain pending until confirmed
+	tx.finalizeStatus(db, ntwrk, blockNumber)
 	result := db.Save(&tx)
 	errors := result.GetErrors()
 	if len(errors) > 0 {
@@ -545,7 +557,7 @@ func consumeTxReceiptMsg(msg *nats.Msg) {
 		return
 	}
 
-	signer, err := tx.signerFactory(db)
+	ntwrk, signer, err := tx.resolveSigner(db)
 	if err != nil {
 		desc := "failed to resolve tx signing account or HD wallet"
 		common.Log.Warningf(desc)
@@ -554,13 +566,15 @@ func consumeTxReceiptMsg(msg *nats.Msg) {
 		return
 	}
 
-	err = tx.fetchReceipt(db, signer.Network, signer.Address())
+	err = tx.fetchReceipt(db, ntwrk, signer.Address())
 	if err != nil {
 		common.Log.Debugf(fmt.Sprintf("failed to fetch tx receipt; %s", err.Error()))
 		// msg.Nak()
 		return
 	} else {
 		common.Log.Debugf("fetched tx receipt for hash: %s", *tx.Hash)
+		tx.settleRelayedGas(db, tx.Response.Receipt.(*provide.TxReceipt).GasUsed)
+		tx.settleSafeTransaction(db, tx.Response.Receipt.(*provide.TxReceipt).Status == 1)
 
 		blockNumber := tx.Response.Receipt.(*provide.TxReceipt).BlockNumber
 		// if we have a block number in the receipt, and the tx has no block
@@ -573,7 +587,140 @@ func consumeTxReceiptMsg(msg *nats.Msg) {
 			common.Log.Debugf("tx %s finalized in block %v at %s", *tx.Hash, blockNumber, receiptFinalized.Format("Mon, 02 Jan 2006 15:04:05 MST"))
 		}
 
-		tx.updateStatus(db, "success", nil)
+		// post-byzantium receipts have a status of 0 when the tx reverted; pre-byzantium receipts include the post-state root instead
+		receipt := tx.Response.Receipt.(*provide.TxReceipt)
+		if ntwrk.IsEthereumNetwork() && len(receipt.PostState) == 0 && receipt.Status == 0 {
+			reason := tx.replayFailedTx(db, ntwrk, signer.Address(), blockNumber)
+			if reason == nil {
+				reason = &RevertReason{
+					Type:    revertReasonTypeUnknown,
+					Message: common.StringOrNil("tx reverted on-chain but did not revert when replayed; it may have run out of gas"),
+				}
+			}
+			tx.setRevertReason(reason)
+			common.Log.Debugf("tx %s reverted on-chain; %s", *tx.Hash, *tx.Description)
+		}
+
+		head := latestBlock(ntwrk)
+		if head == nil || (tx.Block != nil && *head < *tx.Block) {
+			head = tx.Block
+		}
+		if head != nil {
+			tx.finalizeStatus(db, ntwrk, *head)
+		} else {
+			tx.updateStatus(db, "success", nil)
+			tx.markReplacedTxs(db)
+		}
+		msg.Ack()
+	}
+}
+
+func consumeTxConfirmMsg(msg *nats.Msg) {
+	common.Log.Tracef("consuming %d-byte NATS tx confirm message", len(msg.Data))
+
+	var params map[string]interface{}
+	err := json.Unmarshal(msg.Data, &params)
+	if err != nil {
+		common.Log.Warningf("failed to umarshal tx confirm message; %s", err.Error())
+		msg.Term()
+		return
+	}
+
+	networkID, networkIDOk := params["network_id"].(string)
+	block, blockOk := params["block"].(float64)
+	if !networkIDOk || !blockOk {
+		common.Log.Warningf("failed to confirm txs; network_id and block are required")
+		msg.Term()
+		return
+	}
+
+	db := dbconf.DatabaseConnection()
+
+	ntwrk := &network.Network{}
+	db.Where("id = ?", networkID).Find(&ntwrk)
+	if ntwrk == nil || ntwrk.ID == uuid.Nil {
+		common.Log.Warningf("failed to confirm txs; network not resolved: %s", networkID)
+		msg.Term()
+		return
+	}
+
+	confirmed := confirmTxs(db, ntwrk, uint64(block))
+	common.Log.Debugf("finalized %d confirmed tx(s) as of block %d on network: %s", confirmed, uint64(block), networkID)
+	msg.Ack()
+}
+
+func consumeTxReorgMsg(msg *nats.Msg) {
+	common.Log.Tracef("consuming %d-byte NATS tx reorg message", len(msg.Data))
+
+	var params map[string]interface{}
+	err := json.Unmarshal(msg.Data, &params)
+	if err != nil {
+		common.Log.Warningf("failed to umarshal tx reorg message; %s", err.Error())
+		msg.Term()
+		return
+	}
+
+	networkIDStr, networkIDOk := params["network_id"].(string)
+	block, blockOk := params["block"].(float64)
+	if !networkIDOk || !blockOk {
+		common.Log.Warningf("failed to revert orphaned txs; network_id and block are required")
+		msg.Term()
+		return
+	}
+
+	networkID, err := uuid.FromString(networkIDStr)
+	if err != nil {
+		common.Log.Warningf("failed to revert orphaned txs; invalid network id: %s", networkIDStr)
+		msg.Term()
+		return
+	}
+
+	reverted, err := revertOrphanedTxs(dbconf.DatabaseConnection(), networkID, uint64(block))
+	if err != nil {
+		common.Log.Warningf("failed to revert orphaned txs; %s", err.Error())
+		msg.Nak()
+		return
+	}
+
+	common.Log.Debugf("reverted %d tx(s) orphaned in block %d on network: %s", reverted, uint64(block), networkID)
+	msg.Ack()
+}
+
+// consumeTxMsg creates and broadcasts a single tx which was submitted in a batch or was scheduled
+func consumeTxMsg(db *gorm.DB, msg *nats.Msg, tx *Transaction) {
+	// the ref makes the tx message idempotent, so a tx message without one is never consumed
+	if tx.Ref == nil {
+		common.Log.Warningf("dropping %d-byte NATS tx message without a ref on subject: %s", len(msg.Data), msg.Subject)
+		msg.Term()
+		return
+	}
+
+	// NATS may redeliver the tx message; never create the same ref twice
+	if existing := tx.findByRef(db); existing != nil {
+		common.Log.Debugf("tx with ref %s already created: %s; status: %s", *tx.Ref, existing.ID, *existing.Status)
+		msg.Ack()
+		return
+	}
+
+	if tx.Create(db) {
+		common.Log.Debugf("created tx %s with ref %s", tx.ID, *tx.Ref)
+		msg.Ack()
+		return
+	}
+
+	// the tx will never be broadcast; release its batch-allocated nonce unless it was signed, in which case
+	// the broadcast released it if the network did not consume it
+	tx.releaseBatchNonce(db)
+
+	if tx.ID != uuid.Nil {
+		// the tx was persisted but its signing or broadcast failed; the failure is recorded on the tx
+		common.Log.Debugf("failed to broadcast tx %s with ref %s", tx.ID, *tx.Ref)
 		msg.Ack()
+		return
+	}
+
+	if len(tx.Errors) > 0 {
+		common.Log.Warningf("failed to create tx with ref %s; %s", *tx.Ref, *tx.Errors[0].Message)
 	}
+	msg.Term()
 }
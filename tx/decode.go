@@ -0,0 +1,69 @@
+package tx
+
+import (
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	ethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/jinzhu/gorm"
+	uuid "github.com/kthomas/go.uuid"
+	"github.com/provideplatform/nchain/common"
+	"github.com/provideplatform/nchain/contract"
+	"github.com/provideplatform/nchain/network"
+	providecrypto "github.com/provideplatform/provide-go/crypto"
+)
+
+// decode decodes the calldata of the tx and the logs of its receipt using the ABIs of the known contracts
+// which were called and which emitted the logs; calldata and logs which cannot be decoded are omitted
+func (t *Transaction) decode(db *gorm.DB, ntwrk *network.Network) {
+	if !ntwrk.IsEthereumNetwork() {
+		return
+	}
+
+	if t.To != nil && t.Data != nil {
+		kontract := t.GetContract(db)
+		if kontract != nil && kontract.ID != uuid.Nil {
+			input, err := kontract.DecodeInput(ethcommon.FromHex(*t.Data))
+			if err != nil {
+				common.Log.Debugf("failed to decode calldata of tx %s using abi of contract %s; %s", t.ID, kontract.ID, err.Error())
+			} else {
+				t.DecodedInput = input
+			}
+		}
+	}
+
+	if t.Hash == nil {
+		return
+	}
+
+	receipt, err := providecrypto.EVMGetTxReceipt(ntwrk.ID.String(), ntwrk.RPCURL(), *t.Hash, "")
+	if err != nil || receipt == nil {
+		return
+	}
+
+	abis := map[ethcommon.Address]*abi.ABI{}
+	for _, log := range receipt.Logs {
+		_abi, abiOk := abis[log.Address]
+		if !abiOk {
+			kontract := contract.Find(db, t.NetworkID, log.Address.Hex())
+			if kontract != nil {
+				_abi, err = kontract.ReadEthereumContractAbi()
+				if err != nil {
+					common.Log.Debugf("failed to read abi of contract %s; %s", kontract.ID, err.Error())
+				}
+			}
+			abis[log.Address] = _abi
+		}
+		if _abi == nil {
+			continue
+		}
+
+		decoded, err := contract.DecodeLog(_abi, log.Address.Hex(), log.Topics, log.Data)
+		if err != nil {
+			common.Log.Debugf("failed to decode log %d of tx %s; %s", log.Index, t.ID, err.Error())
+			continue
+		}
+
+		logIndex := log.Index
+		decoded.LogIndex = &logIndex
+		t.DecodedLogs = append(t.DecodedLogs, decoded)
+	}
+}
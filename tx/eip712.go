@@ -0,0 +1,96 @@
+package tx
+
+import (
+	"fmt"
+	"math/big"
+	"strings"
+
+	ethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// eip712Domain is the EIP-712 signing domain of a verifying contract; optional fields are
+// omitted from the domain type when nil
+type eip712Domain struct {
+	Name              *string
+	Version           *string
+	ChainID           *big.Int
+	VerifyingContract *ethcommon.Address
+}
+
+// separator returns the EIP-712 domain separator
+func (d *eip712Domain) separator() []byte {
+	fields := make([]string, 0)
+	values := make([][]byte, 0)
+
+	if d.Name != nil {
+		fields = append(fields, "string name")
+		values = append(values, crypto.Keccak256([]byte(*d.Name)))
+	}
+	if d.Version != nil {
+		fields = append(fields, "string version")
+		values = append(values, crypto.Keccak256([]byte(*d.Version)))
+	}
+	if d.ChainID != nil {
+		fields = append(fields, "uint256 chainId")
+		values = append(values, eip712Uint(d.ChainID))
+	}
+	if d.VerifyingContract != nil {
+		fields = append(fields, "address verifyingContract")
+		values = append(values, eip712Address(*d.VerifyingContract))
+	}
+
+	typeHash := crypto.Keccak256([]byte("EIP712Domain(" + strings.Join(fields, ",") + ")"))
+	return crypto.Keccak256(append([][]byte{typeHash}, values...)...)
+}
+
+// eip712Hash returns the EIP-712 signing hash of the given struct hash within the domain
+func (d *eip712Domain) hash(structHash []byte) []byte {
+	return crypto.Keccak256([]byte{0x19, 0x01}, d.separator(), structHash)
+}
+
+// eip712StructHash returns the hash of a struct of the given type having the given encoded member values
+func eip712StructHash(typ string, values ...[]byte) []byte {
+	return crypto.Keccak256(append([][]byte{crypto.Keccak256([]byte(typ))}, values...)...)
+}
+
+// eip712Uint encodes the given uint256 struct member
+func eip712Uint(val *big.Int) []byte {
+	if val == nil {
+		return make([]byte, 32)
+	}
+	return ethcommon.LeftPadBytes(val.Bytes(), 32)
+}
+
+// eip712Address encodes the given address struct member
+func eip712Address(addr ethcommon.Address) []byte {
+	return ethcommon.LeftPadBytes(addr.Bytes(), 32)
+}
+
+// eip712Bytes encodes the given dynamic bytes struct member
+func eip712Bytes(data []byte) []byte {
+	return crypto.Keccak256(data)
+}
+
+// typedDataSigner resolves the address and hash signer used to sign EIP-712 typed data, i.e. forward requests
+// and Safe txs, on behalf of the given signer; accounts of a registered external type must sign using local key material
+func typedDataSigner(txs *TransactionSigner) (ethcommon.Address, hashSignerFunc, error) {
+	if txs.Account != nil && txs.Account.Type != nil {
+		if factory, factoryOk := signerFactories[*txs.Account.Type]; factoryOk {
+			signer, err := factory(txs)
+			if err != nil {
+				return ethcommon.Address{}, nil, fmt.Errorf("failed to resolve %s signer for account %s; %s", *txs.Account.Type, txs.Account.ID, err.Error())
+			}
+			if s, hashSignerOk := signer.(*hashSigner); hashSignerOk {
+				return ethcommon.HexToAddress(s.Address()), s.signHash, nil
+			}
+			return ethcommon.Address{}, nil, fmt.Errorf("%s accounts cannot sign typed data", *txs.Account.Type)
+		}
+	}
+
+	address, vaultID, keyID, opts, err := txs.signingIdentity()
+	if err != nil {
+		return ethcommon.Address{}, nil, err
+	}
+	return ethcommon.HexToAddress(address), vaultSignHash(vaultID, keyID, opts), nil
+}
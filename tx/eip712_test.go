@@ -0,0 +1,104 @@
+// +build unit
+
+package tx
+
+import (
+	"math/big"
+	"testing"
+
+	ethcommon "github.com/ethereum/go-ethereum/common"
+	ethcrypto "github.com/ethereum/go-ethereum/crypto"
+	"github.com/provideplatform/nchain/common"
+)
+
+// forwardRequestVectorHash is the EIP-712 signing hash of the forward request returned by forwardRequestFactory,
+// as computed by the go-ethereum typed data implementation used by eth_signTypedData_v4
+const forwardRequestVectorHash = "bfc1573bbf205e3e228e29afc1e1a00b8ac903007ea086febd2874d82efdadb2"
+
+// forwardRequestFactory returns a forward request of the envelope vector sender to the envelope vector recipient
+func forwardRequestFactory() *forwardRequest {
+	return &forwardRequest{
+		From:  ethcommon.HexToAddress(envelopeVectorSender),
+		To:    envelopeVectorRecipient,
+		Value: big.NewInt(0),
+		Gas:   big.NewInt(100000),
+		Nonce: big.NewInt(3),
+		Data:  ethcommon.FromHex("0xa9059cbb"),
+	}
+}
+
+func forwarderRelayerFactory() *Relayer {
+	return &Relayer{
+		ForwarderAddress: common.StringOrNil("0x4242424242424242424242424242424242424242"),
+	}
+}
+
+func TestForwardRequestHash(t *testing.T) {
+	hash := forwardRequestFactory().hash(forwarderRelayerFactory().domain(big.NewInt(5)))
+	if ethcommon.Bytes2Hex(hash) != forwardRequestVectorHash {
+		t.Errorf("expected forward request hash %s; got %x", forwardRequestVectorHash, hash)
+	}
+}
+
+func TestForwardRequestHashCoversEveryField(t *testing.T) {
+	domain := forwarderRelayerFactory().domain(big.NewInt(5))
+
+	mutations := map[string]func(req *forwardRequest){
+		"from": func(req *forwardRequest) {
+			req.From = ethcommon.HexToAddress("0x4343434343434343434343434343434343434343")
+		},
+		"to": func(req *forwardRequest) {
+			req.To = ethcommon.HexToAddress("0x4343434343434343434343434343434343434343")
+		},
+		"value": func(req *forwardRequest) { req.Value = big.NewInt(1) },
+		"gas":   func(req *forwardRequest) { req.Gas = big.NewInt(100001) },
+		"nonce": func(req *forwardRequest) { req.Nonce = big.NewInt(4) },
+		"data":  func(req *forwardRequest) { req.Data = ethcommon.FromHex("0x095ea7b3") },
+	}
+
+	for field, mutate := range mutations {
+		req := forwardRequestFactory()
+		mutate(req)
+		if ethcommon.Bytes2Hex(req.hash(domain)) == forwardRequestVectorHash {
+			t.Errorf("expected forward request hash to change with its %s", field)
+		}
+	}
+}
+
+func TestForwarderDomain(t *testing.T) {
+	relayer := forwarderRelayerFactory()
+	req := forwardRequestFactory()
+
+	// the domain of a forwarder of another name, version or chain must not be replayable
+	name := "Forwarder"
+	version := "1.0.0"
+	relayer.ForwarderName = &name
+	if ethcommon.Bytes2Hex(req.hash(relayer.domain(big.NewInt(5)))) == forwardRequestVectorHash {
+		t.Errorf("expected forward request hash to change with the forwarder name")
+	}
+
+	relayer.ForwarderName = nil
+	relayer.ForwarderVersion = &version
+	if ethcommon.Bytes2Hex(req.hash(relayer.domain(big.NewInt(5)))) == forwardRequestVectorHash {
+		t.Errorf("expected forward request hash to change with the forwarder version")
+	}
+
+	relayer.ForwarderVersion = nil
+	if ethcommon.Bytes2Hex(req.hash(relayer.domain(big.NewInt(1)))) == forwardRequestVectorHash {
+		t.Errorf("expected forward request hash to change with the chain id")
+	}
+}
+
+func TestEIP712DomainSeparatorOmitsUnsetFields(t *testing.T) {
+	forwarder := ethcommon.HexToAddress("0x4242424242424242424242424242424242424242")
+	domain := &eip712Domain{
+		ChainID:           big.NewInt(5),
+		VerifyingContract: &forwarder,
+	}
+
+	typeHash := ethcrypto.Keccak256([]byte("EIP712Domain(uint256 chainId,address verifyingContract)"))
+	expected := ethcrypto.Keccak256(typeHash, eip712Uint(big.NewInt(5)), eip712Address(forwarder))
+	if ethcommon.Bytes2Hex(domain.separator()) != ethcommon.Bytes2Hex(expected) {
+		t.Errorf("expected domain separator of %x; got %x", expected, domain.separator())
+	}
+}
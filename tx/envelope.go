@@ -0,0 +1,288 @@
+package tx
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+
+	ethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	ethcrypto "github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/rlp"
+	providecrypto "github.com/provideplatform/provide-go/crypto"
+)
+
+// EIP-2718 typed transaction envelope types
+const (
+	accessListTxType = byte(0x01) // EIP-2930
+	dynamicFeeTxType = byte(0x02) // EIP-1559
+)
+
+// AccessTuple is a single EIP-2930 access list entry; its field order matches the RLP encoding
+type AccessTuple struct {
+	Address     ethcommon.Address `json:"address"`
+	StorageKeys []ethcommon.Hash  `json:"storageKeys"`
+}
+
+// AccessList is an EIP-2930 access list
+type AccessList []AccessTuple
+
+// TypedTransaction is an EIP-2718 typed transaction envelope; the EVM signing path uses it for
+// the tx types which predate support in the underlying go-ethereum dependency
+type TypedTransaction struct {
+	Type    byte
+	ChainID *big.Int
+	Nonce   uint64
+
+	// GasPrice is used by type-1 (access list) txs
+	GasPrice *big.Int
+
+	// GasTipCap (max_priority_fee_per_gas) and GasFeeCap (max_fee_per_gas) are used by type-2 (dynamic fee) txs
+	GasTipCap *big.Int
+	GasFeeCap *big.Int
+
+	Gas        uint64
+	To         *ethcommon.Address
+	Value      *big.Int
+	Data       []byte
+	AccessList AccessList
+
+	// Signature values; V is the y-parity of the signature (0 or 1)
+	V *big.Int
+	R *big.Int
+	S *big.Int
+}
+
+// payload returns the type-specific fields of the envelope, excluding the signature values
+func (t *TypedTransaction) payload() ([]interface{}, error) {
+	var to []byte
+	if t.To != nil {
+		to = t.To.Bytes()
+	}
+
+	value := t.Value
+	if value == nil {
+		value = big.NewInt(0)
+	}
+
+	accessList := t.AccessList
+	if accessList == nil {
+		accessList = AccessList{}
+	}
+
+	switch t.Type {
+	case accessListTxType:
+		return []interface{}{
+			t.ChainID,
+			t.Nonce,
+			t.GasPrice,
+			t.Gas,
+			to,
+			value,
+			t.Data,
+			accessList,
+		}, nil
+	case dynamicFeeTxType:
+		return []interface{}{
+			t.ChainID,
+			t.Nonce,
+			t.GasTipCap,
+			t.GasFeeCap,
+			t.Gas,
+			to,
+			value,
+			t.Data,
+			accessList,
+		}, nil
+	}
+
+	return nil, fmt.Errorf("unsupported typed tx envelope type: %d", t.Type)
+}
+
+// encode returns the envelope type byte followed by the RLP encoding of the given fields
+func (t *TypedTransaction) encode(fields []interface{}) ([]byte, error) {
+	encoded, err := rlp.EncodeToBytes(fields)
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte{t.Type}, encoded...), nil
+}
+
+// SigningHash returns the digest which is signed by the sender
+func (t *TypedTransaction) SigningHash() ([]byte, error) {
+	fields, err := t.payload()
+	if err != nil {
+		return nil, err
+	}
+
+	encoded, err := t.encode(fields)
+	if err != nil {
+		return nil, err
+	}
+
+	return ethcrypto.Keccak256(encoded), nil
+}
+
+// WithSignature sets the signature values from the given 65-byte [R || S || V] signature
+func (t *TypedTransaction) WithSignature(sig []byte) error {
+	if len(sig) != 65 {
+		return fmt.Errorf("invalid %d-byte signature for typed tx envelope", len(sig))
+	}
+
+	v := sig[64]
+	if v >= 27 {
+		v -= 27
+	}
+
+	if v > 1 {
+		return fmt.Errorf("invalid signature recovery id for typed tx envelope: %d", sig[64])
+	}
+
+	t.R = new(big.Int).SetBytes(sig[:32])
+	t.S = new(big.Int).SetBytes(sig[32:64])
+	t.V = big.NewInt(int64(v))
+	return nil
+}
+
+// MarshalBinary returns the canonical encoding of the signed envelope, as broadcast via eth_sendRawTransaction
+func (t *TypedTransaction) MarshalBinary() ([]byte, error) {
+	if t.V == nil || t.R == nil || t.S == nil {
+		return nil, errors.New("unable to encode unsigned typed tx envelope")
+	}
+
+	fields, err := t.payload()
+	if err != nil {
+		return nil, err
+	}
+
+	return t.encode(append(fields, t.V, t.R, t.S))
+}
+
+// Hash returns the hash of the signed envelope
+func (t *TypedTransaction) Hash() ethcommon.Hash {
+	encoded, err := t.MarshalBinary()
+	if err != nil {
+		return ethcommon.Hash{}
+	}
+	return ethcommon.BytesToHash(ethcrypto.Keccak256(encoded))
+}
+
+// UnmarshalBinary decodes the canonical encoding of a signed typed tx envelope
+func (t *TypedTransaction) UnmarshalBinary(data []byte) error {
+	if len(data) < 2 {
+		return errors.New("invalid typed tx envelope; envelope too short")
+	}
+
+	var to []byte
+	t.Type = data[0]
+
+	switch t.Type {
+	case accessListTxType:
+		var fields struct {
+			ChainID    *big.Int
+			Nonce      uint64
+			GasPrice   *big.Int
+			Gas        uint64
+			To         []byte
+			Value      *big.Int
+			Data       []byte
+			AccessList AccessList
+			V, R, S    *big.Int
+		}
+		if err := rlp.DecodeBytes(data[1:], &fields); err != nil {
+			return fmt.Errorf("failed to decode type-%d tx envelope; %s", t.Type, err.Error())
+		}
+		t.ChainID, t.Nonce, t.GasPrice, t.Gas, to = fields.ChainID, fields.Nonce, fields.GasPrice, fields.Gas, fields.To
+		t.Value, t.Data, t.AccessList = fields.Value, fields.Data, fields.AccessList
+		t.V, t.R, t.S = fields.V, fields.R, fields.S
+	case dynamicFeeTxType:
+		var fields struct {
+			ChainID    *big.Int
+			Nonce      uint64
+			GasTipCap  *big.Int
+			GasFeeCap  *big.Int
+			Gas        uint64
+			To         []byte
+			Value      *big.Int
+			Data       []byte
+			AccessList AccessList
+			V, R, S    *big.Int
+		}
+		if err := rlp.DecodeBytes(data[1:], &fields); err != nil {
+			return fmt.Errorf("failed to decode type-%d tx envelope; %s", t.Type, err.Error())
+		}
+		t.ChainID, t.Nonce, t.GasTipCap, t.GasFeeCap, t.Gas, to = fields.ChainID, fields.Nonce, fields.GasTipCap, fields.GasFeeCap, fields.Gas, fields.To
+		t.Value, t.Data, t.AccessList = fields.Value, fields.Data, fields.AccessList
+		t.V, t.R, t.S = fields.V, fields.R, fields.S
+	default:
+		return fmt.Errorf("unsupported typed tx envelope type: %d", t.Type)
+	}
+
+	if len(to) > 0 {
+		if len(to) != ethcommon.AddressLength {
+			return fmt.Errorf("invalid %d-byte recipient address in typed tx envelope", len(to))
+		}
+		addr := ethcommon.BytesToAddress(to)
+		t.To = &addr
+	}
+
+	return nil
+}
+
+// Sender recovers the address which signed the envelope
+func (t *TypedTransaction) Sender() (ethcommon.Address, error) {
+	if t.V == nil || t.R == nil || t.S == nil {
+		return ethcommon.Address{}, errors.New("unable to recover sender of unsigned typed tx envelope")
+	}
+
+	if !t.V.IsUint64() || t.V.Uint64() > 1 {
+		return ethcommon.Address{}, fmt.Errorf("invalid signature y-parity for typed tx envelope: %s", t.V.String())
+	}
+
+	if !ethcrypto.ValidateSignatureValues(byte(t.V.Uint64()), t.R, t.S, true) {
+		return ethcommon.Address{}, errors.New("invalid signature values for typed tx envelope")
+	}
+
+	hash, err := t.SigningHash()
+	if err != nil {
+		return ethcommon.Address{}, err
+	}
+
+	sig := make([]byte, 65)
+	copy(sig[32-len(t.R.Bytes()):32], t.R.Bytes())
+	copy(sig[64-len(t.S.Bytes()):64], t.S.Bytes())
+	sig[64] = byte(t.V.Uint64())
+
+	pubkey, err := ethcrypto.SigToPub(hash, sig)
+	if err != nil {
+		return ethcommon.Address{}, fmt.Errorf("failed to recover sender of typed tx envelope; %s", err.Error())
+	}
+
+	return ethcrypto.PubkeyToAddress(*pubkey), nil
+}
+
+// broadcastTypedTx emits the given signed typed tx envelope for inclusion in a block
+func broadcastTypedTx(rpcClientKey, rpcURL string, signedTx *TypedTransaction) error {
+	encoded, err := signedTx.MarshalBinary()
+	if err != nil {
+		return err
+	}
+
+	return sendRawTx(rpcClientKey, rpcURL, encoded)
+}
+
+// sendRawTx transmits the given encoded signed tx using eth_sendRawTransaction
+func sendRawTx(rpcClientKey, rpcURL string, encoded []byte) error {
+	client, err := providecrypto.EVMResolveJsonRpcClient(rpcClientKey, rpcURL)
+	if err != nil {
+		return fmt.Errorf("failed to dial JSON-RPC host; %s", err.Error())
+	}
+
+	err = client.CallContext(context.TODO(), nil, "eth_sendRawTransaction", hexutil.Encode(encoded))
+	if err != nil {
+		return fmt.Errorf("failed to transmit signed tx to JSON-RPC host; %s", err.Error())
+	}
+
+	return nil
+}
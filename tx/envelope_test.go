@@ -0,0 +1,329 @@
+// +build unit
+
+package tx
+
+import (
+	"bytes"
+	"encoding/json"
+	"math/big"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	ethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	ethcrypto "github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/provideplatform/nchain/common"
+	"github.com/provideplatform/nchain/network"
+)
+
+// envelope vectors were generated and signed using go-ethereum v1.10.26 with the following key
+const envelopeVectorPrivateKey = "4c0883a69102937d6231471b5dbb6204fe5129617082792ae468d01a3f362318"
+const envelopeVectorSender = "0x2c7536E3605D9C16a7a3D7b1898e529396a65c23"
+
+type envelopeVector struct {
+	name        string
+	tx          *TypedTransaction
+	signingHash string
+	raw         string
+	hash        string
+}
+
+var envelopeVectorRecipient = ethcommon.HexToAddress("0x3535353535353535353535353535353535353535")
+
+func dynamicFeeEnvelopeVectors() []*envelopeVector {
+	return []*envelopeVector{
+		{
+			name: "value transfer",
+			tx: &TypedTransaction{
+				Type:      dynamicFeeTxType,
+				ChainID:   big.NewInt(1),
+				Nonce:     9,
+				GasTipCap: big.NewInt(2000000000),
+				GasFeeCap: big.NewInt(100000000000),
+				Gas:       21000,
+				To:        &envelopeVectorRecipient,
+				Value:     big.NewInt(1000000000000000000),
+			},
+			signingHash: "0xd6a0cf4cd9a39c3e71eb5638a92a3a9cfee2004137b97a49aff978c9e2094ede",
+			raw:         "0x02f8730109847735940085174876e800825208943535353535353535353535353535353535353535880de0b6b3a764000080c080a0c3ac7c5fa2a6e5775fed363da0bcb70a29325fe85aea896b8d7fa13ab8c67be3a030341927cafb974d3dad76a1835eed58e3ef67f32f506a319d8750230addfc32",
+			hash:        "0xf042747c0aca58093fc723846de434f72584bebe657886a191a162870cce413d",
+		},
+		{
+			name: "odd y-parity",
+			tx: &TypedTransaction{
+				Type:      dynamicFeeTxType,
+				ChainID:   big.NewInt(5),
+				Nonce:     10,
+				GasTipCap: big.NewInt(2000000000),
+				GasFeeCap: big.NewInt(100000000000),
+				Gas:       21000,
+				To:        &envelopeVectorRecipient,
+				Value:     big.NewInt(1),
+			},
+			signingHash: "0x9e354e7aed516a4b97bf274097ddd589e8a64c5a72dcbdc54034aff6eb87f64f",
+			raw:         "0x02f86b050a847735940085174876e8008252089435353535353535353535353535353535353535350180c001a0f39ade0597167adff6709c30ea130d56c7f9648dc298d75d4e71303b25cc332ea00988a2add713ea03bf848c80f480ca30994ff70c0db187558c781a2f43fa6f2e",
+			hash:        "0xaa947fc98f42222d4966ae3af74238b8c440aecaa703fb1fe2aea73183d003a6",
+		},
+		{
+			name: "contract call with access list",
+			tx: &TypedTransaction{
+				Type:      dynamicFeeTxType,
+				ChainID:   big.NewInt(1),
+				Nonce:     3,
+				GasTipCap: big.NewInt(1500000000),
+				GasFeeCap: big.NewInt(30000000000),
+				Gas:       60000,
+				To:        &envelopeVectorRecipient,
+				Value:     big.NewInt(0),
+				Data:      ethcommon.FromHex("0xa9059cbb0000000000000000000000003535353535353535353535353535353535353535000000000000000000000000000000000000000000000000000000000000000a"),
+				AccessList: AccessList{
+					{Address: envelopeVectorRecipient, StorageKeys: []ethcommon.Hash{ethcommon.HexToHash("0x01")}},
+				},
+			},
+			signingHash: "0xb961d25919e154bc6516baca3b34e964b3e17eb2e7dd0144bd4d8340a8aa2f87",
+			raw:         "0x02f8e901038459682f008506fc23ac0082ea6094353535353535353535353535353535353535353580b844a9059cbb0000000000000000000000003535353535353535353535353535353535353535000000000000000000000000000000000000000000000000000000000000000af838f7943535353535353535353535353535353535353535e1a0000000000000000000000000000000000000000000000000000000000000000180a03035e0ceb50935ffd4326d018fbb299fc36e345fc6742c0c769c6978b9469651a01604c311ff7daba9fcc61a17bf5681b9958f569ff4839d6376727d56129ae31b",
+			hash:        "0x11f68ba825b426b76e5029be0a4cac5c8340ca284eb7047dc118e991784d1124",
+		},
+		{
+			name: "contract creation",
+			tx: &TypedTransaction{
+				Type:      dynamicFeeTxType,
+				ChainID:   big.NewInt(1),
+				Nonce:     0,
+				GasTipCap: big.NewInt(1),
+				GasFeeCap: big.NewInt(2),
+				Gas:       100000,
+				Value:     big.NewInt(0),
+				Data:      ethcommon.FromHex("0x6080604052"),
+			},
+			signingHash: "0x74e80e938d6e5fcd8a78fbe1dc48f34efcae6d81a12cbdf244feecaadf176dac",
+			raw:         "0x02f85401800102830186a08080856080604052c080a0c39d6139d3509fcc64fda953adad3ce3cc8a2ac49a26df657332384f6383c511a0018bb54772ff1c5f7fb0e60eb9a29ce75bb5f418d526d5f926e63bf6345d1a80",
+			hash:        "0xa7fd5ddffeaf776752f0448e65e7a22f46931ce81ca968df4e380fb0aac2c42c",
+		},
+	}
+}
+
+func accessListEnvelopeVectors() []*envelopeVector {
+	return []*envelopeVector{
+		{
+			name: "access list having an empty storage keys list",
+			tx: &TypedTransaction{
+				Type:     accessListTxType,
+				ChainID:  big.NewInt(1),
+				Nonce:    7,
+				GasPrice: big.NewInt(20000000000),
+				Gas:      50000,
+				To:       &envelopeVectorRecipient,
+				Value:    big.NewInt(10),
+				AccessList: AccessList{
+					{Address: envelopeVectorRecipient, StorageKeys: []ethcommon.Hash{ethcommon.HexToHash("0x00"), ethcommon.HexToHash("0x01")}},
+					{Address: ethcommon.HexToAddress("0xde0b295669a9fd93d5f28d9ec85e40f4cb697bae"), StorageKeys: []ethcommon.Hash{}},
+				},
+			},
+			signingHash: "0xfdf44e2baf9dd50684a014b78ec9e06ef1d13948c31e69d24d39ef15ee6b2a24",
+			raw:         "0x01f8d901078504a817c80082c3509435353535353535353535353535353535353535350a80f872f859943535353535353535353535353535353535353535f842a00000000000000000000000000000000000000000000000000000000000000000a00000000000000000000000000000000000000000000000000000000000000001d694de0b295669a9fd93d5f28d9ec85e40f4cb697baec080a0d094d6d0d1afce1be948e05dd9709ab72c3cec414015f594cbe96f8faf5b665ea02d86234ea375bc92e78f7b0e0398de772973a5cdffb3794bee34e2b46920b161",
+			hash:        "0x8cd2c9d32bb8cc8acc9be48ecf302fd7dc582370fa16834eb8af6911fe4d0a2b",
+		},
+		{
+			name: "empty access list",
+			tx: &TypedTransaction{
+				Type:     accessListTxType,
+				ChainID:  big.NewInt(1),
+				Nonce:    0,
+				GasPrice: big.NewInt(1000000000),
+				Gas:      21000,
+				To:       &envelopeVectorRecipient,
+				Value:    big.NewInt(1),
+			},
+			signingHash: "0xe44e53f57c2dd98ea421a728c5ab8c004680328dd3a628b120fc77ae64a1b7f2",
+			raw:         "0x01f8650180843b9aca008252089435353535353535353535353535353535353535350180c080a0a25746ca2ed3fd39a990813affa73231c08b0d0566f96f5a5e6f047e959ed182a00a69d4fe1c68704e4396c7482327275873be2b58581ef732f3a079699bfbcafc",
+			hash:        "0x8ea2269bd6558e661f7bc67a0a01b422ed0ec4fba0c86e8a19427f33dfcb403f",
+		},
+	}
+}
+
+// testEnvelopeVectors signs each vector using the vector key and asserts the signing hash, canonical encoding,
+// hash, round-trip decoding and sender recovery of the envelope match the vector
+func testEnvelopeVectors(t *testing.T, vectors []*envelopeVector) {
+	key, err := ethcrypto.HexToECDSA(envelopeVectorPrivateKey)
+	if err != nil {
+		t.Fatalf("failed to parse vector private key; %s", err.Error())
+	}
+
+	for _, vector := range vectors {
+		t.Run(vector.name, func(t *testing.T) {
+			signingHash, err := vector.tx.SigningHash()
+			if err != nil {
+				t.Fatalf("failed to resolve signing hash; %s", err.Error())
+			}
+			if hexutil.Encode(signingHash) != vector.signingHash {
+				t.Errorf("expected signing hash %s; got %s", vector.signingHash, hexutil.Encode(signingHash))
+			}
+
+			if _, err := vector.tx.MarshalBinary(); err == nil {
+				t.Errorf("expected unsigned envelope not to be encoded")
+			}
+
+			sig, err := ethcrypto.Sign(signingHash, key)
+			if err != nil {
+				t.Fatalf("failed to sign envelope; %s", err.Error())
+			}
+			sig[64] += 27 // signers may return the recovery id offset by 27
+			if err := vector.tx.WithSignature(sig); err != nil {
+				t.Fatalf("failed to set envelope signature; %s", err.Error())
+			}
+
+			raw, err := vector.tx.MarshalBinary()
+			if err != nil {
+				t.Fatalf("failed to encode signed envelope; %s", err.Error())
+			}
+			if hexutil.Encode(raw) != vector.raw {
+				t.Errorf("expected raw envelope %s; got %s", vector.raw, hexutil.Encode(raw))
+			}
+			if vector.tx.Hash().Hex() != vector.hash {
+				t.Errorf("expected envelope hash %s; got %s", vector.hash, vector.tx.Hash().Hex())
+			}
+
+			decoded := &TypedTransaction{}
+			if err := decoded.UnmarshalBinary(ethcommon.FromHex(vector.raw)); err != nil {
+				t.Fatalf("failed to decode raw envelope; %s", err.Error())
+			}
+			if decoded.Type != vector.tx.Type || decoded.Nonce != vector.tx.Nonce || decoded.Gas != vector.tx.Gas || decoded.ChainID.Cmp(vector.tx.ChainID) != 0 {
+				t.Errorf("decoded envelope does not match vector")
+			}
+			if (decoded.To == nil) != (vector.tx.To == nil) || (decoded.To != nil && *decoded.To != *vector.tx.To) {
+				t.Errorf("expected decoded recipient %v; got %v", vector.tx.To, decoded.To)
+			}
+			if decoded.Value.Cmp(vector.tx.Value) != 0 || !bytes.Equal(decoded.Data, vector.tx.Data) || len(decoded.AccessList) != len(vector.tx.AccessList) {
+				t.Errorf("decoded envelope value, data or access list does not match vector")
+			}
+
+			reencoded, err := decoded.MarshalBinary()
+			if err != nil {
+				t.Fatalf("failed to encode decoded envelope; %s", err.Error())
+			}
+			if hexutil.Encode(reencoded) != vector.raw {
+				t.Errorf("expected decoded envelope to encode as %s; got %s", vector.raw, hexutil.Encode(reencoded))
+			}
+
+			sender, err := decoded.Sender()
+			if err != nil {
+				t.Fatalf("failed to recover envelope sender; %s", err.Error())
+			}
+			if sender.Hex() != envelopeVectorSender {
+				t.Errorf("expected sender %s; got %s", envelopeVectorSender, sender.Hex())
+			}
+		})
+	}
+}
+
+func TestDynamicFeeEnvelopeVectors(t *testing.T) {
+	testEnvelopeVectors(t, dynamicFeeEnvelopeVectors())
+}
+
+func TestAccessListEnvelopeVectors(t *testing.T) {
+	testEnvelopeVectors(t, accessListEnvelopeVectors())
+}
+
+func TestAccessListEncoding(t *testing.T) {
+	// the access_list param uses the JSON-RPC encoding; storage keys may be empty or omitted
+	var param interface{}
+	json.Unmarshal([]byte(`[
+		{"address": "0x3535353535353535353535353535353535353535", "storageKeys": ["0x0000000000000000000000000000000000000000000000000000000000000000", "0x0000000000000000000000000000000000000000000000000000000000000001"]},
+		{"address": "0xde0b295669a9fd93d5f28d9ec85e40f4cb697bae", "storageKeys": []}
+	]`), &param)
+
+	accessList, err := parseAccessList(param)
+	if err != nil {
+		t.Fatalf("failed to parse access list; %s", err.Error())
+	}
+
+	encoded, err := rlp.EncodeToBytes(accessList)
+	if err != nil {
+		t.Fatalf("failed to encode access list; %s", err.Error())
+	}
+
+	// the access list of the first type-1 vector, as encoded by go-ethereum
+	expected := "0xf872f859943535353535353535353535353535353535353535f842a00000000000000000000000000000000000000000000000000000000000000000a00000000000000000000000000000000000000000000000000000000000000001d694de0b295669a9fd93d5f28d9ec85e40f4cb697baec0"
+	if hexutil.Encode(encoded) != expected {
+		t.Errorf("expected access list encoding %s; got %s", expected, hexutil.Encode(encoded))
+	}
+
+	json.Unmarshal([]byte(`[{"address": "0xde0b295669a9fd93d5f28d9ec85e40f4cb697bae"}]`), &param)
+	accessList, err = parseAccessList(param)
+	if err != nil {
+		t.Fatalf("failed to parse access list; %s", err.Error())
+	}
+
+	encoded, _ = rlp.EncodeToBytes(accessList)
+	if hexutil.Encode(encoded) != "0xd7d694de0b295669a9fd93d5f28d9ec85e40f4cb697baec0" {
+		t.Errorf("expected omitted storage keys to encode as an empty list; got %s", hexutil.Encode(encoded))
+	}
+
+	encoded, _ = rlp.EncodeToBytes(AccessList{})
+	if hexutil.Encode(encoded) != "0xc0" {
+		t.Errorf("expected empty access list to encode as an empty list; got %s", hexutil.Encode(encoded))
+	}
+}
+
+func TestEnvelopeSenderRejectsInvalidYParity(t *testing.T) {
+	tx := &TypedTransaction{}
+	if err := tx.UnmarshalBinary(ethcommon.FromHex(dynamicFeeEnvelopeVectors()[0].raw)); err != nil {
+		t.Fatalf("failed to decode raw envelope; %s", err.Error())
+	}
+
+	tx.V = big.NewInt(27)
+	if _, err := tx.Sender(); err == nil {
+		t.Errorf("expected sender recovery to fail for legacy recovery id")
+	}
+}
+
+func TestEnvelopeRejectsUnsupportedType(t *testing.T) {
+	tx := &TypedTransaction{}
+	err := tx.UnmarshalBinary(ethcommon.FromHex("0x03c0"))
+	if err == nil || !strings.Contains(err.Error(), "unsupported") {
+		t.Errorf("expected unsupported envelope type to be rejected; %v", err)
+	}
+}
+
+// gasEstimateStubFactory starts a JSON-RPC server which estimates 21000 gas for any tx
+func gasEstimateStubFactory(t *testing.T) (*network.Network, *httptest.Server) {
+	pending := uint64(7)
+	return jsonRPCStubFactory(t, map[string]jsonRPCHandler{
+		"eth_chainId":             func(params []json.RawMessage) (interface{}, error) { return "0x5", nil },
+		"eth_estimateGas":         func(params []json.RawMessage) (interface{}, error) { return "0x5208", nil },
+		"eth_gasPrice":            func(params []json.RawMessage) (interface{}, error) { return "0x3b9aca00", nil },
+		"eth_getTransactionCount": pendingNonceStub(&pending),
+	})
+}
+
+func TestSignTypedTxAppliesGasEstimateMultiplier(t *testing.T) {
+	ntwrk, srv := gasEstimateStubFactory(t)
+	defer srv.Close()
+
+	key, _ := ethcrypto.HexToECDSA(envelopeVectorPrivateKey)
+	signHash := func(hash []byte) ([]byte, error) {
+		sig, err := ethcrypto.Sign(hash, key)
+		if err == nil {
+			sig[64] += 27
+		}
+		return sig, err
+	}
+
+	tx := &Transaction{
+		To:    common.StringOrNil(envelopeVectorRecipient.Hex()),
+		Value: NewTxValue(1),
+	}
+	txs := &TransactionSigner{Network: ntwrk}
+	signedTx, _, err := txs.signTypedTx(tx, envelopeVectorSender, signHash, nil, 0, nil, nil, nil)
+	if err != nil {
+		t.Fatalf("failed to sign typed tx; %s", err.Error())
+	}
+
+	expected := uint64(float64(21000) * ntwrk.GasEstimateMultiplier())
+	if gas := signedTx.(*TypedTransaction).Gas; gas != expected {
+		t.Errorf("expected estimated gas of %d to be multiplied to %d; got %d", 21000, expected, gas)
+	}
+}
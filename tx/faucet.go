@@ -0,0 +1,400 @@
+package tx
+
+import (
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+	"time"
+
+	ethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/jinzhu/gorm"
+	dbconf "github.com/kthomas/go-db-config"
+	redisutil "github.com/kthomas/go-redisutil"
+	uuid "github.com/kthomas/go.uuid"
+	"github.com/provideplatform/nchain/common"
+	"github.com/provideplatform/nchain/network"
+	"github.com/provideplatform/nchain/wallet"
+	provide "github.com/provideplatform/provide-go/api"
+)
+
+// faucetDripDescription is the description of the txs broadcast by faucets
+const faucetDripDescription = "faucet drip"
+
+// Faucet funds addresses on a network with a fixed drip of the native currency from the faucet account;
+// drips are rate-limited per beneficiary address and per requesting application
+type Faucet struct {
+	provide.Model
+	NetworkID uuid.UUID  `sql:"not null;type:uuid" json:"network_id"`
+	UserID    *uuid.UUID `sql:"type:uuid" json:"user_id,omitempty"`   // user who configured the faucet
+	AccountID uuid.UUID  `sql:"not null;type:uuid" json:"account_id"` // account which signs and funds drips
+	Enabled   bool       `sql:"not null" json:"enabled"`
+	DripValue string     `sql:"not null;type:numeric" json:"drip_value"` // in wei
+
+	// Cooldowns, in seconds, between drips to the same address and drips requested by the same application
+	AddressCooldown     *uint64 `json:"address_cooldown,omitempty"`
+	ApplicationCooldown *uint64 `json:"application_cooldown,omitempty"`
+
+	// Maximum number of drips per UTC day to the same address and requested by the same application
+	AddressDailyCap     *uint64 `json:"address_daily_cap,omitempty"`
+	ApplicationDailyCap *uint64 `json:"application_daily_cap,omitempty"`
+}
+
+// FaucetDrip is the audit record of a drip requested from a faucet
+type FaucetDrip struct {
+	provide.Model
+	FaucetID       uuid.UUID  `sql:"not null;type:uuid" json:"faucet_id"`
+	NetworkID      uuid.UUID  `sql:"not null;type:uuid" json:"network_id"`
+	ApplicationID  *uuid.UUID `sql:"type:uuid" json:"application_id,omitempty"`
+	OrganizationID *uuid.UUID `sql:"type:uuid" json:"organization_id,omitempty"`
+	UserID         *uuid.UUID `sql:"type:uuid" json:"user_id,omitempty"`
+	TransactionID  *uuid.UUID `sql:"type:uuid" json:"transaction_id,omitempty"`
+	Beneficiary    string     `sql:"not null" json:"beneficiary"`
+	Value          string     `sql:"not null;type:numeric" json:"value"` // in wei
+	Status         string     `sql:"not null" json:"status"`             // broadcast or failed
+	Description    *string    `json:"description,omitempty"`
+}
+
+// FaucetRateLimitError is returned when a drip would exceed a cooldown or daily cap of the faucet
+type FaucetRateLimitError struct {
+	message string
+}
+
+// Error returns the reason the drip was rate-limited
+func (e *FaucetRateLimitError) Error() string {
+	return e.message
+}
+
+// faucetKey returns the key prefix of the rate-limit counters of the given faucet
+func faucetKey(faucetID uuid.UUID) string {
+	return fmt.Sprintf("faucet.%s", faucetID.String())
+}
+
+// faucetMutexKey returns the key which represents the distributed lock for rate-limiting drips of the given faucet
+func faucetMutexKey(faucetID uuid.UUID) string {
+	return fmt.Sprintf("%s.mutex", faucetKey(faucetID))
+}
+
+// faucetCooldownKey returns the key which is set for the duration of the cooldown of the given subject
+func faucetCooldownKey(faucetID uuid.UUID, subject string) string {
+	return fmt.Sprintf("%s.%s.cooldown", faucetKey(faucetID), subject)
+}
+
+// faucetDailyDripsKey returns the key where the number of drips to the given subject on the given day is cached
+func faucetDailyDripsKey(faucetID uuid.UUID, subject string, day time.Time) string {
+	return fmt.Sprintf("%s.%s.drips.%s", faucetKey(faucetID), subject, day.UTC().Format("20060102"))
+}
+
+// resolveFaucet returns the faucet of the given network, if any
+func resolveFaucet(db *gorm.DB, networkID uuid.UUID) *Faucet {
+	faucet := &Faucet{}
+	db.Where("network_id = ?", networkID).Find(&faucet)
+	if faucet == nil || faucet.ID == uuid.Nil {
+		return nil
+	}
+	return faucet
+}
+
+// Create and persist a faucet
+func (f *Faucet) Create() bool {
+	db := dbconf.DatabaseConnection()
+
+	if !f.Validate() {
+		return false
+	}
+
+	if db.NewRecord(f) {
+		result := db.Create(&f)
+		rowsAffected := result.RowsAffected
+		errors := result.GetErrors()
+		if len(errors) > 0 {
+			for _, err := range errors {
+				f.Errors = append(f.Errors, &provide.Error{
+					Message: common.StringOrNil(err.Error()),
+				})
+			}
+		}
+		if !db.NewRecord(f) {
+			return rowsAffected > 0
+		}
+	}
+	return false
+}
+
+// Update an existing faucet
+func (f *Faucet) Update() bool {
+	db := dbconf.DatabaseConnection()
+
+	if !f.Validate() {
+		return false
+	}
+
+	result := db.Model(&f).Select(
+		"account_id", "enabled", "drip_value", "address_cooldown", "application_cooldown",
+		"address_daily_cap", "application_daily_cap",
+	).Updates(map[string]interface{}{
+		"account_id":            f.AccountID,
+		"enabled":               f.Enabled,
+		"drip_value":            f.DripValue,
+		"address_cooldown":      f.AddressCooldown,
+		"application_cooldown":  f.ApplicationCooldown,
+		"address_daily_cap":     f.AddressDailyCap,
+		"application_daily_cap": f.ApplicationDailyCap,
+	})
+	errors := result.GetErrors()
+	if len(errors) > 0 {
+		for _, err := range errors {
+			f.Errors = append(f.Errors, &provide.Error{
+				Message: common.StringOrNil(err.Error()),
+			})
+		}
+	}
+	return len(f.Errors) == 0
+}
+
+// Validate a faucet for persistence
+func (f *Faucet) Validate() bool {
+	db := dbconf.DatabaseConnection()
+	f.Errors = make([]*provide.Error, 0)
+
+	ntwrk := &network.Network{}
+	db.Where("id = ?", f.NetworkID).Find(&ntwrk)
+	if ntwrk == nil || ntwrk.ID == uuid.Nil {
+		f.Errors = append(f.Errors, &provide.Error{
+			Message: common.StringOrNil("faucet network not found"),
+		})
+	} else if !ntwrk.IsEthereumNetwork() {
+		f.Errors = append(f.Errors, &provide.Error{
+			Message: common.StringOrNil(fmt.Sprintf("faucet not supported by network %s", ntwrk.ID)),
+		})
+	}
+
+	account := &wallet.Account{}
+	db.Where("id = ?", f.AccountID).Find(&account)
+	if account == nil || account.ID == uuid.Nil {
+		f.Errors = append(f.Errors, &provide.Error{
+			Message: common.StringOrNil("faucet account not found"),
+		})
+	} else if account.NetworkID == nil || *account.NetworkID != f.NetworkID {
+		f.Errors = append(f.Errors, &provide.Error{
+			Message: common.StringOrNil("faucet account must belong to the faucet network"),
+		})
+	} else if f.UserID != nil && (account.UserID == nil || *account.UserID != *f.UserID) {
+		f.Errors = append(f.Errors, &provide.Error{
+			Message: common.StringOrNil("faucet account must belong to the user who configured the faucet"),
+		})
+	}
+
+	if dripValue, dripValueOk := new(big.Int).SetString(f.DripValue, 10); !dripValueOk || dripValue.Sign() <= 0 {
+		f.Errors = append(f.Errors, &provide.Error{
+			Message: common.StringOrNil("faucet drip_value must be a positive integer amount of wei"),
+		})
+	}
+
+	return len(f.Errors) == 0
+}
+
+// signer returns the transaction signer of the faucet account
+func (f *Faucet) signer(db *gorm.DB) (*TransactionSigner, error) {
+	ntwrk := &network.Network{}
+	db.Where("id = ?", f.NetworkID).Find(&ntwrk)
+	if ntwrk == nil || ntwrk.ID == uuid.Nil {
+		return nil, fmt.Errorf("invalid network for faucet %s", f.ID)
+	}
+
+	account := &wallet.Account{}
+	db.Where("id = ?", f.AccountID).Find(&account)
+	if account == nil || account.ID == uuid.Nil {
+		return nil, fmt.Errorf("invalid account for faucet %s", f.ID)
+	}
+
+	return &TransactionSigner{
+		DB:      db,
+		Network: ntwrk,
+		Account: account,
+	}, nil
+}
+
+// faucetLimit is a cooldown and daily cap of a faucet which applies to a rate-limited subject
+type faucetLimit struct {
+	subject  string
+	cooldown *uint64
+	dailyCap *uint64
+}
+
+// limits returns the cooldowns and daily caps which apply to a drip to the given beneficiary requested
+// by the given application
+func (f *Faucet) limits(beneficiary string, applicationID *uuid.UUID) []*faucetLimit {
+	limits := []*faucetLimit{
+		{
+			subject:  fmt.Sprintf("address.%s", strings.ToLower(beneficiary)),
+			cooldown: f.AddressCooldown,
+			dailyCap: f.AddressDailyCap,
+		},
+	}
+
+	if applicationID != nil {
+		limits = append(limits, &faucetLimit{
+			subject:  fmt.Sprintf("application.%s", applicationID.String()),
+			cooldown: f.ApplicationCooldown,
+			dailyCap: f.ApplicationDailyCap,
+		})
+	}
+
+	return limits
+}
+
+// reserve atomically checks the cooldowns and daily caps which apply to the drip and, if none would be exceeded,
+// starts the cooldowns and counts the drip against the daily caps
+func (f *Faucet) reserve(limits []*faucetLimit, now time.Time) error {
+	var reserveErr error
+
+	err := redisutil.WithRedlock(faucetMutexKey(f.ID), func() error {
+		counts := make([]uint64, len(limits))
+
+		for i, limit := range limits {
+			if limit.cooldown != nil && *limit.cooldown > 0 {
+				cooldown, _ := redisutil.Get(faucetCooldownKey(f.ID, limit.subject))
+				if cooldown != nil {
+					reserveErr = &FaucetRateLimitError{
+						message: fmt.Sprintf("faucet %s cooldown of %ds has not elapsed for %s", f.ID, *limit.cooldown, limit.subject),
+					}
+					return nil
+				}
+			}
+
+			if limit.dailyCap != nil {
+				raw, _ := redisutil.Get(faucetDailyDripsKey(f.ID, limit.subject, now))
+				if raw != nil {
+					counts[i], _ = strconv.ParseUint(*raw, 10, 64)
+				}
+				if counts[i] >= *limit.dailyCap {
+					reserveErr = &FaucetRateLimitError{
+						message: fmt.Sprintf("faucet %s daily cap of %d drip(s) reached for %s", f.ID, *limit.dailyCap, limit.subject),
+					}
+					return nil
+				}
+			}
+		}
+
+		// daily counters expire a day after the end of the UTC day they count
+		year, month, day := now.UTC().Date()
+		dailyTTL := time.Date(year, month, day, 0, 0, 0, 0, time.UTC).AddDate(0, 0, 2).Sub(now)
+
+		for i, limit := range limits {
+			if limit.cooldown != nil && *limit.cooldown > 0 {
+				ttl := time.Duration(*limit.cooldown) * time.Second
+				redisutil.Set(faucetCooldownKey(f.ID, limit.subject), now.Unix(), &ttl)
+			}
+
+			if limit.dailyCap != nil {
+				redisutil.Set(faucetDailyDripsKey(f.ID, limit.subject, now), counts[i]+1, &dailyTTL)
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to acquire lock for faucet %s; %s", f.ID, err.Error())
+	}
+
+	return reserveErr
+}
+
+// release reverts the reservation of a drip which was not broadcast
+func (f *Faucet) release(limits []*faucetLimit, now time.Time) {
+	err := redisutil.WithRedlock(faucetMutexKey(f.ID), func() error {
+		for _, limit := range limits {
+			if limit.cooldown != nil && *limit.cooldown > 0 {
+				ttl := time.Millisecond // expires the cooldown, as keys cannot be deleted using redisutil
+				redisutil.Set(faucetCooldownKey(f.ID, limit.subject), now.Unix(), &ttl)
+			}
+
+			if limit.dailyCap != nil {
+				redisutil.Decrement(faucetDailyDripsKey(f.ID, limit.subject, now))
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		common.Log.Warningf("failed to release rate limits of faucet %s; %s", f.ID, err.Error())
+	}
+}
+
+// Drip broadcasts a tx funding the given beneficiary with the faucet drip value, unless a cooldown or daily cap
+// would be exceeded; the drip is recorded in the audit trail of the faucet unless it was rate-limited
+func (f *Faucet) Drip(db *gorm.DB, beneficiary string, applicationID, organizationID, userID *uuid.UUID) (*FaucetDrip, error) {
+	if !f.Enabled {
+		return nil, fmt.Errorf("faucet %s is disabled", f.ID)
+	}
+
+	if !ethcommon.IsHexAddress(beneficiary) {
+		return nil, fmt.Errorf("invalid faucet beneficiary address: %s", beneficiary)
+	}
+	beneficiary = ethcommon.HexToAddress(beneficiary).Hex()
+
+	dripValue, dripValueOk := new(big.Int).SetString(f.DripValue, 10)
+	if !dripValueOk {
+		return nil, fmt.Errorf("invalid drip value of faucet %s", f.ID)
+	}
+
+	now := time.Now()
+	limits := f.limits(beneficiary, applicationID)
+	if err := f.reserve(limits, now); err != nil {
+		return nil, err
+	}
+
+	signer, err := f.signer(db)
+	if err != nil {
+		f.release(limits, now)
+		return nil, err
+	}
+
+	drip := &FaucetDrip{
+		FaucetID:       f.ID,
+		NetworkID:      f.NetworkID,
+		ApplicationID:  applicationID,
+		OrganizationID: organizationID,
+		UserID:         userID,
+		Beneficiary:    beneficiary,
+		Value:          dripValue.String(),
+	}
+
+	tx := &Transaction{
+		NetworkID:      f.NetworkID,
+		ApplicationID:  signer.Account.ApplicationID,
+		OrganizationID: signer.Account.OrganizationID,
+		UserID:         signer.Account.UserID,
+		AccountID:      &f.AccountID,
+		To:             common.StringOrNil(beneficiary),
+		Value:          &TxValue{value: dripValue},
+		Description:    common.StringOrNil(faucetDripDescription),
+	}
+
+	if tx.Create(db) {
+		drip.TransactionID = &tx.ID
+		drip.Status = "broadcast"
+		common.Log.Debugf("faucet %s dripped %s wei to %s; tx: %s", f.ID, drip.Value, beneficiary, tx.ID)
+	} else {
+		f.release(limits, now)
+
+		msgs := make([]string, 0)
+		for _, err := range tx.Errors {
+			if err.Message != nil {
+				msgs = append(msgs, *err.Message)
+			}
+		}
+		if tx.ID != uuid.Nil {
+			drip.TransactionID = &tx.ID
+		}
+		drip.Status = "failed"
+		drip.Description = common.StringOrNil(strings.Join(msgs, "; "))
+		common.Log.Warningf("faucet %s failed to drip %s wei to %s; %s", f.ID, drip.Value, beneficiary, *drip.Description)
+	}
+
+	if err := db.Create(&drip).Error; err != nil {
+		common.Log.Warningf("failed to persist audit record of faucet %s drip to %s; %s", f.ID, beneficiary, err.Error())
+	}
+
+	return drip, nil
+}
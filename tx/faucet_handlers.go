@@ -0,0 +1,223 @@
+package tx
+
+import (
+	"encoding/json"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	dbconf "github.com/kthomas/go-db-config"
+	uuid "github.com/kthomas/go.uuid"
+	"github.com/provideplatform/nchain/network"
+	provide "github.com/provideplatform/provide-go/common"
+	util "github.com/provideplatform/provide-go/common/util"
+)
+
+// installFaucetsAPI installs the network faucet handlers using the given gin Engine
+func installFaucetsAPI(r *gin.Engine) {
+	r.GET("/api/v1/networks/:id/faucet", faucetDetailsHandler)
+	r.PUT("/api/v1/networks/:id/faucet", updateFaucetHandler)
+	r.POST("/api/v1/networks/:id/faucet", faucetDripHandler)
+	r.GET("/api/v1/networks/:id/faucet/drips", faucetDripsListHandler)
+}
+
+// resolveFaucetNetwork returns the network with the given id and whether the authorized user is permitted to
+// configure its faucet; an error is rendered and nil returned if the network does not exist
+func resolveFaucetNetwork(c *gin.Context) (*network.Network, bool) {
+	ntwrk := &network.Network{}
+	dbconf.DatabaseConnection().Where("id = ?", c.Param("id")).Find(&ntwrk)
+	if ntwrk == nil || ntwrk.ID == uuid.Nil {
+		provide.RenderError("network not found", 404, c)
+		return nil, false
+	}
+
+	userID := util.AuthorizedSubjectID(c, "user")
+	owner := userID != nil && (ntwrk.UserID == nil || *ntwrk.UserID == *userID)
+	return ntwrk, owner
+}
+
+func faucetDetailsHandler(c *gin.Context) {
+	appID := util.AuthorizedSubjectID(c, "application")
+	orgID := util.AuthorizedSubjectID(c, "organization")
+	userID := util.AuthorizedSubjectID(c, "user")
+	if appID == nil && orgID == nil && userID == nil {
+		provide.RenderError("unauthorized", 401, c)
+		return
+	}
+
+	ntwrk, _ := resolveFaucetNetwork(c)
+	if ntwrk == nil {
+		return
+	}
+
+	faucet := resolveFaucet(dbconf.DatabaseConnection(), ntwrk.ID)
+	if faucet == nil {
+		provide.RenderError("faucet not found", 404, c)
+		return
+	}
+
+	provide.Render(faucet, 200, c)
+}
+
+// updateFaucetHandler configures the faucet of the network, creating it if the network has no faucet
+func updateFaucetHandler(c *gin.Context) {
+	userID := util.AuthorizedSubjectID(c, "user")
+	if userID == nil {
+		provide.RenderError("unauthorized", 401, c)
+		return
+	}
+
+	buf, err := c.GetRawData()
+	if err != nil {
+		provide.RenderError(err.Error(), 400, c)
+		return
+	}
+
+	ntwrk, owner := resolveFaucetNetwork(c)
+	if ntwrk == nil {
+		return
+	} else if !owner {
+		provide.RenderError("forbidden", 403, c)
+		return
+	}
+
+	faucet := resolveFaucet(dbconf.DatabaseConnection(), ntwrk.ID)
+	exists := faucet != nil
+	if !exists {
+		faucet = &Faucet{}
+	}
+
+	faucetID := faucet.ID
+	createdAt := faucet.CreatedAt
+	faucetUserID := faucet.UserID
+
+	err = json.Unmarshal(buf, faucet)
+	if err != nil {
+		provide.RenderError(err.Error(), 422, c)
+		return
+	}
+	faucet.ID = faucetID
+	faucet.CreatedAt = createdAt
+	faucet.NetworkID = ntwrk.ID
+	faucet.UserID = faucetUserID
+	if faucet.UserID == nil {
+		faucet.UserID = userID
+	}
+
+	if !exists {
+		var params map[string]interface{}
+		json.Unmarshal(buf, &params)
+		if _, enabledOk := params["enabled"]; !enabledOk {
+			faucet.Enabled = true
+		}
+
+		if faucet.Create() {
+			provide.Render(faucet, 201, c)
+			return
+		}
+	} else if faucet.Update() {
+		provide.Render(nil, 204, c)
+		return
+	}
+
+	obj := map[string]interface{}{}
+	obj["errors"] = faucet.Errors
+	provide.Render(obj, 422, c)
+}
+
+// faucetDripHandler funds the given address using the faucet of the network
+func faucetDripHandler(c *gin.Context) {
+	appID := util.AuthorizedSubjectID(c, "application")
+	orgID := util.AuthorizedSubjectID(c, "organization")
+	userID := util.AuthorizedSubjectID(c, "user")
+	if appID == nil && orgID == nil && userID == nil {
+		provide.RenderError("unauthorized", 401, c)
+		return
+	}
+
+	buf, err := c.GetRawData()
+	if err != nil {
+		provide.RenderError(err.Error(), 400, c)
+		return
+	}
+
+	params := map[string]interface{}{}
+	err = json.Unmarshal(buf, &params)
+	if err != nil {
+		provide.RenderError(err.Error(), 400, c)
+		return
+	}
+
+	address, addressOk := params["address"].(string)
+	if !addressOk || address == "" {
+		provide.RenderError("address required", 422, c)
+		return
+	}
+
+	ntwrk, _ := resolveFaucetNetwork(c)
+	if ntwrk == nil {
+		return
+	}
+
+	db := dbconf.DatabaseConnection()
+	faucet := resolveFaucet(db, ntwrk.ID)
+	if faucet == nil || !faucet.Enabled {
+		provide.RenderError("faucet not found", 404, c)
+		return
+	}
+
+	drip, err := faucet.Drip(db, address, appID, orgID, userID)
+	if err != nil {
+		if _, rateLimited := err.(*FaucetRateLimitError); rateLimited {
+			provide.RenderError(err.Error(), 429, c)
+			return
+		}
+		provide.RenderError(err.Error(), 422, c)
+		return
+	}
+
+	if drip.Status == "failed" {
+		provide.Render(drip, 422, c)
+		return
+	}
+	provide.Render(drip, 201, c)
+}
+
+// faucetDripsListHandler renders the audit trail of the faucet of the network; the network owner may list
+// all drips, and other subjects may list the drips they requested
+func faucetDripsListHandler(c *gin.Context) {
+	appID := util.AuthorizedSubjectID(c, "application")
+	orgID := util.AuthorizedSubjectID(c, "organization")
+	userID := util.AuthorizedSubjectID(c, "user")
+	if appID == nil && orgID == nil && userID == nil {
+		provide.RenderError("unauthorized", 401, c)
+		return
+	}
+
+	ntwrk, owner := resolveFaucetNetwork(c)
+	if ntwrk == nil {
+		return
+	}
+
+	query := dbconf.DatabaseConnection().Where("faucet_drips.network_id = ?", ntwrk.ID)
+	if !owner {
+		if appID != nil {
+			query = query.Where("faucet_drips.application_id = ?", appID)
+		} else if orgID != nil {
+			query = query.Where("faucet_drips.organization_id = ?", orgID)
+		} else {
+			query = query.Where("faucet_drips.user_id = ?", userID)
+		}
+	}
+
+	if c.Query("beneficiary") != "" {
+		query = query.Where("LOWER(faucet_drips.beneficiary) = ?", strings.ToLower(c.Query("beneficiary")))
+	}
+	if c.Query("status") != "" {
+		query = query.Where("faucet_drips.status = ?", c.Query("status"))
+	}
+	query = query.Order("faucet_drips.created_at DESC")
+
+	var drips []*FaucetDrip
+	provide.Paginate(c, query, &FaucetDrip{}).Find(&drips)
+	provide.Render(drips, 200, c)
+}
@@ -0,0 +1,149 @@
+package tx
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"sort"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/provideplatform/nchain/common"
+	"github.com/provideplatform/nchain/network"
+	providecrypto "github.com/provideplatform/provide-go/crypto"
+)
+
+// feeHistoryBlockCount is the number of recent blocks sampled via eth_feeHistory when suggesting dynamic fees
+const feeHistoryBlockCount = 10
+
+// feeHistoryRewardPercentile is the percentile of effective priority fees paid in each sampled block
+const feeHistoryRewardPercentile = float64(50)
+
+// defaultMaxPriorityFeePerGas is the priority fee suggested when no priority fees were observed in the sampled blocks (1.5 gwei)
+const defaultMaxPriorityFeePerGas = int64(1500000000)
+
+// baseFeeMultiplier is applied to the next base fee when suggesting the max fee per gas, so the tx remains
+// includable over several consecutive blocks of maximally increasing base fees
+const baseFeeMultiplier = int64(2)
+
+// dynamicFees are the EIP-1559 fee caps used to sign a type-2 tx
+type dynamicFees struct {
+	GasTipCap *big.Int // max_priority_fee_per_gas
+	GasFeeCap *big.Int // max_fee_per_gas
+}
+
+// feeHistory is the eth_feeHistory response
+type feeHistory struct {
+	OldestBlock   *hexutil.Big     `json:"oldestBlock"`
+	BaseFeePerGas []*hexutil.Big   `json:"baseFeePerGas"`
+	GasUsedRatio  []float64        `json:"gasUsedRatio"`
+	Reward        [][]*hexutil.Big `json:"reward"`
+}
+
+// resolveDynamicFees resolves the dynamic fee caps for the given tx params; nil is returned when the
+// tx should be signed as a legacy tx, i.e. when a gas price was provided or the network is not London-enabled
+func resolveDynamicFees(ntwrk *network.Network, params map[string]interface{}, gasPrice *uint64) (*dynamicFees, error) {
+	if gasPrice != nil {
+		return nil, nil
+	}
+
+	var maxFeePerGas *big.Int
+	if maxFee, maxFeeOk := params["max_fee_per_gas"].(float64); maxFeeOk {
+		maxFeePerGas = new(big.Int).SetUint64(uint64(maxFee))
+	}
+
+	var maxPriorityFeePerGas *big.Int
+	if maxPriorityFee, maxPriorityFeeOk := params["max_priority_fee_per_gas"].(float64); maxPriorityFeeOk {
+		maxPriorityFeePerGas = new(big.Int).SetUint64(uint64(maxPriorityFee))
+	}
+
+	if maxFeePerGas != nil && maxPriorityFeePerGas != nil {
+		return validateDynamicFees(&dynamicFees{
+			GasTipCap: maxPriorityFeePerGas,
+			GasFeeCap: maxFeePerGas,
+		})
+	}
+
+	suggested, err := suggestDynamicFees(ntwrk)
+	if err != nil {
+		if maxFeePerGas != nil || maxPriorityFeePerGas != nil {
+			return nil, err
+		}
+		common.Log.Debugf("signing legacy tx on network: %s; %s", ntwrk.ID, err.Error())
+		return nil, nil
+	}
+
+	if maxPriorityFeePerGas != nil {
+		suggested.GasFeeCap = new(big.Int).Add(new(big.Int).Sub(suggested.GasFeeCap, suggested.GasTipCap), maxPriorityFeePerGas)
+		suggested.GasTipCap = maxPriorityFeePerGas
+	} else if maxFeePerGas != nil {
+		suggested.GasFeeCap = maxFeePerGas
+		if suggested.GasTipCap.Cmp(maxFeePerGas) > 0 {
+			suggested.GasTipCap = maxFeePerGas
+		}
+	}
+
+	return validateDynamicFees(suggested)
+}
+
+// validateDynamicFees ensures the priority fee does not exceed the max fee
+func validateDynamicFees(fees *dynamicFees) (*dynamicFees, error) {
+	if fees.GasTipCap.Cmp(fees.GasFeeCap) > 0 {
+		return nil, fmt.Errorf("max_priority_fee_per_gas (%s) must not exceed max_fee_per_gas (%s)", fees.GasTipCap.String(), fees.GasFeeCap.String())
+	}
+	return fees, nil
+}
+
+// suggestDynamicFees suggests dynamic fee caps from the base fees and priority fees observed in recent blocks,
+// as reported by eth_feeHistory; an error is returned if the network is not London-enabled
+func suggestDynamicFees(ntwrk *network.Network) (*dynamicFees, error) {
+	client, err := providecrypto.EVMResolveJsonRpcClient(ntwrk.ID.String(), ntwrk.RPCURL())
+	if err != nil {
+		return nil, fmt.Errorf("failed to dial JSON-RPC host; %s", err.Error())
+	}
+
+	var history *feeHistory
+	err = client.CallContext(
+		context.TODO(),
+		&history,
+		"eth_feeHistory",
+		hexutil.EncodeUint64(feeHistoryBlockCount),
+		"latest",
+		[]float64{feeHistoryRewardPercentile},
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve fee history; %s", err.Error())
+	}
+
+	if history == nil || len(history.BaseFeePerGas) == 0 {
+		return nil, errors.New("no base fee reported by fee history; network is not London-enabled")
+	}
+
+	// the last base fee reported by eth_feeHistory is that of the next block
+	nextBaseFee := history.BaseFeePerGas[len(history.BaseFeePerGas)-1]
+	if nextBaseFee == nil || nextBaseFee.ToInt().Sign() == 0 {
+		return nil, errors.New("zero base fee reported by fee history; network is not London-enabled")
+	}
+
+	rewards := make([]*big.Int, 0)
+	for _, reward := range history.Reward {
+		if len(reward) > 0 && reward[0] != nil && reward[0].ToInt().Sign() > 0 {
+			rewards = append(rewards, reward[0].ToInt())
+		}
+	}
+
+	gasTipCap := big.NewInt(defaultMaxPriorityFeePerGas)
+	if len(rewards) > 0 {
+		sort.Slice(rewards, func(i, j int) bool { return rewards[i].Cmp(rewards[j]) < 0 })
+		gasTipCap = rewards[len(rewards)/2]
+	}
+
+	gasFeeCap := new(big.Int).Mul(nextBaseFee.ToInt(), big.NewInt(baseFeeMultiplier))
+	gasFeeCap.Add(gasFeeCap, gasTipCap)
+
+	common.Log.Debugf("suggested dynamic fees for network: %s; base fee: %s; max priority fee: %s; max fee: %s", ntwrk.ID, nextBaseFee.ToInt().String(), gasTipCap.String(), gasFeeCap.String())
+	return &dynamicFees{
+		GasTipCap: gasTipCap,
+		GasFeeCap: gasFeeCap,
+	}, nil
+}
@@ -18,18 +18,18 @@ import (
 	providecrypto "github.com/provideplatform/provide-go/crypto"
 )
 
-func executeTransaction(c *contract.Contract, execution *contract.Execution) (*contract.ExecutionResponse, error) {
-	db := dbconf.DatabaseConnection()
+// executionTransactionFactory builds the tx which executes the given contract execution
+func executionTransactionFactory(c *contract.Contract, execution *contract.Execution) *Transaction {
 	hdDerivationPath := execution.HDPath
 	publishedAt := execution.PublishedAt
-	method := execution.Method
-	params := execution.Params
 	ref := execution.Ref
 	value := execution.Value
 	walletID := execution.WalletID
 	accountID := execution.AccountID
 	gas := execution.Gas
 	gasPrice := execution.GasPrice
+	maxFeePerGas := execution.MaxFeePerGas
+	maxPriorityFeePerGas := execution.MaxPriorityFeePerGas
 	nonce := execution.Nonce
 	path := execution.HDPath
 
@@ -49,6 +49,18 @@ func executeTransaction(c *contract.Contract, execution *contract.Execution) (*c
 		txParams["gas_price"] = gasPrice
 	}
 
+	if maxFeePerGas != nil {
+		txParams["max_fee_per_gas"] = maxFeePerGas
+	}
+
+	if maxPriorityFeePerGas != nil {
+		txParams["max_priority_fee_per_gas"] = maxPriorityFeePerGas
+	}
+
+	if execution.AccessList != nil {
+		txParams["access_list"] = execution.AccessList
+	}
+
 	if nonce != nil {
 		txParams["nonce"] = *nonce
 	}
@@ -57,6 +69,10 @@ func executeTransaction(c *contract.Contract, execution *contract.Execution) (*c
 		txParams["hd_derivation_path"] = *path
 	}
 
+	if execution.Relay {
+		txParams["relay"] = true
+	}
+
 	txParamsJSON, _ := json.Marshal(txParams)
 	_txParamsJSON := json.RawMessage(txParamsJSON)
 
@@ -72,12 +88,24 @@ func executeTransaction(c *contract.Contract, execution *contract.Execution) (*c
 		Value:          &TxValue{value: value},
 		Params:         &_txParamsJSON,
 		Ref:            ref,
+		BatchID:        execution.BatchID,
 	}
 
 	if publishedAt != nil {
 		tx.PublishedAt = publishedAt
 	}
 
+	return tx
+}
+
+func executeTransaction(c *contract.Contract, execution *contract.Execution) (*contract.ExecutionResponse, error) {
+	db := dbconf.DatabaseConnection()
+	method := execution.Method
+	params := execution.Params
+	ref := execution.Ref
+
+	tx := executionTransactionFactory(c, execution)
+
 	var err error
 	_abi, err := c.ReadEthereumContractAbi()
 	if err != nil {
@@ -109,6 +137,8 @@ func executeTransaction(c *contract.Contract, execution *contract.Execution) (*c
 	if err != nil {
 		desc := err.Error()
 		tx.updateStatus(db, "failed", &desc)
+		// a batched execution which failed before it was signed, i.e. when gas estimation reverts, is never retried
+		tx.releaseBatchNonce(db)
 		return nil, fmt.Errorf("Unable to execute %s contract; %s", *n.Name, err.Error())
 	}
 
@@ -146,6 +176,37 @@ func executeTransaction(c *contract.Contract, execution *contract.Execution) (*c
 	return &resp, nil
 }
 
+// simulateTransaction dry-runs the tx which would execute the given contract execution against the given block
+func simulateTransaction(c *contract.Contract, execution *contract.Execution, block *uint64) (*Simulation, error) {
+	db := dbconf.DatabaseConnection()
+	method := execution.Method
+
+	tx := executionTransactionFactory(c, execution)
+	if !tx.Validate() {
+		return nil, fmt.Errorf("Failed to simulate contract method %s on contract: %s; %s", method, c.ID, *tx.Errors[0].Message)
+	}
+
+	_abi, err := c.ReadEthereumContractAbi()
+	if err != nil {
+		return nil, fmt.Errorf("Failed to simulate contract method %s on contract: %s; no ABI resolved: %s", method, c.ID, err.Error())
+	}
+
+	abiMethod, ok := _abi.Methods[method]
+	if !ok {
+		return nil, fmt.Errorf("Failed to simulate contract method %s on contract: %s; method not found in ABI", method, c.ID)
+	}
+
+	invocationSig, err := providecrypto.EVMEncodeABI(&abiMethod, execution.Params...)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to encode %d parameters prior to attempting simulation of method %s on contract: %s; %s", len(execution.Params), method, c.ID, err.Error())
+	}
+
+	data := fmt.Sprintf("0x%s", ethcommon.Bytes2Hex(invocationSig))
+	tx.Data = &data
+
+	return tx.Simulate(db, block)
+}
+
 func getTransactionResponse(tx *Transaction, c *contract.Contract, network *network.Network, methodDescriptor, method string, abiMethod *abi.Method, params []interface{}) (map[string]interface{}, error) {
 	var err error
 	result := make([]byte, 32)
@@ -181,6 +242,15 @@ func getTransactionResponse(tx *Transaction, c *contract.Contract, network *netw
 					return nil, err
 				}
 			} else {
+				if gas, gasOk := tx.ParseParams()["gas"].(float64); !gasOk || gas == 0 {
+					_, err = tx.estimateGas(network, signer.Address())
+					if err != nil {
+						err = fmt.Errorf("Failed to execute %s on contract: %s; %s", methodDescriptor, c.ID, err.Error())
+						common.Log.Warning(err.Error())
+						return nil, err
+					}
+				}
+
 				var txResponse *contract.ExecutionResponse
 				if tx.Create(db) {
 					common.Log.Debugf("Executed %s on contract: %s", methodDescriptor, c.ID)
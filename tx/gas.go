@@ -0,0 +1,304 @@
+package tx
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/jinzhu/gorm"
+	uuid "github.com/kthomas/go.uuid"
+	"github.com/provideplatform/nchain/common"
+	"github.com/provideplatform/nchain/network"
+	"github.com/provideplatform/nchain/prices"
+	provideapi "github.com/provideplatform/provide-go/api/nchain"
+	providecrypto "github.com/provideplatform/provide-go/crypto"
+)
+
+// gasReportDayFormat is the format of the days of a gas report
+const gasReportDayFormat = "2006-01-02"
+
+// nativeCurrencyDecimals is the number of decimals of the native currency of EVM networks
+const nativeCurrencyDecimals = 18
+
+// gasReportGroups are the columns by which the breakdown of a gas report can be grouped, keyed by the name of the group
+var gasReportGroups = map[string]string{
+	"day":          "day",
+	"network":      "network_id",
+	"application":  "application_id",
+	"organization": "organization_id",
+	"account":      "account_id",
+}
+
+// GasReport is the gas spend of an application or on a network over a range of days
+type GasReport struct {
+	Start     *string     `json:"start,omitempty"`
+	End       *string     `json:"end,omitempty"`
+	GroupBy   string      `json:"group_by"`
+	Totals    []*GasSpend `json:"totals"`    // per network
+	Breakdown []*GasSpend `json:"breakdown"` // per network and group
+}
+
+// GasSpend is the gas used and fees paid by the txs aggregated into a row of a gas report
+type GasSpend struct {
+	Day            *string    `json:"day,omitempty"`
+	NetworkID      uuid.UUID  `json:"network_id"`
+	ApplicationID  *uuid.UUID `json:"application_id,omitempty"`
+	OrganizationID *uuid.UUID `json:"organization_id,omitempty"`
+	AccountID      *uuid.UUID `json:"account_id,omitempty"`
+	NativeCurrency string     `json:"native_currency,omitempty"`
+	TxCount        uint64     `json:"tx_count"`
+	GasUsed        string     `json:"gas_used"`
+	FeePaid        string     `json:"fee_paid"` // in wei
+
+	// Fee paid, valued at the current price of the native currency
+	Fiat      *string  `json:"fiat,omitempty"`
+	FiatValue *float64 `json:"fiat_value,omitempty"`
+}
+
+// gasSpendRow is an aggregated row of the daily gas spend
+type gasSpendRow struct {
+	Day            *string
+	NetworkID      uuid.UUID
+	ApplicationID  *uuid.UUID
+	OrganizationID *uuid.UUID
+	AccountID      *uuid.UUID
+	TxCount        uint64
+	GasUsed        string
+	FeePaid        string
+}
+
+// effectiveGasPrice returns the gas price paid by the mined tx with the given hash; the effectiveGasPrice
+// of the receipt is used when the network provides it, otherwise the gas price of the mined tx
+func effectiveGasPrice(ntwrk *network.Network, hash string) (*big.Int, error) {
+	client, err := providecrypto.EVMResolveJsonRpcClient(ntwrk.ID.String(), ntwrk.RPCURL())
+	if err != nil {
+		return nil, err
+	}
+
+	var receipt map[string]interface{}
+	err = client.CallContext(context.TODO(), &receipt, "eth_getTransactionReceipt", hash)
+	if err != nil {
+		return nil, err
+	}
+	if price, priceOk := receipt["effectiveGasPrice"].(string); priceOk {
+		return hexutil.DecodeBig(price)
+	}
+
+	var tx map[string]interface{}
+	err = client.CallContext(context.TODO(), &tx, "eth_getTransactionByHash", hash)
+	if err != nil {
+		return nil, err
+	}
+	if price, priceOk := tx["gasPrice"].(string); priceOk {
+		return hexutil.DecodeBig(price)
+	}
+
+	return nil, fmt.Errorf("unable to resolve effective gas price of tx %s", hash)
+}
+
+// recordGasSpend persists the gas used, effective gas price and fee paid by the mined tx and adds them to the
+// daily gas spend of its application, organization, account and network; the spend of a tx is recorded once,
+// so a receipt which is fetched again, i.e. after a reorg, is not counted twice
+func (t *Transaction) recordGasSpend(db *gorm.DB, ntwrk *network.Network, receipt *provideapi.TxReceipt) error {
+	if t.Hash == nil || !ntwrk.IsEthereumNetwork() {
+		return nil
+	}
+
+	gasPrice, err := effectiveGasPrice(ntwrk, *t.Hash)
+	if err != nil {
+		return err
+	}
+
+	gasUsed := receipt.GasUsed
+	feePaid := new(big.Int).Mul(new(big.Int).SetUint64(gasUsed), gasPrice)
+
+	day := time.Now().UTC()
+	if t.BlockTimestamp != nil {
+		day = t.BlockTimestamp.UTC()
+	}
+
+	optionalID := func(id *uuid.UUID) uuid.UUID {
+		if id == nil {
+			return uuid.Nil
+		}
+		return *id
+	}
+
+	tx := db.Begin()
+	result := tx.Exec(
+		"UPDATE transactions SET gas_used = ?, effective_gas_price = ?, fee_paid = ? WHERE id = ? AND fee_paid IS NULL",
+		gasUsed, gasPrice.String(), feePaid.String(), t.ID,
+	)
+	if err := result.Error; err != nil {
+		tx.Rollback()
+		return fmt.Errorf("failed to record gas spend of tx %s; %s", t.ID, err.Error())
+	}
+	if result.RowsAffected == 0 {
+		tx.Rollback()
+		return nil
+	}
+
+	err = tx.Exec(
+		`INSERT INTO gas_spend_daily (day, network_id, application_id, organization_id, account_id, tx_count, gas_used, fee_paid)
+		VALUES (?, ?, ?, ?, ?, 1, ?, ?)
+		ON CONFLICT (day, network_id, application_id, organization_id, account_id) DO UPDATE SET
+			tx_count = gas_spend_daily.tx_count + 1,
+			gas_used = gas_spend_daily.gas_used + EXCLUDED.gas_used,
+			fee_paid = gas_spend_daily.fee_paid + EXCLUDED.fee_paid`,
+		day.Format(gasReportDayFormat), t.NetworkID, optionalID(t.ApplicationID), optionalID(t.OrganizationID), optionalID(t.AccountID),
+		gasUsed, feePaid.String(),
+	).Error
+	if err != nil {
+		tx.Rollback()
+		return fmt.Errorf("failed to aggregate gas spend of tx %s; %s", t.ID, err.Error())
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return fmt.Errorf("failed to record gas spend of tx %s; %s", t.ID, err.Error())
+	}
+
+	effectiveGasPrice := gasPrice.String()
+	fee := feePaid.String()
+	t.GasUsed = &gasUsed
+	t.EffectiveGasPrice = &effectiveGasPrice
+	t.FeePaid = &fee
+
+	common.Log.Debugf("recorded gas spend of tx %s; %d gas used at %s wei; fee paid: %s wei", t.ID, gasUsed, effectiveGasPrice, fee)
+	return nil
+}
+
+// gasReportFactory builds the gas report of the daily gas spend matching the given query; the days are
+// inclusive, and fees are valued in the given fiat currency, i.e. USD, when one is given
+func gasReportFactory(db *gorm.DB, query *gorm.DB, start, end, groupBy, fiat string) (*GasReport, error) {
+	report := &GasReport{
+		GroupBy:   "day",
+		Totals:    make([]*GasSpend, 0),
+		Breakdown: make([]*GasSpend, 0),
+	}
+
+	if start != "" {
+		if _, err := time.Parse(gasReportDayFormat, start); err != nil {
+			return nil, fmt.Errorf("invalid start; YYYY-MM-DD date required: %s", start)
+		}
+		report.Start = common.StringOrNil(start)
+		query = query.Where("gas_spend_daily.day >= ?", start)
+	}
+
+	if end != "" {
+		if _, err := time.Parse(gasReportDayFormat, end); err != nil {
+			return nil, fmt.Errorf("invalid end; YYYY-MM-DD date required: %s", end)
+		}
+		report.End = common.StringOrNil(end)
+		query = query.Where("gas_spend_daily.day <= ?", end)
+	}
+
+	if groupBy != "" {
+		report.GroupBy = strings.ToLower(groupBy)
+	}
+	column, columnOk := gasReportGroups[report.GroupBy]
+	if !columnOk {
+		return nil, fmt.Errorf("invalid group_by: %s; must be one of day, network, application, organization or account", groupBy)
+	}
+
+	sums := "SUM(gas_spend_daily.tx_count) AS tx_count, SUM(gas_spend_daily.gas_used)::text AS gas_used, SUM(gas_spend_daily.fee_paid)::text AS fee_paid"
+
+	var totals []*gasSpendRow
+	err := query.Table("gas_spend_daily").
+		Select(fmt.Sprintf("gas_spend_daily.network_id, %s", sums)).
+		Group("gas_spend_daily.network_id").
+		Order("gas_spend_daily.network_id").
+		Scan(&totals).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to aggregate gas spend; %s", err.Error())
+	}
+
+	selection := fmt.Sprintf("gas_spend_daily.network_id, gas_spend_daily.%s, %s", column, sums)
+	if column == "day" {
+		selection = fmt.Sprintf("gas_spend_daily.network_id, to_char(gas_spend_daily.day, 'YYYY-MM-DD') AS day, %s", sums)
+	}
+	grouping := "gas_spend_daily.network_id"
+	if column != "network_id" {
+		grouping = fmt.Sprintf("gas_spend_daily.network_id, gas_spend_daily.%s", column)
+	}
+
+	var breakdown []*gasSpendRow
+	err = query.Table("gas_spend_daily").
+		Select(selection).
+		Group(grouping).
+		Order(grouping).
+		Scan(&breakdown).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to aggregate gas spend; %s", err.Error())
+	}
+
+	currencies := map[uuid.UUID]string{}
+	nativeCurrency := func(networkID uuid.UUID) string {
+		symbol, symbolOk := currencies[networkID]
+		if !symbolOk {
+			ntwrk := &network.Network{}
+			db.Where("id = ?", networkID).Find(&ntwrk)
+			if ntwrk != nil && ntwrk.ID != uuid.Nil {
+				symbol = ntwrk.NativeCurrency()
+			}
+			currencies[networkID] = symbol
+		}
+		return symbol
+	}
+
+	for _, row := range totals {
+		report.Totals = append(report.Totals, row.gasSpend(nativeCurrency(row.NetworkID), fiat))
+	}
+	for _, row := range breakdown {
+		report.Breakdown = append(report.Breakdown, row.gasSpend(nativeCurrency(row.NetworkID), fiat))
+	}
+
+	return report, nil
+}
+
+// gasSpend returns the gas spend of the aggregated row, valuing the fee paid in the given fiat currency at
+// the current price of the given native currency, if it is known
+func (r *gasSpendRow) gasSpend(nativeCurrency, fiat string) *GasSpend {
+	optionalID := func(id *uuid.UUID) *uuid.UUID {
+		if id == nil || *id == uuid.Nil {
+			return nil
+		}
+		return id
+	}
+
+	spend := &GasSpend{
+		Day:            r.Day,
+		NetworkID:      r.NetworkID,
+		ApplicationID:  optionalID(r.ApplicationID),
+		OrganizationID: optionalID(r.OrganizationID),
+		AccountID:      optionalID(r.AccountID),
+		NativeCurrency: nativeCurrency,
+		TxCount:        r.TxCount,
+		GasUsed:        r.GasUsed,
+		FeePaid:        r.FeePaid,
+	}
+
+	if fiat == "" || nativeCurrency == "" {
+		return spend
+	}
+
+	fiat = strings.ToUpper(fiat)
+	price, err := prices.CurrentPrice(fmt.Sprintf("%s-%s", strings.ToUpper(nativeCurrency), fiat))
+	if err != nil || price == nil || *price <= 0 {
+		return spend
+	}
+
+	feePaid, feePaidOk := new(big.Float).SetString(r.FeePaid)
+	if !feePaidOk {
+		return spend
+	}
+
+	unit := new(big.Float).SetInt(new(big.Int).Exp(big.NewInt(10), big.NewInt(nativeCurrencyDecimals), nil))
+	value, _ := new(big.Float).Mul(new(big.Float).Quo(feePaid, unit), big.NewFloat(*price)).Float64()
+	spend.Fiat = common.StringOrNil(fiat)
+	spend.FiatValue = &value
+
+	return spend
+}
@@ -0,0 +1,90 @@
+package tx
+
+import (
+	"github.com/gin-gonic/gin"
+	dbconf "github.com/kthomas/go-db-config"
+	uuid "github.com/kthomas/go.uuid"
+	"github.com/provideplatform/nchain/network"
+	provide "github.com/provideplatform/provide-go/common"
+	util "github.com/provideplatform/provide-go/common/util"
+)
+
+// installGasReportsAPI installs the gas report handlers using the given gin Engine
+func installGasReportsAPI(r *gin.Engine) {
+	r.GET("/api/v1/networks/:id/gas_report", networkGasReportHandler)
+	r.GET("/api/v1/applications/:id/gas_report", applicationGasReportHandler)
+}
+
+// networkGasReportHandler renders the gas spend on the network of the authorized application or organization;
+// the network owner may report the gas spend of every tx on the network
+func networkGasReportHandler(c *gin.Context) {
+	appID := util.AuthorizedSubjectID(c, "application")
+	orgID := util.AuthorizedSubjectID(c, "organization")
+	userID := util.AuthorizedSubjectID(c, "user")
+	if appID == nil && orgID == nil && userID == nil {
+		provide.RenderError("unauthorized", 401, c)
+		return
+	}
+
+	db := dbconf.DatabaseConnection()
+
+	ntwrk := &network.Network{}
+	db.Where("id = ?", c.Param("id")).Find(&ntwrk)
+	if ntwrk == nil || ntwrk.ID == uuid.Nil {
+		provide.RenderError("network not found", 404, c)
+		return
+	}
+
+	query := db.Where("gas_spend_daily.network_id = ?", ntwrk.ID)
+	if appID != nil {
+		query = query.Where("gas_spend_daily.application_id = ?", appID)
+	} else if orgID != nil {
+		query = query.Where("gas_spend_daily.organization_id = ?", orgID)
+	} else if ntwrk.UserID == nil || *ntwrk.UserID != *userID {
+		provide.RenderError("forbidden", 403, c)
+		return
+	}
+
+	if c.Query("account_id") != "" {
+		query = query.Where("gas_spend_daily.account_id = ?", c.Query("account_id"))
+	}
+
+	report, err := gasReportFactory(db, query, c.Query("start"), c.Query("end"), c.Query("group_by"), c.Query("fiat"))
+	if err != nil {
+		provide.RenderError(err.Error(), 400, c)
+		return
+	}
+
+	provide.Render(report, 200, c)
+}
+
+// applicationGasReportHandler renders the gas spend of the authorized application across networks
+func applicationGasReportHandler(c *gin.Context) {
+	appID := util.AuthorizedSubjectID(c, "application")
+	if appID == nil {
+		provide.RenderError("unauthorized", 401, c)
+		return
+	}
+
+	if c.Param("id") != appID.String() {
+		provide.RenderError("forbidden", 403, c)
+		return
+	}
+
+	db := dbconf.DatabaseConnection()
+	query := db.Where("gas_spend_daily.application_id = ?", appID)
+	if c.Query("network_id") != "" {
+		query = query.Where("gas_spend_daily.network_id = ?", c.Query("network_id"))
+	}
+	if c.Query("account_id") != "" {
+		query = query.Where("gas_spend_daily.account_id = ?", c.Query("account_id"))
+	}
+
+	report, err := gasReportFactory(db, query, c.Query("start"), c.Query("end"), c.Query("group_by"), c.Query("fiat"))
+	if err != nil {
+		provide.RenderError(err.Error(), 400, c)
+		return
+	}
+
+	provide.Render(report, 200, c)
+}
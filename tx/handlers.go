@@ -23,11 +23,25 @@ import (
 func InstallTransactionsAPI(r *gin.Engine) {
 	r.GET("/api/v1/transactions", transactionsListHandler)
 	r.POST("/api/v1/transactions", createTransactionHandler)
+	r.POST("/api/v1/transactions/simulate", simulateTransactionHandler)
+	r.GET("/api/v1/transactions/internal_transfers", internalTransfersListHandler)
 	r.GET("/api/v1/transactions/:id", transactionDetailsHandler)
+	r.POST("/api/v1/transactions/:id/replace", replaceTransactionHandler)
+	r.POST("/api/v1/transactions/:id/cancel", cancelTransactionHandler)
+	r.GET("/api/v1/transactions/:id/internal_transfers", transactionInternalTransfersListHandler)
 	r.GET("/api/v1/networks/:id/transactions", networkTransactionsListHandler)
+	r.GET("/api/v1/networks/:id/transactions/sweep", networkTransactionSweepReportHandler)
 	r.GET("/api/v1/networks/:id/transactions/:transactionId", networkTransactionDetailsHandler)
 
 	r.POST("/api/v1/contracts/:id/execute", contractExecutionHandler)
+
+	installRelayersAPI(r)
+	installFaucetsAPI(r)
+	installSafeTransactionsAPI(r)
+	installGasReportsAPI(r)
+	installScheduledTransactionsAPI(r)
+	installTransactionApprovalsAPI(r)
+	installTransactionBatchesAPI(r)
 }
 
 func transactionsListHandler(c *gin.Context) {
@@ -48,49 +62,79 @@ func transactionsListHandler(c *gin.Context) {
 		query = dbconf.DatabaseConnection().Where("transactions.user_id = ?", userID)
 	}
 
-	filterContractCreationTx := strings.ToLower(c.Query("filter_contract_creations")) == "true"
-	if filterContractCreationTx {
-		query = query.Where("transactions.to IS NULL")
+	query, err := filterTransactionsQuery(c, query)
+	if err != nil {
+		provide.RenderError(err.Error(), 400, c)
+		return
 	}
 
-	if c.Query("status") != "" {
-		query = query.Where("transactions.status IN ?", strings.Split(c.Query("status"), ","))
+	renderTransactions(c, query)
+}
+
+func createTransactionHandler(c *gin.Context) {
+	appID := util.AuthorizedSubjectID(c, "application")
+	orgID := util.AuthorizedSubjectID(c, "organization")
+	userID := util.AuthorizedSubjectID(c, "user")
+	if appID == nil && orgID == nil && userID == nil {
+		provide.RenderError("unauthorized", 401, c)
+		return
 	}
 
-	if c.Query("to") != "" {
-		query = query.Where("transactions.to = ?", c.Query("to"))
+	buf, err := c.GetRawData()
+	if err != nil {
+		provide.RenderError(err.Error(), 400, c)
+		return
 	}
 
-	if c.Query("from") != "" {
-		from := &wallet.Account{}
-		db := dbconf.DatabaseConnection()
-		fromQuery := db.Where("address = ?", from)
-		if c.Query("network_id") != "" {
-			fromQuery = fromQuery.Where("network_id = ?", c.Query("network_id"))
-		}
-		fromQuery.Find(&from)
-		if from != nil && from.ID != uuid.Nil {
-			query = query.Where("transactions.account_id= ?", from.ID)
-		} else {
-			provide.RenderError("from address not resolved to a known signer", 404, c)
+	tx := &Transaction{}
+	err = json.Unmarshal(buf, tx)
+	if err != nil {
+		provide.RenderError(err.Error(), 422, c)
+		return
+	}
+
+	tx.ApplicationID = appID
+	tx.OrganizationID = orgID
+	tx.UserID = userID
+
+	if key := idempotencyKey(c); key != nil {
+		if tx.Ref != nil && *tx.Ref != *key {
+			provide.RenderError("ref does not match Idempotency-Key header", 422, c)
+			return
 		}
+		tx.Ref = key
 	}
 
-	if c.Query("network_id") != "" {
-		query = query.Where("transactions.network_id = ?", c.Query("network_id"))
+	db := dbconf.DatabaseConnection()
+
+	if existing := tx.findByRef(db); existing != nil {
+		provide.Render(existing, 200, c)
+		return
 	}
 
-	if c.Query("account_id") != "" {
-		query = query.Where("transactions.account_id= ?", c.Query("account_id"))
+	if tx.ScheduledAt != nil || tx.NotBeforeBlock != nil {
+		scheduled, err := scheduleTransaction(db, tx)
+		if err != nil {
+			provide.RenderError(err.Error(), 422, c)
+			return
+		}
+
+		provide.Render(scheduled, 202, c)
+		return
 	}
 
-	var txs []Transaction
-	query = query.Order("created_at DESC")
-	provide.Paginate(c, query, &Transaction{}).Find(&txs)
-	provide.Render(txs, 200, c)
+	if tx.Create(db) {
+		provide.Render(tx, 201, c)
+	} else {
+		obj := map[string]interface{}{}
+		obj["errors"] = tx.Errors
+		provide.Render(obj, 422, c)
+	}
 }
 
-func createTransactionHandler(c *gin.Context) {
+// simulateTransactionHandler dry-runs the given tx against the latest block, or the given block,
+// without signing or persisting it
+func simulateTransactionHandler(c *gin.Context) {
 	appID := util.AuthorizedSubjectID(c, "application")
 	orgID := util.AuthorizedSubjectID(c, "organization")
 	userID := util.AuthorizedSubjectID(c, "user")
@@ -116,15 +160,24 @@ func createTransactionHandler(c *gin.Context) {
 	tx.OrganizationID = orgID
 	tx.UserID = userID
 
-	db := dbconf.DatabaseConnection()
+	if tx.Value == nil {
+		tx.Value = NewTxValue(0)
+	}
 
-	if tx.Create(db) {
-		provide.Render(tx, 201, c)
-	} else {
+	if !tx.Validate() {
 		obj := map[string]interface{}{}
 		obj["errors"] = tx.Errors
 		provide.Render(obj, 422, c)
+		return
+	}
+
+	simulation, err := tx.Simulate(dbconf.DatabaseConnection(), tx.Block)
+	if err != nil {
+		provide.RenderError(err.Error(), 422, c)
+		return
 	}
+
+	provide.Render(simulation, 200, c)
 }
 
 func transactionDetailsHandler(c *gin.Context) {
@@ -165,6 +218,153 @@ func transactionDetailsHandler(c *gin.Context) {
 	provide.Render(tx, 200, c)
 }
 
+func replaceTransactionHandler(c *gin.Context) {
+	replacementHandler(c, false)
+}
+
+func cancelTransactionHandler(c *gin.Context) {
+	replacementHandler(c, true)
+}
+
+// replacementHandler re-signs a pending tx with the same nonce and a bumped gas price;
+// when cancel is true, the replacement is a 0-value self-send
+func replacementHandler(c *gin.Context, cancel bool) {
+	appID := util.AuthorizedSubjectID(c, "application")
+	orgID := util.AuthorizedSubjectID(c, "organization")
+	userID := util.AuthorizedSubjectID(c, "user")
+	if appID == nil && orgID == nil && userID == nil {
+		provide.RenderError("unauthorized", 401, c)
+		return
+	}
+
+	buf, err := c.GetRawData()
+	if err != nil {
+		provide.RenderError(err.Error(), 400, c)
+		return
+	}
+
+	params := map[string]interface{}{}
+	if len(buf) > 0 {
+		err = json.Unmarshal(buf, &params)
+		if err != nil {
+			provide.RenderError(err.Error(), 400, c)
+			return
+		}
+	}
+
+	var gasPrice *uint64
+	if gp, gpOk := params["gas_price"].(float64); gpOk {
+		_gasPrice := uint64(gp)
+		gasPrice = &_gasPrice
+	}
+
+	db := dbconf.DatabaseConnection()
+
+	var tx = &Transaction{}
+	db.Where("id = ?", c.Param("id")).Find(&tx)
+	if tx == nil || tx.ID == uuid.Nil {
+		provide.RenderError("transaction not found", 404, c)
+		return
+	}
+
+	validApp := appID != nil && (tx.ApplicationID != nil && *tx.ApplicationID == *appID)
+	validOrg := orgID != nil && (tx.OrganizationID != nil && *tx.OrganizationID == *orgID)
+	validUser := userID != nil && (tx.UserID != nil && *tx.UserID == *userID)
+
+	if !validApp && !validOrg && !validUser {
+		provide.RenderError("forbidden", 403, c)
+		return
+	}
+
+	var replacement *Transaction
+	if cancel {
+		replacement, err = tx.Cancel(db, gasPrice)
+	} else {
+		replacement, err = tx.Replace(db, gasPrice)
+	}
+
+	if err != nil {
+		provide.RenderError(err.Error(), 422, c)
+		return
+	}
+
+	provide.Render(replacement, 201, c)
+}
+
+// transactionInternalTransfersListHandler renders the native currency transfers made by contracts during the execution of the tx
+func transactionInternalTransfersListHandler(c *gin.Context) {
+	appID := util.AuthorizedSubjectID(c, "application")
+	orgID := util.AuthorizedSubjectID(c, "organization")
+	userID := util.AuthorizedSubjectID(c, "user")
+	if appID == nil && orgID == nil && userID == nil {
+		provide.RenderError("unauthorized", 401, c)
+		return
+	}
+
+	db := dbconf.DatabaseConnection()
+
+	var tx = &Transaction{}
+	db.Where("id = ?", c.Param("id")).Find(&tx)
+	if tx == nil || tx.ID == uuid.Nil {
+		provide.RenderError("transaction not found", 404, c)
+		return
+	}
+
+	validApp := appID != nil && (tx.ApplicationID != nil && *tx.ApplicationID == *appID)
+	validOrg := orgID != nil && (tx.OrganizationID != nil && *tx.OrganizationID == *orgID)
+	validUser := userID != nil && (tx.UserID != nil && *tx.UserID == *userID)
+
+	if !validApp && !validOrg && !validUser {
+		provide.RenderError("forbidden", 403, c)
+		return
+	}
+
+	query := db.Where("transaction_internal_transfers.transaction_id = ?", tx.ID)
+	query = query.Order("transaction_internal_transfers.trace_address ASC")
+
+	var transfers []*InternalTransfer
+	provide.Paginate(c, query, &InternalTransfer{}).Find(&transfers)
+	provide.Render(transfers, 200, c)
+}
+
+// internalTransfersListHandler renders the internal transfers of the txs of the authorized subject, optionally
+// filtered by network and by the address which sent or received them
+func internalTransfersListHandler(c *gin.Context) {
+	appID := util.AuthorizedSubjectID(c, "application")
+	orgID := util.AuthorizedSubjectID(c, "organization")
+	userID := util.AuthorizedSubjectID(c, "user")
+	if appID == nil && orgID == nil && userID == nil {
+		provide.RenderError("unauthorized", 401, c)
+		return
+	}
+
+	query := dbconf.DatabaseConnection().
+		Joins("JOIN transactions ON transactions.id = transaction_internal_transfers.transaction_id")
+	if appID != nil {
+		query = query.Where("transactions.application_id = ?", appID)
+	} else if orgID != nil {
+		query = query.Where("transactions.organization_id = ?", orgID)
+	} else {
+		query = query.Where("transactions.user_id = ?", userID)
+	}
+
+	if c.Query("network_id") != "" {
+		query = query.Where("transaction_internal_transfers.network_id = ?", c.Query("network_id"))
+	}
+
+	if c.Query("address") != "" {
+		address := strings.ToLower(c.Query("address"))
+		query = query.Where("LOWER(transaction_internal_transfers.from_address) = ? OR LOWER(transaction_internal_transfers.to_address) = ?", address, address)
+	}
+
+	query = query.Select("transaction_internal_transfers.*").
+		Order("transaction_internal_transfers.block DESC NULLS LAST, transaction_internal_transfers.created_at DESC")
+
+	var transfers []*InternalTransfer
+	provide.Paginate(c, query, &InternalTransfer{}).Find(&transfers)
+	provide.Render(transfers, 200, c)
+}
+
 func networkTransactionsListHandler(c *gin.Context) {
 	userID := util.AuthorizedSubjectID(c, "user")
 	if userID == nil {
@@ -173,20 +373,36 @@ func networkTransactionsListHandler(c *gin.Context) {
 	}
 
 	query := dbconf.DatabaseConnection().Where("transactions.network_id = ? AND transactions.application_id IS NULL", c.Param("id"))
+	query, err := filterTransactionsQuery(c, query)
+	if err != nil {
+		provide.RenderError(err.Error(), 400, c)
+		return
+	}
+
+	renderTransactions(c, query)
+}
+
+// networkTransactionSweepReportHandler renders the counts of stuck txs resolved by the most recent tx sweep of the network
+func networkTransactionSweepReportHandler(c *gin.Context) {
+	userID := util.AuthorizedSubjectID(c, "user")
+	if userID == nil {
+		provide.RenderError("unauthorized", 401, c)
+		return
+	}
 
-	filterContractCreationTx := strings.ToLower(c.Query("filter_contract_creations")) == "true"
-	if filterContractCreationTx {
-		query = query.Where("transactions.to IS NULL")
+	networkID, err := uuid.FromString(c.Param("id"))
+	if err != nil {
+		provide.RenderError("invalid network id", 400, c)
+		return
 	}
 
-	if c.Query("status") != "" {
-		query = query.Where("transactions.status IN ?", strings.Split(c.Query("status"), ","))
+	report, err := CachedTxSweepReport(networkID)
+	if err != nil {
+		provide.RenderError("tx sweep report not found", 404, c)
+		return
 	}
 
-	var txs []Transaction
-	query = query.Order("created_at DESC")
-	provide.Paginate(c, query, &Transaction{}).Find(&txs)
-	provide.Render(txs, 200, c)
+	provide.Render(report, 200, c)
 }
 
 func networkTransactionDetailsHandler(c *gin.Context) {
@@ -385,54 +601,22 @@ func contractExecutionHandler(c *gin.Context) {
 	// 	return
 	// }
 
-	var contractObj = &contract.Contract{}
-
-	db.Where("id = ?", contractID).Find(&contractObj)
-
-	// if we can't find by ID, attempt to lookup the contract by address
-	// ensure that the contract returned is the valid ID for the provided token data
-	if contractObj == nil || contractObj.ID == uuid.Nil {
-		query := db.Where("address = ?", c.Param("id"))
-		if appID != nil {
-			query = query.Where("contracts.application_id = ?", appID)
-		}
-		if orgID != nil {
-			query = query.Where("contracts.organization_id = ?", orgID)
-		}
-		if userID != nil {
-			query = query.Where("contracts.application_id IS NULL", userID)
-		}
-		query.Find(&contractObj)
-	}
-
-	if contractObj == nil || contractObj.ID == uuid.Nil {
-		//if appID != nil {
-		provide.RenderError("contract not found", 404, c)
-		return
-		//}
-
-		// common.Log.Debugf("Attempting arbitrary, non-permissioned contract execution on behalf of user with id: %s", userID)
-		// contractArbitraryExecutionHandler(c, db, buf)
-		// return
-	}
-
-	if appID != nil && *contractObj.ApplicationID != *appID {
-		provide.RenderError("forbidden", 403, c)
-		return
-	}
-
-	if orgID != nil && *contractObj.OrganizationID != *orgID {
-		provide.RenderError("forbidden", 403, c)
+	contractObj := resolveExecutionContract(c, db, contractID, appID, orgID, userID)
+	if contractObj == nil {
 		return
 	}
 
-	ref, err := uuid.NewV4()
-	if err != nil {
-		common.Log.Warningf("Failed to generate ref id; %s", err.Error())
+	key := idempotencyKey(c)
+	if key == nil {
+		ref, err := uuid.NewV4()
+		if err != nil {
+			common.Log.Warningf("Failed to generate ref id; %s", err.Error())
+		}
+		key = common.StringOrNil(ref.String())
 	}
 
 	execution := &contract.Execution{
-		Ref: common.StringOrNil(ref.String()),
+		Ref: key,
 	}
 
 	err = json.Unmarshal(buf, &execution)
@@ -441,6 +625,11 @@ func contractExecutionHandler(c *gin.Context) {
 		return
 	}
 
+	if idempotencyKey(c) != nil && (execution.Ref == nil || *execution.Ref != *key) {
+		provide.RenderError("ref does not match Idempotency-Key header", 422, c)
+		return
+	}
+
 	execution.Contract = contractObj
 	execution.ContractID = &contractObj.ID
 
@@ -516,8 +705,11 @@ func contractExecutionHandler(c *gin.Context) {
 	}
 	gas, gasOk := params["gas"].(float64)
 	gasPrice, gasPriceOk := params["gas_price"].(float64)
+	maxFeePerGas, maxFeePerGasOk := params["max_fee_per_gas"].(float64)
+	maxPriorityFeePerGas, maxPriorityFeePerGasOk := params["max_priority_fee_per_gas"].(float64)
 	nonce, nonceOk := params["nonce"].(float64)
 	subsidize, subsidizeOk := params["subsidize"].(bool)
+	relay, relayOk := params["relay"].(bool)
 
 	if gasOk {
 		execution.Gas = &gas
@@ -527,13 +719,86 @@ func contractExecutionHandler(c *gin.Context) {
 		execution.GasPrice = &gasPrice
 	}
 
+	if maxFeePerGasOk {
+		execution.MaxFeePerGas = &maxFeePerGas
+	}
+
+	if maxPriorityFeePerGasOk {
+		execution.MaxPriorityFeePerGas = &maxPriorityFeePerGas
+	}
+
+	if accessList, accessListOk := params["access_list"]; accessListOk {
+		execution.AccessList = accessList
+	}
+
 	if nonceOk {
 		nonceUint := uint64(nonce)
 		execution.Nonce = &nonceUint
 	}
 
-	if subsidizeOk {
-		execution.Subsidize = subsidize
+	if subsidizeOk && subsidize {
+		if relay {
+			provide.RenderError("subsidize is deprecated and cannot be combined with relay", 422, c)
+			return
+		}
+		common.Log.Warningf("deprecated subsidize param provided for execution of contract %s is ignored; use relay or the network faucet", contractObj.ID)
+	}
+
+	if relayOk {
+		execution.Relay = relay
+	}
+
+	// simulations are neither proposed to a Safe nor scheduled, so nothing is signed or persisted
+	if simulate, simulateOk := params["simulate"].(bool); simulateOk && simulate {
+		var block *uint64
+		if blockFloat, blockOk := params["block"].(float64); blockOk {
+			blockUint := uint64(blockFloat)
+			block = &blockUint
+		}
+
+		simulation, err := simulateTransaction(contractObj, execution, block)
+		if err != nil {
+			common.Log.Debugf("transaction simulation failed; %s", err.Error())
+			provide.RenderError(err.Error(), 422, c)
+			return
+		}
+
+		provide.Render(simulation, 200, c)
+		return
+	}
+
+	if safeAddress, safeAddressOk := params["safe_address"].(string); safeAddressOk && safeAddress != "" {
+		safeTx, err := proposeSafeExecution(db, contractObj, execution, safeAddress, appID, orgID, userID)
+		if err != nil {
+			provide.RenderError(err.Error(), 422, c)
+			return
+		}
+
+		safeTx.Signatures = safeTx.confirmations(db)
+		provide.Render(safeTx, 202, c)
+		return
+	}
+
+	if execution.ScheduledAt != nil || execution.NotBeforeBlock != nil {
+		_abi, err := contractObj.ReadEthereumContractAbi()
+		if err != nil {
+			provide.RenderError(err.Error(), 422, c)
+			return
+		}
+
+		if mthd, mthdOk := _abi.Methods[execution.Method]; mthdOk && mthd.IsConstant() {
+			provide.RenderError(fmt.Sprintf("unable to schedule execution of view method %s", execution.Method), 422, c)
+			return
+		}
+
+		scheduled, err := scheduleExecution(db, contractObj, execution)
+		if err != nil {
+			provide.RenderError(err.Error(), 422, c)
+			return
+		}
+
+		provide.Render(scheduled, 202, c)
+		return
 	}
 
 	executionResponse, err := executeTransaction(contractObj, execution)
@@ -564,6 +829,53 @@ func contractExecutionHandler(c *gin.Context) {
 	provide.Render(resp, 202, c)
 }
 
+// resolveExecutionContract resolves the contract, by id or address, on which the authorized subject is executing
+// a method; an error is rendered and nil returned if the contract cannot be resolved or is not authorized
+func resolveExecutionContract(c *gin.Context, db *gorm.DB, contractID string, appID, orgID, userID *uuid.UUID) *contract.Contract {
+	var contractObj = &contract.Contract{}
+
+	db.Where("id = ?", contractID).Find(&contractObj)
+
+	// if we can't find by ID, attempt to lookup the contract by address
+	// ensure that the contract returned is the valid ID for the provided token data
+	if contractObj == nil || contractObj.ID == uuid.Nil {
+		query := db.Where("address = ?", contractID)
+		if appID != nil {
+			query = query.Where("contracts.application_id = ?", appID)
+		}
+		if orgID != nil {
+			query = query.Where("contracts.organization_id = ?", orgID)
+		}
+		if userID != nil {
+			query = query.Where("contracts.application_id IS NULL", userID)
+		}
+		query.Find(&contractObj)
+	}
+
+	if contractObj == nil || contractObj.ID == uuid.Nil {
+		//if appID != nil {
+		provide.RenderError("contract not found", 404, c)
+		return nil
+		//}
+
+		// common.Log.Debugf("Attempting arbitrary, non-permissioned contract execution on behalf of user with id: %s", userID)
+		// contractArbitraryExecutionHandler(c, db, buf)
+		// return
+	}
+
+	if appID != nil && *contractObj.ApplicationID != *appID {
+		provide.RenderError("forbidden", 403, c)
+		return nil
+	}
+
+	if orgID != nil && *contractObj.OrganizationID != *orgID {
+		provide.RenderError("forbidden", 403, c)
+		return nil
+	}
+
+	return contractObj
+}
+
 func invokeTxFilters(applicationID *uuid.UUID, payload []byte, db *gorm.DB) *float64 {
 	if applicationID == nil {
 		common.Log.Warningf("tx filters are not currently supported for transactions outside of the scope of an application context")
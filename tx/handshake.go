@@ -0,0 +1,252 @@
+package tx
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+
+	"github.com/btcsuite/btcd/wire"
+	"github.com/btcsuite/btcutil/bech32"
+	"github.com/provideplatform/nchain/network"
+	"golang.org/x/crypto/blake2b"
+)
+
+// handshake script opcodes used to build the signature script of witness pubkey hash coins
+const (
+	handshakeOpDup         = 0x76
+	handshakeOpEqualVerify = 0x88
+	handshakeOpCheckSig    = 0xac
+	handshakeOpBlake160    = 0xc0
+)
+
+// handshakeSigHashAll is the sighash type which commits to all inputs and outputs of a handshake tx
+const handshakeSigHashAll = 0x01
+
+// handshakeTx is a handshake tx; unlike bitcoin, outputs are paid to an address and carry a covenant, and
+// txids and signature hashes are blake2b-256 digests
+type handshakeTx struct {
+	Version  uint32
+	Inputs   []*handshakeInput
+	Outputs  []*handshakeOutput
+	Locktime uint32
+}
+
+// handshakeInput spends a coin of a handshake tx
+type handshakeInput struct {
+	Hash     []byte // prevout txid
+	Index    uint32
+	Sequence uint32
+	Value    int64
+	Witness  [][]byte
+}
+
+// handshakeOutput pays to a handshake address; outputs built by nchain carry no covenant
+type handshakeOutput struct {
+	Value   int64
+	Version byte
+	Hash    []byte
+}
+
+// encode writes the output with an empty covenant
+func (o *handshakeOutput) encode(buf *bytes.Buffer) {
+	binary.Write(buf, binary.LittleEndian, uint64(o.Value))
+	buf.WriteByte(o.Version)
+	buf.WriteByte(byte(len(o.Hash)))
+	buf.Write(o.Hash)
+	buf.WriteByte(0x00) // covenant type: NONE
+	wire.WriteVarInt(buf, 0, 0)
+}
+
+// size returns the size of the encoded output
+func (o *handshakeOutput) size() int64 {
+	return int64(8 + 1 + 1 + len(o.Hash) + 1 + 1)
+}
+
+// encode returns the serialized tx; the witnesses of all inputs follow the base tx
+func (t *handshakeTx) encode(witness bool) []byte {
+	buf := new(bytes.Buffer)
+	binary.Write(buf, binary.LittleEndian, t.Version)
+
+	wire.WriteVarInt(buf, 0, uint64(len(t.Inputs)))
+	for _, input := range t.Inputs {
+		buf.Write(input.Hash)
+		binary.Write(buf, binary.LittleEndian, input.Index)
+		binary.Write(buf, binary.LittleEndian, input.Sequence)
+	}
+
+	wire.WriteVarInt(buf, 0, uint64(len(t.Outputs)))
+	for _, output := range t.Outputs {
+		output.encode(buf)
+	}
+
+	binary.Write(buf, binary.LittleEndian, t.Locktime)
+
+	if witness {
+		for _, input := range t.Inputs {
+			wire.WriteVarInt(buf, 0, uint64(len(input.Witness)))
+			for _, item := range input.Witness {
+				wire.WriteVarBytes(buf, 0, item)
+			}
+		}
+	}
+
+	return buf.Bytes()
+}
+
+// txid returns the blake2b-256 digest of the base tx
+func (t *handshakeTx) txid() []byte {
+	digest := blake2b.Sum256(t.encode(false))
+	return digest[:]
+}
+
+// signatureHash returns the version 0 witness signature hash of the given input using SIGHASH_ALL
+func (t *handshakeTx) signatureHash(index int, prevScript []byte) []byte {
+	prevouts := new(bytes.Buffer)
+	sequences := new(bytes.Buffer)
+	for _, input := range t.Inputs {
+		prevouts.Write(input.Hash)
+		binary.Write(prevouts, binary.LittleEndian, input.Index)
+		binary.Write(sequences, binary.LittleEndian, input.Sequence)
+	}
+
+	outputs := new(bytes.Buffer)
+	for _, output := range t.Outputs {
+		output.encode(outputs)
+	}
+
+	hashPrevouts := blake2b.Sum256(prevouts.Bytes())
+	hashSequences := blake2b.Sum256(sequences.Bytes())
+	hashOutputs := blake2b.Sum256(outputs.Bytes())
+
+	input := t.Inputs[index]
+	buf := new(bytes.Buffer)
+	binary.Write(buf, binary.LittleEndian, t.Version)
+	buf.Write(hashPrevouts[:])
+	buf.Write(hashSequences[:])
+	buf.Write(input.Hash)
+	binary.Write(buf, binary.LittleEndian, input.Index)
+	wire.WriteVarBytes(buf, 0, prevScript)
+	binary.Write(buf, binary.LittleEndian, uint64(input.Value))
+	binary.Write(buf, binary.LittleEndian, input.Sequence)
+	buf.Write(hashOutputs[:])
+	binary.Write(buf, binary.LittleEndian, t.Locktime)
+	binary.Write(buf, binary.LittleEndian, uint32(handshakeSigHashAll))
+
+	digest := blake2b.Sum256(buf.Bytes())
+	return digest[:]
+}
+
+// decodeHandshakeAddress returns the witness version and program of the given bech32 handshake address
+func decodeHandshakeAddress(address, hrp string) (byte, []byte, error) {
+	_hrp, data, err := bech32.Decode(address)
+	if err != nil {
+		return 0, nil, err
+	}
+	if _hrp != hrp {
+		return 0, nil, fmt.Errorf("address %s is not a %s address", address, hrp)
+	}
+	if len(data) < 1 {
+		return 0, nil, fmt.Errorf("invalid address %s", address)
+	}
+
+	program, err := bech32.ConvertBits(data[1:], 5, 8, false)
+	if err != nil {
+		return 0, nil, err
+	}
+	if len(program) < 2 || len(program) > 40 {
+		return 0, nil, fmt.Errorf("invalid %d-byte witness program of address %s", len(program), address)
+	}
+	return data[0], program, nil
+}
+
+// signHandshakeTx builds a tx spending the given witness pubkey hash coins of the signing account and signs
+// each of its inputs using the given hash signer
+func (txs *TransactionSigner) signHandshakeTx(tx *Transaction, publicKey []byte, coins []*network.BcoinCoin, feeRate int64, signHash hashSignerFunc) (*UTXOTransaction, error) {
+	if tx.Data != nil && *tx.Data != "" && *tx.Data != "0x" {
+		return nil, fmt.Errorf("data is not supported by handshake txs")
+	}
+
+	hrp := txs.Network.HandshakeAddressHRP()
+	toVersion, toHash, err := decodeHandshakeAddress(*tx.To, hrp)
+	if err != nil {
+		return nil, fmt.Errorf("invalid recipient address %s; %s", *tx.To, err.Error())
+	}
+
+	_, fromHash, err := decodeHandshakeAddress(txs.Account.Address, hrp)
+	if err != nil {
+		return nil, fmt.Errorf("invalid address of signing account %s; %s", txs.Account.Address, err.Error())
+	}
+
+	hasher, _ := blake2b.New(20, nil)
+	hasher.Write(publicKey)
+	if !bytes.Equal(hasher.Sum(nil), fromHash) {
+		return nil, fmt.Errorf("public key of signing account does not match address %s", txs.Account.Address)
+	}
+
+	value := tx.Value.BigInt().Int64()
+	outputs := []*handshakeOutput{{Value: value, Version: toVersion, Hash: toHash}}
+	change := &handshakeOutput{Version: 0x00, Hash: fromHash}
+
+	selected, fee, changeValue, err := selectCoins(coins, value, feeRate, change.size(), handshakeTxSizes, []int64{outputs[0].size()})
+	if err != nil {
+		return nil, err
+	}
+	if changeValue > 0 {
+		change.Value = changeValue
+		outputs = append(outputs, change)
+	}
+
+	_tx := &handshakeTx{
+		Version: 0,
+		Outputs: outputs,
+	}
+
+	spent := make([]string, 0)
+	for _, coin := range selected {
+		hash, err := hex.DecodeString(coin.Hash)
+		if err != nil || len(hash) != 32 {
+			return nil, fmt.Errorf("invalid coin hash %s", coin.Hash)
+		}
+		_tx.Inputs = append(_tx.Inputs, &handshakeInput{
+			Hash:     hash,
+			Index:    coin.Index,
+			Sequence: wire.MaxTxInSequenceNum,
+			Value:    coin.Value,
+		})
+		spent = append(spent, fmt.Sprintf("%s:%d", coin.Hash, coin.Index))
+	}
+
+	// witness pubkey hash coins are signed against the equivalent pubkey hash script
+	prevScript := append([]byte{handshakeOpDup, handshakeOpBlake160, byte(len(fromHash))}, fromHash...)
+	prevScript = append(prevScript, handshakeOpEqualVerify, handshakeOpCheckSig)
+
+	for i, input := range _tx.Inputs {
+		sig, err := signHash(_tx.signatureHash(i, prevScript))
+		if err != nil {
+			return nil, err
+		}
+		r, s, err := signatureValues(sig)
+		if err != nil {
+			return nil, err
+		}
+
+		// handshake signatures are 64-byte [R || S] followed by the sighash type
+		_sig := make([]byte, 65)
+		copy(_sig[32-len(r.Bytes()):32], r.Bytes())
+		copy(_sig[64-len(s.Bytes()):64], s.Bytes())
+		_sig[64] = handshakeSigHashAll
+
+		input.Witness = [][]byte{_sig, publicKey}
+	}
+
+	return &UTXOTransaction{
+		Hash:    hex.EncodeToString(_tx.txid()),
+		Raw:     _tx.encode(true),
+		Fee:     fee,
+		FeeRate: feeRate,
+		Inputs:  len(selected),
+		Network: "handshake",
+		coins:   spent,
+	}, nil
+}
@@ -0,0 +1,56 @@
+package tx
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jinzhu/gorm"
+	uuid "github.com/kthomas/go.uuid"
+)
+
+// idempotencyKeyHeader is the request header which, when present, is used as the ref of the created tx
+// so that retried requests return the original tx instead of creating and broadcasting another
+const idempotencyKeyHeader = "Idempotency-Key"
+
+// idempotencyKey returns the Idempotency-Key header of the request, if one was provided
+func idempotencyKey(c *gin.Context) *string {
+	key := strings.TrimSpace(c.GetHeader(idempotencyKeyHeader))
+	if key == "" {
+		return nil
+	}
+	return &key
+}
+
+// refScope returns the column and value which scope the uniqueness of the tx ref; a ref is unique
+// per application, organization or user, in that order of precedence
+func (t *Transaction) refScope() (string, *uuid.UUID) {
+	if t.ApplicationID != nil {
+		return "application_id", t.ApplicationID
+	} else if t.OrganizationID != nil {
+		return "organization_id", t.OrganizationID
+	} else if t.UserID != nil {
+		return "user_id", t.UserID
+	}
+	return "", nil
+}
+
+// findByRef returns the previously-created tx having the same ref within the scope of the tx, if any
+func (t *Transaction) findByRef(db *gorm.DB) *Transaction {
+	if t.Ref == nil {
+		return nil
+	}
+
+	scope, scopeID := t.refScope()
+	if scopeID == nil {
+		return nil
+	}
+
+	existing := &Transaction{}
+	db.Where(fmt.Sprintf("ref = ? AND %s = ?", scope), *t.Ref, scopeID).Find(&existing)
+	if existing == nil || existing.ID == uuid.Nil {
+		return nil
+	}
+
+	return existing
+}
@@ -0,0 +1,77 @@
+// +build unit
+
+package tx
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	redisutil "github.com/kthomas/go-redisutil"
+	uuid "github.com/kthomas/go.uuid"
+	"github.com/provideplatform/nchain/network"
+)
+
+func init() {
+	redisutil.RequireRedis()
+}
+
+// jsonRPCHandler answers a JSON-RPC call with the given params
+type jsonRPCHandler func(params []json.RawMessage) (interface{}, error)
+
+// jsonRPCStubFactory starts a JSON-RPC server answering the given methods and returns an EVM network
+// configured to use it; the caller must close the returned server
+func jsonRPCStubFactory(t *testing.T, handlers map[string]jsonRPCHandler) (*network.Network, *httptest.Server) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			ID     json.RawMessage   `json:"id"`
+			Method string            `json:"method"`
+			Params []json.RawMessage `json:"params"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			w.WriteHeader(400)
+			return
+		}
+
+		resp := map[string]interface{}{
+			"jsonrpc": "2.0",
+			"id":      req.ID,
+		}
+
+		handler, handlerOk := handlers[req.Method]
+		if !handlerOk {
+			t.Logf("unexpected JSON-RPC call: %s", req.Method)
+			resp["error"] = map[string]interface{}{"code": -32601, "message": fmt.Sprintf("method %s not found", req.Method)}
+		} else if result, err := handler(req.Params); err != nil {
+			resp["error"] = map[string]interface{}{"code": -32000, "message": err.Error()}
+		} else {
+			resp["result"] = result
+		}
+
+		w.Header().Set("content-type", "application/json")
+		json.NewEncoder(w).Encode(resp)
+	}))
+
+	networkID, _ := uuid.NewV4()
+	cfg, _ := json.Marshal(map[string]interface{}{
+		"is_ethereum_network": true,
+		"json_rpc_url":        srv.URL,
+	})
+	rawCfg := json.RawMessage(cfg)
+
+	ntwrk := &network.Network{
+		Config: &rawCfg,
+	}
+	ntwrk.ID = networkID
+
+	return ntwrk, srv
+}
+
+// pendingNonceStub answers eth_getTransactionCount with the given pending nonce
+func pendingNonceStub(pending *uint64) jsonRPCHandler {
+	return func(params []json.RawMessage) (interface{}, error) {
+		return fmt.Sprintf("0x%x", *pending), nil
+	}
+}
@@ -0,0 +1,271 @@
+package tx
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jinzhu/gorm"
+	uuid "github.com/kthomas/go.uuid"
+	"github.com/provideplatform/nchain/common"
+	provide "github.com/provideplatform/provide-go/common"
+)
+
+// txExportFlushInterval is the number of rows written to an export before the response is flushed
+const txExportFlushInterval = 1000
+
+// txExportColumns are the columns of a csv tx export
+var txExportColumns = []string{
+	"id", "created_at", "network_id", "application_id", "organization_id", "user_id", "account_id", "wallet_id",
+	"ref", "description", "from", "to", "value", "nonce", "hash", "status", "block", "block_timestamp",
+	"broadcast_at", "finalized_at", "queue_latency", "network_latency", "e2e_latency", "gas_used",
+	"effective_gas_price", "fee_paid",
+}
+
+// txSortColumns are the columns by which txs can be sorted, keyed by the name of the sort
+var txSortColumns = map[string]string{
+	"created": "transactions.created_at",
+	"block":   "transactions.block",
+	"latency": "transactions.e2e_latency",
+}
+
+// filterTransactionsQuery applies the filters and sort order given in the query string of the request
+// to the given tx query
+func filterTransactionsQuery(c *gin.Context, query *gorm.DB) (*gorm.DB, error) {
+	if strings.ToLower(c.Query("filter_contract_creations")) == "true" {
+		query = query.Where("transactions.to IS NULL")
+	}
+
+	if c.Query("status") != "" {
+		query = query.Where("transactions.status IN (?)", strings.Split(c.Query("status"), ","))
+	}
+
+	if c.Query("from") != "" {
+		query = query.Where("LOWER(transactions.from) = ?", strings.ToLower(c.Query("from")))
+	}
+
+	if c.Query("to") != "" {
+		query = query.Where("LOWER(transactions.to) = ?", strings.ToLower(c.Query("to")))
+	}
+
+	if c.Query("network_id") != "" {
+		query = query.Where("transactions.network_id = ?", c.Query("network_id"))
+	}
+
+	if c.Query("account_id") != "" {
+		query = query.Where("transactions.account_id = ?", c.Query("account_id"))
+	}
+
+	if c.Query("wallet_id") != "" {
+		query = query.Where("transactions.wallet_id = ?", c.Query("wallet_id"))
+	}
+
+	// txs executing the contract, and the tx which deployed it
+	if c.Query("contract_id") != "" {
+		query = query.Where(
+			"transactions.id IN (SELECT transaction_id FROM contracts WHERE id = ?) OR (transactions.network_id, LOWER(transactions.to)) IN (SELECT network_id, LOWER(address) FROM contracts WHERE id = ? AND address IS NOT NULL)",
+			c.Query("contract_id"), c.Query("contract_id"),
+		)
+	}
+
+	if c.Query("ref_prefix") != "" {
+		prefix := strings.NewReplacer("\\", "\\\\", "%", "\\%", "_", "\\_").Replace(c.Query("ref_prefix"))
+		query = query.Where("transactions.ref LIKE ?", fmt.Sprintf("%s%%", prefix))
+	}
+
+	for _, param := range []string{"min_block", "max_block"} {
+		if c.Query(param) == "" {
+			continue
+		}
+		block, err := strconv.ParseUint(c.Query(param), 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s: %s", param, c.Query(param))
+		}
+		if param == "min_block" {
+			query = query.Where("transactions.block >= ?", block)
+		} else {
+			query = query.Where("transactions.block <= ?", block)
+		}
+	}
+
+	timeRanges := []struct {
+		param  string
+		clause string
+	}{
+		{"created_after", "transactions.created_at >= ?"},
+		{"created_before", "transactions.created_at < ?"},
+		{"finalized_after", "transactions.finalized_at >= ?"},
+		{"finalized_before", "transactions.finalized_at < ?"},
+	}
+	for _, timeRange := range timeRanges {
+		if c.Query(timeRange.param) == "" {
+			continue
+		}
+		timestamp, err := time.Parse(time.RFC3339, c.Query(timeRange.param))
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s; RFC3339 timestamp required: %s", timeRange.param, c.Query(timeRange.param))
+		}
+		query = query.Where(timeRange.clause, timestamp)
+	}
+
+	sort := "created"
+	if c.Query("sort") != "" {
+		sort = strings.ToLower(c.Query("sort"))
+	}
+	column, columnOk := txSortColumns[sort]
+	if !columnOk {
+		return nil, fmt.Errorf("invalid sort: %s; must be one of created, block or latency", sort)
+	}
+
+	direction := "DESC"
+	if c.Query("order") != "" {
+		direction = strings.ToUpper(c.Query("order"))
+		if direction != "ASC" && direction != "DESC" {
+			return nil, fmt.Errorf("invalid order: %s; must be asc or desc", c.Query("order"))
+		}
+	}
+
+	// the id breaks ties so the order is stable across pages and exports
+	query = query.Order(fmt.Sprintf("%s %s NULLS LAST, transactions.id %s", column, direction, direction))
+	return query, nil
+}
+
+// renderTransactions renders the page of txs matching the given query or, when an export format
+// is requested, streams every matching tx as csv or ndjson
+func renderTransactions(c *gin.Context, query *gorm.DB) {
+	switch strings.ToLower(c.Query("format")) {
+	case "":
+		var txs []Transaction
+		provide.Paginate(c, query, &Transaction{}).Find(&txs)
+		populateConfirmations(txs)
+		provide.Render(txs, 200, c)
+	case "csv":
+		exportTransactions(c, query, "csv")
+	case "ndjson":
+		exportTransactions(c, query, "ndjson")
+	default:
+		provide.RenderError(fmt.Sprintf("invalid format: %s; must be csv or ndjson", c.Query("format")), 400, c)
+	}
+}
+
+// exportTransactions streams every tx matching the given query in the given format; rows are read
+// from the database cursor one at a time so the export is never buffered in memory
+func exportTransactions(c *gin.Context, query *gorm.DB, format string) {
+	rows, err := query.Model(&Transaction{}).Rows()
+	if err != nil {
+		provide.RenderError(fmt.Sprintf("failed to export txs; %s", err.Error()), 500, c)
+		return
+	}
+	defer rows.Close()
+
+	contentType := "text/csv"
+	if format == "ndjson" {
+		contentType = "application/x-ndjson"
+	}
+	c.Header("content-type", contentType)
+	c.Header("content-disposition", fmt.Sprintf("attachment; filename=\"transactions-%s.%s\"", time.Now().UTC().Format("20060102150405"), format))
+	c.Status(200)
+
+	csvWriter := csv.NewWriter(c.Writer)
+	encoder := json.NewEncoder(c.Writer)
+	if format == "csv" {
+		csvWriter.Write(txExportColumns)
+	}
+
+	n := 0
+	for rows.Next() {
+		tx := Transaction{}
+		if err := query.ScanRows(rows, &tx); err != nil {
+			common.Log.Warningf("failed to scan tx for export; %s", err.Error())
+			break
+		}
+
+		if format == "csv" {
+			err = csvWriter.Write(tx.exportRecord())
+		} else {
+			err = encoder.Encode(tx)
+		}
+		if err != nil {
+			common.Log.Debugf("tx export aborted after %d row(s); %s", n, err.Error())
+			return
+		}
+
+		n++
+		if n%txExportFlushInterval == 0 {
+			csvWriter.Flush()
+			c.Writer.Flush()
+		}
+	}
+
+	if err := rows.Err(); err != nil {
+		common.Log.Warningf("tx export failed after %d row(s); %s", n, err.Error())
+	}
+
+	csvWriter.Flush()
+	c.Writer.Flush()
+	common.Log.Debugf("exported %d tx(s) as %s", n, format)
+}
+
+// exportRecord returns the csv record of the tx, in the order of the tx export columns
+func (t *Transaction) exportRecord() []string {
+	str := func(val *string) string {
+		if val == nil {
+			return ""
+		}
+		return *val
+	}
+	number := func(val *uint64) string {
+		if val == nil {
+			return ""
+		}
+		return strconv.FormatUint(*val, 10)
+	}
+	timestamp := func(val *time.Time) string {
+		if val == nil {
+			return ""
+		}
+		return val.UTC().Format(time.RFC3339)
+	}
+
+	value := ""
+	if t.Value != nil && t.Value.BigInt() != nil {
+		value = t.Value.BigInt().String()
+	}
+
+	record := []string{
+		t.ID.String(),
+		t.CreatedAt.UTC().Format(time.RFC3339),
+		t.NetworkID.String(),
+		"", "", "", "", "",
+		str(t.Ref),
+		str(t.Description),
+		str(t.From),
+		str(t.To),
+		value,
+		number(t.Nonce),
+		str(t.Hash),
+		str(t.Status),
+		number(t.Block),
+		timestamp(t.BlockTimestamp),
+		timestamp(t.BroadcastAt),
+		timestamp(t.FinalizedAt),
+		number(t.QueueLatency),
+		number(t.NetworkLatency),
+		number(t.E2ELatency),
+		number(t.GasUsed),
+		str(t.EffectiveGasPrice),
+		str(t.FeePaid),
+	}
+
+	for i, subject := range []*uuid.UUID{t.ApplicationID, t.OrganizationID, t.UserID, t.AccountID, t.WalletID} {
+		if subject != nil {
+			record[3+i] = subject.String()
+		}
+	}
+
+	return record
+}
@@ -0,0 +1,143 @@
+// +build unit
+
+package tx
+
+import (
+	"fmt"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	dbconf "github.com/kthomas/go-db-config"
+)
+
+// queryRecorder records the statements logged by gorm
+type queryRecorder struct {
+	sql  string
+	vars []interface{}
+}
+
+func (r *queryRecorder) Print(v ...interface{}) {
+	if len(v) > 4 && v[0] == "sql" {
+		r.sql, _ = v[3].(string)
+		r.vars, _ = v[4].([]interface{})
+	}
+}
+
+// filteredTransactionsQuery applies the filters of a tx list request having the given query string and
+// returns the resulting statement and its vars
+func filteredTransactionsQuery(t *testing.T, rawQuery string) (string, []interface{}, error) {
+	gin.SetMode(gin.TestMode)
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest("GET", "/api/v1/transactions?"+rawQuery, nil)
+
+	recorder := &queryRecorder{}
+	db := dbconf.DatabaseConnection().New()
+	db.LogMode(true)
+	db.SetLogger(recorder)
+
+	query, err := filterTransactionsQuery(c, db.Model(&Transaction{}))
+	if err != nil {
+		return "", nil, err
+	}
+
+	var txs []Transaction
+	query.Find(&txs)
+	if recorder.sql == "" {
+		t.Fatalf("failed to record filtered tx query")
+	}
+	return recorder.sql, recorder.vars, nil
+}
+
+func TestFilterTransactionsQueryDefaultOrder(t *testing.T) {
+	sql, _, err := filteredTransactionsQuery(t, "")
+	if err != nil {
+		t.Fatalf("failed to filter txs; %s", err.Error())
+	}
+	if !strings.Contains(sql, "ORDER BY transactions.created_at DESC NULLS LAST, transactions.id DESC") {
+		t.Errorf("expected txs to be ordered by creation, newest first; got %s", sql)
+	}
+}
+
+func TestFilterTransactionsQuerySort(t *testing.T) {
+	sorts := map[string]string{
+		"sort=block&order=asc": "ORDER BY transactions.block ASC NULLS LAST, transactions.id ASC",
+		"sort=LATENCY":         "ORDER BY transactions.e2e_latency DESC NULLS LAST, transactions.id DESC",
+		"order=Asc":            "ORDER BY transactions.created_at ASC NULLS LAST, transactions.id ASC",
+	}
+
+	for rawQuery, expected := range sorts {
+		sql, _, err := filteredTransactionsQuery(t, rawQuery)
+		if err != nil {
+			t.Errorf("failed to filter txs by %s; %s", rawQuery, err.Error())
+			continue
+		}
+		if !strings.Contains(sql, expected) {
+			t.Errorf("expected %s to be ordered by %s; got %s", rawQuery, expected, sql)
+		}
+	}
+}
+
+func TestFilterTransactionsQueryFilters(t *testing.T) {
+	sql, vars, err := filteredTransactionsQuery(t, "status=pending,failed&from=0xABC&min_block=100&max_block=200&ref_prefix=inv_10%25&created_after=2021-09-14T00:00:00Z")
+	if err != nil {
+		t.Fatalf("failed to filter txs; %s", err.Error())
+	}
+
+	clauses := []string{
+		"transactions.status IN",
+		"LOWER(transactions.from) =",
+		"transactions.block >=",
+		"transactions.block <=",
+		"transactions.ref LIKE",
+		"transactions.created_at >=",
+	}
+	for _, clause := range clauses {
+		if !strings.Contains(sql, clause) {
+			t.Errorf("expected filtered query to contain %s; got %s", clause, sql)
+		}
+	}
+
+	expected := map[string]bool{
+		"pending":              false,
+		"failed":               false,
+		"0xabc":                false,
+		"100":                  false,
+		"200":                  false,
+		"inv\\_10\\%%":         false,
+		"2021-09-14T00:00:00Z": false,
+	}
+	for _, v := range vars {
+		val := fmt.Sprintf("%v", v)
+		if timestamp, ok := v.(time.Time); ok {
+			val = timestamp.UTC().Format(time.RFC3339)
+		}
+		if _, ok := expected[val]; ok {
+			expected[val] = true
+		}
+	}
+	for val, bound := range expected {
+		if !bound {
+			t.Errorf("expected filtered query to be bound to %s; got %v", val, vars)
+		}
+	}
+}
+
+func TestFilterTransactionsQueryRejectsInvalidParams(t *testing.T) {
+	invalid := []string{
+		"sort=hash",
+		"order=up",
+		"min_block=-1",
+		"max_block=latest",
+		"created_after=2021-09-14",
+		"finalized_before=yesterday",
+	}
+
+	for _, rawQuery := range invalid {
+		if _, _, err := filteredTransactionsQuery(t, rawQuery); err == nil {
+			t.Errorf("expected %s to be rejected", rawQuery)
+		}
+	}
+}
@@ -0,0 +1,74 @@
+package tx
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	ethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/provideplatform/nchain/network"
+	providecrypto "github.com/provideplatform/provide-go/crypto"
+)
+
+// multicall3ABI is the subset of the Multicall3 ABI used to aggregate reads
+const multicall3ABI = `[{"inputs":[{"components":[{"internalType":"address","name":"target","type":"address"},{"internalType":"bool","name":"allowFailure","type":"bool"},{"internalType":"bytes","name":"callData","type":"bytes"}],"internalType":"struct Multicall3.Call3[]","name":"calls","type":"tuple[]"}],"name":"aggregate3","outputs":[{"components":[{"internalType":"bool","name":"success","type":"bool"},{"internalType":"bytes","name":"returnData","type":"bytes"}],"internalType":"struct Multicall3.Result[]","name":"returnData","type":"tuple[]"}],"stateMutability":"payable","type":"function"}]`
+
+// multicall3Call is a single read aggregated by Multicall3; field order and names match the Call3 tuple
+type multicall3Call struct {
+	Target       ethcommon.Address
+	AllowFailure bool
+	CallData     []byte
+}
+
+// multicall3Result is the result of a single aggregated read; field order matches the Result tuple
+type multicall3Result struct {
+	Success    bool
+	ReturnData []byte
+}
+
+// multicall packs the given reads into a single eth_call to the Multicall3 contract configured for the network;
+// each read is allowed to fail independently
+func multicall(ntwrk *network.Network, from string, calls []multicall3Call) ([]multicall3Result, error) {
+	_abi, err := abi.JSON(strings.NewReader(multicall3ABI))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse Multicall3 ABI; %s", err.Error())
+	}
+
+	data, err := _abi.Pack("aggregate3", calls)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode %d aggregated read(s); %s", len(calls), err.Error())
+	}
+
+	client, err := providecrypto.EVMDialJsonRpc(ntwrk.ID.String(), ntwrk.RPCURL())
+	if err != nil {
+		return nil, fmt.Errorf("failed to dial JSON-RPC host; %s", err.Error())
+	}
+
+	multicallAddress := ethcommon.HexToAddress(ntwrk.Multicall3Address())
+	msg := ethereum.CallMsg{
+		To:   &multicallAddress,
+		Data: data,
+	}
+	if from != "" {
+		msg.From = ethcommon.HexToAddress(from)
+	}
+
+	out, err := client.CallContract(context.TODO(), msg, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute %d aggregated read(s) using Multicall3 at %s; %s", len(calls), multicallAddress.Hex(), err.Error())
+	}
+
+	var results []multicall3Result
+	err = _abi.Unpack(&results, "aggregate3", out)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode %d aggregated read(s); %s", len(calls), err.Error())
+	}
+
+	if len(results) != len(calls) {
+		return nil, fmt.Errorf("Multicall3 returned %d result(s) for %d aggregated read(s)", len(results), len(calls))
+	}
+
+	return results, nil
+}
@@ -0,0 +1,250 @@
+package tx
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	ethcommon "github.com/ethereum/go-ethereum/common"
+	redisutil "github.com/kthomas/go-redisutil"
+	uuid "github.com/kthomas/go.uuid"
+	"github.com/provideplatform/nchain/common"
+	"github.com/provideplatform/nchain/network"
+	providecrypto "github.com/provideplatform/provide-go/crypto"
+)
+
+// nonceDriftTolerance is the number of allocated nonces the manager may run ahead of the
+// pending nonce reported by the network before it is considered to have drifted and resynced
+const nonceDriftTolerance = uint64(64)
+
+// NonceManager allocates monotonically increasing nonces for a single signing address on a
+// single network; allocation is mutually exclusive across all nchain instances by way of a
+// distributed lock, and nonces reclaimed from failed broadcasts are reissued before new ones
+type NonceManager struct {
+	Network *network.Network
+	Address string
+}
+
+// NonceKey returns the key, unique-per-network-and-signer, where the next nonce is cached
+func NonceKey(networkID uuid.UUID, address string) string {
+	return fmt.Sprintf("network.%s.signer.%s.nonce", networkID.String(), strings.ToLower(address))
+}
+
+// NonceMutexKey returns the key, unique-per-network-and-signer, which represents the
+// distributed lock for nonce allocation
+func NonceMutexKey(networkID uuid.UUID, address string) string {
+	return fmt.Sprintf("%s.mutex", NonceKey(networkID, address))
+}
+
+// NonceReclaimedKey returns the key, unique-per-network-and-signer, where the nonces reclaimed
+// from failed broadcasts are cached until they are reissued
+func NonceReclaimedKey(networkID uuid.UUID, address string) string {
+	return fmt.Sprintf("%s.reclaimed", NonceKey(networkID, address))
+}
+
+// nonceManagerFactory returns a nonce manager for the given network and signing address
+func nonceManagerFactory(ntwrk *network.Network, address string) *NonceManager {
+	return &NonceManager{
+		Network: ntwrk,
+		Address: address,
+	}
+}
+
+// Allocate returns the next nonce for the signer; reclaimed gaps are reissued first
+func (m *NonceManager) Allocate() (*uint64, error) {
+	nonces, err := m.AllocateN(1)
+	if err != nil {
+		return nil, err
+	}
+	return &nonces[0], nil
+}
+
+// AllocateN returns the next n nonces for the signer in ascending order; the nonces are allocated
+// while holding the lock so a batch of txs signed by the same signer receives sequential nonces.
+// Reclaimed gaps are reissued first
+func (m *NonceManager) AllocateN(n int) ([]uint64, error) {
+	nonces := make([]uint64, 0, n)
+
+	err := redisutil.WithRedlock(m.mutexKey(), func() error {
+		pending, err := m.pendingNonce()
+		if err != nil {
+			return err
+		}
+
+		reclaimed := m.reclaimed(pending)
+		for len(reclaimed) > 0 && len(nonces) < n {
+			nonces = append(nonces, reclaimed[0])
+			common.Log.Debugf("reissued reclaimed nonce %d for signer %s on network: %s", reclaimed[0], m.Address, m.Network.ID)
+			reclaimed = reclaimed[1:]
+		}
+		if len(nonces) > 0 {
+			err = m.setReclaimed(reclaimed)
+			if err != nil {
+				return err
+			}
+		}
+
+		if len(nonces) == n {
+			return nil
+		}
+
+		next := m.cached()
+		if next == nil || *next < pending || *next > pending+nonceDriftTolerance {
+			if next != nil {
+				common.Log.Debugf("resyncing drifted nonce for signer %s on network: %s; cached: %d; pending: %d", m.Address, m.Network.ID, *next, pending)
+			}
+			next = &pending
+		}
+
+		_nonce := *next
+		for len(nonces) < n {
+			nonces = append(nonces, _nonce)
+			_nonce++
+		}
+		return m.setCached(_nonce)
+	})
+
+	if err != nil {
+		common.Log.Warningf("failed to allocate %d nonce(s) for signer %s on network: %s; %s", n, m.Address, m.Network.ID, err.Error())
+		return nil, err
+	}
+
+	common.Log.Debugf("allocated %d nonce(s) starting at %d for signer %s on network: %s", n, nonces[0], m.Address, m.Network.ID)
+	return nonces, nil
+}
+
+// Reclaim returns the given nonce to the manager so it is reissued by a subsequent allocation;
+// this should only be called when a tx signed with the nonce was never accepted by the network
+func (m *NonceManager) Reclaim(nonce uint64) error {
+	err := redisutil.WithRedlock(m.mutexKey(), func() error {
+		reclaimed := m.reclaimed(0)
+		for _, n := range reclaimed {
+			if n == nonce {
+				return nil
+			}
+		}
+		return m.setReclaimed(append(reclaimed, nonce))
+	})
+
+	if err != nil {
+		common.Log.Warningf("failed to reclaim nonce %d for signer %s on network: %s; %s", nonce, m.Address, m.Network.ID, err.Error())
+		return err
+	}
+
+	common.Log.Debugf("reclaimed nonce %d for signer %s on network: %s", nonce, m.Address, m.Network.ID)
+	return nil
+}
+
+// Resync discards the cached and reclaimed nonces for the signer so the next allocation
+// is resolved from the pending nonce reported by the network
+func (m *NonceManager) Resync() error {
+	return redisutil.WithRedlock(m.mutexKey(), func() error {
+		pending, err := m.pendingNonce()
+		if err != nil {
+			return err
+		}
+
+		err = m.setReclaimed(make([]uint64, 0))
+		if err != nil {
+			return err
+		}
+
+		common.Log.Debugf("resynced nonce for signer %s on network: %s; pending: %d", m.Address, m.Network.ID, pending)
+		return m.setCached(pending)
+	})
+}
+
+func (m *NonceManager) key() string {
+	return NonceKey(m.Network.ID, m.Address)
+}
+
+func (m *NonceManager) mutexKey() string {
+	return NonceMutexKey(m.Network.ID, m.Address)
+}
+
+func (m *NonceManager) reclaimedKey() string {
+	return NonceReclaimedKey(m.Network.ID, m.Address)
+}
+
+// cached returns the next nonce cached for the signer, or nil if none is cached
+func (m *NonceManager) cached() *uint64 {
+	raw, err := redisutil.Get(m.key())
+	if err != nil || raw == nil {
+		return nil
+	}
+
+	next, err := strconv.ParseUint(*raw, 10, 64)
+	if err != nil {
+		common.Log.Warningf("failed to parse cached nonce for signer %s on network: %s; %s", m.Address, m.Network.ID, err.Error())
+		return nil
+	}
+
+	return &next
+}
+
+func (m *NonceManager) setCached(next uint64) error {
+	return redisutil.Set(m.key(), strconv.FormatUint(next, 10), nil)
+}
+
+// reclaimed returns the sorted reclaimed nonces which are greater than or equal to the given floor;
+// reclaimed nonces below the floor have since been consumed on-chain and are discarded
+func (m *NonceManager) reclaimed(floor uint64) []uint64 {
+	nonces := make([]uint64, 0)
+
+	raw, err := redisutil.Get(m.reclaimedKey())
+	if err != nil || raw == nil {
+		return nonces
+	}
+
+	var cached []uint64
+	err = json.Unmarshal([]byte(*raw), &cached)
+	if err != nil {
+		common.Log.Warningf("failed to unmarshal reclaimed nonces for signer %s on network: %s; %s", m.Address, m.Network.ID, err.Error())
+		return nonces
+	}
+
+	for _, nonce := range cached {
+		if nonce >= floor {
+			nonces = append(nonces, nonce)
+		}
+	}
+
+	sort.Slice(nonces, func(i, j int) bool { return nonces[i] < nonces[j] })
+	return nonces
+}
+
+func (m *NonceManager) setReclaimed(nonces []uint64) error {
+	raw, _ := json.Marshal(nonces)
+	return redisutil.Set(m.reclaimedKey(), string(raw), nil)
+}
+
+// pendingNonce returns the pending nonce for the signer via eth_getTransactionCount
+func (m *NonceManager) pendingNonce() (uint64, error) {
+	if !m.Network.IsEthereumNetwork() {
+		return 0, fmt.Errorf("nonce management not supported for network: %s", m.Network.ID)
+	}
+
+	client, err := providecrypto.EVMDialJsonRpc(m.Network.ID.String(), m.Network.RPCURL())
+	if err != nil {
+		return 0, err
+	}
+
+	return client.PendingNonceAt(context.TODO(), ethcommon.HexToAddress(m.Address))
+}
+
+// isNonceConsumedErr returns true if the given broadcast error indicates the tx nonce was
+// consumed by the network (or is already pending), in which case it must not be reclaimed
+func isNonceConsumedErr(err error) bool {
+	if err == nil {
+		return false
+	}
+
+	msg := strings.ToLower(err.Error())
+	return strings.Contains(msg, "nonce too low") ||
+		strings.Contains(msg, "already known") ||
+		strings.Contains(msg, "known transaction") ||
+		strings.Contains(msg, "replacement transaction underpriced")
+}
@@ -0,0 +1,197 @@
+// +build unit
+
+package tx
+
+import (
+	"errors"
+	"sync"
+	"testing"
+)
+
+const nonceTestSigner = "0x96216849c49358B10257cb55b28eA603c874b05E"
+
+func TestNonceManagerAllocatesFromPendingNonce(t *testing.T) {
+	pending := uint64(12)
+	ntwrk, srv := jsonRPCStubFactory(t, map[string]jsonRPCHandler{
+		"eth_getTransactionCount": pendingNonceStub(&pending),
+	})
+	defer srv.Close()
+
+	manager := nonceManagerFactory(ntwrk, nonceTestSigner)
+	for expected := uint64(12); expected < 15; expected++ {
+		nonce, err := manager.Allocate()
+		if err != nil {
+			t.Fatalf("failed to allocate nonce; %s", err.Error())
+		}
+		if *nonce != expected {
+			t.Errorf("expected nonce %d; got %d", expected, *nonce)
+		}
+	}
+
+	nonces, err := manager.AllocateN(3)
+	if err != nil {
+		t.Fatalf("failed to allocate nonces; %s", err.Error())
+	}
+	if nonces[0] != 15 || nonces[1] != 16 || nonces[2] != 17 {
+		t.Errorf("expected sequential nonces 15, 16 and 17; got %v", nonces)
+	}
+}
+
+func TestNonceManagerReissuesReclaimedNoncesInOrder(t *testing.T) {
+	pending := uint64(0)
+	ntwrk, srv := jsonRPCStubFactory(t, map[string]jsonRPCHandler{
+		"eth_getTransactionCount": pendingNonceStub(&pending),
+	})
+	defer srv.Close()
+
+	manager := nonceManagerFactory(ntwrk, nonceTestSigner)
+	manager.AllocateN(5) // 0 through 4
+
+	manager.Reclaim(3)
+	manager.Reclaim(1)
+	manager.Reclaim(3) // reclaiming the same nonce twice must not reissue it twice
+
+	nonces, err := manager.AllocateN(3)
+	if err != nil {
+		t.Fatalf("failed to allocate nonces; %s", err.Error())
+	}
+	if nonces[0] != 1 || nonces[1] != 3 || nonces[2] != 5 {
+		t.Errorf("expected reclaimed nonces 1 and 3 to be reissued before 5; got %v", nonces)
+	}
+}
+
+func TestNonceManagerDiscardsReclaimedNoncesConsumedOnChain(t *testing.T) {
+	pending := uint64(0)
+	ntwrk, srv := jsonRPCStubFactory(t, map[string]jsonRPCHandler{
+		"eth_getTransactionCount": pendingNonceStub(&pending),
+	})
+	defer srv.Close()
+
+	manager := nonceManagerFactory(ntwrk, nonceTestSigner)
+	manager.AllocateN(4) // 0 through 3
+	manager.Reclaim(1)
+
+	// nonce 1 was since consumed, i.e. by a tx broadcast out of band
+	pending = 4
+
+	nonce, err := manager.Allocate()
+	if err != nil {
+		t.Fatalf("failed to allocate nonce; %s", err.Error())
+	}
+	if *nonce != 4 {
+		t.Errorf("expected consumed reclaimed nonce to be discarded; got %d", *nonce)
+	}
+}
+
+func TestNonceManagerResyncsDriftedNonce(t *testing.T) {
+	pending := uint64(10)
+	ntwrk, srv := jsonRPCStubFactory(t, map[string]jsonRPCHandler{
+		"eth_getTransactionCount": pendingNonceStub(&pending),
+	})
+	defer srv.Close()
+
+	manager := nonceManagerFactory(ntwrk, nonceTestSigner)
+	manager.Allocate()
+
+	// the pending nonce moved ahead of the cached nonce
+	pending = 20
+	nonce, _ := manager.Allocate()
+	if *nonce != 20 {
+		t.Errorf("expected nonce behind the pending nonce to be resynced to 20; got %d", *nonce)
+	}
+
+	// the cached nonce ran further ahead of the pending nonce than is tolerated
+	manager.setCached(pending + nonceDriftTolerance + 1)
+	nonce, _ = manager.Allocate()
+	if *nonce != 20 {
+		t.Errorf("expected nonce beyond the drift tolerance to be resynced to 20; got %d", *nonce)
+	}
+
+	// a cached nonce within the drift tolerance is trusted
+	manager.setCached(pending + nonceDriftTolerance)
+	nonce, _ = manager.Allocate()
+	if *nonce != pending+nonceDriftTolerance {
+		t.Errorf("expected nonce within the drift tolerance to be allocated; got %d", *nonce)
+	}
+}
+
+func TestNonceManagerResyncDiscardsReclaimedNonces(t *testing.T) {
+	pending := uint64(0)
+	ntwrk, srv := jsonRPCStubFactory(t, map[string]jsonRPCHandler{
+		"eth_getTransactionCount": pendingNonceStub(&pending),
+	})
+	defer srv.Close()
+
+	manager := nonceManagerFactory(ntwrk, nonceTestSigner)
+	manager.AllocateN(3)
+	manager.Reclaim(1)
+
+	pending = 2
+	err := manager.Resync()
+	if err != nil {
+		t.Fatalf("failed to resync nonce; %s", err.Error())
+	}
+
+	nonce, _ := manager.Allocate()
+	if *nonce != 2 {
+		t.Errorf("expected the pending nonce to be allocated after resync; got %d", *nonce)
+	}
+}
+
+func TestNonceManagerConcurrentAllocationsAreUnique(t *testing.T) {
+	pending := uint64(0)
+	ntwrk, srv := jsonRPCStubFactory(t, map[string]jsonRPCHandler{
+		"eth_getTransactionCount": pendingNonceStub(&pending),
+	})
+	defer srv.Close()
+
+	var mutex sync.Mutex
+	var wg sync.WaitGroup
+	allocated := map[uint64]bool{}
+
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			nonce, err := nonceManagerFactory(ntwrk, nonceTestSigner).Allocate()
+			if err != nil {
+				t.Errorf("failed to allocate nonce; %s", err.Error())
+				return
+			}
+
+			mutex.Lock()
+			defer mutex.Unlock()
+			if allocated[*nonce] {
+				t.Errorf("nonce %d allocated more than once", *nonce)
+			}
+			allocated[*nonce] = true
+		}()
+	}
+	wg.Wait()
+
+	for nonce := uint64(0); nonce < 20; nonce++ {
+		if !allocated[nonce] {
+			t.Errorf("expected nonce %d to be allocated", nonce)
+		}
+	}
+}
+
+func TestIsNonceConsumedErr(t *testing.T) {
+	consumed := []string{
+		"nonce too low",
+		"already known",
+		"Known transaction: 0x5e1f",
+		"replacement transaction underpriced",
+	}
+	for _, msg := range consumed {
+		if !isNonceConsumedErr(errors.New(msg)) {
+			t.Errorf("expected %q to indicate the nonce was consumed", msg)
+		}
+	}
+
+	for _, err := range []error{nil, errors.New("insufficient funds for gas * price + value"), errors.New("connection refused")} {
+		if isNonceConsumedErr(err) {
+			t.Errorf("expected %v not to indicate the nonce was consumed", err)
+		}
+	}
+}
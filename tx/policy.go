@@ -0,0 +1,326 @@
+package tx
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"sort"
+	"time"
+
+	"github.com/jinzhu/gorm"
+	redisutil "github.com/kthomas/go-redisutil"
+	uuid "github.com/kthomas/go.uuid"
+	"github.com/provideplatform/nchain/common"
+	"github.com/provideplatform/nchain/wallet"
+	"github.com/provideplatform/nchain/webhook"
+	provide "github.com/provideplatform/provide-go/api"
+)
+
+// policyReservationTTL is the duration for which the value of a tx is counted against the daily limits of
+// its policies before the tx is persisted; reservations outlive a crashed instance by no more than this
+const policyReservationTTL = time.Minute * 5
+
+// policyReservation is the value of a tx counted against the daily limits of the policies of the given
+// subjects, i.e. accounts and HD wallets, from the time the tx is evaluated until it is persisted
+type policyReservation struct {
+	id       string
+	subjects []string
+	value    *big.Int
+}
+
+// policyReservationEntry is a reservation as it is stored in redis
+type policyReservationEntry struct {
+	Value     string    `json:"value"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// enforcePolicies evaluates the tx against the spending policies of its signing account and/or HD wallet;
+// the first violation of a hard limit is returned, otherwise the approvals required to sign the tx, if any
+func (t *Transaction) enforcePolicies(db *gorm.DB) ([]*approvalRequirement, error) {
+	policies := wallet.ResolvePolicies(db, t.AccountID, t.WalletID)
+	if len(policies) == 0 {
+		return make([]*approvalRequirement, 0), nil
+	}
+
+	var value *big.Int
+	if t.Value != nil {
+		value = t.Value.BigInt()
+	}
+
+	req := &wallet.PolicyRequest{
+		To:        t.To,
+		Value:     value,
+		Data:      t.Data,
+		Timestamp: time.Now(),
+		Spent:     spentSince(db),
+	}
+
+	return t.evaluatePolicies(policies, req)
+}
+
+// evaluatePolicies evaluates the tx against the given policies under the lock of each account and HD wallet
+// subject to the policies; the value of a tx which is not rejected is reserved until the tx is persisted, so
+// concurrent txs are evaluated against the value spent by one another and cannot exceed a daily limit
+func (t *Transaction) evaluatePolicies(policies []*wallet.Policy, req *wallet.PolicyRequest) ([]*approvalRequirement, error) {
+	subjects := make([]string, 0)
+	for _, policy := range policies {
+		subject, err := policySubject(policy)
+		if err != nil {
+			return nil, err
+		}
+		subjects = append(subjects, subject)
+	}
+	subjects = distinctPolicySubjects(subjects)
+
+	spent := req.Spent
+	req.Spent = func(policy *wallet.Policy, since time.Time) (*big.Int, error) {
+		val, err := spent(policy, since)
+		if err != nil {
+			return nil, err
+		}
+		subject, _ := policySubject(policy)
+		return val.Add(val, reservedPolicySpend(subject, req.Timestamp)), nil
+	}
+
+	var requirements []*approvalRequirement
+	var evaluationErr error
+
+	err := withPolicyLocks(subjects, func() error {
+		requirements = make([]*approvalRequirement, 0)
+
+		for _, policy := range policies {
+			err := policy.Evaluate(req)
+			if err == nil {
+				continue
+			}
+
+			if violation, violationOk := err.(*wallet.PolicyViolation); violationOk && violation.RequiredApprovals > 0 {
+				requirement, err := approvalRequirementFactory(policy)
+				if err != nil {
+					evaluationErr = err
+					return nil
+				}
+				requirements = append(requirements, requirement)
+				continue
+			}
+
+			evaluationErr = err
+			return nil
+		}
+
+		if req.Value != nil && req.Value.Sign() > 0 {
+			reservation, err := reservePolicySpend(subjects, req.Value, req.Timestamp)
+			if err != nil {
+				return err
+			}
+			t.policyReservation = reservation
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to evaluate spending policies of tx with ref %v; %s", t.Ref, err.Error())
+	}
+	if evaluationErr != nil {
+		return nil, evaluationErr
+	}
+
+	common.Log.Debugf("tx with ref %v satisfies %d spending policies; %d approval requirement(s)", t.Ref, len(policies), len(requirements))
+	return requirements, nil
+}
+
+// releasePolicyReservation releases the value of the tx reserved against the daily limits of its policies,
+// if any; it is released once the tx is persisted, or fails to be, as persisted txs are counted as spent
+func (t *Transaction) releasePolicyReservation() {
+	reservation := t.policyReservation
+	if reservation == nil {
+		return
+	}
+	t.policyReservation = nil
+
+	err := withPolicyLocks(reservation.subjects, func() error {
+		for _, subject := range reservation.subjects {
+			entries := policyReservationEntries(subject, time.Now())
+			delete(entries, reservation.id)
+			setPolicyReservationEntries(subject, entries)
+		}
+		return nil
+	})
+	if err != nil {
+		common.Log.Warningf("failed to release spending policy reservation %s of tx with ref %v; %s", reservation.id, t.Ref, err.Error())
+	}
+}
+
+// rejectByPolicy persists the tx as failed, without signing it, for having violated a spending policy
+func (t *Transaction) rejectByPolicy(db *gorm.DB, signer *TransactionSigner, violation error) bool {
+	common.Log.Debugf("rejecting tx with ref %v; %s", t.Ref, violation.Error())
+
+	t.reclaimBatchNonce(signer)
+
+	if t.AccountID != nil && *t.AccountID == uuid.Nil {
+		t.AccountID = nil
+	}
+	if t.WalletID != nil && *t.WalletID == uuid.Nil {
+		t.WalletID = nil
+	}
+
+	desc := violation.Error()
+	t.Status = common.StringOrNil("failed")
+	t.Description = &desc
+
+	result := db.Create(&t)
+	errors := result.GetErrors()
+	if len(errors) > 0 {
+		for _, err := range errors {
+			t.Errors = append(t.Errors, &provide.Error{
+				Message: common.StringOrNil(err.Error()),
+			})
+		}
+	} else {
+		t.dispatchWebhook(db, webhook.EventTransactionFailed)
+	}
+
+	t.Errors = append(t.Errors, &provide.Error{
+		Message: common.StringOrNil(desc),
+	})
+	return false
+}
+
+// spentSince returns the function which resolves the aggregate value of the txs signed by the account or HD
+// wallet of a policy since the given time; txs which failed or were dropped are not counted
+func spentSince(db *gorm.DB) func(*wallet.Policy, time.Time) (*big.Int, error) {
+	return func(policy *wallet.Policy, since time.Time) (*big.Int, error) {
+		query := db.Table("transactions").
+			Select("COALESCE(SUM(CAST(value AS numeric)), 0)::text").
+			Where("created_at >= ? AND status NOT IN (?)", since, []string{"failed", txStatusDropped})
+
+		if policy.AccountID != nil {
+			query = query.Where("account_id = ?", policy.AccountID)
+		} else if policy.WalletID != nil {
+			query = query.Where("wallet_id = ?", policy.WalletID)
+		} else {
+			return nil, fmt.Errorf("policy %s is not associated with an account or HD wallet", policy.ID)
+		}
+
+		var spent string
+		err := query.Row().Scan(&spent)
+		if err != nil {
+			return nil, err
+		}
+
+		val, ok := new(big.Int).SetString(spent, 10)
+		if !ok {
+			return nil, fmt.Errorf("failed to parse spent value: %s", spent)
+		}
+		return val, nil
+	}
+}
+
+// policySubject returns the account or HD wallet subject to the policy
+func policySubject(policy *wallet.Policy) (string, error) {
+	if policy.AccountID != nil {
+		return fmt.Sprintf("account.%s", policy.AccountID.String()), nil
+	} else if policy.WalletID != nil {
+		return fmt.Sprintf("wallet.%s", policy.WalletID.String()), nil
+	}
+	return "", fmt.Errorf("policy %s is not associated with an account or HD wallet", policy.ID)
+}
+
+// distinctPolicySubjects returns the distinct subjects in the order in which their locks are acquired
+func distinctPolicySubjects(subjects []string) []string {
+	distinct := make([]string, 0)
+	seen := map[string]bool{}
+	for _, subject := range subjects {
+		if !seen[subject] {
+			seen[subject] = true
+			distinct = append(distinct, subject)
+		}
+	}
+	sort.Strings(distinct)
+	return distinct
+}
+
+// withPolicyLocks executes the given function under the lock of each of the given subjects; the locks must
+// be acquired in a consistent order, as txs signed by an account of an HD wallet are subject to both
+func withPolicyLocks(subjects []string, fn func() error) error {
+	if len(subjects) == 0 {
+		return fn()
+	}
+	return redisutil.WithRedlock(policyMutexKey(subjects[0]), func() error {
+		return withPolicyLocks(subjects[1:], fn)
+	})
+}
+
+func policyMutexKey(subject string) string {
+	return fmt.Sprintf("tx.policy.%s", subject)
+}
+
+func policyReservationsKey(subject string) string {
+	return fmt.Sprintf("tx.policy.%s.reserved", subject)
+}
+
+// reservePolicySpend reserves the given value against the daily limits of the policies of the given subjects;
+// the caller must hold the lock of each subject
+func reservePolicySpend(subjects []string, value *big.Int, now time.Time) (*policyReservation, error) {
+	reservationID, err := uuid.NewV4()
+	if err != nil {
+		return nil, err
+	}
+
+	reservation := &policyReservation{
+		id:       reservationID.String(),
+		subjects: subjects,
+		value:    value,
+	}
+
+	for _, subject := range subjects {
+		entries := policyReservationEntries(subject, now)
+		entries[reservation.id] = &policyReservationEntry{
+			Value:     value.String(),
+			ExpiresAt: now.Add(policyReservationTTL),
+		}
+		err := setPolicyReservationEntries(subject, entries)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return reservation, nil
+}
+
+// reservedPolicySpend returns the aggregate value reserved against the daily limits of the policies of the
+// given subject by txs which have not yet been persisted
+func reservedPolicySpend(subject string, now time.Time) *big.Int {
+	reserved := big.NewInt(0)
+	for _, entry := range policyReservationEntries(subject, now) {
+		if val, valOk := new(big.Int).SetString(entry.Value, 10); valOk {
+			reserved.Add(reserved, val)
+		}
+	}
+	return reserved
+}
+
+// policyReservationEntries returns the unexpired reservations of the given subject, keyed by reservation id
+func policyReservationEntries(subject string, now time.Time) map[string]*policyReservationEntry {
+	entries := map[string]*policyReservationEntry{}
+	raw, _ := redisutil.Get(policyReservationsKey(subject))
+	if raw != nil {
+		json.Unmarshal([]byte(*raw), &entries)
+	}
+
+	for id, entry := range entries {
+		if !now.Before(entry.ExpiresAt) {
+			delete(entries, id)
+		}
+	}
+	return entries
+}
+
+func setPolicyReservationEntries(subject string, entries map[string]*policyReservationEntry) error {
+	raw, _ := json.Marshal(entries)
+	ttl := policyReservationTTL
+	if len(entries) == 0 {
+		ttl = time.Millisecond // expires the reservations, as keys cannot be deleted using redisutil
+	}
+	return redisutil.Set(policyReservationsKey(subject), string(raw), &ttl)
+}
@@ -0,0 +1,97 @@
+// +build unit
+
+package tx
+
+import (
+	"math/big"
+	"sync"
+	"testing"
+	"time"
+
+	uuid "github.com/kthomas/go.uuid"
+	"github.com/provideplatform/nchain/common"
+	"github.com/provideplatform/nchain/wallet"
+)
+
+func dailyLimitPolicyFactory(dailyLimit string) *wallet.Policy {
+	accountID, _ := uuid.NewV4()
+	policy := &wallet.Policy{
+		AccountID:  &accountID,
+		Enabled:    true,
+		DailyLimit: common.StringOrNil(dailyLimit),
+	}
+	policy.ID, _ = uuid.NewV4()
+	return policy
+}
+
+func nothingSpent(policy *wallet.Policy, since time.Time) (*big.Int, error) {
+	return big.NewInt(0), nil
+}
+
+func TestConcurrentTxsCannotExceedDailyLimit(t *testing.T) {
+	policy := dailyLimitPolicyFactory("5000")
+
+	var wg sync.WaitGroup
+	var mutex sync.Mutex
+	accepted := make([]*Transaction, 0)
+
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			tx := &Transaction{}
+			_, err := tx.evaluatePolicies([]*wallet.Policy{policy}, &wallet.PolicyRequest{
+				Value:     big.NewInt(1000),
+				Timestamp: time.Now(),
+				Spent:     nothingSpent,
+			})
+			if err == nil {
+				mutex.Lock()
+				accepted = append(accepted, tx)
+				mutex.Unlock()
+			} else if _, violationOk := err.(*wallet.PolicyViolation); !violationOk {
+				t.Errorf("failed to evaluate spending policy; %s", err.Error())
+			}
+		}()
+	}
+	wg.Wait()
+
+	if len(accepted) != 5 {
+		t.Errorf("expected 5 concurrent txs to be accepted against a daily limit of 5000; %d were accepted", len(accepted))
+	}
+
+	subject, _ := policySubject(policy)
+	if reserved := reservedPolicySpend(subject, time.Now()); reserved.Cmp(big.NewInt(5000)) != 0 {
+		t.Errorf("expected 5000 to be reserved; got %s", reserved.String())
+	}
+
+	for _, tx := range accepted {
+		tx.releasePolicyReservation()
+		tx.releasePolicyReservation() // the reservation must not be released twice
+	}
+
+	if reserved := reservedPolicySpend(subject, time.Now()); reserved.Sign() != 0 {
+		t.Errorf("expected all reservations to be released; %s remains reserved", reserved.String())
+	}
+}
+
+func TestExpiredPolicyReservationIsNotCounted(t *testing.T) {
+	policy := dailyLimitPolicyFactory("1000")
+
+	tx := &Transaction{}
+	_, err := tx.evaluatePolicies([]*wallet.Policy{policy}, &wallet.PolicyRequest{
+		Value:     big.NewInt(1000),
+		Timestamp: time.Now(),
+		Spent:     nothingSpent,
+	})
+	if err != nil {
+		t.Fatalf("expected tx to be accepted; %s", err.Error())
+	}
+
+	// a reservation which outlives the instance which made it expires
+	subject, _ := policySubject(policy)
+	if reserved := reservedPolicySpend(subject, time.Now().Add(policyReservationTTL)); reserved.Sign() != 0 {
+		t.Errorf("expected expired reservation not to be counted; %s is reserved", reserved.String())
+	}
+}
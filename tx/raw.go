@@ -0,0 +1,252 @@
+package tx
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+
+	ethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/jinzhu/gorm"
+	natsutil "github.com/kthomas/go-natsutil"
+	uuid "github.com/kthomas/go.uuid"
+	"github.com/provideplatform/nchain/common"
+	"github.com/provideplatform/nchain/network"
+	provide "github.com/provideplatform/provide-go/api"
+	providecrypto "github.com/provideplatform/provide-go/crypto"
+)
+
+// rawTxSigner is the Signer of a tx which was signed outside of nchain and submitted as a raw tx;
+// it cannot sign and only identifies the recovered sender
+type rawTxSigner struct {
+	address string
+}
+
+// Address returns the recovered sender of the raw tx
+func (s *rawTxSigner) Address() string {
+	return s.address
+}
+
+// Sign is not supported for pre-signed raw txs
+func (s *rawTxSigner) Sign(tx *Transaction) (signedTx interface{}, hash []byte, err error) {
+	return nil, nil, errors.New("unable to sign pre-signed raw tx")
+}
+
+// String prints a description of the raw tx signer
+func (s *rawTxSigner) String() string {
+	return fmt.Sprintf("pre-signed raw tx sender: %s", s.address)
+}
+
+// createRaw decodes, persists and broadcasts the pre-signed raw tx
+func (t *Transaction) createRaw(db *gorm.DB) bool {
+	if !t.Validate() {
+		return false
+	}
+
+	if t.AccountID != nil || t.WalletID != nil {
+		t.Errors = append(t.Errors, &provide.Error{
+			Message: common.StringOrNil("provided raw tx and account_id or wallet_id to tx creation, which is ambiguous"),
+		})
+		return false
+	}
+
+	ntwrk := &network.Network{}
+	db.Model(t).Related(&ntwrk)
+	if ntwrk == nil || ntwrk.ID == uuid.Nil {
+		t.Errors = append(t.Errors, &provide.Error{
+			Message: common.StringOrNil("invalid network for raw tx broadcast"),
+		})
+		return false
+	}
+
+	err := t.decodeRaw(ntwrk)
+	if err != nil {
+		t.Errors = append(t.Errors, &provide.Error{
+			Message: common.StringOrNil(err.Error()),
+		})
+		return false
+	}
+
+	existing := &Transaction{}
+	db.Where("network_id = ? AND hash = ?", t.NetworkID, t.Hash).Find(&existing)
+	if existing != nil && existing.ID != uuid.Nil {
+		t.Errors = append(t.Errors, &provide.Error{
+			Message: common.StringOrNil(fmt.Sprintf("raw tx %s has already been submitted as tx: %s", *t.Hash, existing.ID)),
+		})
+		return false
+	}
+
+	result := db.Create(&t)
+	errs := result.GetErrors()
+	if len(errs) > 0 {
+		for _, err := range errs {
+			t.Errors = append(t.Errors, &provide.Error{
+				Message: common.StringOrNil(err.Error()),
+			})
+		}
+		return false
+	}
+
+	if db.NewRecord(t) || result.RowsAffected == 0 {
+		return false
+	}
+
+	err = t.broadcast(db, ntwrk, &rawTxSigner{address: *t.From})
+	if err != nil {
+		return false
+	}
+
+	payload, _ := json.Marshal(map[string]interface{}{
+		"transaction_id": t.ID.String(),
+	})
+	natsutil.NatsJetstreamPublish(natsTxReceiptSubject, payload)
+
+	return true
+}
+
+// decodeRaw decodes the hex-encoded signed tx, which is either a legacy or an EIP-2718 typed tx envelope;
+// the chain id of the signed tx is verified against the network and its sender is recovered
+func (t *Transaction) decodeRaw(ntwrk *network.Network) error {
+	if !ntwrk.IsEthereumNetwork() {
+		return fmt.Errorf("unable to broadcast raw tx for unsupported network: %s", *ntwrk.Name)
+	}
+
+	raw, err := hexutil.Decode(*t.Raw)
+	if err != nil || len(raw) == 0 {
+		return errors.New("failed to decode raw tx; raw tx must be hex-encoded with a 0x prefix")
+	}
+
+	chainID, err := networkChainID(ntwrk)
+	if err != nil {
+		return err
+	}
+
+	var to *ethcommon.Address
+	var sender ethcommon.Address
+	var nonce uint64
+	var value *big.Int
+	var data []byte
+	var hash ethcommon.Hash
+
+	// an RLP list prefix is at least 0xc0; lower first bytes denote the EIP-2718 tx type
+	if raw[0] >= 0xc0 {
+		signedTx := &types.Transaction{}
+		err = rlp.DecodeBytes(raw, signedTx)
+		if err != nil {
+			return fmt.Errorf("failed to decode raw tx; %s", err.Error())
+		}
+
+		if !signedTx.Protected() {
+			return errors.New("unable to broadcast raw tx which is not replay-protected; sign the tx using EIP-155")
+		}
+
+		if signedTx.ChainId().Cmp(chainID) != 0 {
+			return fmt.Errorf("raw tx chain id %s does not match network chain id %s", signedTx.ChainId().String(), chainID.String())
+		}
+
+		sender, err = types.Sender(types.NewEIP155Signer(chainID), signedTx)
+		if err != nil {
+			return fmt.Errorf("failed to recover sender of raw tx; %s", err.Error())
+		}
+
+		t.SignedTx = signedTx
+		to, nonce, value, data, hash = signedTx.To(), signedTx.Nonce(), signedTx.Value(), signedTx.Data(), signedTx.Hash()
+	} else {
+		signedTx := &TypedTransaction{}
+		err = signedTx.UnmarshalBinary(raw)
+		if err != nil {
+			return fmt.Errorf("failed to decode raw tx; %s", err.Error())
+		}
+
+		if signedTx.ChainID == nil || signedTx.ChainID.Cmp(chainID) != 0 {
+			return fmt.Errorf("raw tx chain id %v does not match network chain id %s", signedTx.ChainID, chainID.String())
+		}
+
+		sender, err = signedTx.Sender()
+		if err != nil {
+			return err
+		}
+
+		t.SignedTx = signedTx
+		to, nonce, value, data, hash = signedTx.To, signedTx.Nonce, signedTx.Value, signedTx.Data, signedTx.Hash()
+	}
+
+	if to != nil {
+		t.To = common.StringOrNil(to.Hex())
+	}
+	if value == nil {
+		value = big.NewInt(0)
+	}
+	t.Value = &TxValue{value: value}
+	if len(data) > 0 {
+		t.Data = common.StringOrNil(hexutil.Encode(data))
+	}
+	t.Nonce = &nonce
+	t.From = common.StringOrNil(sender.Hex())
+	t.Hash = common.StringOrNil(hash.Hex())
+
+	common.Log.Debugf("decoded raw tx %s signed by %s for network: %s", *t.Hash, *t.From, ntwrk.ID)
+	return nil
+}
+
+// networkChainID returns the chain id of the network; when the chain id has not been persisted for the
+// network, it is resolved using eth_chainId
+func networkChainID(ntwrk *network.Network) (*big.Int, error) {
+	if ntwrk.ChainID != nil && *ntwrk.ChainID != "" {
+		chainID, err := hexutil.DecodeBig(*ntwrk.ChainID)
+		if err == nil {
+			return chainID, nil
+		}
+
+		chainID, ok := new(big.Int).SetString(strings.TrimSpace(*ntwrk.ChainID), 10)
+		if ok {
+			return chainID, nil
+		}
+	}
+
+	client, err := providecrypto.EVMDialJsonRpc(ntwrk.ID.String(), ntwrk.RPCURL())
+	if err != nil {
+		return nil, fmt.Errorf("failed to dial JSON-RPC host; %s", err.Error())
+	}
+
+	chainID, err := client.ChainID(context.TODO())
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve network chain id; %s", err.Error())
+	}
+
+	return chainID, nil
+}
+
+// resolveSigner resolves the network and the signer of the tx; a tx which was submitted as a
+// pre-signed raw tx has no custodial signing identity and is resolved to its recovered sender, and
+// a relayed tx is resolved to the account of its relayer
+func (t *Transaction) resolveSigner(db *gorm.DB) (*network.Network, Signer, error) {
+	if t.RelayerID != nil {
+		signer, err := t.relayerSigner(db)
+		if err != nil {
+			return nil, nil, err
+		}
+		return signer.Network, signer, nil
+	}
+
+	if t.AccountID == nil && t.WalletID == nil && t.From != nil {
+		ntwrk := &network.Network{}
+		db.Model(t).Related(&ntwrk)
+		if ntwrk == nil || ntwrk.ID == uuid.Nil {
+			return nil, nil, errors.New("invalid network for tx")
+		}
+		return ntwrk, &rawTxSigner{address: *t.From}, nil
+	}
+
+	signer, err := t.signerFactory(db)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return signer.Network, signer, nil
+}
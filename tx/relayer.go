@@ -0,0 +1,553 @@
+package tx
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	ethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/jinzhu/gorm"
+	dbconf "github.com/kthomas/go-db-config"
+	uuid "github.com/kthomas/go.uuid"
+	"github.com/provideplatform/nchain/common"
+	"github.com/provideplatform/nchain/network"
+	"github.com/provideplatform/nchain/wallet"
+	provide "github.com/provideplatform/provide-go/api"
+	providecrypto "github.com/provideplatform/provide-go/crypto"
+)
+
+// defaultForwarderName is the EIP-712 domain name of the trusted forwarder when the relayer does not specify one
+const defaultForwarderName = "MinimalForwarder"
+
+// defaultForwarderVersion is the EIP-712 domain version of the trusted forwarder when the relayer does not specify one
+const defaultForwarderVersion = "0.0.1"
+
+// forwardRequestType is the EIP-712 type of the forward request verified by the trusted forwarder
+const forwardRequestType = "ForwardRequest(address from,address to,uint256 value,uint256 gas,uint256 nonce,bytes data)"
+
+// minimalForwarderABI is the subset of the EIP-2771 MinimalForwarder ABI used to relay forward requests
+const minimalForwarderABI = `[{"inputs":[{"internalType":"address","name":"from","type":"address"}],"name":"getNonce","outputs":[{"internalType":"uint256","name":"","type":"uint256"}],"stateMutability":"view","type":"function"},{"inputs":[{"components":[{"internalType":"address","name":"from","type":"address"},{"internalType":"address","name":"to","type":"address"},{"internalType":"uint256","name":"value","type":"uint256"},{"internalType":"uint256","name":"gas","type":"uint256"},{"internalType":"uint256","name":"nonce","type":"uint256"},{"internalType":"bytes","name":"data","type":"bytes"}],"internalType":"struct MinimalForwarder.ForwardRequest","name":"req","type":"tuple"},{"internalType":"bytes","name":"signature","type":"bytes"}],"name":"execute","outputs":[{"internalType":"bool","name":"","type":"bool"},{"internalType":"bytes","name":"","type":"bytes"}],"stateMutability":"payable","type":"function"}]`
+
+// relayerBudgetPeriods are the supported gas budget periods, keyed by name
+var relayerBudgetPeriods = map[string]func(time.Time) time.Time{
+	"daily":   func(start time.Time) time.Time { return start.AddDate(0, 0, 1) },
+	"weekly":  func(start time.Time) time.Time { return start.AddDate(0, 0, 7) },
+	"monthly": func(start time.Time) time.Time { return start.AddDate(0, 1, 0) },
+}
+
+// forwardRequest is an EIP-2771 forward request; field order and names match the ForwardRequest tuple
+type forwardRequest struct {
+	From  ethcommon.Address
+	To    ethcommon.Address
+	Value *big.Int
+	Gas   *big.Int
+	Nonce *big.Int
+	Data  []byte
+}
+
+// hash returns the EIP-712 signing hash of the forward request within the given forwarder domain
+func (r *forwardRequest) hash(domain *eip712Domain) []byte {
+	return domain.hash(eip712StructHash(
+		forwardRequestType,
+		eip712Address(r.From),
+		eip712Address(r.To),
+		eip712Uint(r.Value),
+		eip712Uint(r.Gas),
+		eip712Uint(r.Nonce),
+		eip712Bytes(r.Data),
+	))
+}
+
+// Relayer submits EIP-2771 meta-txs, signed by the accounts of its application, to a trusted forwarder
+// using the relayer account, which pays for gas; the gas used is tracked against an optional budget
+type Relayer struct {
+	provide.Model
+	ApplicationID *uuid.UUID `sql:"not null;type:uuid" json:"application_id"`
+	NetworkID     uuid.UUID  `sql:"not null;type:uuid" json:"network_id"`
+	AccountID     uuid.UUID  `sql:"not null;type:uuid" json:"account_id"` // account which signs and pays for relayed txs
+	Name          *string    `json:"name,omitempty"`
+	Enabled       bool       `sql:"not null" json:"enabled"`
+
+	// Trusted forwarder contract and its EIP-712 signing domain
+	ForwarderAddress *string `sql:"not null" json:"forwarder_address"`
+	ForwarderName    *string `json:"forwarder_name,omitempty"`
+	ForwarderVersion *string `json:"forwarder_version,omitempty"`
+
+	// Gas budget; the budget is unlimited when nil, and is never reset when no budget period is set
+	GasBudget       *uint64    `json:"gas_budget,omitempty"`
+	BudgetPeriod    *string    `json:"budget_period,omitempty"` // daily, weekly or monthly
+	GasUsed         uint64     `sql:"not null" json:"gas_used"`
+	PeriodStartedAt *time.Time `json:"period_started_at,omitempty"`
+}
+
+// resolveRelayer returns the enabled relayer of the given application on the given network, if any
+func resolveRelayer(db *gorm.DB, applicationID, networkID uuid.UUID) *Relayer {
+	relayer := &Relayer{}
+	db.Where("application_id = ? AND network_id = ? AND enabled = true", applicationID, networkID).
+		Order("created_at ASC").
+		First(&relayer)
+	if relayer == nil || relayer.ID == uuid.Nil {
+		return nil
+	}
+	return relayer
+}
+
+// Create and persist a relayer
+func (r *Relayer) Create() bool {
+	db := dbconf.DatabaseConnection()
+
+	if !r.Validate() {
+		return false
+	}
+
+	if r.BudgetPeriod != nil {
+		periodStartedAt := time.Now()
+		r.PeriodStartedAt = &periodStartedAt
+	}
+	r.GasUsed = 0
+
+	if db.NewRecord(r) {
+		result := db.Create(&r)
+		rowsAffected := result.RowsAffected
+		errors := result.GetErrors()
+		if len(errors) > 0 {
+			for _, err := range errors {
+				r.Errors = append(r.Errors, &provide.Error{
+					Message: common.StringOrNil(err.Error()),
+				})
+			}
+		}
+		if !db.NewRecord(r) {
+			return rowsAffected > 0
+		}
+	}
+	return false
+}
+
+// Update an existing relayer; the gas used and current budget period are not updated
+func (r *Relayer) Update() bool {
+	db := dbconf.DatabaseConnection()
+
+	if !r.Validate() {
+		return false
+	}
+
+	if r.BudgetPeriod != nil && r.PeriodStartedAt == nil {
+		periodStartedAt := time.Now()
+		r.PeriodStartedAt = &periodStartedAt
+	}
+
+	result := db.Model(&r).Select(
+		"name", "enabled", "account_id", "forwarder_address", "forwarder_name", "forwarder_version",
+		"gas_budget", "budget_period", "period_started_at",
+	).Updates(map[string]interface{}{
+		"name":              r.Name,
+		"enabled":           r.Enabled,
+		"account_id":        r.AccountID,
+		"forwarder_address": r.ForwarderAddress,
+		"forwarder_name":    r.ForwarderName,
+		"forwarder_version": r.ForwarderVersion,
+		"gas_budget":        r.GasBudget,
+		"budget_period":     r.BudgetPeriod,
+		"period_started_at": r.PeriodStartedAt,
+	})
+	errors := result.GetErrors()
+	if len(errors) > 0 {
+		for _, err := range errors {
+			r.Errors = append(r.Errors, &provide.Error{
+				Message: common.StringOrNil(err.Error()),
+			})
+		}
+	}
+	return len(r.Errors) == 0
+}
+
+// Delete a relayer
+func (r *Relayer) Delete() bool {
+	db := dbconf.DatabaseConnection()
+	result := db.Delete(r)
+	errors := result.GetErrors()
+	if len(errors) > 0 {
+		for _, err := range errors {
+			r.Errors = append(r.Errors, &provide.Error{
+				Message: common.StringOrNil(err.Error()),
+			})
+		}
+	}
+	return len(r.Errors) == 0
+}
+
+// Validate a relayer for persistence
+func (r *Relayer) Validate() bool {
+	db := dbconf.DatabaseConnection()
+	r.Errors = make([]*provide.Error, 0)
+
+	if r.ApplicationID == nil || *r.ApplicationID == uuid.Nil {
+		r.Errors = append(r.Errors, &provide.Error{
+			Message: common.StringOrNil("relayer must be associated with an application"),
+		})
+	}
+
+	ntwrk := &network.Network{}
+	db.Where("id = ?", r.NetworkID).Find(&ntwrk)
+	if ntwrk == nil || ntwrk.ID == uuid.Nil {
+		r.Errors = append(r.Errors, &provide.Error{
+			Message: common.StringOrNil("relayer network not found"),
+		})
+	} else if !ntwrk.IsEthereumNetwork() {
+		r.Errors = append(r.Errors, &provide.Error{
+			Message: common.StringOrNil(fmt.Sprintf("relayed txs not supported by network %s", ntwrk.ID)),
+		})
+	}
+
+	account := &wallet.Account{}
+	db.Where("id = ?", r.AccountID).Find(&account)
+	if account == nil || account.ID == uuid.Nil {
+		r.Errors = append(r.Errors, &provide.Error{
+			Message: common.StringOrNil("relayer account not found"),
+		})
+	} else if account.ApplicationID == nil || r.ApplicationID == nil || *account.ApplicationID != *r.ApplicationID {
+		r.Errors = append(r.Errors, &provide.Error{
+			Message: common.StringOrNil("relayer account must belong to the relayer application"),
+		})
+	} else if account.NetworkID == nil || *account.NetworkID != r.NetworkID {
+		r.Errors = append(r.Errors, &provide.Error{
+			Message: common.StringOrNil("relayer account must belong to the relayer network"),
+		})
+	}
+
+	if r.ForwarderAddress == nil || !ethcommon.IsHexAddress(*r.ForwarderAddress) {
+		r.Errors = append(r.Errors, &provide.Error{
+			Message: common.StringOrNil("relayer forwarder_address must be a valid address"),
+		})
+	}
+
+	if r.BudgetPeriod != nil {
+		if _, periodOk := relayerBudgetPeriods[*r.BudgetPeriod]; !periodOk {
+			r.Errors = append(r.Errors, &provide.Error{
+				Message: common.StringOrNil(fmt.Sprintf("relayer budget_period must be one of daily, weekly or monthly; got %s", *r.BudgetPeriod)),
+			})
+		}
+	}
+
+	return len(r.Errors) == 0
+}
+
+// signer returns the transaction signer of the relayer account
+func (r *Relayer) signer(db *gorm.DB) (*TransactionSigner, error) {
+	ntwrk := &network.Network{}
+	db.Where("id = ?", r.NetworkID).Find(&ntwrk)
+	if ntwrk == nil || ntwrk.ID == uuid.Nil {
+		return nil, fmt.Errorf("invalid network for relayer %s", r.ID)
+	}
+
+	account := &wallet.Account{}
+	db.Where("id = ?", r.AccountID).Find(&account)
+	if account == nil || account.ID == uuid.Nil {
+		return nil, fmt.Errorf("invalid account for relayer %s", r.ID)
+	}
+
+	return &TransactionSigner{
+		DB:      db,
+		Network: ntwrk,
+		Account: account,
+	}, nil
+}
+
+// domain returns the EIP-712 signing domain of the trusted forwarder
+func (r *Relayer) domain(chainID *big.Int) *eip712Domain {
+	name := defaultForwarderName
+	if r.ForwarderName != nil {
+		name = *r.ForwarderName
+	}
+
+	version := defaultForwarderVersion
+	if r.ForwarderVersion != nil {
+		version = *r.ForwarderVersion
+	}
+
+	forwarder := ethcommon.HexToAddress(*r.ForwarderAddress)
+	return &eip712Domain{
+		Name:              &name,
+		Version:           &version,
+		ChainID:           chainID,
+		VerifyingContract: &forwarder,
+	}
+}
+
+// rollBudgetPeriod resets the gas used when the current budget period has elapsed; the reset is
+// conditional on the period having not been rolled concurrently
+func (r *Relayer) rollBudgetPeriod(db *gorm.DB) {
+	if r.BudgetPeriod == nil {
+		return
+	}
+
+	periodEnd, periodOk := relayerBudgetPeriods[*r.BudgetPeriod]
+	if !periodOk {
+		return
+	}
+
+	now := time.Now()
+	if r.PeriodStartedAt != nil && now.Before(periodEnd(*r.PeriodStartedAt)) {
+		return
+	}
+
+	query := db.Model(&Relayer{}).Where("id = ?", r.ID)
+	if r.PeriodStartedAt != nil {
+		query = query.Where("period_started_at = ?", r.PeriodStartedAt)
+	} else {
+		query = query.Where("period_started_at IS NULL")
+	}
+
+	result := query.Updates(map[string]interface{}{
+		"gas_used":          0,
+		"period_started_at": now,
+	})
+	if result.RowsAffected > 0 {
+		common.Log.Debugf("rolled %s gas budget period of relayer %s; %d gas used in previous period", *r.BudgetPeriod, r.ID, r.GasUsed)
+	}
+
+	db.Where("id = ?", r.ID).Find(&r)
+}
+
+// reserveGas atomically reserves the given gas against the budget of the relayer
+func (r *Relayer) reserveGas(db *gorm.DB, gas uint64) error {
+	r.rollBudgetPeriod(db)
+
+	result := db.Exec(
+		"UPDATE relayers SET gas_used = gas_used + ? WHERE id = ? AND enabled = true AND (gas_budget IS NULL OR gas_used + ? <= gas_budget)",
+		gas, r.ID, gas,
+	)
+	if err := result.Error; err != nil {
+		return fmt.Errorf("failed to reserve %d gas of relayer %s; %s", gas, r.ID, err.Error())
+	}
+	if result.RowsAffected == 0 {
+		budget := uint64(0)
+		if r.GasBudget != nil {
+			budget = *r.GasBudget
+		}
+		return fmt.Errorf("gas budget of relayer %s exhausted; %d of %d gas used; %d gas required", r.ID, r.GasUsed, budget, gas)
+	}
+
+	return nil
+}
+
+// shouldRelay returns true if the tx should be submitted as an EIP-2771 meta-tx by the relayer of its application
+func (t *Transaction) shouldRelay() bool {
+	if relay, relayOk := t.ParseParams()["relay"].(bool); relayOk {
+		return relay
+	}
+	return false
+}
+
+// relay wraps the tx in an EIP-2771 forward request signed using the given signer, and rewrites the tx as
+// the execution of the request by the trusted forwarder; the returned signer of the relayer account signs
+// and pays for the rewritten tx, whose gas limit is reserved against the gas budget of the relayer
+func (t *Transaction) relay(db *gorm.DB, signer *TransactionSigner) (*TransactionSigner, error) {
+	if !signer.Network.IsEthereumNetwork() {
+		return nil, fmt.Errorf("relayed txs not supported by network %s", signer.Network.ID)
+	}
+	if t.ApplicationID == nil {
+		return nil, fmt.Errorf("unable to relay tx outside of an application context")
+	}
+	if t.To == nil {
+		return nil, fmt.Errorf("unable to relay contract creation tx")
+	}
+	if t.Value != nil && t.Value.BigInt().Sign() > 0 {
+		return nil, fmt.Errorf("unable to relay tx having non-zero value")
+	}
+
+	relayer := resolveRelayer(db, *t.ApplicationID, t.NetworkID)
+	if relayer == nil {
+		return nil, fmt.Errorf("no relayer enabled for application %s on network %s", t.ApplicationID, t.NetworkID)
+	}
+
+	relayerSigner, err := relayer.signer(db)
+	if err != nil {
+		return nil, err
+	}
+
+	from, signHash, err := typedDataSigner(signer)
+	if err != nil {
+		return nil, err
+	}
+
+	client, err := providecrypto.EVMDialJsonRpc(signer.Network.ID.String(), signer.Network.RPCURL())
+	if err != nil {
+		return nil, fmt.Errorf("failed to dial JSON-RPC host; %s", err.Error())
+	}
+
+	chainID, err := client.ChainID(context.TODO())
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve chain id; %s", err.Error())
+	}
+
+	_abi, err := abi.JSON(strings.NewReader(minimalForwarderABI))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse forwarder ABI; %s", err.Error())
+	}
+
+	forwarder := ethcommon.HexToAddress(*relayer.ForwarderAddress)
+	nonce, err := forwarderNonce(client, &_abi, forwarder, from)
+	if err != nil {
+		return nil, err
+	}
+
+	var data []byte
+	if t.Data != nil {
+		data = ethcommon.FromHex(*t.Data)
+	}
+
+	// the gas param, when provided, limits the gas forwarded to the target call
+	gas, _, _ := signingParams(t)
+	if gas == 0 {
+		to := ethcommon.HexToAddress(*t.To)
+		estimate, err := client.EstimateGas(context.TODO(), ethereum.CallMsg{
+			From: from,
+			To:   &to,
+			Data: data,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to estimate gas of forward request; %s", err.Error())
+		}
+		gas = uint64(float64(estimate) * signer.Network.GasEstimateMultiplier())
+	}
+
+	req := forwardRequest{
+		From:  from,
+		To:    ethcommon.HexToAddress(*t.To),
+		Value: big.NewInt(0),
+		Gas:   new(big.Int).SetUint64(gas),
+		Nonce: nonce,
+		Data:  data,
+	}
+
+	sig, err := signHash(req.hash(relayer.domain(chainID)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to sign forward request; %s", err.Error())
+	}
+	if len(sig) != 65 {
+		return nil, fmt.Errorf("failed to sign forward request; invalid %d-byte signature", len(sig))
+	}
+	if sig[64] < 27 {
+		sig[64] += 27
+	}
+
+	calldata, err := _abi.Pack("execute", req, sig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode forward request; %s", err.Error())
+	}
+
+	relayerAddress := ethcommon.HexToAddress(relayerSigner.Address())
+	estimate, err := client.EstimateGas(context.TODO(), ethereum.CallMsg{
+		From: relayerAddress,
+		To:   &forwarder,
+		Data: calldata,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to estimate gas of relayed tx; %s", err.Error())
+	}
+	relayedGas := uint64(float64(estimate) * signer.Network.GasEstimateMultiplier())
+
+	err = relayer.reserveGas(db, relayedGas)
+	if err != nil {
+		return nil, err
+	}
+
+	relayedAt := time.Now()
+	t.To = common.StringOrNil(forwarder.Hex())
+	t.Data = common.StringOrNil(fmt.Sprintf("0x%x", calldata))
+	t.RelayerID = &relayer.ID
+	t.RelayerGasReserved = &relayedGas
+	t.RelayedAt = &relayedAt
+
+	// the nonce param, if any, is the nonce of the forward request signer and not of the relayer account
+	params := t.ParseParams()
+	delete(params, "nonce")
+	params["gas"] = relayedGas
+	params["gas_estimate"] = estimate
+	params["forward_request"] = map[string]interface{}{
+		"from":      req.From.Hex(),
+		"to":        req.To.Hex(),
+		"value":     req.Value.String(),
+		"gas":       gas,
+		"nonce":     req.Nonce.String(),
+		"data":      fmt.Sprintf("0x%x", req.Data),
+		"signature": fmt.Sprintf("0x%x", sig),
+	}
+	t.setParams(params)
+
+	common.Log.Debugf("relaying forward request from %s to %s via forwarder %s using relayer %s; reserved %d gas", req.From.Hex(), req.To.Hex(), forwarder.Hex(), relayer.ID, relayedGas)
+	return relayerSigner, nil
+}
+
+// settleRelayedGas releases the gas reserved for the relayed tx in excess of the given gas used; the
+// excess is only released when the budget period in which it was reserved has not since elapsed
+func (t *Transaction) settleRelayedGas(db *gorm.DB, gasUsed uint64) {
+	if t.RelayerID == nil || t.RelayerGasReserved == nil {
+		return
+	}
+
+	reserved := *t.RelayerGasReserved
+	t.RelayerGasReserved = nil
+
+	// settlement of a persisted tx is idempotent
+	if !db.NewRecord(t) {
+		result := db.Model(&Transaction{}).
+			Where("id = ? AND relayer_gas_reserved IS NOT NULL", t.ID).
+			Update("relayer_gas_reserved", gorm.Expr("NULL"))
+		if result.RowsAffected == 0 {
+			return
+		}
+	}
+
+	if gasUsed >= reserved {
+		return
+	}
+
+	result := db.Exec(
+		"UPDATE relayers SET gas_used = GREATEST(gas_used - ?, 0) WHERE id = ? AND (period_started_at IS NULL OR period_started_at <= ?)",
+		reserved-gasUsed, t.RelayerID, t.RelayedAt,
+	)
+	if err := result.Error; err != nil {
+		common.Log.Warningf("failed to release %d unused gas of relayer %s for tx %s; %s", reserved-gasUsed, t.RelayerID, t.ID, err.Error())
+		return
+	}
+
+	common.Log.Debugf("settled relayed tx %s; %d gas used; released %d of %d reserved gas", t.ID, gasUsed, reserved-gasUsed, reserved)
+}
+
+// relayerSigner returns the signer of the relayer account which submitted the relayed tx
+func (t *Transaction) relayerSigner(db *gorm.DB) (*TransactionSigner, error) {
+	relayer := &Relayer{}
+	db.Where("id = ?", t.RelayerID).Find(&relayer)
+	if relayer == nil || relayer.ID == uuid.Nil {
+		return nil, fmt.Errorf("relayer %s of tx %s not found", t.RelayerID, t.ID)
+	}
+	return relayer.signer(db)
+}
+
+// forwarderNonce returns the current nonce of the given forward request signer
+func forwarderNonce(client *ethclient.Client, _abi *abi.ABI, forwarder, from ethcommon.Address) (*big.Int, error) {
+	data, err := _abi.Pack("getNonce", from)
+	if err != nil {
+		return nil, err
+	}
+
+	out, err := client.CallContract(context.TODO(), ethereum.CallMsg{To: &forwarder, Data: data}, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read forwarder nonce of %s; %s", from.Hex(), err.Error())
+	}
+
+	var nonce *big.Int
+	err = _abi.Unpack(&nonce, "getNonce", out)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode forwarder nonce of %s; %s", from.Hex(), err.Error())
+	}
+	return nonce, nil
+}
@@ -0,0 +1,155 @@
+package tx
+
+import (
+	"encoding/json"
+
+	"github.com/gin-gonic/gin"
+	dbconf "github.com/kthomas/go-db-config"
+	uuid "github.com/kthomas/go.uuid"
+	provide "github.com/provideplatform/provide-go/common"
+	util "github.com/provideplatform/provide-go/common/util"
+)
+
+// installRelayersAPI installs the relayer handlers using the given gin Engine
+func installRelayersAPI(r *gin.Engine) {
+	r.GET("/api/v1/relayers", relayersListHandler)
+	r.POST("/api/v1/relayers", createRelayerHandler)
+	r.GET("/api/v1/relayers/:id", relayerDetailsHandler)
+	r.PUT("/api/v1/relayers/:id", updateRelayerHandler)
+	r.DELETE("/api/v1/relayers/:id", deleteRelayerHandler)
+}
+
+// resolveAuthorizedRelayer returns the relayer with the given id if it belongs to the authorized
+// application; an error is rendered and nil returned otherwise
+func resolveAuthorizedRelayer(c *gin.Context) *Relayer {
+	appID := util.AuthorizedSubjectID(c, "application")
+	if appID == nil {
+		provide.RenderError("unauthorized", 401, c)
+		return nil
+	}
+
+	relayer := &Relayer{}
+	dbconf.DatabaseConnection().Where("id = ? AND application_id = ?", c.Param("id"), appID).Find(&relayer)
+	if relayer == nil || relayer.ID == uuid.Nil {
+		provide.RenderError("relayer not found", 404, c)
+		return nil
+	}
+
+	return relayer
+}
+
+func relayersListHandler(c *gin.Context) {
+	appID := util.AuthorizedSubjectID(c, "application")
+	if appID == nil {
+		provide.RenderError("unauthorized", 401, c)
+		return
+	}
+
+	query := dbconf.DatabaseConnection().Where("relayers.application_id = ?", appID)
+	if c.Query("network_id") != "" {
+		query = query.Where("relayers.network_id = ?", c.Query("network_id"))
+	}
+	query = query.Order("relayers.created_at ASC")
+
+	var relayers []*Relayer
+	provide.Paginate(c, query, &Relayer{}).Find(&relayers)
+	provide.Render(relayers, 200, c)
+}
+
+func relayerDetailsHandler(c *gin.Context) {
+	relayer := resolveAuthorizedRelayer(c)
+	if relayer == nil {
+		return
+	}
+
+	provide.Render(relayer, 200, c)
+}
+
+func createRelayerHandler(c *gin.Context) {
+	appID := util.AuthorizedSubjectID(c, "application")
+	if appID == nil {
+		provide.RenderError("unauthorized", 401, c)
+		return
+	}
+
+	buf, err := c.GetRawData()
+	if err != nil {
+		provide.RenderError(err.Error(), 400, c)
+		return
+	}
+
+	relayer := &Relayer{}
+	err = json.Unmarshal(buf, relayer)
+	if err != nil {
+		provide.RenderError(err.Error(), 422, c)
+		return
+	}
+	relayer.ApplicationID = appID
+
+	var params map[string]interface{}
+	json.Unmarshal(buf, &params)
+	if _, enabledOk := params["enabled"]; !enabledOk {
+		relayer.Enabled = true
+	}
+
+	if relayer.Create() {
+		provide.Render(relayer, 201, c)
+	} else {
+		obj := map[string]interface{}{}
+		obj["errors"] = relayer.Errors
+		provide.Render(obj, 422, c)
+	}
+}
+
+func updateRelayerHandler(c *gin.Context) {
+	buf, err := c.GetRawData()
+	if err != nil {
+		provide.RenderError(err.Error(), 400, c)
+		return
+	}
+
+	relayer := resolveAuthorizedRelayer(c)
+	if relayer == nil {
+		return
+	}
+
+	relayerID := relayer.ID
+	createdAt := relayer.CreatedAt
+	applicationID := relayer.ApplicationID
+	networkID := relayer.NetworkID
+	gasUsed := relayer.GasUsed
+	periodStartedAt := relayer.PeriodStartedAt
+
+	err = json.Unmarshal(buf, relayer)
+	if err != nil {
+		provide.RenderError(err.Error(), 422, c)
+		return
+	}
+	relayer.ID = relayerID
+	relayer.CreatedAt = createdAt
+	relayer.ApplicationID = applicationID
+	relayer.NetworkID = networkID
+	relayer.GasUsed = gasUsed
+	relayer.PeriodStartedAt = periodStartedAt
+
+	if relayer.Update() {
+		provide.Render(nil, 204, c)
+	} else {
+		obj := map[string]interface{}{}
+		obj["errors"] = relayer.Errors
+		provide.Render(obj, 422, c)
+	}
+}
+
+func deleteRelayerHandler(c *gin.Context) {
+	relayer := resolveAuthorizedRelayer(c)
+	if relayer == nil {
+		return
+	}
+
+	if !relayer.Delete() {
+		provide.RenderError("relayer not deleted", 500, c)
+		return
+	}
+	provide.Render(nil, 204, c)
+}
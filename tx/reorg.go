@@ -0,0 +1,162 @@
+package tx
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/jinzhu/gorm"
+	natsutil "github.com/kthomas/go-natsutil"
+	uuid "github.com/kthomas/go.uuid"
+	"github.com/provideplatform/nchain/common"
+	"github.com/provideplatform/nchain/contract"
+	"github.com/provideplatform/nchain/network"
+	"github.com/provideplatform/nchain/token"
+	providecrypto "github.com/provideplatform/provide-go/crypto"
+)
+
+// confirmations returns the number of blocks, including the block in which the tx was mined, which have
+// been appended to the chain as of the given head block
+func (t *Transaction) confirmations(head uint64) uint64 {
+	if t.Block == nil || head < *t.Block {
+		return 0
+	}
+	return head - *t.Block + 1
+}
+
+// setConfirmations populates the ephemeral confirmations of the mined tx as of the given head block
+func (t *Transaction) setConfirmations(head *uint64) {
+	if t.Block == nil || head == nil {
+		return
+	}
+	confirmations := t.confirmations(*head)
+	t.Confirmations = &confirmations
+}
+
+// finalizeStatus sets the status of the mined tx to success, or failed if its execution reverted, once the tx
+// has the number of confirmations required by the network; until then, the tx remains pending
+func (t *Transaction) finalizeStatus(db *gorm.DB, ntwrk *network.Network, head uint64) {
+	if t.FailureReason != nil {
+		t.updateStatus(db, "failed", t.Description)
+		t.markReplacedTxs(db)
+		return
+	}
+
+	depth := ntwrk.ConfirmationDepth()
+	if t.confirmations(head) < depth {
+		common.Log.Debugf("tx %s mined in block %d has %d of %d required confirmations", t.ID, *t.Block, t.confirmations(head), depth)
+		t.updateStatus(db, "pending", t.Description)
+		return
+	}
+
+	t.updateStatus(db, "success", nil)
+	t.markReplacedTxs(db)
+}
+
+// latestBlock returns the current head block of the network, if it can be resolved; the network is queried
+// when its stats have not been cached
+func latestBlock(ntwrk *network.Network) *uint64 {
+	if head := cachedLatestBlock(ntwrk.ID); head != nil {
+		return head
+	}
+	return providecrypto.EVMGetBlockNumber(ntwrk.ID.String(), ntwrk.RPCURL())
+}
+
+// cachedLatestBlock returns the head block of the network from its cached stats, if any; the network is never
+// queried, so confirmations can be populated when txs are read
+func cachedLatestBlock(networkID uuid.UUID) *uint64 {
+	stats, err := network.Stats(networkID)
+	if err == nil && stats != nil && stats.Block > 0 {
+		return &stats.Block
+	}
+	return nil
+}
+
+// populateConfirmations sets the confirmations of each of the given txs using the cached head block of its network
+func populateConfirmations(txs []Transaction) {
+	heads := map[uuid.UUID]*uint64{}
+	for i := range txs {
+		tx := &txs[i]
+		if tx.Block == nil {
+			continue
+		}
+
+		head, ok := heads[tx.NetworkID]
+		if !ok {
+			head = cachedLatestBlock(tx.NetworkID)
+			heads[tx.NetworkID] = head
+		}
+
+		tx.setConfirmations(head)
+	}
+}
+
+// confirmTxs finalizes the pending txs on the network which have the number of confirmations required
+// by the network as of the given head block
+func confirmTxs(db *gorm.DB, ntwrk *network.Network, head uint64) int {
+	depth := ntwrk.ConfirmationDepth()
+	if head+1 < depth {
+		return 0
+	}
+
+	var txs []*Transaction
+	db.Where("network_id = ? AND status = ? AND block IS NOT NULL AND block <= ?", ntwrk.ID, "pending", head+1-depth).Find(&txs)
+	for _, tx := range txs {
+		tx.finalizeStatus(db, ntwrk, head)
+	}
+
+	return len(txs)
+}
+
+// revertOrphanedTxs reverts the txs mined in the orphaned block to pending and requeues them so their receipts
+// are re-resolved against the canonical chain; contracts created by the reverted txs are cleared and re-resolved
+// from the canonical receipts and traces
+func revertOrphanedTxs(db *gorm.DB, networkID uuid.UUID, block uint64) (int, error) {
+	var txs []*Transaction
+	db.Where("network_id = ? AND block = ? AND status IN (?, ?, ?)", networkID, block, "pending", "success", "failed").Find(&txs)
+
+	for _, tx := range txs {
+		tx.Block = nil
+		tx.BlockTimestamp = nil
+		tx.FinalizedAt = nil
+		tx.NetworkLatency = nil
+		tx.E2ELatency = nil
+		tx.FailureReason = nil
+
+		desc := fmt.Sprintf("block %d orphaned by chain reorganization", block)
+		tx.updateStatus(db, "pending", &desc)
+		tx.clearInternalContracts(db)
+
+		payload, _ := json.Marshal(map[string]interface{}{
+			"transaction_id": tx.ID.String(),
+		})
+		_, err := natsutil.NatsJetstreamPublish(natsTxReceiptSubject, payload)
+		if err != nil {
+			return 0, fmt.Errorf("failed to requeue tx %s orphaned in block %d; %s", tx.ID, block, err.Error())
+		}
+
+		common.Log.Debugf("reverted tx %s orphaned in block %d to pending", tx.ID, block)
+	}
+
+	return len(txs), nil
+}
+
+// clearInternalContracts deletes the contracts, and their tokens, created by contract-internal CREATE opcodes of
+// the tx, which may not exist in the canonical chain; they are re-resolved from the traces of the tx once it is mined
+func (t *Transaction) clearInternalContracts(db *gorm.DB) {
+	var contractIDs []string
+	db.Table("contracts").Where("transaction_id = ? AND contract_id IS NOT NULL", t.ID).Pluck("id", &contractIDs)
+	if len(contractIDs) == 0 {
+		return
+	}
+
+	if err := db.Where("contract_id IN (?)", contractIDs).Delete(&token.Token{}).Error; err != nil {
+		common.Log.Warningf("failed to clear tokens of contracts created by orphaned tx %s; %s", t.ID, err.Error())
+		return
+	}
+	if err := db.Where("id IN (?)", contractIDs).Delete(&contract.Contract{}).Error; err != nil {
+		common.Log.Warningf("failed to clear contracts created by orphaned tx %s; %s", t.ID, err.Error())
+		return
+	}
+
+	common.Log.Debugf("cleared %d contract(s) created by orphaned tx %s", len(contractIDs), t.ID)
+}
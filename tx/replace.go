@@ -0,0 +1,269 @@
+package tx
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+
+	ethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/jinzhu/gorm"
+	"github.com/provideplatform/nchain/common"
+	"github.com/provideplatform/nchain/network"
+	provide "github.com/provideplatform/provide-go/api"
+	providecrypto "github.com/provideplatform/provide-go/crypto"
+)
+
+// txReplacementGasPriceBumpPercent is the default percentage by which the gas price of a
+// stuck tx is bumped when it is replaced; most clients require a bump of at least 10%
+const txReplacementGasPriceBumpPercent = int64(125)
+
+// txReplacementMinGasPriceBumpPercent is the minimum percentage of the stuck tx gas price
+// which is accepted for an explicitly-provided replacement gas price
+const txReplacementMinGasPriceBumpPercent = int64(110)
+
+// txCancellationGas is the gas limit used for the 0-value self-send which cancels a stuck tx
+const txCancellationGas = uint64(21000)
+
+const txStatusReplaced = "replaced"
+
+// Replace re-signs and broadcasts the pending tx using the same nonce and a bumped gas
+// price; the original tx is linked to the returned replacement
+func (t *Transaction) Replace(db *gorm.DB, gasPrice *uint64) (*Transaction, error) {
+	return t.replace(db, gasPrice, false)
+}
+
+// Cancel replaces the pending tx with a 0-value self-send using the same nonce and a
+// bumped gas price; the original tx is linked to the returned replacement
+func (t *Transaction) Cancel(db *gorm.DB, gasPrice *uint64) (*Transaction, error) {
+	return t.replace(db, gasPrice, true)
+}
+
+func (t *Transaction) replace(db *gorm.DB, gasPrice *uint64, cancel bool) (*Transaction, error) {
+	if t.Status == nil || *t.Status != "pending" {
+		return nil, fmt.Errorf("unable to replace tx: %s; only pending txs can be replaced", t.ID)
+	}
+
+	if t.Hash == nil {
+		return nil, fmt.Errorf("unable to replace tx: %s; tx has not been broadcast", t.ID)
+	}
+
+	if t.ReplacedByID != nil {
+		return nil, fmt.Errorf("unable to replace tx: %s; tx has already been replaced by tx: %s", t.ID, t.ReplacedByID)
+	}
+
+	if t.RelayerID != nil {
+		return nil, fmt.Errorf("unable to replace tx: %s; relayed txs cannot be replaced", t.ID)
+	}
+
+	signer, err := t.signerFactory(db)
+	if err != nil {
+		return nil, err
+	}
+
+	if !signer.Network.IsEthereumNetwork() {
+		return nil, fmt.Errorf("unable to replace tx: %s; unsupported network: %s", t.ID, *signer.Network.Name)
+	}
+
+	pendingTx, err := t.fetchPendingEthereumTx(signer.Network)
+	if err != nil {
+		return nil, err
+	}
+
+	params := map[string]interface{}{
+		"nonce": uint64(pendingTx.Nonce),
+		"gas":   uint64(pendingTx.Gas),
+	}
+
+	if pendingTx.isDynamicFeeTx() && gasPrice == nil {
+		fees, err := replacementDynamicFees(signer.Network, pendingTx)
+		if err != nil {
+			return nil, err
+		}
+		params["max_fee_per_gas"] = fees.GasFeeCap.Uint64()
+		params["max_priority_fee_per_gas"] = fees.GasTipCap.Uint64()
+	} else {
+		// a dynamic fee tx may be replaced by a legacy tx priced at or above its bumped max fee
+		original := pendingTx.GasPrice.ToInt()
+		if pendingTx.isDynamicFeeTx() {
+			original = pendingTx.MaxFeePerGas.ToInt()
+		}
+
+		replacementGasPrice, err := replacementGasPrice(signer.Network, original, gasPrice)
+		if err != nil {
+			return nil, err
+		}
+		params["gas_price"] = replacementGasPrice
+	}
+
+	replacement := &Transaction{
+		NetworkID:      t.NetworkID,
+		ApplicationID:  t.ApplicationID,
+		OrganizationID: t.OrganizationID,
+		UserID:         t.UserID,
+		AccountID:      t.AccountID,
+		WalletID:       t.WalletID,
+		Path:           t.Path,
+		To:             t.To,
+		Value:          &TxValue{value: t.Value.BigInt()},
+		Data:           t.Data,
+		PublishedAt:    t.PublishedAt,
+	}
+
+	// the replacement of a tx signed with an access list is signed with the same list
+	if t.AccessList != nil && !cancel {
+		var accessList interface{}
+		if err := json.Unmarshal(*t.AccessList, &accessList); err == nil {
+			params["access_list"] = accessList
+		}
+	}
+
+	if cancel {
+		params["gas"] = txCancellationGas
+		replacement.To = common.StringOrNil(signer.Address())
+		replacement.Value = NewTxValue(0)
+		replacement.Data = nil
+	}
+
+	replacement.setParams(params)
+
+	if !replacement.Create(db) || (replacement.Status != nil && *replacement.Status == "failed") {
+		err := fmt.Errorf("failed to broadcast replacement for tx: %s", t.ID)
+		if len(replacement.Errors) > 0 {
+			err = fmt.Errorf("%s; %s", err.Error(), *replacement.Errors[0].Message)
+		}
+		common.Log.Warning(err.Error())
+		return nil, err
+	}
+
+	t.ReplacedByID = &replacement.ID
+	result := db.Save(&t)
+	errs := result.GetErrors()
+	if len(errs) > 0 {
+		for _, err := range errs {
+			t.Errors = append(t.Errors, &provide.Error{
+				Message: common.StringOrNil(err.Error()),
+			})
+		}
+		return nil, errs[0]
+	}
+
+	common.Log.Debugf("broadcast replacement tx %s for tx %s with nonce %d", replacement.ID, t.ID, uint64(pendingTx.Nonce))
+	return replacement, nil
+}
+
+// pendingEthereumTx is the subset of the eth_getTransactionByHash response needed to replace a pending tx;
+// it is resolved as raw JSON so typed tx envelopes are supported
+type pendingEthereumTx struct {
+	BlockHash            *ethcommon.Hash `json:"blockHash"`
+	Type                 *hexutil.Uint64 `json:"type"`
+	Nonce                hexutil.Uint64  `json:"nonce"`
+	Gas                  hexutil.Uint64  `json:"gas"`
+	GasPrice             *hexutil.Big    `json:"gasPrice"`
+	MaxFeePerGas         *hexutil.Big    `json:"maxFeePerGas"`
+	MaxPriorityFeePerGas *hexutil.Big    `json:"maxPriorityFeePerGas"`
+}
+
+func (p *pendingEthereumTx) isDynamicFeeTx() bool {
+	return p.Type != nil && byte(*p.Type) == dynamicFeeTxType && p.MaxFeePerGas != nil && p.MaxPriorityFeePerGas != nil
+}
+
+// fetchPendingEthereumTx resolves the original tx from the network, which is the authoritative
+// source of the nonce, gas and gas price to be replaced
+func (t *Transaction) fetchPendingEthereumTx(ntwrk *network.Network) (*pendingEthereumTx, error) {
+	client, err := providecrypto.EVMResolveJsonRpcClient(ntwrk.ID.String(), ntwrk.RPCURL())
+	if err != nil {
+		return nil, err
+	}
+
+	var pendingTx *pendingEthereumTx
+	err = client.CallContext(context.TODO(), &pendingTx, "eth_getTransactionByHash", ethcommon.HexToHash(*t.Hash))
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve tx %s for replacement; %s", *t.Hash, err.Error())
+	}
+
+	if pendingTx == nil {
+		return nil, fmt.Errorf("failed to resolve tx %s for replacement; tx not found", *t.Hash)
+	}
+
+	if pendingTx.BlockHash != nil && *pendingTx.BlockHash != (ethcommon.Hash{}) {
+		return nil, fmt.Errorf("unable to replace tx %s; tx has already been mined", *t.Hash)
+	}
+
+	if pendingTx.GasPrice == nil && !pendingTx.isDynamicFeeTx() {
+		return nil, fmt.Errorf("unable to replace tx %s; no gas price resolved", *t.Hash)
+	}
+
+	return pendingTx, nil
+}
+
+// replacementDynamicFees returns the fee caps for a replacement of the given dynamic fee tx; each cap is
+// the greater of the bumped original and the currently-suggested cap
+func replacementDynamicFees(ntwrk *network.Network, original *pendingEthereumTx) (*dynamicFees, error) {
+	bump := func(val *big.Int) *big.Int {
+		return new(big.Int).Div(new(big.Int).Mul(val, big.NewInt(txReplacementGasPriceBumpPercent)), big.NewInt(100))
+	}
+
+	fees := &dynamicFees{
+		GasTipCap: bump(original.MaxPriorityFeePerGas.ToInt()),
+		GasFeeCap: bump(original.MaxFeePerGas.ToInt()),
+	}
+
+	suggested, err := suggestDynamicFees(ntwrk)
+	if err == nil {
+		if suggested.GasTipCap.Cmp(fees.GasTipCap) > 0 {
+			fees.GasTipCap = suggested.GasTipCap
+		}
+		if suggested.GasFeeCap.Cmp(fees.GasFeeCap) > 0 {
+			fees.GasFeeCap = suggested.GasFeeCap
+		}
+	}
+
+	if !fees.GasTipCap.IsUint64() || !fees.GasFeeCap.IsUint64() {
+		return nil, errors.New("replacement dynamic fee overflow")
+	}
+
+	return validateDynamicFees(fees)
+}
+
+// replacementGasPrice returns the gas price for a replacement of a tx with the given gas price;
+// when no gas price is provided, the greater of the bumped and the suggested gas price is used
+func replacementGasPrice(ntwrk *network.Network, original *big.Int, gasPrice *uint64) (uint64, error) {
+	minGasPrice := new(big.Int).Div(new(big.Int).Mul(original, big.NewInt(txReplacementMinGasPriceBumpPercent)), big.NewInt(100))
+
+	if gasPrice != nil {
+		if new(big.Int).SetUint64(*gasPrice).Cmp(minGasPrice) < 0 {
+			return 0, fmt.Errorf("replacement gas price must be at least %s; %d provided", minGasPrice.String(), *gasPrice)
+		}
+		return *gasPrice, nil
+	}
+
+	bumped := new(big.Int).Div(new(big.Int).Mul(original, big.NewInt(txReplacementGasPriceBumpPercent)), big.NewInt(100))
+
+	client, err := providecrypto.EVMDialJsonRpc(ntwrk.ID.String(), ntwrk.RPCURL())
+	if err == nil {
+		suggested, err := client.SuggestGasPrice(context.TODO())
+		if err == nil && suggested.Cmp(bumped) > 0 {
+			bumped = suggested
+		}
+	}
+
+	if !bumped.IsUint64() {
+		return 0, errors.New("replacement gas price overflow")
+	}
+
+	return bumped.Uint64(), nil
+}
+
+// markReplacedTxs marks the txs which were replaced by this tx as replaced; called when this tx is finalized
+func (t *Transaction) markReplacedTxs(db *gorm.DB) {
+	var replaced []*Transaction
+	db.Where("replaced_by_id = ? AND status IN (?, ?)", t.ID, "pending", "failed").Find(&replaced)
+	for _, tx := range replaced {
+		desc := fmt.Sprintf("replaced by tx: %s", t.ID)
+		tx.updateStatus(db, txStatusReplaced, &desc)
+		common.Log.Debugf("marked tx %s replaced by finalized tx: %s", tx.ID, t.ID)
+	}
+}
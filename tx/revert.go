@@ -0,0 +1,300 @@
+package tx
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	ethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/jinzhu/gorm"
+	uuid "github.com/kthomas/go.uuid"
+	"github.com/provideplatform/nchain/common"
+	"github.com/provideplatform/nchain/network"
+	providecrypto "github.com/provideplatform/provide-go/crypto"
+)
+
+// revertErrorSelector is the 4-byte selector of the solidity Error(string) revert reason
+var revertErrorSelector = []byte{0x08, 0xc3, 0x79, 0xa0}
+
+// revertPanicSelector is the 4-byte selector of the solidity Panic(uint256) revert reason
+var revertPanicSelector = []byte{0x4e, 0x48, 0x7b, 0x71}
+
+// revertPanicCodes describes the panic codes emitted by the solidity compiler
+var revertPanicCodes = map[uint64]string{
+	0x00: "generic compiler inserted panic",
+	0x01: "assertion failed",
+	0x11: "arithmetic underflow or overflow",
+	0x12: "division or modulo by zero",
+	0x21: "invalid enum value",
+	0x22: "invalid storage byte array encoding",
+	0x31: "pop on empty array",
+	0x32: "array index out of bounds",
+	0x41: "out of memory",
+	0x51: "call to zero-initialized internal function",
+}
+
+// Revert reason types
+const (
+	revertReasonTypeError   = "error"
+	revertReasonTypePanic   = "panic"
+	revertReasonTypeCustom  = "custom"
+	revertReasonTypeUnknown = "unknown"
+)
+
+// RevertReason is the structured reason a tx execution reverted, as decoded from its revert data
+type RevertReason struct {
+	Type      string                 `json:"type"`
+	Message   *string                `json:"message,omitempty"`
+	Code      *uint64                `json:"code,omitempty"`
+	Name      *string                `json:"name,omitempty"`
+	Signature *string                `json:"signature,omitempty"`
+	Args      map[string]interface{} `json:"args,omitempty"`
+	Data      *string                `json:"data,omitempty"`
+	Block     *uint64                `json:"block,omitempty"`
+}
+
+// String returns a human-readable description of the revert reason
+func (r *RevertReason) String() string {
+	switch r.Type {
+	case revertReasonTypeError:
+		return fmt.Sprintf("execution reverted: %s", *r.Message)
+	case revertReasonTypePanic:
+		return fmt.Sprintf("execution reverted: panic 0x%x (%s)", *r.Code, *r.Message)
+	case revertReasonTypeCustom:
+		if len(r.Args) > 0 {
+			args, _ := json.Marshal(r.Args)
+			return fmt.Sprintf("execution reverted: %s %s", *r.Signature, string(args))
+		}
+		return fmt.Sprintf("execution reverted: %s", *r.Signature)
+	}
+
+	if r.Message != nil {
+		return fmt.Sprintf("execution reverted: %s", *r.Message)
+	}
+	return "execution reverted"
+}
+
+// revertData returns the revert data included in the given JSON-RPC error, if any
+func revertData(err error) []byte {
+	dataErr, ok := err.(rpc.DataError)
+	if !ok {
+		return nil
+	}
+
+	data, ok := dataErr.ErrorData().(string)
+	if !ok {
+		return nil
+	}
+
+	// parity-based clients prefix the revert data, i.e. "Reverted 0x..."
+	if idx := strings.Index(data, "0x"); idx != -1 {
+		data = data[idx:]
+	}
+
+	return ethcommon.FromHex(data)
+}
+
+// decodeRevertReason decodes the solidity Error(string), Panic(uint256) and custom error revert data;
+// nil is returned if the given data is not a recognized revert reason
+func decodeRevertReason(data []byte, customErrors []abi.Method) *RevertReason {
+	if len(data) < 4 {
+		return nil
+	}
+
+	selector := data[:4]
+	encoded := common.StringOrNil(hexutil.Encode(data))
+
+	if bytes.Equal(selector, revertErrorSelector) {
+		typ, _ := abi.NewType("string", "", nil)
+		vals, err := abi.Arguments{{Type: typ}}.UnpackValues(data[4:])
+		if err != nil || len(vals) != 1 {
+			return nil
+		}
+		if msg, ok := vals[0].(string); ok {
+			return &RevertReason{
+				Type:    revertReasonTypeError,
+				Message: common.StringOrNil(msg),
+				Data:    encoded,
+			}
+		}
+	} else if bytes.Equal(selector, revertPanicSelector) && len(data) >= 36 {
+		code := new(big.Int).SetBytes(data[4:36])
+		if !code.IsUint64() {
+			return nil
+		}
+
+		_code := code.Uint64()
+		desc, ok := revertPanicCodes[_code]
+		if !ok {
+			desc = "unknown panic code"
+		}
+
+		return &RevertReason{
+			Type:    revertReasonTypePanic,
+			Message: common.StringOrNil(desc),
+			Code:    &_code,
+			Data:    encoded,
+		}
+	}
+
+	for _, customError := range customErrors {
+		if !bytes.Equal(selector, customError.ID) {
+			continue
+		}
+
+		args := map[string]interface{}{}
+		err := customError.Inputs.UnpackIntoMap(args, data[4:])
+		if err != nil {
+			common.Log.Debugf("failed to unpack arguments of custom error %s; %s", customError.Sig, err.Error())
+			args = nil
+		}
+
+		return &RevertReason{
+			Type:      revertReasonTypeCustom,
+			Name:      common.StringOrNil(customError.RawName),
+			Signature: common.StringOrNil(customError.Sig),
+			Args:      args,
+			Data:      encoded,
+		}
+	}
+
+	return nil
+}
+
+// revertReason decodes the revert reason of the given JSON-RPC error using the custom errors declared
+// by the contract the tx was sent to, if any; nil is returned if the error is not a revert
+func (t *Transaction) revertReason(db *gorm.DB, err error) *RevertReason {
+	data := revertData(err)
+	if data == nil {
+		return nil
+	}
+
+	reason := decodeRevertReason(data, t.customErrors(db))
+	if reason == nil {
+		reason = &RevertReason{
+			Type:    revertReasonTypeUnknown,
+			Message: common.StringOrNil(err.Error()),
+			Data:    common.StringOrNil(hexutil.Encode(data)),
+		}
+	}
+
+	return reason
+}
+
+// customErrors returns the custom errors declared in the ABI of the contract the tx was sent to
+func (t *Transaction) customErrors(db *gorm.DB) []abi.Method {
+	if t.To == nil {
+		return nil
+	}
+
+	kontract := t.GetContract(db)
+	if kontract == nil || kontract.ID == uuid.Nil {
+		return nil
+	}
+
+	customErrors, err := kontract.ReadEthereumContractErrors()
+	if err != nil {
+		common.Log.Debugf("failed to read custom errors from contract: %s; %s", kontract.ID, err.Error())
+		return nil
+	}
+
+	return customErrors
+}
+
+// replayFailedTx resolves the reason the tx, which failed in the given block, reverted; the mined tx is traced
+// when the network p2p provider supports it, otherwise the tx is replayed via eth_call against the state of the
+// parent block, as the state of the block in which the tx was mined already includes its effects
+func (t *Transaction) replayFailedTx(db *gorm.DB, ntwrk *network.Network, signerAddress string, block *big.Int) *RevertReason {
+	reason := t.traceRevertReason(db, ntwrk)
+	if reason == nil {
+		reason = t.callRevertReason(db, ntwrk, signerAddress, block)
+	}
+
+	if reason != nil && block != nil {
+		_block := block.Uint64()
+		reason.Block = &_block
+	}
+
+	return reason
+}
+
+// traceRevertReason returns the decoded reason the mined tx reverted using its traced call tree, if the
+// network p2p provider supports tracing
+func (t *Transaction) traceRevertReason(db *gorm.DB, ntwrk *network.Network) *RevertReason {
+	frame := t.traceCallTree(ntwrk)
+	if frame == nil || frame.Error == "" {
+		return nil
+	}
+
+	reason := decodeRevertReason(frame.Output, t.customErrors(db))
+	if reason == nil {
+		reason = &RevertReason{
+			Type:    revertReasonTypeUnknown,
+			Message: common.StringOrNil(frame.Error),
+		}
+		if len(frame.Output) > 0 {
+			reason.Data = common.StringOrNil(hexutil.Encode(frame.Output))
+		}
+	}
+
+	return reason
+}
+
+// callRevertReason replays the tx via eth_call against the state of the parent of the given block, returning
+// the decoded reason the execution reverted
+func (t *Transaction) callRevertReason(db *gorm.DB, ntwrk *network.Network, signerAddress string, block *big.Int) *RevertReason {
+	params := t.ParseParams()
+
+	gas := uint64(0)
+	if _gas, gasOk := params["gas"].(float64); gasOk {
+		gas = uint64(_gas)
+	}
+
+	gasPrice := uint64(0)
+	if gp, gpOk := params["gas_price"].(float64); gpOk {
+		gasPrice = uint64(gp)
+	}
+
+	client, err := providecrypto.EVMDialJsonRpc(ntwrk.ID.String(), ntwrk.RPCURL())
+	if err != nil {
+		common.Log.Warningf("failed to dial JSON-RPC host to replay failed tx: %s; %s", t.ID, err.Error())
+		return nil
+	}
+
+	var parent *big.Int
+	if block != nil && block.Sign() > 0 {
+		parent = new(big.Int).Sub(block, big.NewInt(1))
+	}
+
+	var reason *RevertReason
+
+	result, err := client.CallContract(context.TODO(), t.asEthereumCallMsg(signerAddress, gasPrice, gas), parent)
+	if err != nil {
+		reason = t.revertReason(db, err)
+		if reason == nil {
+			reason = &RevertReason{
+				Type:    revertReasonTypeUnknown,
+				Message: common.StringOrNil(err.Error()),
+			}
+		}
+	} else {
+		// some clients return the revert data as the result of the call
+		reason = decodeRevertReason(result, t.customErrors(db))
+	}
+
+	return reason
+}
+
+// setRevertReason sets the structured failure reason and description of the tx
+func (t *Transaction) setRevertReason(reason *RevertReason) {
+	raw, _ := json.Marshal(reason)
+	failureReason := json.RawMessage(raw)
+	t.FailureReason = &failureReason
+	t.Description = common.StringOrNil(reason.String())
+}
@@ -0,0 +1,44 @@
+// +build unit
+
+package tx
+
+import (
+	"encoding/json"
+	"math/big"
+	"testing"
+
+	dbconf "github.com/kthomas/go-db-config"
+)
+
+func TestFailedTxReplayedAgainstParentBlock(t *testing.T) {
+	var replayedAt string
+	ntwrk, srv := jsonRPCStubFactory(t, map[string]jsonRPCHandler{
+		"eth_call": func(params []json.RawMessage) (interface{}, error) {
+			json.Unmarshal(params[1], &replayedAt)
+			// Error("insufficient balance")
+			return "0x08c379a0" +
+				"0000000000000000000000000000000000000000000000000000000000000020" +
+				"0000000000000000000000000000000000000000000000000000000000000014" +
+				"696e73756666696369656e742062616c616e6365000000000000000000000000", nil
+		},
+	})
+	defer srv.Close()
+
+	tx := &Transaction{
+		Value: &TxValue{value: big.NewInt(0)},
+	}
+	reason := tx.replayFailedTx(dbconf.DatabaseConnection(), ntwrk, "0x8C8d1BDB3f1e9A9e1a6d2A4b5f4c1a2B3C4d5E6f", big.NewInt(100))
+	if reason == nil {
+		t.Fatalf("expected revert reason of replayed tx")
+	}
+
+	if replayedAt != "0x63" {
+		t.Errorf("expected failed tx mined in block 100 to be replayed against block 0x63; replayed against %s", replayedAt)
+	}
+	if reason.Message == nil || *reason.Message != "insufficient balance" {
+		t.Errorf("expected revert reason message insufficient balance; got %v", reason.Message)
+	}
+	if reason.Block == nil || *reason.Block != 100 {
+		t.Errorf("expected revert reason to reference block 100; got %v", reason.Block)
+	}
+}
@@ -0,0 +1,680 @@
+package tx
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"math/big"
+	"sort"
+	"strings"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	ethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	ethcrypto "github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/jinzhu/gorm"
+	uuid "github.com/kthomas/go.uuid"
+	"github.com/provideplatform/nchain/common"
+	"github.com/provideplatform/nchain/contract"
+	"github.com/provideplatform/nchain/network"
+	"github.com/provideplatform/nchain/wallet"
+	provide "github.com/provideplatform/provide-go/api"
+	providecrypto "github.com/provideplatform/provide-go/crypto"
+)
+
+// safeTxType is the EIP-712 type of the Safe tx signed by the owners of a Safe
+const safeTxType = "SafeTx(address to,uint256 value,bytes data,uint8 operation,uint256 safeTxGas,uint256 baseGas,uint256 gasPrice,address gasToken,address refundReceiver,uint256 nonce)"
+
+// safeABI is the subset of the Safe (v1.3.0+) ABI used to propose and execute Safe txs
+const safeABI = `[{"inputs":[],"name":"nonce","outputs":[{"internalType":"uint256","name":"","type":"uint256"}],"stateMutability":"view","type":"function"},{"inputs":[],"name":"getThreshold","outputs":[{"internalType":"uint256","name":"","type":"uint256"}],"stateMutability":"view","type":"function"},{"inputs":[],"name":"getOwners","outputs":[{"internalType":"address[]","name":"","type":"address[]"}],"stateMutability":"view","type":"function"},{"inputs":[{"internalType":"address","name":"to","type":"address"},{"internalType":"uint256","name":"value","type":"uint256"},{"internalType":"bytes","name":"data","type":"bytes"},{"internalType":"enum Enum.Operation","name":"operation","type":"uint8"},{"internalType":"uint256","name":"safeTxGas","type":"uint256"},{"internalType":"uint256","name":"baseGas","type":"uint256"},{"internalType":"uint256","name":"gasPrice","type":"uint256"},{"internalType":"address","name":"gasToken","type":"address"},{"internalType":"address","name":"refundReceiver","type":"address"},{"internalType":"uint256","name":"_nonce","type":"uint256"}],"name":"getTransactionHash","outputs":[{"internalType":"bytes32","name":"","type":"bytes32"}],"stateMutability":"view","type":"function"},{"inputs":[{"internalType":"address","name":"to","type":"address"},{"internalType":"uint256","name":"value","type":"uint256"},{"internalType":"bytes","name":"data","type":"bytes"},{"internalType":"enum Enum.Operation","name":"operation","type":"uint8"},{"internalType":"uint256","name":"safeTxGas","type":"uint256"},{"internalType":"uint256","name":"baseGas","type":"uint256"},{"internalType":"uint256","name":"gasPrice","type":"uint256"},{"internalType":"address","name":"gasToken","type":"address"},{"internalType":"address payable","name":"refundReceiver","type":"address"},{"internalType":"bytes","name":"signatures","type":"bytes"}],"name":"execTransaction","outputs":[{"internalType":"bool","name":"success","type":"bool"}],"stateMutability":"payable","type":"function"}]`
+
+// Safe tx statuses
+const (
+	safeTxStatusPending   = "pending"   // awaiting owner confirmations
+	safeTxStatusConfirmed = "confirmed" // threshold met; awaiting execution
+	safeTxStatusExecuting = "executing" // execTransaction broadcast; awaiting receipt
+	safeTxStatusExecuted  = "executed"
+	safeTxStatusFailed    = "failed"
+)
+
+// SafeTransaction is a contract execution proposed as a tx of a Safe multisig; once confirmed by the threshold
+// number of owners, it is executed via execTransaction by the executor account. Safe txs are proposed without
+// gas refunds, so a failed inner call reverts the execTransaction tx.
+type SafeTransaction struct {
+	provide.Model
+	NetworkID      uuid.UUID  `sql:"not null;type:uuid" json:"network_id"`
+	ApplicationID  *uuid.UUID `sql:"type:uuid" json:"application_id,omitempty"`
+	OrganizationID *uuid.UUID `sql:"type:uuid" json:"organization_id,omitempty"`
+	UserID         *uuid.UUID `sql:"type:uuid" json:"user_id,omitempty"`
+	ContractID     *uuid.UUID `sql:"type:uuid" json:"contract_id,omitempty"`
+	AccountID      *uuid.UUID `sql:"type:uuid" json:"account_id,omitempty"` // account which executes the Safe tx once confirmed
+	SafeAddress    string     `sql:"not null" json:"safe_address"`
+
+	// SafeTx fields
+	To             string  `sql:"not null" json:"to"`
+	Value          string  `sql:"not null;type:numeric" json:"value"` // in wei
+	Data           *string `json:"data,omitempty"`
+	Operation      uint8   `sql:"not null" json:"operation"` // 0 (call) or 1 (delegatecall)
+	SafeTxGas      uint64  `sql:"not null" json:"safe_tx_gas"`
+	BaseGas        uint64  `sql:"not null" json:"base_gas"`
+	GasPrice       string  `sql:"not null;type:numeric" json:"gas_price"`
+	GasToken       string  `sql:"not null" json:"gas_token"`
+	RefundReceiver string  `sql:"not null" json:"refund_receiver"`
+	Nonce          uint64  `gorm:"column:safe_nonce" sql:"not null" json:"nonce"`
+	SafeTxHash     string  `sql:"not null" json:"safe_tx_hash"`
+
+	Threshold     uint64  `sql:"not null" json:"threshold"`
+	Confirmations uint64  `sql:"not null" json:"confirmations"`
+	Status        string  `sql:"not null" json:"status"`
+	Description   *string `json:"description,omitempty"`
+
+	// Tx which executed the Safe tx, once the threshold was met
+	TransactionID *uuid.UUID `sql:"type:uuid" json:"transaction_id,omitempty"`
+
+	Signatures []*SafeConfirmation `sql:"-" json:"signatures,omitempty"`
+}
+
+// SafeConfirmation is the signature of a Safe tx by one of the owners of the Safe
+type SafeConfirmation struct {
+	provide.Model
+	SafeTransactionID uuid.UUID  `sql:"not null;type:uuid" json:"safe_transaction_id"`
+	Owner             string     `sql:"not null" json:"owner"`
+	Signature         string     `sql:"not null" json:"signature"`
+	AccountID         *uuid.UUID `sql:"type:uuid" json:"account_id,omitempty"` // custodial account which signed, if any
+	UserID            *uuid.UUID `sql:"type:uuid" json:"user_id,omitempty"`
+}
+
+// TableName returns the table name of Safe confirmations
+func (c *SafeConfirmation) TableName() string {
+	return "safe_transaction_confirmations"
+}
+
+// safeContract is a Safe deployed on a network
+type safeContract struct {
+	abi     *abi.ABI
+	address ethcommon.Address
+	client  *ethclient.Client
+	chainID *big.Int
+}
+
+// safeContractFactory dials the network and returns the Safe at the given address
+func safeContractFactory(ntwrk *network.Network, address string) (*safeContract, error) {
+	if !ethcommon.IsHexAddress(address) {
+		return nil, fmt.Errorf("invalid safe address: %s", address)
+	}
+
+	_abi, err := abi.JSON(strings.NewReader(safeABI))
+	if err != nil {
+		return nil, err
+	}
+
+	client, err := providecrypto.EVMDialJsonRpc(ntwrk.ID.String(), ntwrk.RPCURL())
+	if err != nil {
+		return nil, fmt.Errorf("failed to dial JSON-RPC host; %s", err.Error())
+	}
+
+	chainID, err := client.ChainID(context.TODO())
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve chain id; %s", err.Error())
+	}
+
+	return &safeContract{
+		abi:     &_abi,
+		address: ethcommon.HexToAddress(address),
+		client:  client,
+		chainID: chainID,
+	}, nil
+}
+
+// call reads the given view method of the Safe into the given result
+func (s *safeContract) call(result interface{}, method string, args ...interface{}) error {
+	data, err := s.abi.Pack(method, args...)
+	if err != nil {
+		return err
+	}
+
+	out, err := s.client.CallContract(context.TODO(), ethereum.CallMsg{To: &s.address, Data: data}, nil)
+	if err != nil {
+		return fmt.Errorf("failed to call %s of safe %s; %s", method, s.address.Hex(), err.Error())
+	}
+
+	err = s.abi.Unpack(result, method, out)
+	if err != nil {
+		return fmt.Errorf("failed to decode %s of safe %s; %s", method, s.address.Hex(), err.Error())
+	}
+	return nil
+}
+
+// nonce returns the nonce of the next Safe tx to be executed
+func (s *safeContract) nonce() (uint64, error) {
+	var nonce *big.Int
+	if err := s.call(&nonce, "nonce"); err != nil {
+		return 0, err
+	}
+	return nonce.Uint64(), nil
+}
+
+// threshold returns the number of owner confirmations required to execute a Safe tx
+func (s *safeContract) threshold() (uint64, error) {
+	var threshold *big.Int
+	if err := s.call(&threshold, "getThreshold"); err != nil {
+		return 0, err
+	}
+	return threshold.Uint64(), nil
+}
+
+// isOwner returns true if the given address is an owner of the Safe
+func (s *safeContract) isOwner(address ethcommon.Address) (bool, error) {
+	var owners []ethcommon.Address
+	if err := s.call(&owners, "getOwners"); err != nil {
+		return false, err
+	}
+
+	for _, owner := range owners {
+		if owner == address {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// domain returns the EIP-712 signing domain of the Safe
+func (s *safeContract) domain() *eip712Domain {
+	return &eip712Domain{
+		ChainID:           s.chainID,
+		VerifyingContract: &s.address,
+	}
+}
+
+// signingHash returns the EIP-712 SafeTx hash of the given Safe tx within the signing domain of the Safe
+func (s *safeContract) signingHash(safeTx *SafeTransaction) []byte {
+	value, _ := new(big.Int).SetString(safeTx.Value, 10)
+	gasPrice, _ := new(big.Int).SetString(safeTx.GasPrice, 10)
+
+	return s.domain().hash(eip712StructHash(
+		safeTxType,
+		eip712Address(ethcommon.HexToAddress(safeTx.To)),
+		eip712Uint(value),
+		eip712Bytes(safeTx.calldata()),
+		eip712Uint(big.NewInt(int64(safeTx.Operation))),
+		eip712Uint(new(big.Int).SetUint64(safeTx.SafeTxGas)),
+		eip712Uint(new(big.Int).SetUint64(safeTx.BaseGas)),
+		eip712Uint(gasPrice),
+		eip712Address(ethcommon.HexToAddress(safeTx.GasToken)),
+		eip712Address(ethcommon.HexToAddress(safeTx.RefundReceiver)),
+		eip712Uint(new(big.Int).SetUint64(safeTx.Nonce)),
+	))
+}
+
+// hash returns the EIP-712 SafeTx hash of the given Safe tx; the hash is verified against the getTransactionHash
+// of the Safe, so Safes which use a different signing domain, i.e. those predating v1.3.0, are rejected
+func (s *safeContract) hash(safeTx *SafeTransaction) ([]byte, error) {
+	value, _ := new(big.Int).SetString(safeTx.Value, 10)
+	gasPrice, _ := new(big.Int).SetString(safeTx.GasPrice, 10)
+	to := ethcommon.HexToAddress(safeTx.To)
+	gasToken := ethcommon.HexToAddress(safeTx.GasToken)
+	refundReceiver := ethcommon.HexToAddress(safeTx.RefundReceiver)
+	data := safeTx.calldata()
+	hash := s.signingHash(safeTx)
+
+	var safeTxHash [32]byte
+	err := s.call(&safeTxHash, "getTransactionHash",
+		to, value, data, safeTx.Operation,
+		new(big.Int).SetUint64(safeTx.SafeTxGas), new(big.Int).SetUint64(safeTx.BaseGas), gasPrice,
+		gasToken, refundReceiver, new(big.Int).SetUint64(safeTx.Nonce),
+	)
+	if err != nil {
+		return nil, err
+	}
+	if !bytes.Equal(hash, safeTxHash[:]) {
+		return nil, fmt.Errorf("safe %s uses an unsupported signing domain; SafeTx hash mismatch", s.address.Hex())
+	}
+
+	return hash, nil
+}
+
+// calldata returns the calldata of the Safe tx
+func (s *SafeTransaction) calldata() []byte {
+	if s.Data == nil {
+		return []byte{}
+	}
+	return ethcommon.FromHex(*s.Data)
+}
+
+// resolveAccount resolves the account with the given id on the network of the Safe tx, scoped to the
+// application, organization or user which proposed the Safe tx
+func (s *SafeTransaction) resolveAccount(db *gorm.DB, accountID string) *wallet.Account {
+	account := &wallet.Account{}
+	query := db.Where("id = ? AND network_id = ?", accountID, s.NetworkID)
+	if s.ApplicationID != nil {
+		query = query.Where("application_id = ?", s.ApplicationID)
+	} else if s.OrganizationID != nil {
+		query = query.Where("organization_id = ?", s.OrganizationID)
+	} else if s.UserID != nil {
+		query = query.Where("user_id = ?", s.UserID)
+	}
+	query.Find(&account)
+	if account == nil || account.ID == uuid.Nil {
+		return nil
+	}
+	return account
+}
+
+// proposeSafeExecution proposes the given contract execution as a tx of the Safe at the given address; the executor
+// account of the execution confirms the Safe tx if it is an owner of the Safe
+func proposeSafeExecution(db *gorm.DB, c *contract.Contract, execution *contract.Execution, safeAddress string, applicationID, organizationID, userID *uuid.UUID) (*SafeTransaction, error) {
+	if c.Address == nil {
+		return nil, fmt.Errorf("unable to propose execution of undeployed contract %s as safe tx", c.ID)
+	}
+
+	_abi, err := c.ReadEthereumContractAbi()
+	if err != nil {
+		return nil, fmt.Errorf("failed to propose execution of contract %s as safe tx; no ABI resolved: %s", c.ID, err.Error())
+	}
+
+	method, methodOk := _abi.Methods[execution.Method]
+	if !methodOk {
+		return nil, fmt.Errorf("failed to propose execution of contract %s as safe tx; method %s not found in ABI", c.ID, execution.Method)
+	}
+
+	data, err := providecrypto.EVMEncodeABI(&method, execution.Params...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode %d parameters of method %s on contract %s; %s", len(execution.Params), execution.Method, c.ID, err.Error())
+	}
+
+	value := big.NewInt(0)
+	if execution.Value != nil {
+		value = execution.Value
+	}
+
+	safeTx := &SafeTransaction{
+		NetworkID:      c.NetworkID,
+		ApplicationID:  applicationID,
+		OrganizationID: organizationID,
+		UserID:         userID,
+		ContractID:     &c.ID,
+		SafeAddress:    ethcommon.HexToAddress(safeAddress).Hex(),
+		To:             ethcommon.HexToAddress(*c.Address).Hex(),
+		Value:          value.String(),
+		Data:           common.StringOrNil(hexutil.Encode(data)),
+		Operation:      0,
+		GasPrice:       "0",
+		GasToken:       ethcommon.Address{}.Hex(),
+		RefundReceiver: ethcommon.Address{}.Hex(),
+		Status:         safeTxStatusPending,
+		Description:    common.StringOrNil(fmt.Sprintf("%s on contract %s", method.Sig, *c.Address)),
+	}
+
+	account := &wallet.Account{}
+	if execution.AccountID != nil && *execution.AccountID != uuid.Nil {
+		db.Where("id = ?", execution.AccountID).Find(&account)
+	} else if execution.AccountAddress != nil {
+		db.Where("network_id = ? AND address = ?", c.NetworkID, execution.AccountAddress).Find(&account)
+	}
+	if account == nil || account.ID == uuid.Nil {
+		return nil, fmt.Errorf("safe txs must be proposed using an account_id or account_address of the account which executes them")
+	}
+	safeTx.AccountID = &account.ID
+
+	if err := safeTx.propose(db); err != nil {
+		return nil, err
+	}
+
+	// the proposer confirms the safe tx when it is an owner; other proposers only execute it
+	if _, err := safeTx.confirmWithAccount(db, account, userID); err != nil {
+		common.Log.Debugf("safe tx %s not confirmed by proposing account %s; %s", safeTx.ID, account.ID, err.Error())
+	}
+
+	return safeTx, nil
+}
+
+// propose resolves the Safe nonce, threshold and SafeTx hash of the Safe tx and persists it
+func (s *SafeTransaction) propose(db *gorm.DB) error {
+	ntwrk := &network.Network{}
+	db.Where("id = ?", s.NetworkID).Find(&ntwrk)
+	if ntwrk == nil || ntwrk.ID == uuid.Nil || !ntwrk.IsEthereumNetwork() {
+		return fmt.Errorf("safe txs not supported by network %s", s.NetworkID)
+	}
+
+	safe, err := safeContractFactory(ntwrk, s.SafeAddress)
+	if err != nil {
+		return err
+	}
+
+	nonce, err := safe.nonce()
+	if err != nil {
+		return err
+	}
+
+	// proposals awaiting execution are queued behind one another using sequential safe nonces
+	var queued struct {
+		Nonce *uint64
+	}
+	db.Raw(
+		"SELECT MAX(safe_nonce) AS nonce FROM safe_transactions WHERE network_id = ? AND LOWER(safe_address) = ? AND status IN (?) AND safe_nonce >= ?",
+		s.NetworkID, strings.ToLower(s.SafeAddress), []string{safeTxStatusPending, safeTxStatusConfirmed, safeTxStatusExecuting}, nonce,
+	).Scan(&queued)
+	if queued.Nonce != nil {
+		nonce = *queued.Nonce + 1
+	}
+	s.Nonce = nonce
+
+	s.Threshold, err = safe.threshold()
+	if err != nil {
+		return err
+	}
+
+	hash, err := safe.hash(s)
+	if err != nil {
+		return err
+	}
+	s.SafeTxHash = hexutil.Encode(hash)
+
+	result := db.Create(&s)
+	if errs := result.GetErrors(); len(errs) > 0 {
+		return fmt.Errorf("failed to persist safe tx; %s", errs[0].Error())
+	}
+
+	common.Log.Debugf("proposed safe tx %s on safe %s; nonce: %d; threshold: %d; hash: %s", s.ID, s.SafeAddress, s.Nonce, s.Threshold, s.SafeTxHash)
+	return nil
+}
+
+// confirmWithAccount signs the SafeTx hash using the given custodial owner account and records the confirmation
+func (s *SafeTransaction) confirmWithAccount(db *gorm.DB, account *wallet.Account, userID *uuid.UUID) (*SafeConfirmation, error) {
+	ntwrk := &network.Network{}
+	db.Where("id = ?", s.NetworkID).Find(&ntwrk)
+
+	_, signHash, err := typedDataSigner(&TransactionSigner{
+		DB:      db,
+		Network: ntwrk,
+		Account: account,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sig, err := signHash(ethcommon.FromHex(s.SafeTxHash))
+	if err != nil {
+		return nil, fmt.Errorf("failed to sign safe tx %s using account %s; %s", s.ID, account.ID, err.Error())
+	}
+	if len(sig) != 65 {
+		return nil, fmt.Errorf("failed to sign safe tx %s using account %s; invalid %d-byte signature", s.ID, account.ID, len(sig))
+	}
+	if sig[64] < 27 {
+		sig[64] += 27
+	}
+
+	return s.confirm(db, hexutil.Encode(sig), &account.ID, userID)
+}
+
+// recoverSafeSigner recovers the owner which signed the given SafeTx hash; eth_sign signatures, which the Safe
+// identifies by a v of 31 or 32, are recovered from the prefixed hash
+func recoverSafeSigner(hash, sig []byte) (ethcommon.Address, error) {
+	if len(sig) != 65 {
+		return ethcommon.Address{}, fmt.Errorf("invalid %d-byte safe tx signature", len(sig))
+	}
+
+	digest := hash
+	v := sig[64]
+	if v > 30 {
+		digest = ethcrypto.Keccak256([]byte("\x19Ethereum Signed Message:\n32"), hash)
+		v -= 4
+	}
+	if v != 27 && v != 28 {
+		return ethcommon.Address{}, fmt.Errorf("unsupported safe tx signature type; v: %d", sig[64])
+	}
+
+	_sig := make([]byte, 65)
+	copy(_sig, sig)
+	_sig[64] = v - 27
+
+	pubkey, err := ethcrypto.SigToPub(digest, _sig)
+	if err != nil {
+		return ethcommon.Address{}, fmt.Errorf("failed to recover safe tx signer; %s", err.Error())
+	}
+	return ethcrypto.PubkeyToAddress(*pubkey), nil
+}
+
+// confirm records the given owner signature of the Safe tx and executes it when the threshold is met
+func (s *SafeTransaction) confirm(db *gorm.DB, signature string, accountID, userID *uuid.UUID) (*SafeConfirmation, error) {
+	if s.Status != safeTxStatusPending && s.Status != safeTxStatusConfirmed {
+		return nil, fmt.Errorf("unable to confirm safe tx %s; status: %s", s.ID, s.Status)
+	}
+
+	sig, err := hexutil.Decode(signature)
+	if err != nil {
+		return nil, fmt.Errorf("invalid safe tx signature; %s", err.Error())
+	}
+
+	owner, err := recoverSafeSigner(ethcommon.FromHex(s.SafeTxHash), sig)
+	if err != nil {
+		return nil, err
+	}
+
+	safe, err := s.safeContract(db)
+	if err != nil {
+		return nil, err
+	}
+
+	isOwner, err := safe.isOwner(owner)
+	if err != nil {
+		return nil, err
+	}
+	if !isOwner {
+		return nil, fmt.Errorf("signer %s is not an owner of safe %s", owner.Hex(), s.SafeAddress)
+	}
+
+	confirmation := &SafeConfirmation{
+		SafeTransactionID: s.ID,
+		Owner:             owner.Hex(),
+		Signature:         hexutil.Encode(sig),
+		AccountID:         accountID,
+		UserID:            userID,
+	}
+
+	existing := &SafeConfirmation{}
+	db.Where("safe_transaction_id = ? AND owner = ?", s.ID, confirmation.Owner).Find(&existing)
+	if existing != nil && existing.ID != uuid.Nil {
+		return nil, fmt.Errorf("safe tx %s already confirmed by owner %s", s.ID, confirmation.Owner)
+	}
+
+	if err := db.Create(&confirmation).Error; err != nil {
+		return nil, fmt.Errorf("failed to persist confirmation of safe tx %s by owner %s; %s", s.ID, confirmation.Owner, err.Error())
+	}
+
+	// the threshold of the safe may have changed since the safe tx was proposed
+	if threshold, err := safe.threshold(); err == nil {
+		s.Threshold = threshold
+	}
+
+	db.Exec(
+		"UPDATE safe_transactions SET threshold = ?, confirmations = (SELECT COUNT(*) FROM safe_transaction_confirmations WHERE safe_transaction_id = ?) WHERE id = ?",
+		s.Threshold, s.ID, s.ID,
+	)
+	db.Exec(
+		"UPDATE safe_transactions SET status = ? WHERE id = ? AND status = ? AND confirmations >= threshold",
+		safeTxStatusConfirmed, s.ID, safeTxStatusPending,
+	)
+	db.Where("id = ?", s.ID).Find(&s)
+
+	common.Log.Debugf("safe tx %s confirmed by owner %s; %d of %d confirmation(s)", s.ID, confirmation.Owner, s.Confirmations, s.Threshold)
+
+	if s.Status == safeTxStatusConfirmed && s.AccountID != nil {
+		if err := s.execute(db, nil); err != nil {
+			common.Log.Warningf("failed to execute confirmed safe tx %s; %s", s.ID, err.Error())
+		}
+	}
+
+	return confirmation, nil
+}
+
+// safeContract returns the Safe of the Safe tx
+func (s *SafeTransaction) safeContract(db *gorm.DB) (*safeContract, error) {
+	ntwrk := &network.Network{}
+	db.Where("id = ?", s.NetworkID).Find(&ntwrk)
+	return safeContractFactory(ntwrk, s.SafeAddress)
+}
+
+// confirmations returns the owner signatures of the Safe tx
+func (s *SafeTransaction) confirmations(db *gorm.DB) []*SafeConfirmation {
+	confirmations := make([]*SafeConfirmation, 0)
+	db.Where("safe_transaction_id = ?", s.ID).Order("created_at ASC").Find(&confirmations)
+	return confirmations
+}
+
+// qualifiedConfirmations returns the signatures of the Safe tx by accounts which are still owners of the Safe;
+// signatures of owners removed since they confirmed are dropped, and the confirmations and threshold of the
+// Safe tx are updated to reflect the current owners and threshold of the Safe
+func (s *SafeTransaction) qualifiedConfirmations(db *gorm.DB, safe *safeContract) ([]*SafeConfirmation, error) {
+	qualified := make([]*SafeConfirmation, 0)
+	for _, confirmation := range s.confirmations(db) {
+		isOwner, err := safe.isOwner(ethcommon.HexToAddress(confirmation.Owner))
+		if err != nil {
+			return nil, err
+		}
+		if !isOwner {
+			common.Log.Debugf("dropping confirmation of safe tx %s by %s; no longer an owner of safe %s", s.ID, confirmation.Owner, s.SafeAddress)
+			db.Delete(confirmation)
+			continue
+		}
+		qualified = append(qualified, confirmation)
+	}
+
+	threshold, err := safe.threshold()
+	if err != nil {
+		return nil, err
+	}
+
+	s.Threshold = threshold
+	s.Confirmations = uint64(len(qualified))
+	db.Exec("UPDATE safe_transactions SET threshold = ?, confirmations = ? WHERE id = ?", s.Threshold, s.Confirmations, s.ID)
+
+	return qualified, nil
+}
+
+// execute broadcasts the execTransaction tx of the confirmed Safe tx using the given account or, if none is given,
+// the executor account of the Safe tx; the Safe tx is executing until the receipt of the tx is settled
+func (s *SafeTransaction) execute(db *gorm.DB, accountID *uuid.UUID) error {
+	if accountID == nil {
+		accountID = s.AccountID
+	}
+	if accountID == nil {
+		return fmt.Errorf("no account to execute safe tx %s", s.ID)
+	}
+
+	// the status transition guards against executing the same safe tx twice
+	result := db.Exec("UPDATE safe_transactions SET status = ?, account_id = ? WHERE id = ? AND status = ?", safeTxStatusExecuting, accountID, s.ID, safeTxStatusConfirmed)
+	if result.Error != nil {
+		return fmt.Errorf("failed to execute safe tx %s; %s", s.ID, result.Error.Error())
+	}
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("unable to execute safe tx %s; status: %s", s.ID, s.Status)
+	}
+	s.Status = safeTxStatusExecuting
+	s.AccountID = accountID
+
+	calldata, err := s.execTransactionCalldata(db)
+	if err == nil {
+		tx := &Transaction{
+			NetworkID:      s.NetworkID,
+			ApplicationID:  s.ApplicationID,
+			OrganizationID: s.OrganizationID,
+			UserID:         s.UserID,
+			AccountID:      accountID,
+			To:             common.StringOrNil(s.SafeAddress),
+			Value:          NewTxValue(0),
+			Data:           common.StringOrNil(hexutil.Encode(calldata)),
+			Description:    common.StringOrNil(fmt.Sprintf("execute safe tx %s", s.SafeTxHash)),
+		}
+
+		created := tx.Create(db)
+		if tx.ID != uuid.Nil {
+			s.TransactionID = &tx.ID
+			db.Model(&s).Update("transaction_id", tx.ID)
+		}
+		if !created && len(tx.Errors) > 0 {
+			err = fmt.Errorf("failed to broadcast execTransaction; %s", *tx.Errors[0].Message)
+		} else if !created {
+			err = fmt.Errorf("failed to broadcast execTransaction")
+		}
+	}
+
+	if err != nil {
+		// the safe tx may be executed again once the cause of the failure is resolved, or confirmed again
+		// if confirmations were dropped
+		status := safeTxStatusConfirmed
+		if s.Confirmations < s.Threshold {
+			status = safeTxStatusPending
+		}
+		db.Model(&s).Update("status", status)
+		s.Status = status
+		return err
+	}
+
+	common.Log.Debugf("broadcast execTransaction of safe tx %s; tx: %s", s.ID, s.TransactionID)
+	return nil
+}
+
+// execTransactionCalldata encodes the execTransaction call of the Safe tx using the signatures of its current
+// owners; signatures are ordered by owner address, as required by the Safe
+func (s *SafeTransaction) execTransactionCalldata(db *gorm.DB) ([]byte, error) {
+	_abi, err := abi.JSON(strings.NewReader(safeABI))
+	if err != nil {
+		return nil, err
+	}
+
+	// owners may have been removed from the safe since they confirmed the safe tx
+	safe, err := s.safeContract(db)
+	if err != nil {
+		return nil, err
+	}
+
+	confirmations, err := s.qualifiedConfirmations(db, safe)
+	if err != nil {
+		return nil, fmt.Errorf("failed to verify owners which confirmed safe tx %s; %s", s.ID, err.Error())
+	}
+	if uint64(len(confirmations)) < s.Threshold {
+		return nil, fmt.Errorf("safe tx %s has %d of %d required confirmation(s)", s.ID, len(confirmations), s.Threshold)
+	}
+
+	sort.Slice(confirmations, func(i, j int) bool {
+		return bytes.Compare(ethcommon.HexToAddress(confirmations[i].Owner).Bytes(), ethcommon.HexToAddress(confirmations[j].Owner).Bytes()) < 0
+	})
+
+	signatures := make([]byte, 0)
+	for _, confirmation := range confirmations {
+		signatures = append(signatures, ethcommon.FromHex(confirmation.Signature)...)
+	}
+
+	value, _ := new(big.Int).SetString(s.Value, 10)
+	gasPrice, _ := new(big.Int).SetString(s.GasPrice, 10)
+
+	return _abi.Pack(
+		"execTransaction",
+		ethcommon.HexToAddress(s.To),
+		value,
+		s.calldata(),
+		s.Operation,
+		new(big.Int).SetUint64(s.SafeTxGas),
+		new(big.Int).SetUint64(s.BaseGas),
+		gasPrice,
+		ethcommon.HexToAddress(s.GasToken),
+		ethcommon.HexToAddress(s.RefundReceiver),
+		signatures,
+	)
+}
+
+// settleSafeTransaction settles the status of the Safe tx executed by the tx, if any, using the status of its receipt
+func (t *Transaction) settleSafeTransaction(db *gorm.DB, success bool) {
+	status := safeTxStatusExecuted
+	if !success {
+		status = safeTxStatusFailed
+	}
+
+	result := db.Exec("UPDATE safe_transactions SET status = ? WHERE transaction_id = ? AND status = ?", status, t.ID, safeTxStatusExecuting)
+	if result.RowsAffected > 0 {
+		common.Log.Debugf("settled safe tx executed by tx %s; status: %s", t.ID, status)
+	}
+}
@@ -0,0 +1,191 @@
+package tx
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jinzhu/gorm"
+	dbconf "github.com/kthomas/go-db-config"
+	uuid "github.com/kthomas/go.uuid"
+	provide "github.com/provideplatform/provide-go/common"
+	util "github.com/provideplatform/provide-go/common/util"
+)
+
+// installSafeTransactionsAPI installs the Safe tx handlers using the given gin Engine
+func installSafeTransactionsAPI(r *gin.Engine) {
+	r.GET("/api/v1/safe_transactions", safeTransactionsListHandler)
+	r.GET("/api/v1/safe_transactions/:id", safeTransactionDetailsHandler)
+	r.POST("/api/v1/safe_transactions/:id/confirmations", confirmSafeTransactionHandler)
+	r.POST("/api/v1/safe_transactions/:id/execute", executeSafeTransactionHandler)
+}
+
+// resolveAuthorizedSafeTransaction resolves the Safe tx with the id given in the path on behalf of the
+// authorized application, organization or user
+func resolveAuthorizedSafeTransaction(c *gin.Context) *SafeTransaction {
+	appID := util.AuthorizedSubjectID(c, "application")
+	orgID := util.AuthorizedSubjectID(c, "organization")
+	userID := util.AuthorizedSubjectID(c, "user")
+	if appID == nil && orgID == nil && userID == nil {
+		provide.RenderError("unauthorized", 401, c)
+		return nil
+	}
+
+	query := dbconf.DatabaseConnection().Where("id = ?", c.Param("id"))
+	if appID != nil {
+		query = query.Where("application_id = ?", appID)
+	} else if orgID != nil {
+		query = query.Where("organization_id = ?", orgID)
+	} else if userID != nil {
+		query = query.Where("user_id = ?", userID)
+	}
+
+	safeTx := &SafeTransaction{}
+	query.Find(&safeTx)
+	if safeTx == nil || safeTx.ID == uuid.Nil {
+		provide.RenderError("safe transaction not found", 404, c)
+		return nil
+	}
+
+	return safeTx
+}
+
+func safeTransactionsListHandler(c *gin.Context) {
+	appID := util.AuthorizedSubjectID(c, "application")
+	orgID := util.AuthorizedSubjectID(c, "organization")
+	userID := util.AuthorizedSubjectID(c, "user")
+	if appID == nil && orgID == nil && userID == nil {
+		provide.RenderError("unauthorized", 401, c)
+		return
+	}
+
+	var query *gorm.DB
+	if appID != nil {
+		query = dbconf.DatabaseConnection().Where("safe_transactions.application_id = ?", appID)
+	} else if orgID != nil {
+		query = dbconf.DatabaseConnection().Where("safe_transactions.organization_id = ?", orgID)
+	} else if userID != nil {
+		query = dbconf.DatabaseConnection().Where("safe_transactions.user_id = ?", userID)
+	}
+
+	if c.Query("network_id") != "" {
+		query = query.Where("safe_transactions.network_id = ?", c.Query("network_id"))
+	}
+	if c.Query("safe_address") != "" {
+		query = query.Where("LOWER(safe_transactions.safe_address) = ?", strings.ToLower(c.Query("safe_address")))
+	}
+	if c.Query("status") != "" {
+		query = query.Where("safe_transactions.status IN (?)", strings.Split(c.Query("status"), ","))
+	}
+	query = query.Order("safe_transactions.created_at DESC")
+
+	var safeTxs []*SafeTransaction
+	provide.Paginate(c, query, &SafeTransaction{}).Find(&safeTxs)
+	provide.Render(safeTxs, 200, c)
+}
+
+func safeTransactionDetailsHandler(c *gin.Context) {
+	safeTx := resolveAuthorizedSafeTransaction(c)
+	if safeTx == nil {
+		return
+	}
+
+	safeTx.Signatures = safeTx.confirmations(dbconf.DatabaseConnection())
+	provide.Render(safeTx, 200, c)
+}
+
+// confirmSafeTransactionHandler confirms the Safe tx using the given custodial owner account or the given
+// signature of an external owner
+func confirmSafeTransactionHandler(c *gin.Context) {
+	safeTx := resolveAuthorizedSafeTransaction(c)
+	if safeTx == nil {
+		return
+	}
+	userID := util.AuthorizedSubjectID(c, "user")
+
+	buf, err := c.GetRawData()
+	if err != nil {
+		provide.RenderError(err.Error(), 400, c)
+		return
+	}
+
+	params := map[string]interface{}{}
+	err = json.Unmarshal(buf, &params)
+	if err != nil {
+		provide.RenderError(err.Error(), 400, c)
+		return
+	}
+
+	db := dbconf.DatabaseConnection()
+
+	var confirmation *SafeConfirmation
+	if accountID, accountIDOk := params["account_id"].(string); accountIDOk {
+		account := safeTx.resolveAccount(db, accountID)
+		if account == nil {
+			provide.RenderError("account not found", 404, c)
+			return
+		}
+		confirmation, err = safeTx.confirmWithAccount(db, account, userID)
+	} else if signature, signatureOk := params["signature"].(string); signatureOk {
+		confirmation, err = safeTx.confirm(db, signature, nil, userID)
+	} else {
+		provide.RenderError("account_id or signature required", 422, c)
+		return
+	}
+
+	if err != nil {
+		provide.RenderError(err.Error(), 422, c)
+		return
+	}
+
+	provide.Render(confirmation, 201, c)
+}
+
+// executeSafeTransactionHandler executes the confirmed Safe tx, optionally using the given account
+func executeSafeTransactionHandler(c *gin.Context) {
+	safeTx := resolveAuthorizedSafeTransaction(c)
+	if safeTx == nil {
+		return
+	}
+
+	buf, err := c.GetRawData()
+	if err != nil {
+		provide.RenderError(err.Error(), 400, c)
+		return
+	}
+
+	params := map[string]interface{}{}
+	if len(buf) > 0 {
+		err = json.Unmarshal(buf, &params)
+		if err != nil {
+			provide.RenderError(err.Error(), 400, c)
+			return
+		}
+	}
+
+	db := dbconf.DatabaseConnection()
+
+	var accountID *uuid.UUID
+	if accountIDStr, accountIDOk := params["account_id"].(string); accountIDOk {
+		account := safeTx.resolveAccount(db, accountIDStr)
+		if account == nil {
+			provide.RenderError("account not found", 404, c)
+			return
+		}
+		accountID = &account.ID
+	}
+
+	if safeTx.Status != safeTxStatusConfirmed {
+		provide.RenderError(fmt.Sprintf("unable to execute safe transaction with status: %s", safeTx.Status), 422, c)
+		return
+	}
+
+	err = safeTx.execute(db, accountID)
+	if err != nil {
+		provide.RenderError(err.Error(), 422, c)
+		return
+	}
+
+	provide.Render(safeTx, 202, c)
+}
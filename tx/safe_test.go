@@ -0,0 +1,116 @@
+// +build unit
+
+package tx
+
+import (
+	"math/big"
+	"testing"
+
+	ethcommon "github.com/ethereum/go-ethereum/common"
+	ethcrypto "github.com/ethereum/go-ethereum/crypto"
+	"github.com/provideplatform/nchain/common"
+)
+
+// safeTxVectorHash is the EIP-712 SafeTx hash of the Safe tx returned by safeTxFactory, as computed by
+// the go-ethereum typed data implementation used by eth_signTypedData_v4
+const safeTxVectorHash = "62e4854d8378e8a3e90567ac8c2acdb23212218b75a7bd6f60899ace5fedb032"
+
+// safeContractFixture returns a Safe on chain id 5 which is never dialed
+func safeContractFixture() *safeContract {
+	return &safeContract{
+		address: ethcommon.HexToAddress("0x4343434343434343434343434343434343434343"),
+		chainID: big.NewInt(5),
+	}
+}
+
+// safeTxFactory returns a Safe tx calling the envelope vector recipient without gas refunds
+func safeTxFactory() *SafeTransaction {
+	return &SafeTransaction{
+		To:             envelopeVectorRecipient.Hex(),
+		Value:          "1000",
+		Data:           common.StringOrNil("0xa9059cbb"),
+		GasPrice:       "0",
+		GasToken:       ethcommon.Address{}.Hex(),
+		RefundReceiver: ethcommon.Address{}.Hex(),
+		Nonce:          7,
+	}
+}
+
+func TestSafeTxSigningHash(t *testing.T) {
+	hash := safeContractFixture().signingHash(safeTxFactory())
+	if ethcommon.Bytes2Hex(hash) != safeTxVectorHash {
+		t.Errorf("expected SafeTx hash %s; got %x", safeTxVectorHash, hash)
+	}
+
+	// Safe txs of another Safe or chain must not be replayable
+	safe := safeContractFixture()
+	safe.chainID = big.NewInt(1)
+	if ethcommon.Bytes2Hex(safe.signingHash(safeTxFactory())) == safeTxVectorHash {
+		t.Errorf("expected SafeTx hash to change with the chain id")
+	}
+
+	safe = safeContractFixture()
+	safe.address = ethcommon.HexToAddress("0x4444444444444444444444444444444444444444")
+	if ethcommon.Bytes2Hex(safe.signingHash(safeTxFactory())) == safeTxVectorHash {
+		t.Errorf("expected SafeTx hash to change with the safe address")
+	}
+
+	safeTx := safeTxFactory()
+	safeTx.Operation = 1
+	if ethcommon.Bytes2Hex(safeContractFixture().signingHash(safeTx)) == safeTxVectorHash {
+		t.Errorf("expected SafeTx hash to change with the operation")
+	}
+}
+
+func TestRecoverSafeSigner(t *testing.T) {
+	key, _ := ethcrypto.HexToECDSA(envelopeVectorPrivateKey)
+	hash := safeContractFixture().signingHash(safeTxFactory())
+
+	sig, _ := ethcrypto.Sign(hash, key)
+	sig[64] += 27
+
+	owner, err := recoverSafeSigner(hash, sig)
+	if err != nil {
+		t.Fatalf("failed to recover safe tx signer; %s", err.Error())
+	}
+	if owner.Hex() != envelopeVectorSender {
+		t.Errorf("expected signer %s; got %s", envelopeVectorSender, owner.Hex())
+	}
+}
+
+func TestRecoverSafeSignerEthSign(t *testing.T) {
+	key, _ := ethcrypto.HexToECDSA(envelopeVectorPrivateKey)
+	hash := safeContractFixture().signingHash(safeTxFactory())
+
+	// eth_sign signatures are of the prefixed hash, and identified by the Safe by a v of 31 or 32
+	sig, _ := ethcrypto.Sign(ethcrypto.Keccak256([]byte("\x19Ethereum Signed Message:\n32"), hash), key)
+	sig[64] += 31
+
+	owner, err := recoverSafeSigner(hash, sig)
+	if err != nil {
+		t.Fatalf("failed to recover safe tx signer; %s", err.Error())
+	}
+	if owner.Hex() != envelopeVectorSender {
+		t.Errorf("expected signer %s; got %s", envelopeVectorSender, owner.Hex())
+	}
+}
+
+func TestRecoverSafeSignerRejectsUnsupportedSignatures(t *testing.T) {
+	key, _ := ethcrypto.HexToECDSA(envelopeVectorPrivateKey)
+	hash := safeContractFixture().signingHash(safeTxFactory())
+	sig, _ := ethcrypto.Sign(hash, key)
+
+	if _, err := recoverSafeSigner(hash, sig[:64]); err == nil {
+		t.Errorf("expected 64-byte signature to be rejected")
+	}
+
+	// contract signatures (v of 0) and pre-approved hashes (v of 1) are not supported
+	for _, v := range []byte{0, 1, 29, 33} {
+		_sig := make([]byte, 65)
+		copy(_sig, sig)
+		_sig[64] = v
+		if _, err := recoverSafeSigner(hash, _sig); err == nil {
+			t.Errorf("expected signature having v of %d to be rejected", v)
+		}
+	}
+}
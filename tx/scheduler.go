@@ -0,0 +1,341 @@
+package tx
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jinzhu/gorm"
+	dbconf "github.com/kthomas/go-db-config"
+	natsutil "github.com/kthomas/go-natsutil"
+	redisutil "github.com/kthomas/go-redisutil"
+	uuid "github.com/kthomas/go.uuid"
+	"github.com/provideplatform/nchain/common"
+	"github.com/provideplatform/nchain/contract"
+	"github.com/provideplatform/nchain/network"
+	provide "github.com/provideplatform/provide-go/api"
+)
+
+// txSchedulerTickerInterval is the interval at which scheduled txs are checked and published when due
+const txSchedulerTickerInterval = time.Second * 5
+
+// txSchedulerBatchSize is the maximum number of due scheduled txs published per network on each tick
+const txSchedulerBatchSize = 1024
+
+// txSchedulerMutexKey is the key which represents the distributed lock for publishing scheduled txs
+const txSchedulerMutexKey = "tx.scheduler.mutex"
+
+// Scheduled tx statuses
+const (
+	scheduledTxStatusScheduled = "scheduled"
+	scheduledTxStatusPublished = "published"
+	scheduledTxStatusCanceled  = "canceled"
+)
+
+// ScheduledTransaction is a tx or contract execution which is parked until the time and/or block height at
+// which it was scheduled, after which it is published to nchain.tx for signing and broadcast
+type ScheduledTransaction struct {
+	provide.Model
+	ApplicationID  *uuid.UUID       `sql:"type:uuid" json:"application_id,omitempty"`
+	OrganizationID *uuid.UUID       `sql:"type:uuid" json:"organization_id,omitempty"`
+	UserID         *uuid.UUID       `sql:"type:uuid" json:"user_id,omitempty"`
+	NetworkID      uuid.UUID        `sql:"not null;type:uuid" json:"network_id"`
+	ContractID     *uuid.UUID       `sql:"type:uuid" json:"contract_id,omitempty"` // set when a contract execution is scheduled
+	Ref            *string          `sql:"not null" json:"ref"`
+	Status         *string          `sql:"not null;default:'scheduled'" json:"status"`
+	ScheduledAt    *time.Time       `json:"scheduled_at,omitempty"`
+	NotBeforeBlock *uint64          `json:"not_before_block,omitempty"`
+	Payload        *json.RawMessage `sql:"type:json" json:"-"` // nchain.tx message published when the scheduled tx is due
+	PublishedAt    *time.Time       `json:"published_at,omitempty"`
+	CanceledAt     *time.Time       `json:"canceled_at,omitempty"`
+}
+
+// scheduleTransaction parks the given tx until it is due; the scheduling options are cleared from the
+// parked message so the tx is created as soon as the message is published
+func scheduleTransaction(db *gorm.DB, tx *Transaction) (*ScheduledTransaction, error) {
+	err := validateScheduled(tx)
+	if err != nil {
+		return nil, err
+	}
+
+	if tx.Ref == nil {
+		ref, _ := uuid.NewV4()
+		tx.Ref = common.StringOrNil(ref.String())
+	}
+
+	scheduled := &ScheduledTransaction{
+		ApplicationID:  tx.ApplicationID,
+		OrganizationID: tx.OrganizationID,
+		UserID:         tx.UserID,
+		NetworkID:      tx.NetworkID,
+		Ref:            tx.Ref,
+		ScheduledAt:    tx.ScheduledAt,
+		NotBeforeBlock: tx.NotBeforeBlock,
+	}
+
+	tx.ScheduledAt = nil
+	tx.NotBeforeBlock = nil
+	payload, err := json.Marshal(&txMessage{
+		Transaction: tx,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal scheduled tx; %s", err.Error())
+	}
+
+	err = scheduled.create(db, payload)
+	if err != nil {
+		return nil, err
+	}
+	return scheduled, nil
+}
+
+// scheduleExecution parks the given contract execution until it is due; the scheduling options are cleared
+// from the parked message so the execution is processed as soon as the message is published
+func scheduleExecution(db *gorm.DB, c *contract.Contract, execution *contract.Execution) (*ScheduledTransaction, error) {
+	err := validateScheduled(executionTransactionFactory(c, execution))
+	if err != nil {
+		return nil, err
+	}
+
+	if execution.Ref == nil {
+		ref, _ := uuid.NewV4()
+		execution.Ref = common.StringOrNil(ref.String())
+	}
+
+	scheduled := &ScheduledTransaction{
+		ApplicationID:  c.ApplicationID,
+		OrganizationID: c.OrganizationID,
+		NetworkID:      c.NetworkID,
+		ContractID:     &c.ID,
+		Ref:            execution.Ref,
+		ScheduledAt:    execution.ScheduledAt,
+		NotBeforeBlock: execution.NotBeforeBlock,
+	}
+
+	execution.ScheduledAt = nil
+	execution.NotBeforeBlock = nil
+	payload, err := json.Marshal(execution)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal scheduled contract execution; %s", err.Error())
+	}
+
+	err = scheduled.create(db, payload)
+	if err != nil {
+		return nil, err
+	}
+	return scheduled, nil
+}
+
+// validateScheduled validates the tx before it is parked, so a tx which can never be created is rejected
+// when it is scheduled rather than when it is due
+func validateScheduled(tx *Transaction) error {
+	if tx.Validate() {
+		return nil
+	}
+
+	messages := make([]string, 0)
+	for _, err := range tx.Errors {
+		if err.Message != nil {
+			messages = append(messages, *err.Message)
+		}
+	}
+	return fmt.Errorf("unable to schedule invalid tx; %s", strings.Join(messages, "; "))
+}
+
+// create persists the scheduled tx with the given nchain.tx message
+func (s *ScheduledTransaction) create(db *gorm.DB, payload []byte) error {
+	if s.Ref == nil {
+		return fmt.Errorf("unable to schedule tx without a ref")
+	}
+
+	if s.ScheduledAt == nil && s.NotBeforeBlock == nil {
+		return fmt.Errorf("unable to schedule tx without scheduled_at or not_before_block")
+	}
+
+	// scheduling is idempotent by ref; the message may be redelivered or the request retried
+	existing := &ScheduledTransaction{}
+	db.Where("network_id = ? AND ref = ? AND status != ?", s.NetworkID, *s.Ref, scheduledTxStatusCanceled).Find(&existing)
+	if existing != nil && existing.ID != uuid.Nil {
+		*s = *existing
+		return nil
+	}
+
+	rawPayload := json.RawMessage(payload)
+	s.Payload = &rawPayload
+	s.Status = common.StringOrNil(scheduledTxStatusScheduled)
+
+	result := db.Create(&s)
+	errors := result.GetErrors()
+	if len(errors) > 0 {
+		for _, err := range errors {
+			s.Errors = append(s.Errors, &provide.Error{
+				Message: common.StringOrNil(err.Error()),
+			})
+		}
+		return fmt.Errorf("failed to persist scheduled tx with ref %s; %s", *s.Ref, errors[0].Error())
+	}
+
+	common.Log.Debugf("scheduled tx with ref %s on network: %s", *s.Ref, s.NetworkID)
+	return nil
+}
+
+// Cancel the scheduled tx; only a tx which has not yet been published can be canceled
+func (s *ScheduledTransaction) Cancel(db *gorm.DB) bool {
+	canceledAt := time.Now()
+	result := db.Model(&ScheduledTransaction{}).
+		Where("id = ? AND status = ?", s.ID, scheduledTxStatusScheduled).
+		Updates(map[string]interface{}{
+			"status":      scheduledTxStatusCanceled,
+			"canceled_at": canceledAt,
+		})
+
+	if result.RowsAffected == 0 {
+		s.Errors = append(s.Errors, &provide.Error{
+			Message: common.StringOrNil(fmt.Sprintf("unable to cancel %s tx", *s.Status)),
+		})
+		return false
+	}
+
+	s.Status = common.StringOrNil(scheduledTxStatusCanceled)
+	s.CanceledAt = &canceledAt
+	return true
+}
+
+// due returns true if the scheduled time and block height of the tx, if any, have been reached
+func (s *ScheduledTransaction) due(head *uint64) bool {
+	if s.ScheduledAt != nil && time.Now().Before(*s.ScheduledAt) {
+		return false
+	}
+	if s.NotBeforeBlock != nil && (head == nil || *head < *s.NotBeforeBlock) {
+		return false
+	}
+	return true
+}
+
+// publish claims the scheduled tx and publishes its message to nchain.tx; the claim is released if the
+// message cannot be published
+func (s *ScheduledTransaction) publish(db *gorm.DB) error {
+	publishedAt := time.Now()
+	result := db.Model(&ScheduledTransaction{}).
+		Where("id = ? AND status = ?", s.ID, scheduledTxStatusScheduled).
+		Updates(map[string]interface{}{
+			"status":       scheduledTxStatusPublished,
+			"published_at": publishedAt,
+		})
+	if result.RowsAffected == 0 {
+		return nil // canceled since it was selected
+	}
+
+	var msg map[string]interface{}
+	err := json.Unmarshal(*s.Payload, &msg)
+	if err == nil {
+		if tx, txOk := msg["transaction"].(map[string]interface{}); txOk {
+			tx["published_at"] = publishedAt
+		} else {
+			msg["published_at"] = publishedAt
+		}
+	}
+
+	payload, _ := json.Marshal(msg)
+	if err == nil {
+		_, err = natsutil.NatsJetstreamPublish(natsTxSubject, payload)
+	}
+
+	if err != nil {
+		db.Model(&ScheduledTransaction{}).Where("id = ?", s.ID).Updates(map[string]interface{}{
+			"status":       scheduledTxStatusScheduled,
+			"published_at": nil,
+		})
+		return fmt.Errorf("failed to publish scheduled tx %s; %s", s.ID, err.Error())
+	}
+
+	s.Status = common.StringOrNil(scheduledTxStatusPublished)
+	s.PublishedAt = &publishedAt
+	return nil
+}
+
+// requireTxScheduler starts the scheduler which periodically publishes scheduled txs when they are due
+func requireTxScheduler() {
+	ticker := time.NewTicker(txSchedulerTickerInterval)
+	go func() {
+		for {
+			select {
+			case <-ticker.C:
+				err := redisutil.WithRedlock(txSchedulerMutexKey, func() error {
+					publishDueScheduledTxs(dbconf.DatabaseConnection())
+					return nil
+				})
+				if err != nil {
+					common.Log.Warningf("failed to publish scheduled txs; %s", err.Error())
+				}
+			}
+		}
+	}()
+}
+
+// publishDueScheduledTxs publishes the scheduled txs which are due on each network having scheduled txs
+// whose scheduled time, if any, has been reached
+func publishDueScheduledTxs(db *gorm.DB) {
+	now := time.Now()
+
+	var networkIDs []uuid.UUID
+	db.Model(&ScheduledTransaction{}).
+		Where("status = ? AND (scheduled_at IS NULL OR scheduled_at <= ?)", scheduledTxStatusScheduled, now).
+		Pluck("DISTINCT network_id", &networkIDs)
+
+	published := 0
+	for _, networkID := range networkIDs {
+		published += publishNetworkDueScheduledTxs(db, networkID, now)
+	}
+
+	if published > 0 {
+		common.Log.Debugf("published %d due scheduled tx(s)", published)
+	}
+}
+
+// publishNetworkDueScheduledTxs publishes the scheduled txs on the given network which are due at the given
+// time and the network head; txs awaiting a later block are excluded by the query, so they cannot fill the
+// batch and starve the txs which are due
+func publishNetworkDueScheduledTxs(db *gorm.DB, networkID uuid.UUID, now time.Time) int {
+	query := db.Where("network_id = ? AND status = ? AND (scheduled_at IS NULL OR scheduled_at <= ?)", networkID, scheduledTxStatusScheduled, now)
+
+	var awaitingBlock int
+	query.Model(&ScheduledTransaction{}).Where("not_before_block IS NOT NULL").Count(&awaitingBlock)
+
+	var head *uint64
+	if awaitingBlock > 0 {
+		ntwrk := &network.Network{}
+		db.Where("id = ?", networkID).Find(&ntwrk)
+		if ntwrk != nil && ntwrk.ID != uuid.Nil {
+			head = latestBlock(ntwrk)
+		}
+	}
+
+	if head != nil {
+		query = query.Where("not_before_block IS NULL OR not_before_block <= ?", *head)
+	} else {
+		query = query.Where("not_before_block IS NULL")
+	}
+
+	var scheduled []*ScheduledTransaction
+	query.Order("scheduled_at ASC NULLS LAST, created_at ASC").
+		Limit(txSchedulerBatchSize).
+		Find(&scheduled)
+
+	published := 0
+	for _, s := range scheduled {
+		if !s.due(head) {
+			continue
+		}
+
+		err := s.publish(db)
+		if err != nil {
+			common.Log.Warning(err.Error())
+			continue
+		}
+		published++
+	}
+
+	return published
+}
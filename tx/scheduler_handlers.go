@@ -0,0 +1,117 @@
+package tx
+
+import (
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jinzhu/gorm"
+	dbconf "github.com/kthomas/go-db-config"
+	uuid "github.com/kthomas/go.uuid"
+	provide "github.com/provideplatform/provide-go/common"
+	util "github.com/provideplatform/provide-go/common/util"
+)
+
+// installScheduledTransactionsAPI installs the scheduled tx handlers using the given gin Engine
+func installScheduledTransactionsAPI(r *gin.Engine) {
+	r.GET("/api/v1/transactions/scheduled", scheduledTransactionsListHandler)
+	r.GET("/api/v1/transactions/scheduled/:id", scheduledTransactionDetailsHandler)
+	r.POST("/api/v1/transactions/scheduled/:id/cancel", cancelScheduledTransactionHandler)
+}
+
+// scheduledTransactionsListHandler renders the scheduled txs and contract executions of the authorized subject
+func scheduledTransactionsListHandler(c *gin.Context) {
+	appID := util.AuthorizedSubjectID(c, "application")
+	orgID := util.AuthorizedSubjectID(c, "organization")
+	userID := util.AuthorizedSubjectID(c, "user")
+	if appID == nil && orgID == nil && userID == nil {
+		provide.RenderError("unauthorized", 401, c)
+		return
+	}
+
+	var query *gorm.DB
+	if appID != nil {
+		query = dbconf.DatabaseConnection().Where("scheduled_transactions.application_id = ?", appID)
+	} else if orgID != nil {
+		query = dbconf.DatabaseConnection().Where("scheduled_transactions.organization_id = ?", orgID)
+	} else if userID != nil {
+		query = dbconf.DatabaseConnection().Where("scheduled_transactions.user_id = ?", userID)
+	}
+
+	if c.Query("status") != "" {
+		query = query.Where("scheduled_transactions.status IN (?)", strings.Split(c.Query("status"), ","))
+	}
+
+	if c.Query("network_id") != "" {
+		query = query.Where("scheduled_transactions.network_id = ?", c.Query("network_id"))
+	}
+
+	if c.Query("contract_id") != "" {
+		query = query.Where("scheduled_transactions.contract_id = ?", c.Query("contract_id"))
+	}
+
+	if c.Query("ref") != "" {
+		query = query.Where("scheduled_transactions.ref = ?", c.Query("ref"))
+	}
+
+	var scheduled []*ScheduledTransaction
+	query = query.Order("scheduled_transactions.scheduled_at ASC NULLS LAST, scheduled_transactions.created_at ASC")
+	provide.Paginate(c, query, &ScheduledTransaction{}).Find(&scheduled)
+	provide.Render(scheduled, 200, c)
+}
+
+// resolveScheduledTransaction returns the scheduled tx with the given id if it belongs to the authorized subject;
+// an error is rendered and nil returned otherwise
+func resolveScheduledTransaction(c *gin.Context, db *gorm.DB) *ScheduledTransaction {
+	appID := util.AuthorizedSubjectID(c, "application")
+	orgID := util.AuthorizedSubjectID(c, "organization")
+	userID := util.AuthorizedSubjectID(c, "user")
+	if appID == nil && orgID == nil && userID == nil {
+		provide.RenderError("unauthorized", 401, c)
+		return nil
+	}
+
+	scheduled := &ScheduledTransaction{}
+	db.Where("id = ?", c.Param("id")).Find(&scheduled)
+	if scheduled == nil || scheduled.ID == uuid.Nil {
+		provide.RenderError("scheduled transaction not found", 404, c)
+		return nil
+	}
+
+	validApp := appID != nil && (scheduled.ApplicationID != nil && *scheduled.ApplicationID == *appID)
+	validOrg := orgID != nil && (scheduled.OrganizationID != nil && *scheduled.OrganizationID == *orgID)
+	validUser := userID != nil && (scheduled.UserID != nil && *scheduled.UserID == *userID)
+
+	if !validApp && !validOrg && !validUser {
+		provide.RenderError("forbidden", 403, c)
+		return nil
+	}
+
+	return scheduled
+}
+
+func scheduledTransactionDetailsHandler(c *gin.Context) {
+	scheduled := resolveScheduledTransaction(c, dbconf.DatabaseConnection())
+	if scheduled == nil {
+		return
+	}
+
+	provide.Render(scheduled, 200, c)
+}
+
+func cancelScheduledTransactionHandler(c *gin.Context) {
+	db := dbconf.DatabaseConnection()
+
+	scheduled := resolveScheduledTransaction(c, db)
+	if scheduled == nil {
+		return
+	}
+
+	if !scheduled.Cancel(db) {
+		obj := map[string]interface{}{}
+		obj["errors"] = scheduled.Errors
+		provide.Render(obj, 422, c)
+		return
+	}
+
+	provide.Render(scheduled, 200, c)
+}
@@ -0,0 +1,96 @@
+package tx
+
+import (
+	"encoding/hex"
+	"fmt"
+
+	"github.com/provideplatform/nchain/wallet"
+	vault "github.com/provideplatform/provide-go/api/vault"
+	util "github.com/provideplatform/provide-go/common/util"
+)
+
+// SignerFactory returns the Signer which signs txs on behalf of the account of the given transaction signer
+type SignerFactory func(txs *TransactionSigner) (Signer, error)
+
+// signerFactories are the registered Signer implementations, keyed by account type
+var signerFactories = map[string]SignerFactory{}
+
+// hashSignerFunc returns the 65-byte [R || S || V] secp256k1 signature of the given tx signing hash
+type hashSignerFunc func(hash []byte) ([]byte, error)
+
+func init() {
+	RegisterSigner(wallet.AccountTypeKeystore, keystoreSignerFactory)
+	RegisterSigner(wallet.AccountTypePKCS11, pkcs11SignerFactory)
+	RegisterSigner(wallet.AccountTypeRemote, remoteSignerFactory)
+}
+
+// RegisterSigner registers the Signer implementation used to sign txs on behalf of accounts of the given
+// type; accounts of a registered type are created without Vault key material
+func RegisterSigner(accountType string, factory SignerFactory) {
+	signerFactories[accountType] = factory
+	wallet.RegisterExternalAccountType(accountType)
+}
+
+// signingParams parses the gas limit, gas price and nonce, if any, from the tx params
+func signingParams(tx *Transaction) (gas uint64, gasPrice, nonce *uint64) {
+	params := tx.ParseParams()
+
+	if _gas, gasOk := params["gas"].(float64); gasOk {
+		gas = uint64(_gas)
+	}
+
+	if gp, gpOk := params["gas_price"].(float64); gpOk {
+		_gasPrice := uint64(gp)
+		gasPrice = &_gasPrice
+	}
+
+	if nonceFloat, nonceOk := params["nonce"].(float64); nonceOk {
+		nonceUint := uint64(nonceFloat)
+		nonce = &nonceUint
+	}
+
+	return gas, gasPrice, nonce
+}
+
+// vaultSignHash returns the hash signer which signs using the given Vault key
+func vaultSignHash(vaultID, keyID string, opts map[string]interface{}) hashSignerFunc {
+	return func(hash []byte) ([]byte, error) {
+		sig, err := vault.SignMessage(
+			util.DefaultVaultAccessJWT,
+			vaultID,
+			keyID,
+			fmt.Sprintf("%x", hash),
+			opts,
+		)
+		if err != nil {
+			return nil, err
+		}
+		return hex.DecodeString(*sig.Signature)
+	}
+}
+
+// hashSigner is a Signer which builds txs locally and signs their signing hash using key material
+// held outside of Vault
+type hashSigner struct {
+	txs      *TransactionSigner
+	kind     string
+	signHash hashSignerFunc
+}
+
+// Address implements the Signer interface
+func (s *hashSigner) Address() string {
+	return s.txs.Account.Address
+}
+
+// String implements the Signer interface
+func (s *hashSigner) String() string {
+	return fmt.Sprintf("%s signer for account %s; address: %s", s.kind, s.txs.Account.ID, s.txs.Account.Address)
+}
+
+// Sign implements the Signer interface
+func (s *hashSigner) Sign(tx *Transaction) (signedTx interface{}, hash []byte, err error) {
+	if !s.txs.Network.IsEthereumNetwork() {
+		return nil, nil, fmt.Errorf("unable to generate signed tx for unsupported network: %s", *s.txs.Network.Name)
+	}
+	return s.txs.signEVMTx(tx, s.Address(), s.signHash)
+}
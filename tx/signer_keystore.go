@@ -0,0 +1,79 @@
+package tx
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/accounts/keystore"
+	ethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/provideplatform/nchain/common"
+)
+
+// keystoreSignerFactory returns a Signer which signs using the geth JSON keystore of the account; the keystore
+// is provided inline in the encrypted account config, or read from the configured keystore directory, and is
+// decrypted using the passphrase held in the encrypted account config
+func keystoreSignerFactory(txs *TransactionSigner) (Signer, error) {
+	cfg, err := txs.Account.SignerConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	keyJSON, err := readKeystore(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	passphrase, _ := cfg["passphrase"].(string)
+	key, err := keystore.DecryptKey(keyJSON, passphrase)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt keystore; %s", err.Error())
+	}
+
+	if key.Address != ethcommon.HexToAddress(txs.Account.Address) {
+		return nil, fmt.Errorf("keystore address %s does not match account address %s", key.Address.Hex(), txs.Account.Address)
+	}
+
+	return &hashSigner{
+		txs:  txs,
+		kind: "keystore",
+		signHash: func(hash []byte) ([]byte, error) {
+			return crypto.Sign(hash, key.PrivateKey)
+		},
+	}, nil
+}
+
+// readKeystore reads the geth JSON keystore from the given account signer config
+func readKeystore(cfg map[string]interface{}) ([]byte, error) {
+	switch ks := cfg["keystore"].(type) {
+	case string:
+		return []byte(ks), nil
+	case map[string]interface{}:
+		return json.Marshal(ks)
+	}
+
+	path, pathOk := cfg["keystore_path"].(string)
+	if !pathOk || path == "" {
+		return nil, fmt.Errorf("no keystore configured")
+	}
+
+	if common.DefaultKeystorePath == "" {
+		return nil, fmt.Errorf("unable to read keystore %s; KEYSTORE_PATH is not configured", path)
+	}
+
+	// keystore paths are resolved within the configured keystore directory
+	root, _ := filepath.Abs(common.DefaultKeystorePath)
+	resolved := filepath.Join(root, filepath.Clean(string(filepath.Separator)+path))
+	if !strings.HasPrefix(resolved, root+string(filepath.Separator)) {
+		return nil, fmt.Errorf("keystore path %s is outside of the keystore directory", path)
+	}
+
+	keyJSON, err := ioutil.ReadFile(resolved)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read keystore %s; %s", path, err.Error())
+	}
+	return keyJSON, nil
+}
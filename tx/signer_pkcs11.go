@@ -0,0 +1,215 @@
+// +build pkcs11
+
+package tx
+
+import (
+	"bytes"
+	"encoding/asn1"
+	"encoding/hex"
+	"fmt"
+	"math/big"
+	"strconv"
+	"sync"
+
+	ethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/miekg/pkcs11"
+	"github.com/provideplatform/nchain/common"
+)
+
+// pkcs11Context is the PKCS#11 module loaded from the configured module path; the module is
+// initialized once and shared by all PKCS#11 signers
+var pkcs11Context *pkcs11.Ctx
+var pkcs11ContextMutex sync.Mutex
+
+// secp256k1N is the order of the secp256k1 curve
+var secp256k1N, _ = new(big.Int).SetString("fffffffffffffffffffffffffffffffebaaedce6af48a03bbfd25e8cd0364141", 16)
+
+// pkcs11SignerFactory returns a Signer which signs using a secp256k1 key held by a PKCS#11 token
+func pkcs11SignerFactory(txs *TransactionSigner) (Signer, error) {
+	cfg, err := txs.Account.SignerConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, err := pkcs11ContextFactory()
+	if err != nil {
+		return nil, err
+	}
+
+	slot, err := pkcs11Slot(ctx, cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	pin, _ := cfg["pin"].(string)
+	template := []*pkcs11.Attribute{
+		pkcs11.NewAttribute(pkcs11.CKA_CLASS, pkcs11.CKO_PRIVATE_KEY),
+		pkcs11.NewAttribute(pkcs11.CKA_KEY_TYPE, pkcs11.CKK_EC),
+	}
+	if label, labelOk := cfg["key_label"].(string); labelOk {
+		template = append(template, pkcs11.NewAttribute(pkcs11.CKA_LABEL, label))
+	}
+	if keyID, keyIDOk := cfg["key_id"].(string); keyIDOk {
+		id, err := hex.DecodeString(keyID)
+		if err != nil {
+			return nil, fmt.Errorf("invalid PKCS#11 key_id %s; %s", keyID, err.Error())
+		}
+		template = append(template, pkcs11.NewAttribute(pkcs11.CKA_ID, id))
+	}
+
+	address := ethcommon.HexToAddress(txs.Account.Address)
+
+	return &hashSigner{
+		txs:  txs,
+		kind: "pkcs11",
+		signHash: func(hash []byte) ([]byte, error) {
+			sig, err := pkcs11Sign(ctx, slot, pin, template, hash)
+			if err != nil {
+				return nil, err
+			}
+			return recoverableSignature(hash, sig, address)
+		},
+	}, nil
+}
+
+// pkcs11ContextFactory loads and initializes the PKCS#11 module configured by PKCS11_MODULE_PATH
+func pkcs11ContextFactory() (*pkcs11.Ctx, error) {
+	pkcs11ContextMutex.Lock()
+	defer pkcs11ContextMutex.Unlock()
+
+	if pkcs11Context != nil {
+		return pkcs11Context, nil
+	}
+
+	if common.DefaultPKCS11ModulePath == "" {
+		return nil, fmt.Errorf("unable to load PKCS#11 module; PKCS11_MODULE_PATH is not configured")
+	}
+
+	ctx := pkcs11.New(common.DefaultPKCS11ModulePath)
+	if ctx == nil {
+		return nil, fmt.Errorf("failed to load PKCS#11 module %s", common.DefaultPKCS11ModulePath)
+	}
+
+	err := ctx.Initialize()
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize PKCS#11 module %s; %s", common.DefaultPKCS11ModulePath, err.Error())
+	}
+
+	common.Log.Debugf("initialized PKCS#11 module %s", common.DefaultPKCS11ModulePath)
+	pkcs11Context = ctx
+	return pkcs11Context, nil
+}
+
+// pkcs11Slot resolves the slot of the token identified by the token_label or slot of the given signer config
+func pkcs11Slot(ctx *pkcs11.Ctx, cfg map[string]interface{}) (uint, error) {
+	slots, err := ctx.GetSlotList(true)
+	if err != nil {
+		return 0, fmt.Errorf("failed to list PKCS#11 slots; %s", err.Error())
+	}
+
+	if label, labelOk := cfg["token_label"].(string); labelOk {
+		for _, slot := range slots {
+			info, err := ctx.GetTokenInfo(slot)
+			if err == nil && info.Label == label {
+				return slot, nil
+			}
+		}
+		return 0, fmt.Errorf("PKCS#11 token %s not found", label)
+	}
+
+	var slotID uint64
+	switch s := cfg["slot"].(type) {
+	case float64:
+		slotID = uint64(s)
+	case string:
+		slotID, err = strconv.ParseUint(s, 10, 64)
+		if err != nil {
+			return 0, fmt.Errorf("invalid PKCS#11 slot %s", s)
+		}
+	default:
+		return 0, fmt.Errorf("no PKCS#11 token_label or slot configured")
+	}
+
+	for _, slot := range slots {
+		if uint64(slot) == slotID {
+			return slot, nil
+		}
+	}
+	return 0, fmt.Errorf("PKCS#11 slot %d not found", slotID)
+}
+
+// pkcs11Sign signs the given hash with CKM_ECDSA using the private key matching the given template;
+// the raw [R || S] signature is returned
+func pkcs11Sign(ctx *pkcs11.Ctx, slot uint, pin string, template []*pkcs11.Attribute, hash []byte) ([]byte, error) {
+	session, err := ctx.OpenSession(slot, pkcs11.CKF_SERIAL_SESSION)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open PKCS#11 session; %s", err.Error())
+	}
+	defer ctx.CloseSession(session)
+
+	err = ctx.Login(session, pkcs11.CKU_USER, pin)
+	if err != nil && err != pkcs11.Error(pkcs11.CKR_USER_ALREADY_LOGGED_IN) {
+		return nil, fmt.Errorf("failed to login to PKCS#11 token; %s", err.Error())
+	}
+
+	err = ctx.FindObjectsInit(session, template)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find PKCS#11 private key; %s", err.Error())
+	}
+	keys, _, err := ctx.FindObjects(session, 1)
+	ctx.FindObjectsFinal(session)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find PKCS#11 private key; %s", err.Error())
+	}
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("PKCS#11 private key not found")
+	}
+
+	err = ctx.SignInit(session, []*pkcs11.Mechanism{pkcs11.NewMechanism(pkcs11.CKM_ECDSA, nil)}, keys[0])
+	if err != nil {
+		return nil, fmt.Errorf("failed to sign using PKCS#11 private key; %s", err.Error())
+	}
+
+	sig, err := ctx.Sign(session, hash)
+	if err != nil {
+		return nil, fmt.Errorf("failed to sign using PKCS#11 private key; %s", err.Error())
+	}
+	return sig, nil
+}
+
+// recoverableSignature converts the given [R || S] or DER-encoded ECDSA signature to the 65-byte [R || S || V]
+// secp256k1 signature of the given address; S is normalized to the lower half of the curve order per EIP-2
+func recoverableSignature(hash, sig []byte, address ethcommon.Address) ([]byte, error) {
+	var r, s *big.Int
+	if len(sig) == 64 {
+		r = new(big.Int).SetBytes(sig[:32])
+		s = new(big.Int).SetBytes(sig[32:])
+	} else {
+		var der struct{ R, S *big.Int }
+		_, err := asn1.Unmarshal(sig, &der)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse ECDSA signature; %s", err.Error())
+		}
+		r, s = der.R, der.S
+	}
+
+	halfN := new(big.Int).Rsh(secp256k1N, 1)
+	if s.Cmp(halfN) > 0 {
+		s = new(big.Int).Sub(secp256k1N, s)
+	}
+
+	_sig := make([]byte, 65)
+	copy(_sig[32-len(r.Bytes()):32], r.Bytes())
+	copy(_sig[64-len(s.Bytes()):64], s.Bytes())
+
+	for v := byte(0); v < 2; v++ {
+		_sig[64] = v
+		pubkey, err := crypto.SigToPub(hash, _sig)
+		if err == nil && bytes.Equal(crypto.PubkeyToAddress(*pubkey).Bytes(), address.Bytes()) {
+			return _sig, nil
+		}
+	}
+
+	return nil, fmt.Errorf("ECDSA signature does not recover to address %s", address.Hex())
+}
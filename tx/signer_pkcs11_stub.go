@@ -0,0 +1,12 @@
+// +build !pkcs11
+
+package tx
+
+import (
+	"fmt"
+)
+
+// pkcs11SignerFactory is unavailable unless nchain is built with cgo and the pkcs11 build tag
+func pkcs11SignerFactory(txs *TransactionSigner) (Signer, error) {
+	return nil, fmt.Errorf("PKCS#11 signer support not compiled in; build with -tags pkcs11")
+}
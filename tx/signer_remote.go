@@ -0,0 +1,359 @@
+package tx
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"time"
+
+	ethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/provideplatform/nchain/common"
+	providecrypto "github.com/provideplatform/provide-go/crypto"
+)
+
+// remoteSignerDefaultMethod is the JSON-RPC method used to sign txs using a remote signer, i.e. Clef
+const remoteSignerDefaultMethod = "account_signTransaction"
+
+// remoteSignerTimeout is the maximum duration to wait for a remote signer to sign a tx
+const remoteSignerTimeout = time.Second * 60
+
+// remoteSigner is a Signer which builds tx args locally and delegates signing to a remote signer
+// implementing the Clef/Web3Signer account_signTransaction JSON-RPC method
+type remoteSigner struct {
+	txs    *TransactionSigner
+	url    string
+	method string
+	client *rpc.Client
+}
+
+// remoteSignerTxArgs are the tx args signed by the remote signer
+type remoteSignerTxArgs struct {
+	From                 ethcommon.Address  `json:"from"`
+	To                   *ethcommon.Address `json:"to,omitempty"`
+	Gas                  hexutil.Uint64     `json:"gas"`
+	GasPrice             *hexutil.Big       `json:"gasPrice,omitempty"`
+	MaxFeePerGas         *hexutil.Big       `json:"maxFeePerGas,omitempty"`
+	MaxPriorityFeePerGas *hexutil.Big       `json:"maxPriorityFeePerGas,omitempty"`
+	Value                hexutil.Big        `json:"value"`
+	Nonce                hexutil.Uint64     `json:"nonce"`
+	Data                 *hexutil.Bytes     `json:"data,omitempty"`
+	Input                *hexutil.Bytes     `json:"input,omitempty"`
+	ChainID              *hexutil.Big       `json:"chainId,omitempty"`
+	AccessList           *AccessList        `json:"accessList,omitempty"`
+}
+
+// remoteSignerResult is the account_signTransaction response
+type remoteSignerResult struct {
+	Raw hexutil.Bytes `json:"raw"`
+}
+
+// remoteSignerFactory returns a Signer which signs using the remote signer at the url of the account config
+func remoteSignerFactory(txs *TransactionSigner) (Signer, error) {
+	cfg, err := txs.Account.SignerConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	url, urlOk := cfg["url"].(string)
+	if !urlOk || url == "" {
+		return nil, fmt.Errorf("no remote signer url configured for account %s", txs.Account.ID)
+	}
+
+	method := remoteSignerDefaultMethod
+	if m, methodOk := cfg["method"].(string); methodOk && m != "" {
+		method = m
+	}
+
+	err = common.ValidatePublicURL(url, common.AllowInsecureRemoteSigners)
+	if err != nil {
+		return nil, fmt.Errorf("invalid remote signer url for account %s; %s", txs.Account.ID, err.Error())
+	}
+
+	client, err := rpc.DialHTTPWithClient(url, &http.Client{
+		Timeout: remoteSignerTimeout,
+		Transport: &http.Transport{
+			DialContext: common.PublicDialContext(remoteSignerTimeout, common.AllowInsecureRemoteSigners),
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to dial remote signer %s; %s", url, err.Error())
+	}
+
+	if authorization, authorizationOk := cfg["authorization"].(string); authorizationOk {
+		client.SetHeader("Authorization", authorization)
+	}
+
+	return &remoteSigner{
+		txs:    txs,
+		url:    url,
+		method: method,
+		client: client,
+	}, nil
+}
+
+// Address implements the Signer interface
+func (s *remoteSigner) Address() string {
+	return s.txs.Account.Address
+}
+
+// String implements the Signer interface
+func (s *remoteSigner) String() string {
+	return fmt.Sprintf("remote signer %s for account %s; address: %s", s.url, s.txs.Account.ID, s.txs.Account.Address)
+}
+
+// Sign implements the Signer interface
+func (s *remoteSigner) Sign(tx *Transaction) (signedTx interface{}, hash []byte, err error) {
+	if !s.txs.Network.IsEthereumNetwork() {
+		return nil, nil, fmt.Errorf("unable to generate signed tx for unsupported network: %s", *s.txs.Network.Name)
+	}
+
+	address := s.Address()
+	gas, gasPrice, nonce := signingParams(tx)
+
+	fees, err := resolveDynamicFees(s.txs.Network, tx.ParseParams(), gasPrice)
+	if err != nil {
+		err = fmt.Errorf("failed to resolve dynamic fees for tx; %s", err.Error())
+		common.Log.Warning(err.Error())
+		return nil, nil, err
+	}
+
+	accessList, err := tx.resolveAccessList(s.txs.Network, address, gas)
+	if err != nil {
+		err = fmt.Errorf("failed to resolve access list for tx; %s", err.Error())
+		common.Log.Warning(err.Error())
+		return nil, nil, err
+	}
+
+	signedTx, hash, err = s.txs.withManagedNonce(address, nonce, func(nonce *uint64) (interface{}, []byte, error) {
+		return s.signTx(tx, address, nonce, gas, gasPrice, fees, accessList)
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	s.txs.touch()
+	return signedTx, hash, nil
+}
+
+// signTx requests the remote signer to sign the tx using the given nonce, gas and fees, and verifies the signed tx
+func (s *remoteSigner) signTx(tx *Transaction, address string, nonce *uint64, gas uint64, gasPrice *uint64, fees *dynamicFees, accessList AccessList) (signedTx interface{}, hash []byte, err error) {
+	args, err := s.txArgs(tx, address, nonce, gas, gasPrice, fees)
+	if err != nil {
+		err = fmt.Errorf("failed to sign tx using %s; %s", s.String(), err.Error())
+		common.Log.Warning(err.Error())
+		return nil, nil, err
+	}
+	if accessList != nil {
+		args.AccessList = &accessList
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), remoteSignerTimeout)
+	defer cancel()
+
+	var result json.RawMessage
+	err = s.client.CallContext(ctx, &result, s.method, args)
+	if err != nil {
+		err = fmt.Errorf("failed to sign tx using %s; %s", s.String(), err.Error())
+		common.Log.Warning(err.Error())
+		return nil, nil, err
+	}
+
+	signedTx, hash, err = decodeRemoteSignedTx(result)
+	if err != nil {
+		err = fmt.Errorf("failed to decode tx signed using %s; %s", s.String(), err.Error())
+		common.Log.Warning(err.Error())
+		return nil, nil, err
+	}
+
+	err = verifyRemoteSignedTx(signedTx, args)
+	if err != nil {
+		err = fmt.Errorf("rejected tx signed using %s; %s", s.String(), err.Error())
+		common.Log.Warning(err.Error())
+		return nil, nil, err
+	}
+
+	txNonce := uint64(args.Nonce)
+	tx.Nonce = &txNonce
+	common.Log.Debugf("signed tx using %s; nonce: %d", s.String(), txNonce)
+
+	return signedTx, hash, nil
+}
+
+// txArgs resolves the tx args signed by the remote signer; unresolved nonce, gas and fee params are read from the network
+func (s *remoteSigner) txArgs(tx *Transaction, address string, nonce *uint64, gas uint64, gasPrice *uint64, fees *dynamicFees) (*remoteSignerTxArgs, error) {
+	client, err := providecrypto.EVMDialJsonRpc(s.txs.Network.ID.String(), s.txs.Network.RPCURL())
+	if err != nil {
+		return nil, err
+	}
+
+	chainID, err := client.ChainID(context.TODO())
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve chain id; %s", err.Error())
+	}
+
+	if nonce == nil {
+		pendingNonce, err := client.PendingNonceAt(context.TODO(), ethcommon.HexToAddress(address))
+		if err != nil {
+			return nil, fmt.Errorf("failed to resolve pending nonce; %s", err.Error())
+		}
+		nonce = &pendingNonce
+	}
+
+	if gas == 0 {
+		gas, err = tx.estimateGas(s.txs.Network, address)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	args := &remoteSignerTxArgs{
+		From:    ethcommon.HexToAddress(address),
+		Gas:     hexutil.Uint64(gas),
+		Value:   hexutil.Big(*tx.Value.BigInt()),
+		Nonce:   hexutil.Uint64(*nonce),
+		ChainID: (*hexutil.Big)(chainID),
+	}
+
+	if tx.To != nil {
+		to := ethcommon.HexToAddress(*tx.To)
+		args.To = &to
+	}
+
+	if tx.Data != nil {
+		data := hexutil.Bytes(ethcommon.FromHex(*tx.Data))
+		args.Data = &data
+		args.Input = &data
+	}
+
+	if fees != nil {
+		args.MaxFeePerGas = (*hexutil.Big)(fees.GasFeeCap)
+		args.MaxPriorityFeePerGas = (*hexutil.Big)(fees.GasTipCap)
+	} else if gasPrice != nil {
+		args.GasPrice = (*hexutil.Big)(new(big.Int).SetUint64(*gasPrice))
+	} else {
+		suggested, err := client.SuggestGasPrice(context.TODO())
+		if err != nil {
+			return nil, fmt.Errorf("failed to resolve gas price; %s", err.Error())
+		}
+		args.GasPrice = (*hexutil.Big)(suggested)
+	}
+
+	return args, nil
+}
+
+// decodeRemoteSignedTx decodes the raw signed tx returned by a remote signer; Clef returns an object
+// containing the raw tx, while Web3Signer-style eth_signTransaction returns the raw tx itself
+func decodeRemoteSignedTx(result json.RawMessage) (interface{}, []byte, error) {
+	var raw hexutil.Bytes
+	var signed remoteSignerResult
+	if err := json.Unmarshal(result, &signed); err == nil && len(signed.Raw) > 0 {
+		raw = signed.Raw
+	} else if err := json.Unmarshal(result, &raw); err != nil {
+		return nil, nil, errors.New("unexpected remote signer response")
+	}
+
+	if len(raw) == 0 {
+		return nil, nil, fmt.Errorf("remote signer returned an empty tx")
+	}
+
+	// legacy txs are RLP lists; typed txs are prefixed with their tx type
+	if raw[0] >= 0xc0 {
+		signedTx := &types.Transaction{}
+		err := rlp.DecodeBytes(raw, signedTx)
+		if err != nil {
+			return nil, nil, err
+		}
+		return signedTx, signedTx.Hash().Bytes(), nil
+	}
+
+	signedTx := &TypedTransaction{}
+	err := signedTx.UnmarshalBinary(raw)
+	if err != nil {
+		return nil, nil, err
+	}
+	return signedTx, signedTx.Hash().Bytes(), nil
+}
+
+// verifyRemoteSignedTx verifies the tx signed by a remote signer is replay-protected on the chain of the given tx args,
+// is signed by their sender and commits to their recipient, value, data and nonce, which the remote signer may have altered
+func verifyRemoteSignedTx(signedTx interface{}, args *remoteSignerTxArgs) error {
+	var sender ethcommon.Address
+	var to *ethcommon.Address
+	var value *big.Int
+	var data []byte
+	var nonce uint64
+	var err error
+
+	if args.ChainID == nil {
+		return errors.New("unable to verify signed tx without chain id")
+	}
+	chainID := args.ChainID.ToInt()
+
+	switch tx := signedTx.(type) {
+	case *types.Transaction:
+		if !tx.Protected() {
+			return errors.New("signed tx is not replay-protected")
+		}
+		if tx.ChainId().Cmp(chainID) != 0 {
+			return fmt.Errorf("chain id %s does not match chain id %s", tx.ChainId().String(), chainID.String())
+		}
+		sender, err = types.Sender(types.NewEIP155Signer(chainID), tx)
+		to, value, data, nonce = tx.To(), tx.Value(), tx.Data(), tx.Nonce()
+	case *TypedTransaction:
+		if tx.ChainID == nil || tx.ChainID.Cmp(chainID) != 0 {
+			return fmt.Errorf("chain id %v does not match chain id %s", tx.ChainID, chainID.String())
+		}
+		sender, err = tx.Sender()
+		to, value, data, nonce = tx.To, tx.Value, tx.Data, tx.Nonce
+	default:
+		return fmt.Errorf("unsupported signed tx: %T", signedTx)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to recover sender; %s", err.Error())
+	}
+
+	if sender != args.From {
+		return fmt.Errorf("sender %s does not match signer %s", sender.Hex(), args.From.Hex())
+	}
+
+	if (to == nil) != (args.To == nil) || (to != nil && *to != *args.To) {
+		return fmt.Errorf("recipient %s does not match recipient %s", addressOrNil(to), addressOrNil(args.To))
+	}
+
+	if value == nil {
+		value = big.NewInt(0)
+	}
+	if value.Cmp(args.Value.ToInt()) != 0 {
+		return fmt.Errorf("value %s does not match value %s", value.String(), args.Value.ToInt().String())
+	}
+
+	var argsData []byte
+	if args.Data != nil {
+		argsData = *args.Data
+	}
+	if !bytes.Equal(data, argsData) {
+		return fmt.Errorf("%d-byte data does not match %d-byte data", len(data), len(argsData))
+	}
+
+	if nonce != uint64(args.Nonce) {
+		return fmt.Errorf("nonce %d does not match nonce %d", nonce, uint64(args.Nonce))
+	}
+
+	return nil
+}
+
+// addressOrNil returns the hex representation of the given address, or "nil" for contract creation
+func addressOrNil(addr *ethcommon.Address) string {
+	if addr == nil {
+		return "nil"
+	}
+	return addr.Hex()
+}
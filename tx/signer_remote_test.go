@@ -0,0 +1,210 @@
+// +build unit
+
+package tx
+
+import (
+	"encoding/json"
+	"math/big"
+	"strings"
+	"testing"
+
+	ethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	ethcrypto "github.com/ethereum/go-ethereum/crypto"
+	"github.com/provideplatform/nchain/common"
+	"github.com/provideplatform/nchain/wallet"
+)
+
+// remoteSignerTxArgsFactory returns the tx args sent to the remote signer on behalf of the envelope vector sender
+func remoteSignerTxArgsFactory() *remoteSignerTxArgs {
+	data := hexutil.Bytes(ethcommon.FromHex("0xa9059cbb"))
+	return &remoteSignerTxArgs{
+		From:    ethcommon.HexToAddress(envelopeVectorSender),
+		To:      &envelopeVectorRecipient,
+		Gas:     hexutil.Uint64(50000),
+		Value:   hexutil.Big(*big.NewInt(1000)),
+		Nonce:   hexutil.Uint64(7),
+		Data:    &data,
+		Input:   &data,
+		ChainID: (*hexutil.Big)(big.NewInt(5)),
+	}
+}
+
+// remoteSignedTypedTx returns the dynamic fee tx signed by the remote signer in response to the given args
+func remoteSignedTypedTx(t *testing.T, args *remoteSignerTxArgs) *TypedTransaction {
+	key, _ := ethcrypto.HexToECDSA(envelopeVectorPrivateKey)
+	tx := &TypedTransaction{
+		Type:      dynamicFeeTxType,
+		ChainID:   args.ChainID.ToInt(),
+		Nonce:     uint64(args.Nonce),
+		GasTipCap: big.NewInt(1000000000),
+		GasFeeCap: big.NewInt(2000000000),
+		Gas:       uint64(args.Gas),
+		To:        args.To,
+		Value:     args.Value.ToInt(),
+		Data:      *args.Data,
+	}
+
+	sigHash, _ := tx.SigningHash()
+	sig, err := ethcrypto.Sign(sigHash, key)
+	if err != nil {
+		t.Fatalf("failed to sign typed tx; %s", err.Error())
+	}
+	sig[64] += 27
+	tx.WithSignature(sig)
+	return tx
+}
+
+// remoteSignedLegacyTx returns the EIP-155 tx signed by the remote signer in response to the given args
+func remoteSignedLegacyTx(t *testing.T, args *remoteSignerTxArgs) *types.Transaction {
+	key, _ := ethcrypto.HexToECDSA(envelopeVectorPrivateKey)
+	tx := types.NewTransaction(uint64(args.Nonce), *args.To, args.Value.ToInt(), uint64(args.Gas), big.NewInt(1000000000), *args.Data)
+	signedTx, err := types.SignTx(tx, types.NewEIP155Signer(args.ChainID.ToInt()), key)
+	if err != nil {
+		t.Fatalf("failed to sign legacy tx; %s", err.Error())
+	}
+	return signedTx
+}
+
+func TestRemoteSignedTxMatchingArgsIsAccepted(t *testing.T) {
+	args := remoteSignerTxArgsFactory()
+
+	if err := verifyRemoteSignedTx(remoteSignedTypedTx(t, args), args); err != nil {
+		t.Errorf("expected typed tx matching args to be accepted; %s", err.Error())
+	}
+	if err := verifyRemoteSignedTx(remoteSignedLegacyTx(t, args), args); err != nil {
+		t.Errorf("expected legacy tx matching args to be accepted; %s", err.Error())
+	}
+}
+
+func TestRemoteSignedTxMismatchingArgsIsRejected(t *testing.T) {
+	mismatches := map[string]func(args *remoteSignerTxArgs){
+		"sender": func(args *remoteSignerTxArgs) {
+			args.From = ethcommon.HexToAddress("0x4242424242424242424242424242424242424242")
+		},
+		"recipient": func(args *remoteSignerTxArgs) {
+			to := ethcommon.HexToAddress("0x4343434343434343434343434343434343434343")
+			args.To = &to
+		},
+		"value": func(args *remoteSignerTxArgs) {
+			args.Value = hexutil.Big(*big.NewInt(1001))
+		},
+		"data": func(args *remoteSignerTxArgs) {
+			data := hexutil.Bytes(ethcommon.FromHex("0x095ea7b3"))
+			args.Data = &data
+		},
+		"nonce": func(args *remoteSignerTxArgs) {
+			args.Nonce = hexutil.Uint64(8)
+		},
+	}
+
+	for field, mismatch := range mismatches {
+		signedArgs := remoteSignerTxArgsFactory()
+		typedTx := remoteSignedTypedTx(t, signedArgs)
+		legacyTx := remoteSignedLegacyTx(t, signedArgs)
+
+		args := remoteSignerTxArgsFactory()
+		mismatch(args)
+
+		if err := verifyRemoteSignedTx(typedTx, args); err == nil {
+			t.Errorf("expected typed tx having mismatched %s to be rejected", field)
+		}
+		if err := verifyRemoteSignedTx(legacyTx, args); err == nil {
+			t.Errorf("expected legacy tx having mismatched %s to be rejected", field)
+		}
+	}
+}
+
+func TestRemoteSignedContractCreationIsRejectedForCall(t *testing.T) {
+	signedArgs := remoteSignerTxArgsFactory()
+	signedArgs.To = nil
+
+	key, _ := ethcrypto.HexToECDSA(envelopeVectorPrivateKey)
+	tx := types.NewContractCreation(uint64(signedArgs.Nonce), signedArgs.Value.ToInt(), uint64(signedArgs.Gas), big.NewInt(1000000000), *signedArgs.Data)
+	signedTx, _ := types.SignTx(tx, types.NewEIP155Signer(signedArgs.ChainID.ToInt()), key)
+
+	if err := verifyRemoteSignedTx(signedTx, remoteSignerTxArgsFactory()); err == nil {
+		t.Errorf("expected contract creation signed in response to a contract call to be rejected")
+	}
+}
+
+func TestRemoteSignerFactoryRejectsRestrictedURLs(t *testing.T) {
+	urls := []string{
+		"http://signer.example.com",
+		"https://127.0.0.1:8550",
+		"https://169.254.169.254/latest/meta-data",
+		"https://10.0.0.1",
+		"https://[::1]:8550",
+		"file:///etc/passwd",
+	}
+
+	for _, url := range urls {
+		cfg, _ := json.Marshal(map[string]interface{}{"url": url})
+		rawCfg := json.RawMessage(cfg)
+		accountType := wallet.AccountTypeRemote
+
+		_, err := remoteSignerFactory(&TransactionSigner{
+			Account: &wallet.Account{
+				Address: envelopeVectorSender,
+				Type:    &accountType,
+				Config:  &rawCfg,
+			},
+		})
+		if err == nil {
+			t.Errorf("expected remote signer url %s to be rejected", url)
+		}
+	}
+}
+
+func TestRemoteSignedTxOfAnotherChainIsRejected(t *testing.T) {
+	args := remoteSignerTxArgsFactory()
+	key, _ := ethcrypto.HexToECDSA(envelopeVectorPrivateKey)
+	tx := types.NewTransaction(uint64(args.Nonce), *args.To, args.Value.ToInt(), uint64(args.Gas), big.NewInt(1000000000), *args.Data)
+
+	unprotectedTx, _ := types.SignTx(tx, types.HomesteadSigner{}, key)
+	if err := verifyRemoteSignedTx(unprotectedTx, args); err == nil {
+		t.Errorf("expected legacy tx which is not replay-protected to be rejected")
+	}
+
+	crossChainTx, _ := types.SignTx(tx, types.NewEIP155Signer(big.NewInt(1)), key)
+	if err := verifyRemoteSignedTx(crossChainTx, args); err == nil {
+		t.Errorf("expected legacy tx signed for another chain to be rejected")
+	}
+
+	signedArgs := remoteSignerTxArgsFactory()
+	signedArgs.ChainID = (*hexutil.Big)(big.NewInt(1))
+	if err := verifyRemoteSignedTx(remoteSignedTypedTx(t, signedArgs), args); err == nil {
+		t.Errorf("expected typed tx signed for another chain to be rejected")
+	}
+}
+
+func TestUnexpectedRemoteSignerResponseIsNotEchoed(t *testing.T) {
+	_, _, err := decodeRemoteSignedTx(json.RawMessage(`{"ami-id":"ami-0abcdef1234567890"}`))
+	if err == nil {
+		t.Fatalf("expected unexpected remote signer response to be rejected")
+	}
+	if strings.Contains(err.Error(), "ami-") {
+		t.Errorf("expected remote signer response to be omitted from error; got %s", err.Error())
+	}
+}
+
+func TestRemoteSignerTxArgsApplyGasEstimateMultiplier(t *testing.T) {
+	ntwrk, srv := gasEstimateStubFactory(t)
+	defer srv.Close()
+
+	tx := &Transaction{
+		To:    common.StringOrNil(envelopeVectorRecipient.Hex()),
+		Value: NewTxValue(1),
+	}
+	s := &remoteSigner{txs: &TransactionSigner{Network: ntwrk}}
+	args, err := s.txArgs(tx, envelopeVectorSender, nil, 0, nil, nil)
+	if err != nil {
+		t.Fatalf("failed to resolve remote signer tx args; %s", err.Error())
+	}
+
+	expected := uint64(float64(21000) * ntwrk.GasEstimateMultiplier())
+	if uint64(args.Gas) != expected {
+		t.Errorf("expected estimated gas of %d to be multiplied to %d; got %d", 21000, expected, uint64(args.Gas))
+	}
+}
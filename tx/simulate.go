@@ -0,0 +1,288 @@
+package tx
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	ethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/jinzhu/gorm"
+	uuid "github.com/kthomas/go.uuid"
+	"github.com/provideplatform/nchain/common"
+	"github.com/provideplatform/nchain/contract"
+	"github.com/provideplatform/nchain/network"
+	"github.com/provideplatform/nchain/network/p2p"
+	providecrypto "github.com/provideplatform/provide-go/crypto"
+)
+
+// Simulation is the result of a tx dry-run; a simulated tx is never signed or persisted
+type Simulation struct {
+	Block        string            `json:"block"`
+	Success      bool              `json:"success"`
+	Output       *string           `json:"output,omitempty"`
+	Response     interface{}       `json:"response,omitempty"`
+	GasUsed      *uint64           `json:"gas_used,omitempty"`
+	Events       []*SimulatedEvent `json:"events,omitempty"`
+	RevertReason *RevertReason     `json:"revert_reason,omitempty"`
+	Trace        *p2p.CallFrame    `json:"trace,omitempty"`
+}
+
+// SimulatedEvent is a log emitted during a tx dry-run, decoded using the ABI of the emitting contract when it is known
+type SimulatedEvent struct {
+	Address    string                 `json:"address"`
+	ContractID *uuid.UUID             `json:"contract_id,omitempty"`
+	Name       *string                `json:"name,omitempty"`
+	Signature  *string                `json:"signature,omitempty"`
+	Params     map[string]interface{} `json:"params,omitempty"`
+	Topics     []ethcommon.Hash       `json:"topics"`
+	Data       hexutil.Bytes          `json:"data"`
+}
+
+// Simulate executes the tx via eth_call against the given block, or the latest block if none is given, and
+// traces the call via debug_traceCall when supported by the network p2p provider; the tx is neither signed nor persisted
+func (t *Transaction) Simulate(db *gorm.DB, block *uint64) (*Simulation, error) {
+	signer, err := t.signerFactory(db)
+	if err != nil {
+		return nil, err
+	}
+
+	if !signer.Network.IsEthereumNetwork() {
+		return nil, fmt.Errorf("unable to simulate tx for unsupported network: %s", *signer.Network.Name)
+	}
+
+	params := t.ParseParams()
+
+	gas := uint64(0)
+	if _gas, gasOk := params["gas"].(float64); gasOk {
+		gas = uint64(_gas)
+	}
+
+	gasPrice := uint64(0)
+	if gp, gpOk := params["gas_price"].(float64); gpOk {
+		gasPrice = uint64(gp)
+	}
+
+	from := signer.Address()
+	msg := t.asEthereumCallMsg(from, gasPrice, gas)
+
+	var blockNumber *big.Int
+	blockParam := "latest"
+	if block != nil {
+		blockNumber = new(big.Int).SetUint64(*block)
+		blockParam = hexutil.EncodeUint64(*block)
+	}
+
+	client, err := providecrypto.EVMDialJsonRpc(signer.Network.ID.String(), signer.Network.RPCURL())
+	if err != nil {
+		return nil, fmt.Errorf("failed to dial JSON-RPC host; %s", err.Error())
+	}
+
+	simulation := &Simulation{
+		Block: blockParam,
+	}
+
+	output, err := client.CallContract(context.TODO(), msg, blockNumber)
+	if err != nil {
+		if _, rpcErrOk := err.(rpc.Error); !rpcErrOk {
+			return nil, fmt.Errorf("failed to simulate tx; %s", err.Error())
+		}
+
+		simulation.RevertReason = t.revertReason(db, err)
+		if simulation.RevertReason == nil {
+			simulation.RevertReason = &RevertReason{
+				Type:    revertReasonTypeUnknown,
+				Message: common.StringOrNil(err.Error()),
+			}
+		}
+	} else {
+		simulation.Success = true
+		simulation.Output = common.StringOrNil(hexutil.Encode(output))
+		simulation.Response = t.decodeOutput(db, output)
+	}
+
+	simulation.Trace = t.traceCall(signer.Network, from, msg.Value, gas, gasPrice, blockParam)
+	if simulation.Trace != nil {
+		gasUsed := uint64(simulation.Trace.GasUsed)
+		simulation.GasUsed = &gasUsed
+		simulation.Events = decodeSimulatedEvents(db, t.NetworkID, simulation.Trace)
+	} else if simulation.Success {
+		gasUsed, err := client.EstimateGas(context.TODO(), msg)
+		if err == nil {
+			simulation.GasUsed = &gasUsed
+		}
+	}
+
+	common.Log.Debugf("simulated tx from %s on network: %s; block: %s; success: %v", from, t.NetworkID, blockParam, simulation.Success)
+	return simulation, nil
+}
+
+// traceCall traces the simulated call when the network p2p provider supports debug_traceCall
+func (t *Transaction) traceCall(ntwrk *network.Network, from string, value *big.Int, gas, gasPrice uint64, block string) *p2p.CallFrame {
+	p2pAPI, err := ntwrk.P2PAPIClient()
+	if err != nil {
+		return nil
+	}
+
+	tracer, tracerOk := p2pAPI.(p2p.CallTracer)
+	if !tracerOk {
+		return nil
+	}
+
+	call := map[string]interface{}{
+		"from": from,
+	}
+	if t.To != nil {
+		call["to"] = *t.To
+	}
+	if t.Data != nil {
+		call["data"] = *t.Data
+	}
+	if value != nil {
+		call["value"] = hexutil.EncodeBig(value)
+	}
+	if gas > 0 {
+		call["gas"] = hexutil.EncodeUint64(gas)
+	}
+	if gasPrice > 0 {
+		call["gasPrice"] = hexutil.EncodeUint64(gasPrice)
+	}
+
+	frame, err := tracer.TraceCall(call, block)
+	if err != nil {
+		common.Log.Debugf("failed to trace simulated tx on network: %s; %s", ntwrk.ID, err.Error())
+		return nil
+	}
+
+	return frame
+}
+
+// decodeOutput decodes the return values of the simulated call using the ABI of the contract the tx was sent to
+func (t *Transaction) decodeOutput(db *gorm.DB, output []byte) interface{} {
+	if t.To == nil || t.Data == nil {
+		return nil
+	}
+
+	kontract := t.GetContract(db)
+	if kontract == nil || kontract.ID == uuid.Nil {
+		return nil
+	}
+
+	method := abiMethodBySelector(kontract, ethcommon.FromHex(*t.Data))
+	if method == nil || len(method.Outputs) == 0 {
+		return nil
+	}
+
+	vals, err := method.Outputs.UnpackValues(output)
+	if err != nil {
+		common.Log.Debugf("failed to decode simulated %s output for contract: %s; %s", method.Name, kontract.ID, err.Error())
+		return nil
+	}
+
+	if len(vals) == 1 {
+		return vals[0]
+	}
+	return vals
+}
+
+// abiMethodBySelector resolves the ABI method of the contract matching the selector of the given calldata
+func abiMethodBySelector(kontract *contract.Contract, data []byte) *abi.Method {
+	if len(data) < 4 {
+		return nil
+	}
+
+	_abi, err := kontract.ReadEthereumContractAbi()
+	if err != nil {
+		return nil
+	}
+
+	for _, method := range _abi.Methods {
+		if bytes.Equal(method.ID, data[:4]) {
+			return &method
+		}
+	}
+
+	return nil
+}
+
+// decodeSimulatedEvents decodes the logs emitted throughout the traced call tree, in order
+func decodeSimulatedEvents(db *gorm.DB, networkID uuid.UUID, frame *p2p.CallFrame) []*SimulatedEvent {
+	events := make([]*SimulatedEvent, 0)
+	contracts := map[ethcommon.Address]*contract.Contract{}
+
+	var walk func(*p2p.CallFrame)
+	walk = func(frame *p2p.CallFrame) {
+		if frame.Error != "" {
+			return // logs emitted by reverted frames are discarded
+		}
+
+		for _, log := range frame.Logs {
+			kontract, ok := contracts[log.Address]
+			if !ok {
+				kontract = &contract.Contract{}
+				db.Where("network_id = ? AND address = ?", networkID, log.Address.Hex()).Find(&kontract)
+				contracts[log.Address] = kontract
+			}
+			events = append(events, decodeSimulatedEvent(kontract, log))
+		}
+
+		for _, call := range frame.Calls {
+			walk(call)
+		}
+	}
+
+	walk(frame)
+	return events
+}
+
+// decodeSimulatedEvent decodes the given log using the ABI of the given contract, if it is known
+func decodeSimulatedEvent(kontract *contract.Contract, log *p2p.CallLog) *SimulatedEvent {
+	event := &SimulatedEvent{
+		Address: log.Address.Hex(),
+		Topics:  log.Topics,
+		Data:    log.Data,
+	}
+
+	if kontract == nil || kontract.ID == uuid.Nil || len(log.Topics) == 0 {
+		return event
+	}
+	event.ContractID = &kontract.ID
+
+	_abi, err := kontract.ReadEthereumContractAbi()
+	if err != nil {
+		return event
+	}
+
+	for _, abiEvent := range _abi.Events {
+		if abiEvent.ID != log.Topics[0] {
+			continue
+		}
+
+		event.Name = common.StringOrNil(abiEvent.RawName)
+		event.Signature = common.StringOrNil(abiEvent.Sig)
+
+		params := map[string]interface{}{}
+		err := abiEvent.Inputs.NonIndexed().UnpackIntoMap(params, log.Data)
+		if err == nil {
+			indexed := make(abi.Arguments, 0)
+			for _, input := range abiEvent.Inputs {
+				if input.Indexed {
+					indexed = append(indexed, input)
+				}
+			}
+			err = abi.ParseTopicsIntoMap(params, indexed, log.Topics[1:])
+		}
+
+		if err != nil {
+			common.Log.Debugf("failed to decode simulated %s event emitted by contract: %s; %s", abiEvent.RawName, kontract.ID, err.Error())
+		} else {
+			event.Params = params
+		}
+		break
+	}
+
+	return event
+}
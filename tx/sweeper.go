@@ -0,0 +1,320 @@
+package tx
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	ethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/jinzhu/gorm"
+	dbconf "github.com/kthomas/go-db-config"
+	natsutil "github.com/kthomas/go-natsutil"
+	redisutil "github.com/kthomas/go-redisutil"
+	uuid "github.com/kthomas/go.uuid"
+	"github.com/provideplatform/nchain/common"
+	"github.com/provideplatform/nchain/network"
+	providecrypto "github.com/provideplatform/provide-go/crypto"
+)
+
+// txSweeperTickerInterval is the interval at which pending txs are swept for dropped and orphaned txs
+const txSweeperTickerInterval = time.Minute
+
+// txSweeperBatchSize is the maximum number of stuck txs swept per network on each tick
+const txSweeperBatchSize = 256
+
+// txSweeperMaxRebroadcasts is the number of times the signed payload of a dropped tx is
+// rebroadcast before the tx is marked dropped
+const txSweeperMaxRebroadcasts = 3
+
+// txSweeperRebroadcastsTTL is the duration for which the rebroadcast count of a tx is cached
+const txSweeperRebroadcastsTTL = time.Hour * 24
+
+const txStatusDropped = "dropped"
+
+// TxSweepReport is the summary of the most recent sweep of the stuck txs on a single network
+type TxSweepReport struct {
+	NetworkID   uuid.UUID `json:"network_id"`
+	Swept       int       `json:"swept"`       // pending txs older than the network pending tx timeout
+	Pending     int       `json:"pending"`     // stuck txs which remain in the mempool
+	Mined       int       `json:"mined"`       // stuck txs which were mined but never finalized; requeued for receipt
+	Rebroadcast int       `json:"rebroadcast"` // stuck txs which were dropped from the mempool and rebroadcast
+	Dropped     int       `json:"dropped"`     // stuck txs which were marked dropped
+	Replaced    int       `json:"replaced"`    // stuck txs whose nonce was consumed by a different tx
+	Errors      int       `json:"errors"`      // stuck txs which could not be resolved
+	SweptAt     time.Time `json:"swept_at"`
+}
+
+// TxSweepReportKey returns the key, unique-per-network, where the most recent tx sweep report is cached
+func TxSweepReportKey(networkID uuid.UUID) string {
+	return fmt.Sprintf("network.%s.tx.sweep", networkID.String())
+}
+
+// TxSweepMutexKey returns the key, unique-per-network, which represents the distributed lock for sweeping txs
+func TxSweepMutexKey(networkID uuid.UUID) string {
+	return fmt.Sprintf("%s.mutex", TxSweepReportKey(networkID))
+}
+
+// txRebroadcastsKey returns the key where the number of times the tx was rebroadcast by the sweeper is cached
+func txRebroadcastsKey(txID uuid.UUID) string {
+	return fmt.Sprintf("tx.%s.rebroadcasts", txID.String())
+}
+
+// CachedTxSweepReport returns the most recent tx sweep report for the given network
+func CachedTxSweepReport(networkID uuid.UUID) (*TxSweepReport, error) {
+	key := TxSweepReportKey(networkID)
+	raw, err := redisutil.Get(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve cached tx sweep report from key: %s; %s", key, err.Error())
+	}
+
+	report := &TxSweepReport{}
+	err = json.Unmarshal([]byte(*raw), report)
+	if err != nil {
+		return nil, err
+	}
+	return report, nil
+}
+
+// requireTxSweeper starts the sweeper which periodically resolves pending txs which never received a receipt
+func requireTxSweeper() {
+	ticker := time.NewTicker(txSweeperTickerInterval)
+	go func() {
+		for {
+			select {
+			case <-ticker.C:
+				db := dbconf.DatabaseConnection()
+				sweepPendingTxs(db)
+				expireAwaitingApprovalTxs(db)
+			}
+		}
+	}()
+}
+
+// sweepPendingTxs sweeps the stuck txs on each network having pending txs
+func sweepPendingTxs(db *gorm.DB) {
+	var networkIDs []uuid.UUID
+	db.Model(&Transaction{}).Where("status = ? AND hash IS NOT NULL AND block IS NULL", "pending").Pluck("DISTINCT network_id", &networkIDs)
+
+	for _, networkID := range networkIDs {
+		ntwrk := &network.Network{}
+		db.Where("id = ?", networkID).Find(&ntwrk)
+		if ntwrk == nil || ntwrk.ID == uuid.Nil || !ntwrk.IsEthereumNetwork() {
+			continue
+		}
+
+		err := redisutil.WithRedlock(TxSweepMutexKey(ntwrk.ID), func() error {
+			report := sweepNetworkPendingTxs(db, ntwrk)
+
+			raw, _ := json.Marshal(report)
+			redisutil.Set(TxSweepReportKey(ntwrk.ID), string(raw), nil)
+
+			if report.Swept == 0 {
+				return nil
+			}
+
+			logmsg := fmt.Sprintf("swept %d stuck tx(s) on network: %s; pending: %d; mined: %d; rebroadcast: %d; dropped: %d; replaced: %d; errors: %d",
+				report.Swept, ntwrk.ID, report.Pending, report.Mined, report.Rebroadcast, report.Dropped, report.Replaced, report.Errors)
+			if report.Dropped > 0 || report.Replaced > 0 || report.Errors > 0 {
+				common.Log.Warning(logmsg)
+			} else {
+				common.Log.Debug(logmsg)
+			}
+			return nil
+		})
+		if err != nil {
+			common.Log.Warningf("failed to sweep pending txs on network: %s; %s", networkID, err.Error())
+		}
+	}
+}
+
+// sweepNetworkPendingTxs resolves the pending txs on the network which were broadcast longer ago than the
+// network pending tx timeout
+func sweepNetworkPendingTxs(db *gorm.DB, ntwrk *network.Network) *TxSweepReport {
+	report := &TxSweepReport{
+		NetworkID: ntwrk.ID,
+		SweptAt:   time.Now(),
+	}
+
+	threshold := time.Now().Add(-ntwrk.PendingTxTimeout())
+
+	var txs []*Transaction
+	db.Where("network_id = ? AND status = ? AND hash IS NOT NULL AND block IS NULL AND COALESCE(broadcast_at, created_at) < ?", ntwrk.ID, "pending", threshold).
+		Order("created_at ASC").
+		Limit(txSweeperBatchSize).
+		Find(&txs)
+
+	for _, tx := range txs {
+		report.Swept++
+		err := tx.sweep(db, ntwrk, report)
+		if err != nil {
+			report.Errors++
+			common.Log.Debugf("failed to sweep stuck tx: %s; %s", tx.ID, err.Error())
+		}
+	}
+
+	return report
+}
+
+// sweep resolves the stuck tx by checking its presence in the mempool and the nonce state of its sender;
+// a dropped tx is rebroadcast using its stored signed payload until it is marked dropped
+func (t *Transaction) sweep(db *gorm.DB, ntwrk *network.Network, report *TxSweepReport) error {
+	var pendingTx *pendingEthereumTx
+
+	client, err := providecrypto.EVMResolveJsonRpcClient(ntwrk.ID.String(), ntwrk.RPCURL())
+	if err != nil {
+		return fmt.Errorf("failed to dial JSON-RPC host; %s", err.Error())
+	}
+
+	err = client.CallContext(context.TODO(), &pendingTx, "eth_getTransactionByHash", ethcommon.HexToHash(*t.Hash))
+	if err != nil {
+		return fmt.Errorf("failed to resolve tx %s; %s", *t.Hash, err.Error())
+	}
+
+	if pendingTx != nil {
+		if pendingTx.BlockHash != nil && *pendingTx.BlockHash != (ethcommon.Hash{}) {
+			payload, _ := json.Marshal(map[string]interface{}{
+				"transaction_id": t.ID.String(),
+			})
+			natsutil.NatsJetstreamPublish(natsTxReceiptSubject, payload)
+			report.Mined++
+			common.Log.Debugf("stuck tx %s was mined; requeued for receipt", *t.Hash)
+		} else {
+			report.Pending++
+		}
+		return nil
+	}
+
+	from := t.From
+	if from == nil {
+		_, signer, err := t.resolveSigner(db)
+		if err != nil {
+			return err
+		}
+		from = common.StringOrNil(signer.Address())
+	}
+
+	if t.Nonce != nil {
+		ethClient, err := providecrypto.EVMDialJsonRpc(ntwrk.ID.String(), ntwrk.RPCURL())
+		if err != nil {
+			return fmt.Errorf("failed to dial JSON-RPC host; %s", err.Error())
+		}
+
+		nonce, err := ethClient.NonceAt(context.TODO(), ethcommon.HexToAddress(*from), nil)
+		if err != nil {
+			return fmt.Errorf("failed to resolve nonce of %s; %s", *from, err.Error())
+		}
+
+		if *t.Nonce < nonce {
+			t.markNonceConsumed(db, *from)
+			report.Replaced++
+			return nil
+		}
+	}
+
+	rebroadcasts := t.rebroadcasts()
+	if t.Raw != nil && rebroadcasts < txSweeperMaxRebroadcasts {
+		err = sendRawTx(ntwrk.ID.String(), ntwrk.RPCURL(), ethcommon.FromHex(*t.Raw))
+		if err == nil || isNonceConsumedErr(err) {
+			ttl := txSweeperRebroadcastsTTL
+			redisutil.Set(txRebroadcastsKey(t.ID), rebroadcasts+1, &ttl)
+			report.Rebroadcast++
+			common.Log.Debugf("rebroadcast dropped tx %s; attempt %d of %d", *t.Hash, rebroadcasts+1, txSweeperMaxRebroadcasts)
+			return nil
+		}
+		common.Log.Debugf("failed to rebroadcast dropped tx %s; %s", *t.Hash, err.Error())
+	}
+
+	desc := fmt.Sprintf("tx dropped from mempool after %d rebroadcast(s)", rebroadcasts)
+	if t.Raw == nil {
+		desc = "tx dropped from mempool; no signed payload to rebroadcast"
+	}
+	t.updateStatus(db, txStatusDropped, &desc)
+	report.Dropped++
+
+	if t.Nonce != nil && t.managedNonce() {
+		t.releaseDroppedNonce(ntwrk, *from)
+	}
+
+	return nil
+}
+
+// releaseDroppedNonce reclaims the managed nonce of the dropped tx unless a tx using the nonce, i.e. a
+// rebroadcast of the dropped tx, is pending; the nonce manager is otherwise resynced from the pending nonce
+func (t *Transaction) releaseDroppedNonce(ntwrk *network.Network, from string) {
+	manager := nonceManagerFactory(ntwrk, from)
+
+	pending, err := manager.pendingNonce()
+	if err != nil {
+		common.Log.Warningf("failed to resolve pending nonce of %s to release nonce %d of dropped tx: %s; %s", from, *t.Nonce, t.ID, err.Error())
+		return
+	}
+
+	if pending <= *t.Nonce {
+		manager.Reclaim(*t.Nonce)
+		return
+	}
+
+	err = manager.Resync()
+	if err != nil {
+		common.Log.Warningf("failed to resync nonce of %s after dropping tx: %s; %s", from, t.ID, err.Error())
+	}
+}
+
+// markNonceConsumed marks the stuck tx replaced when its nonce was consumed by a different tx; the
+// replacement is linked when it is known
+func (t *Transaction) markNonceConsumed(db *gorm.DB, from string) {
+	replacement := &Transaction{}
+	db.Where("network_id = ? AND \"from\" = ? AND nonce = ? AND id != ? AND status IN (?, ?)", t.NetworkID, from, *t.Nonce, t.ID, "success", "failed").Find(&replacement)
+
+	desc := fmt.Sprintf("nonce %d consumed by a different tx", *t.Nonce)
+	if replacement != nil && replacement.ID != uuid.Nil {
+		t.ReplacedByID = &replacement.ID
+		desc = fmt.Sprintf("replaced by tx: %s", replacement.ID)
+	}
+
+	t.updateStatus(db, txStatusReplaced, &desc)
+	common.Log.Debugf("marked stuck tx %s replaced; %s", t.ID, desc)
+}
+
+// rebroadcasts returns the number of times the tx was rebroadcast by the sweeper
+func (t *Transaction) rebroadcasts() int {
+	raw, err := redisutil.Get(txRebroadcastsKey(t.ID))
+	if err != nil || raw == nil {
+		return 0
+	}
+
+	rebroadcasts, err := strconv.Atoi(*raw)
+	if err != nil {
+		return 0
+	}
+	return rebroadcasts
+}
+
+// rawSignedTx returns the hex-encoded signed payload of the given signed tx, as broadcast via eth_sendRawTransaction
+func rawSignedTx(signedTx interface{}) *string {
+	var encoded []byte
+	var err error
+
+	switch tx := signedTx.(type) {
+	case *types.Transaction:
+		encoded, err = rlp.EncodeToBytes(tx)
+	case *TypedTransaction:
+		encoded, err = tx.MarshalBinary()
+	case *UTXOTransaction:
+		encoded = tx.Raw
+	default:
+		return nil
+	}
+
+	if err != nil {
+		common.Log.Warningf("failed to encode signed tx; %s", err.Error())
+		return nil
+	}
+
+	return common.StringOrNil(hexutil.Encode(encoded))
+}
@@ -0,0 +1,87 @@
+// +build unit
+
+package tx
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	dbconf "github.com/kthomas/go-db-config"
+	uuid "github.com/kthomas/go.uuid"
+	"github.com/provideplatform/nchain/common"
+)
+
+// droppedTxFactory returns a stuck tx having the given managed nonce which is no longer known to the network
+func droppedTxFactory(from string, nonce uint64) *Transaction {
+	accountID, _ := uuid.NewV4()
+	tx := &Transaction{
+		AccountID: &accountID,
+		From:      common.StringOrNil(from),
+		Hash:      common.StringOrNil("0x7c2a4b0d1e9f6a3c5b8d2e4f6a1c3e5b7d9f1a3c5e7b9d1f3a5c7e9b1d3f5a7c"),
+		Nonce:     &nonce,
+	}
+	tx.ID, _ = uuid.NewV4()
+	return tx
+}
+
+func sweeperJSONRPCHandlers(pending *uint64) map[string]jsonRPCHandler {
+	return map[string]jsonRPCHandler{
+		"eth_getTransactionByHash": func(params []json.RawMessage) (interface{}, error) {
+			return nil, nil
+		},
+		"eth_getTransactionCount": func(params []json.RawMessage) (interface{}, error) {
+			var block string
+			json.Unmarshal(params[1], &block)
+			if block == "pending" {
+				return fmt.Sprintf("0x%x", *pending), nil
+			}
+			return "0x0", nil // none of the txs of the signer were mined
+		},
+	}
+}
+
+func TestSweeperReclaimsNonceOfDroppedTx(t *testing.T) {
+	pending := uint64(4)
+	ntwrk, srv := jsonRPCStubFactory(t, sweeperJSONRPCHandlers(&pending))
+	defer srv.Close()
+
+	from := "0x2C8d1BDB3f1e9A9e1a6d2A4b5f4c1a2B3C4d5E6f"
+	manager := nonceManagerFactory(ntwrk, from)
+	nonces, _ := manager.AllocateN(2) // 4 and 5
+
+	// nonce 4 was dropped from the mempool, so the pending nonce of the signer is 4
+	tx := droppedTxFactory(from, nonces[0])
+	err := tx.sweep(dbconf.DatabaseConnection(), ntwrk, &TxSweepReport{})
+	if err != nil {
+		t.Fatalf("failed to sweep dropped tx; %s", err.Error())
+	}
+
+	nonce, _ := manager.Allocate()
+	if *nonce != 4 {
+		t.Errorf("expected nonce 4 of the dropped tx to be reissued; got %d", *nonce)
+	}
+}
+
+func TestSweeperResyncsNonceOfDroppedTxWhichIsPending(t *testing.T) {
+	pending := uint64(10)
+	ntwrk, srv := jsonRPCStubFactory(t, sweeperJSONRPCHandlers(&pending))
+	defer srv.Close()
+
+	from := "0x3C8d1BDB3f1e9A9e1a6d2A4b5f4c1a2B3C4d5E6f"
+	manager := nonceManagerFactory(ntwrk, from)
+	nonces, _ := manager.AllocateN(3) // 10, 11 and 12
+
+	// a tx using nonce 11 is pending, i.e. a rebroadcast of the dropped tx, so the nonce must not be reissued
+	pending = 12
+	tx := droppedTxFactory(from, nonces[1])
+	err := tx.sweep(dbconf.DatabaseConnection(), ntwrk, &TxSweepReport{})
+	if err != nil {
+		t.Fatalf("failed to sweep dropped tx; %s", err.Error())
+	}
+
+	nonce, _ := manager.Allocate()
+	if *nonce != 12 {
+		t.Errorf("expected nonce to be resynced to the pending nonce 12; got %d", *nonce)
+	}
+}
@@ -0,0 +1,248 @@
+package tx
+
+import (
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/jinzhu/gorm"
+	uuid "github.com/kthomas/go.uuid"
+	"github.com/provideplatform/nchain/common"
+	"github.com/provideplatform/nchain/contract"
+	"github.com/provideplatform/nchain/network"
+	"github.com/provideplatform/nchain/network/p2p"
+	provide "github.com/provideplatform/provide-go/api"
+)
+
+// CallTrace is a frame of the call tree of a tx, normalized from the parity-style traces or geth callTracer
+// output of the network p2p provider; frames calling known contracts are decoded using their ABIs
+type CallTrace struct {
+	Type         string  `json:"type"` // CALL, STATICCALL, DELEGATECALL, CALLCODE, CREATE, CREATE2 or SELFDESTRUCT
+	From         string  `json:"from"`
+	To           *string `json:"to,omitempty"`
+	Value        string  `json:"value"` // in wei
+	Input        string  `json:"input"`
+	Output       *string `json:"output,omitempty"`
+	Gas          uint64  `json:"gas"`
+	GasUsed      uint64  `json:"gas_used"`
+	Error        *string `json:"error,omitempty"`
+	Depth        int     `json:"depth"`
+	TraceAddress []int   `json:"trace_address"`
+
+	ContractID   *uuid.UUID             `json:"contract_id,omitempty"`
+	DecodedInput *contract.DecodedInput `json:"decoded_input,omitempty"`
+	RevertReason *RevertReason          `json:"revert_reason,omitempty"`
+
+	Calls []*CallTrace `json:"calls,omitempty"`
+}
+
+// InternalTransfer is a transfer of the native currency made by a contract during the execution of a tx
+type InternalTransfer struct {
+	provide.Model
+	TransactionID uuid.UUID `sql:"not null;type:uuid" json:"transaction_id"`
+	NetworkID     uuid.UUID `sql:"not null;type:uuid" json:"network_id"`
+	Block         *uint64   `json:"block,omitempty"`
+	Type          string    `sql:"not null" json:"type"`
+	FromAddress   string    `sql:"not null" json:"from"`
+	ToAddress     string    `sql:"not null" json:"to"`
+	Value         string    `sql:"not null;type:numeric" json:"value"` // in wei
+	Depth         int       `sql:"not null" json:"depth"`
+	TraceAddress  string    `sql:"not null" json:"trace_address"` // position of the frame in the call tree, i.e. 0.1
+}
+
+// TableName returns the table name of internal transfers
+func (it *InternalTransfer) TableName() string {
+	return "transaction_internal_transfers"
+}
+
+// callTraceDecoder decodes call frames using the ABIs of the known contracts of a network
+type callTraceDecoder struct {
+	db        *gorm.DB
+	networkID uuid.UUID
+	contracts map[string]*contract.Contract
+}
+
+// contract returns the known contract at the given address, if any
+func (d *callTraceDecoder) contract(address string) *contract.Contract {
+	key := strings.ToLower(address)
+	kontract, kontractOk := d.contracts[key]
+	if !kontractOk {
+		kontract = contract.Find(d.db, d.networkID, address)
+		d.contracts[key] = kontract
+	}
+	return kontract
+}
+
+// traceCallTree traces the call tree of the mined tx when the network p2p provider supports it
+func (t *Transaction) traceCallTree(ntwrk *network.Network) *p2p.CallFrame {
+	if t.Hash == nil || !ntwrk.IsEthereumNetwork() {
+		return nil
+	}
+
+	p2pAPI, err := ntwrk.P2PAPIClient()
+	if err != nil {
+		return nil
+	}
+
+	tracer, tracerOk := p2pAPI.(p2p.TxCallTreeTracer)
+	if !tracerOk {
+		return nil
+	}
+
+	frame, err := tracer.TraceTxCallTree(*t.Hash)
+	if err != nil {
+		common.Log.Debugf("failed to trace call tree of tx %s on network: %s; %s", t.ID, ntwrk.ID, err.Error())
+		return nil
+	}
+
+	return frame
+}
+
+// buildCallTrace normalizes and decodes the given call tree
+func (t *Transaction) buildCallTrace(db *gorm.DB, frame *p2p.CallFrame) *CallTrace {
+	decoder := &callTraceDecoder{
+		db:        db,
+		networkID: t.NetworkID,
+		contracts: map[string]*contract.Contract{},
+	}
+	return decoder.decode(frame, make([]int, 0))
+}
+
+// decode normalizes the given frame at the given position of the call tree, and decodes its input and
+// revert reason using the ABI of the called contract, if it is known
+func (d *callTraceDecoder) decode(frame *p2p.CallFrame, traceAddress []int) *CallTrace {
+	trace := &CallTrace{
+		Type:         strings.ToUpper(frame.Type),
+		From:         frame.From,
+		Value:        "0",
+		Input:        hexutil.Encode(frame.Input),
+		Gas:          uint64(frame.Gas),
+		GasUsed:      uint64(frame.GasUsed),
+		Depth:        len(traceAddress),
+		TraceAddress: traceAddress,
+	}
+
+	if frame.To != "" {
+		trace.To = common.StringOrNil(frame.To)
+	}
+	if frame.Value != nil {
+		trace.Value = frame.Value.ToInt().String()
+	}
+	if len(frame.Output) > 0 {
+		trace.Output = common.StringOrNil(hexutil.Encode(frame.Output))
+	}
+	if frame.Error != "" {
+		trace.Error = common.StringOrNil(frame.Error)
+	}
+
+	if trace.To != nil && !strings.HasPrefix(trace.Type, "CREATE") {
+		if kontract := d.contract(*trace.To); kontract != nil {
+			trace.ContractID = &kontract.ID
+			d.decodeFrame(kontract, frame, trace)
+		}
+	}
+
+	for i, call := range frame.Calls {
+		childAddress := make([]int, len(traceAddress), len(traceAddress)+1)
+		copy(childAddress, traceAddress)
+		trace.Calls = append(trace.Calls, d.decode(call, append(childAddress, i)))
+	}
+
+	return trace
+}
+
+// decodeFrame decodes the input, and the revert reason of a failed frame, using the ABI of the given contract
+func (d *callTraceDecoder) decodeFrame(kontract *contract.Contract, frame *p2p.CallFrame, trace *CallTrace) {
+	if len(frame.Input) >= 4 {
+		input, err := kontract.DecodeInput(frame.Input)
+		if err != nil {
+			common.Log.Tracef("failed to decode input of call frame using abi of contract %s; %s", kontract.ID, err.Error())
+		} else {
+			trace.DecodedInput = input
+		}
+	}
+
+	if frame.Error != "" && len(frame.Output) > 0 {
+		var customErrors []abi.Method
+		if errs, err := kontract.ReadEthereumContractErrors(); err == nil {
+			customErrors = errs
+		}
+		trace.RevertReason = decodeRevertReason(frame.Output, customErrors)
+	}
+}
+
+// internalTransfers returns the native currency transfers made within the given call tree; the top-level
+// call is the tx itself, and transfers made by frames which reverted, or whose callers reverted, are omitted
+func (t *Transaction) internalTransfers(trace *CallTrace, block *uint64) []*InternalTransfer {
+	transfers := make([]*InternalTransfer, 0)
+
+	var walk func(*CallTrace)
+	walk = func(trace *CallTrace) {
+		if trace.Error != nil {
+			return
+		}
+
+		value, valueOk := new(big.Int).SetString(trace.Value, 10)
+		if trace.Depth > 0 && trace.To != nil && trace.Type != "DELEGATECALL" && valueOk && value.Sign() > 0 {
+			position := make([]string, len(trace.TraceAddress))
+			for i, idx := range trace.TraceAddress {
+				position[i] = strconv.Itoa(idx)
+			}
+
+			transfers = append(transfers, &InternalTransfer{
+				TransactionID: t.ID,
+				NetworkID:     t.NetworkID,
+				Block:         block,
+				Type:          trace.Type,
+				FromAddress:   trace.From,
+				ToAddress:     *trace.To,
+				Value:         value.String(),
+				Depth:         trace.Depth,
+				TraceAddress:  strings.Join(position, "."),
+			})
+		}
+
+		for _, call := range trace.Calls {
+			walk(call)
+		}
+	}
+
+	walk(trace)
+	return transfers
+}
+
+// persistInternalTransfers traces the call tree of the mined tx and persists its internal transfers,
+// replacing those persisted if the tx was previously resolved, i.e., before its block was orphaned
+func (t *Transaction) persistInternalTransfers(db *gorm.DB, ntwrk *network.Network, block *uint64) error {
+	frame := t.traceCallTree(ntwrk)
+	if frame == nil {
+		return nil
+	}
+
+	transfers := t.internalTransfers(t.buildCallTrace(db, frame), block)
+
+	tx := db.Begin()
+	if err := tx.Where("transaction_id = ?", t.ID).Delete(&InternalTransfer{}).Error; err != nil {
+		tx.Rollback()
+		return fmt.Errorf("failed to persist internal transfers of tx %s; %s", t.ID, err.Error())
+	}
+
+	for _, transfer := range transfers {
+		if err := tx.Create(&transfer).Error; err != nil {
+			tx.Rollback()
+			return fmt.Errorf("failed to persist internal transfers of tx %s; %s", t.ID, err.Error())
+		}
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return fmt.Errorf("failed to persist internal transfers of tx %s; %s", t.ID, err.Error())
+	}
+
+	if len(transfers) > 0 {
+		common.Log.Debugf("persisted %d internal transfer(s) of tx %s", len(transfers), t.ID)
+	}
+	return nil
+}
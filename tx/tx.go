@@ -1,6 +1,7 @@
 package tx
 
 import (
+	"context"
 	"database/sql/driver"
 	"encoding/hex"
 	"encoding/json"
@@ -23,6 +24,7 @@ import (
 	"github.com/provideplatform/nchain/network"
 	"github.com/provideplatform/nchain/token"
 	"github.com/provideplatform/nchain/wallet"
+	"github.com/provideplatform/nchain/webhook"
 	provide "github.com/provideplatform/provide-go/api"
 	provideapi "github.com/provideplatform/provide-go/api/nchain"
 	vault "github.com/provideplatform/provide-go/api/vault"
@@ -60,20 +62,60 @@ type Transaction struct {
 
 	// Network-agnostic tx fields
 	Signer      *string          `sql:"-" json:"signer,omitempty"`
+	From        *string          `json:"from,omitempty"`
 	To          *string          `json:"to"`
 	Value       *TxValue         `sql:"not null;type:text" json:"value"`
 	Data        *string          `json:"data"`
 	Hash        *string          `json:"hash"`
 	Status      *string          `sql:"not null;default:'pending'" json:"status"`
+	Nonce       *uint64          `json:"nonce,omitempty"`
 	Params      *json.RawMessage `sql:"-" json:"params,omitempty"`
+	Raw         *string          `json:"raw,omitempty"`
 	Ref         *string          `json:"ref"`
 	Description *string          `json:"description"`
 
+	// Scheduling options; when either is set, the tx is parked until the given time and/or block height
+	ScheduledAt    *time.Time `sql:"-" json:"scheduled_at,omitempty"`
+	NotBeforeBlock *uint64    `sql:"-" json:"not_before_block,omitempty"`
+
+	// Batch in which the tx was submitted, if it was submitted as part of a batch
+	BatchID *uuid.UUID `sql:"type:uuid" json:"batch_id,omitempty"`
+
+	// Approvals required by the spending policies of the signer before the tx is signed, if any
+	ApprovalRequirements *json.RawMessage `sql:"type:json" json:"approval_requirements,omitempty"`
+	ApprovalExpiresAt    *time.Time       `json:"approval_expires_at,omitempty"`
+
+	// EIP-2930 access list with which the tx was signed, if any; provided in the access_list param or generated
+	AccessList *json.RawMessage `sql:"type:json" json:"access_list,omitempty"`
+
+	// Relayer which submitted the tx as an EIP-2771 meta-tx, if it was relayed, and the relayer gas
+	// reserved for the tx until its receipt is settled
+	RelayerID          *uuid.UUID `sql:"type:uuid" json:"relayer_id,omitempty"`
+	RelayerGasReserved *uint64    `json:"relayer_gas_reserved,omitempty"`
+	RelayedAt          *time.Time `json:"relayed_at,omitempty"`
+
+	// Replacement tx which was broadcast using the same nonce, if this tx was replaced or cancelled
+	ReplacedByID *uuid.UUID `sql:"type:uuid" json:"replaced_by_id,omitempty"`
+
+	// Structured reason the tx execution reverted, if it failed; decoded from the revert data
+	FailureReason *json.RawMessage `sql:"type:json" json:"failure_reason,omitempty"`
+
 	// Ephemeral fields for managing the tx/rx and tracing lifecycles
 	Response *contract.ExecutionResponse `sql:"-" json:"-"`
 	SignedTx interface{}                 `sql:"-" json:"-"`
 	Traces   interface{}                 `sql:"-" json:"traces,omitempty"`
 
+	// Value reserved against the daily limits of the spending policies of the signer until the tx is persisted
+	policyReservation *policyReservation
+
+	// Calldata, receipt logs and call tree decoded using the ABIs of known contracts
+	DecodedInput *contract.DecodedInput `sql:"-" json:"decoded_input,omitempty"`
+	DecodedLogs  []*contract.DecodedLog `sql:"-" json:"decoded_logs,omitempty"`
+	CallTrace    *CallTrace             `sql:"-" json:"call_trace,omitempty"`
+
+	// Number of blocks, including the block in which the tx was mined, appended to the chain; ephemeral
+	Confirmations *uint64 `sql:"-" json:"confirmations,omitempty"`
+
 	// Transaction metadata/instrumentation
 	Block          *uint64    `json:"block"`
 	BlockTimestamp *time.Time `json:"block_timestamp,omitempty"`                       // timestamp when the tx was finalized on-chain, according to its tx receipt
@@ -83,6 +125,11 @@ type Transaction struct {
 	QueueLatency   *uint64    `json:"queue_latency,omitempty"`                         // broadcast_at - published_at (in millis) -- the amount of time between when a message is enqueued to the NATS broker and when it is broadcast to the network
 	NetworkLatency *uint64    `json:"network_latency,omitempty"`                       // finalized_at - broadcast_at (in millis) -- the amount of time between when a message is broadcast to the network and when it is finalized on-chain
 	E2ELatency     *uint64    `gorm:"column:e2e_latency" json:"e2e_latency,omitempty"` // finalized_at - published_at (in millis) -- the amount of time between when a message is published to the NATS broker and when it is finalized on-chain
+
+	// Gas spend, recorded from the tx receipt; the effective gas price and fee paid are in wei
+	GasUsed           *uint64 `json:"gas_used,omitempty"`
+	EffectiveGasPrice *string `sql:"type:numeric" json:"effective_gas_price,omitempty"`
+	FeePaid           *string `sql:"type:numeric" json:"fee_paid,omitempty"`
 }
 
 // TransactionSigner is either an account or HD wallet; implements the Signer interface
@@ -177,167 +224,280 @@ func (txs *TransactionSigner) Sign(tx *Transaction) (signedTx interface{}, hash
 		return nil, nil, err
 	}
 
-	if txs.Network.IsEthereumNetwork() {
-		params := tx.ParseParams()
-		gas, gasOk := params["gas"].(float64)
-		if !gasOk {
-			gas = float64(0)
+	// accounts of a registered external type are signed by the Signer registered for the type
+	if txs.Account != nil && txs.Account.Type != nil {
+		if factory, factoryOk := signerFactories[*txs.Account.Type]; factoryOk {
+			signer, err := factory(txs)
+			if err != nil {
+				err = fmt.Errorf("failed to resolve %s signer for account %s; %s", *txs.Account.Type, txs.Account.ID, err.Error())
+				common.Log.Warning(err.Error())
+				return nil, nil, err
+			}
+			return signer.Sign(tx)
 		}
+	}
 
-		var gasPrice *uint64
-		gp, gpOk := params["gas_price"].(float64)
-		if gpOk {
-			_gasPrice := uint64(gp)
-			gasPrice = &_gasPrice
+	// bcoin and handshake txs are funded using the coins of the signing account and signed using its Vault key
+	if txs.Network.IsUTXONetwork() {
+		if txs.Account == nil || txs.Account.VaultID == nil || txs.Account.KeyID == nil {
+			return nil, nil, fmt.Errorf("unable to sign %s tx without a Vault-managed signing account", *txs.Network.Name)
 		}
+		return txs.signUTXOTx(tx, vaultSignHash(txs.Account.VaultID.String(), txs.Account.KeyID.String(), map[string]interface{}{}))
+	}
 
-		var nonce *uint64
-		if nonceFloat, nonceOk := params["nonce"].(float64); nonceOk {
-			nonceUint := uint64(nonceFloat)
-			nonce = &nonceUint
+	if !txs.Network.IsEthereumNetwork() {
+		return nil, nil, fmt.Errorf("unable to generate signed tx for unsupported network: %s", *txs.Network.Name)
+	}
+
+	address, vaultID, keyID, opts, err := txs.signingIdentity()
+	if err != nil {
+		common.Log.Warning(err.Error())
+		return nil, nil, err
+	}
+	return txs.signEVMTx(tx, address, vaultSignHash(vaultID, keyID, opts))
+}
+
+// signEVMTx signs the tx on behalf of the given address using the given hash signer; a typed envelope is signed
+// when dynamic fees or an access list apply to the tx, otherwise a legacy tx
+func (txs *TransactionSigner) signEVMTx(tx *Transaction, address string, signHash hashSignerFunc) (interface{}, []byte, error) {
+	gas, gasPrice, nonce := signingParams(tx)
+
+	fees, err := resolveDynamicFees(txs.Network, tx.ParseParams(), gasPrice)
+	if err != nil {
+		err = fmt.Errorf("failed to resolve dynamic fees for tx; %s", err.Error())
+		common.Log.Warning(err.Error())
+		return nil, nil, err
+	}
+
+	accessList, err := tx.resolveAccessList(txs.Network, address, gas)
+	if err != nil {
+		err = fmt.Errorf("failed to resolve access list for tx; %s", err.Error())
+		common.Log.Warning(err.Error())
+		return nil, nil, err
+	}
+
+	signedTx, hash, err := txs.withManagedNonce(address, nonce, func(nonce *uint64) (interface{}, []byte, error) {
+		if fees != nil || accessList != nil {
+			return txs.signTypedTx(tx, address, signHash, nonce, gas, gasPrice, fees, accessList)
 		}
+		return txs.signLegacyTx(tx, address, signHash, nonce, gas, gasPrice)
+	})
+	if err != nil {
+		return nil, nil, err
+	}
 
-		var signer types.Signer
-		var _tx *types.Transaction
-
-		// we are using an account to sign the transaction
-		if txs.Account != nil && txs.Account.VaultID != nil && txs.Account.KeyID != nil {
-			signer, _tx, hash, err = providecrypto.EVMTxFactory(
-				txs.Network.ID.String(),
-				txs.Network.RPCURL(),
-				txs.Account.Address,
-				tx.To,
-				tx.Data,
-				tx.Value.BigInt(),
-				nonce,
-				uint64(gas),
-				gasPrice,
-			)
-			if err != nil {
-				err = fmt.Errorf("failed to sign transaction using signing account %s; %s", txs.Account.Address, err.Error())
-				common.Log.Warning(err.Error())
-				return nil, nil, err
-			}
+	txs.touch()
+	return signedTx, hash, nil
+}
 
-			sig, err := vault.SignMessage(
-				util.DefaultVaultAccessJWT,
-				txs.Account.VaultID.String(),
-				txs.Account.KeyID.String(),
-				fmt.Sprintf("%x", hash),
-				map[string]interface{}{},
-			)
-			if err != nil {
-				err = fmt.Errorf("failed to sign transaction using signing account %s; %s", txs.Account.Address, err.Error())
-				common.Log.Warning(err.Error())
-				return nil, nil, err
-			}
+// withManagedNonce invokes the given signing function using the given nonce or, when no nonce is given, the next
+// nonce allocated by the nonce manager of the address; an allocated nonce is reclaimed if signing fails
+func (txs *TransactionSigner) withManagedNonce(address string, nonce *uint64, sign func(nonce *uint64) (interface{}, []byte, error)) (interface{}, []byte, error) {
+	var nonceManager *NonceManager
+	if nonce == nil {
+		nonceManager, nonce = txs.allocateNonce(address)
+	}
 
-			_sig, err := hex.DecodeString(*sig.Signature)
-			if err != nil {
-				err = fmt.Errorf("failed to sign transaction using signing account %s; %s", txs.Account.Address, err.Error())
-				common.Log.Warning(err.Error())
-				return nil, nil, err
-			}
+	signedTx, hash, err := sign(nonce)
+	if err != nil && nonceManager != nil && nonce != nil {
+		nonceManager.Reclaim(*nonce)
+	}
+	return signedTx, hash, err
+}
 
-			signedTx, err = _tx.WithSignature(signer, _sig)
-			if err != nil {
-				err = fmt.Errorf("failed to sign transaction using signing account %s; %s", txs.Account.Address, err.Error())
-				common.Log.Warning(err.Error())
-				return nil, nil, err
-			}
+// signLegacyTx signs a legacy tx on behalf of the given address using the given hash signer; unresolved nonce,
+// gas and gas price are read from the network
+func (txs *TransactionSigner) signLegacyTx(tx *Transaction, address string, signHash hashSignerFunc, nonce *uint64, gas uint64, gasPrice *uint64) (interface{}, []byte, error) {
+	signer, _tx, hash, err := providecrypto.EVMTxFactory(
+		txs.Network.ID.String(),
+		txs.Network.RPCURL(),
+		address,
+		tx.To,
+		tx.Data,
+		tx.Value.BigInt(),
+		nonce,
+		gas,
+		gasPrice,
+	)
+	if err != nil {
+		err = fmt.Errorf("failed to sign tx using signer %s; %s", address, err.Error())
+		common.Log.Warning(err.Error())
+		return nil, nil, err
+	}
 
-			if err == nil {
-				signedTxJSON, _ := signedTx.(*types.Transaction).MarshalJSON()
-				common.Log.Debugf("signed eth tx: %s", signedTxJSON)
+	sig, err := signHash(hash)
+	if err != nil {
+		err = fmt.Errorf("failed to sign tx using signer %s; %s", address, err.Error())
+		common.Log.Warning(err.Error())
+		return nil, nil, err
+	}
 
-				accessedAt := time.Now()
-				go func() {
-					txs.Account.AccessedAt = &accessedAt
-					txs.DB.Save(&txs.Account)
-				}()
-			}
+	signedTx, err := _tx.WithSignature(signer, sig)
+	if err != nil {
+		err = fmt.Errorf("failed to sign tx using signer %s; %s", address, err.Error())
+		common.Log.Warning(err.Error())
+		return nil, nil, err
+	}
+
+	signedTxJSON, _ := signedTx.MarshalJSON()
+	common.Log.Debugf("signed eth tx: %s", signedTxJSON)
+
+	txNonce := signedTx.Nonce()
+	tx.Nonce = &txNonce
+	return signedTx, hash, nil
+}
+
+// touch records the time at which the signing account was last used to sign a tx
+func (txs *TransactionSigner) touch() {
+	if txs.Account == nil {
+		return
+	}
+
+	accessedAt := time.Now()
+	go func() {
+		txs.Account.AccessedAt = &accessedAt
+		txs.DB.Save(&txs.Account)
+	}()
+}
+
+// signTypedTx signs a typed tx envelope on behalf of the given address using the given hash signer; an EIP-1559 (type-2)
+// tx is signed when dynamic fees are given, otherwise an EIP-2930 (type-1) tx priced at the given or suggested gas price
+func (txs *TransactionSigner) signTypedTx(tx *Transaction, address string, signHash hashSignerFunc, nonce *uint64, gas uint64, gasPrice *uint64, fees *dynamicFees, accessList AccessList) (signedTx interface{}, hash []byte, err error) {
+	client, err := providecrypto.EVMDialJsonRpc(txs.Network.ID.String(), txs.Network.RPCURL())
+	if err != nil {
+		err = fmt.Errorf("failed to sign typed tx using signer %s; %s", address, err.Error())
+		common.Log.Warning(err.Error())
+		return nil, nil, err
+	}
+
+	chainID, err := client.ChainID(context.TODO())
+	if err != nil {
+		err = fmt.Errorf("failed to resolve chain id to sign typed tx using signer %s; %s", address, err.Error())
+		common.Log.Warning(err.Error())
+		return nil, nil, err
+	}
+
+	if nonce == nil {
+		pendingNonce, err := client.PendingNonceAt(context.TODO(), ethcommon.HexToAddress(address))
+		if err != nil {
+			err = fmt.Errorf("failed to resolve pending nonce to sign typed tx using signer %s; %s", address, err.Error())
+			common.Log.Warning(err.Error())
+			return nil, nil, err
 		}
+		nonce = &pendingNonce
+	}
 
-		// var txAddress *string
-		// var txDerivationPath *string
+	if gas == 0 {
+		gas, err = tx.estimateGas(txs.Network, address)
+		if err != nil {
+			err = fmt.Errorf("failed to sign typed tx using signer %s; %s", address, err.Error())
+			common.Log.Warning(err.Error())
+			return nil, nil, err
+		}
+	}
 
-		// we are using a wallet to sign the transaction
-		// it can include a derivation path to generate a specific address
-		// or with no derivation path, signs using the default address from the hd wallet
-		// maybe there's something we can do here with the signer to get the next increment from the signer
-		// which returns a derivation path, which we can use for the transaction creation,
-		// but that seems a little hacky
-		if txs.Wallet != nil && txs.Wallet.VaultID != nil && txs.Wallet.KeyID != nil {
+	var to *ethcommon.Address
+	if tx.To != nil {
+		addr := ethcommon.HexToAddress(*tx.To)
+		to = &addr
+	}
 
-			txAddress, txDerivationPath, err := txs.GetSignerDetails()
-			if err != nil {
-				return nil, nil, err
-			}
+	var data []byte
+	if tx.Data != nil {
+		data = ethcommon.FromHex(*tx.Data)
+	}
 
-			signer, _tx, hash, err = providecrypto.EVMTxFactory(
-				txs.Network.ID.String(),
-				txs.Network.RPCURL(),
-				*txAddress,
-				tx.To,
-				tx.Data,
-				tx.Value.BigInt(),
-				nonce,
-				uint64(gas),
-				gasPrice,
-			)
+	_tx := &TypedTransaction{
+		ChainID:    chainID,
+		Nonce:      *nonce,
+		Gas:        gas,
+		To:         to,
+		Value:      tx.Value.BigInt(),
+		Data:       data,
+		AccessList: accessList,
+	}
 
+	if fees != nil {
+		_tx.Type = dynamicFeeTxType
+		_tx.GasTipCap = fees.GasTipCap
+		_tx.GasFeeCap = fees.GasFeeCap
+	} else {
+		_tx.Type = accessListTxType
+		if gasPrice != nil {
+			_tx.GasPrice = new(big.Int).SetUint64(*gasPrice)
+		} else {
+			_tx.GasPrice, err = client.SuggestGasPrice(context.TODO())
 			if err != nil {
-				err = fmt.Errorf("failed to sign %d-byte transaction payload using hardened account for HD wallet: %s; %s", len(hash), txs.Wallet.ID, err.Error())
+				err = fmt.Errorf("failed to resolve gas price to sign typed tx using signer %s; %s", address, err.Error())
 				common.Log.Warning(err.Error())
 				return nil, nil, err
 			}
+		}
+	}
 
-			// if we were provided, or have generated, a hd derivation path, pass it to the signer
-			opts := map[string]interface{}{}
-			if txDerivationPath != nil {
-				opts = map[string]interface{}{
-					"hdwallet": map[string]interface{}{
-						"hd_derivation_path": txDerivationPath,
-					},
-				}
-			}
+	sigHash, err := _tx.SigningHash()
+	if err != nil {
+		err = fmt.Errorf("failed to sign typed tx using signer %s; %s", address, err.Error())
+		common.Log.Warning(err.Error())
+		return nil, nil, err
+	}
 
-			common.Log.Debugf("vault to sign tx... hash: %s", fmt.Sprintf("%x", hash))
-			//check if the hash is actually hex. not sure if it is, it looks like bytes returned from the signer function
-			// TODO sometimes the db stores the raw hash (no 0x prefix), not the tx hash (0x prefix) - investigate why this occurs
-
-			sig, err := vault.SignMessage(
-				util.DefaultVaultAccessJWT,
-				txs.Wallet.VaultID.String(),
-				txs.Wallet.KeyID.String(),
-				fmt.Sprintf("%x", hash),
-				opts,
-			)
-			if err != nil {
-				err = fmt.Errorf("failed to sign transaction using hardened account for HD wallet: %s; %s", txs.Wallet.ID, err.Error())
-				common.Log.Warning(err.Error())
-				return nil, nil, err
-			}
+	_sig, err := signHash(sigHash)
+	if err == nil {
+		err = _tx.WithSignature(_sig)
+	}
+	if err != nil {
+		err = fmt.Errorf("failed to sign typed tx using signer %s; %s", address, err.Error())
+		common.Log.Warning(err.Error())
+		return nil, nil, err
+	}
 
-			_sig, err := hex.DecodeString(*sig.Signature)
-			if err != nil {
-				err = fmt.Errorf("failed to sign transaction using hardened account for HD wallet: %s; %s", txs.Wallet.ID, err.Error())
-				common.Log.Warning(err.Error())
-				return nil, nil, err
-			}
+	tx.Nonce = nonce
+	if fees != nil {
+		common.Log.Debugf("signed dynamic fee tx using signer %s; nonce: %d; max priority fee: %s; max fee: %s; access list: %d address(es)", address, *nonce, fees.GasTipCap.String(), fees.GasFeeCap.String(), len(accessList))
+	} else {
+		common.Log.Debugf("signed access list tx using signer %s; nonce: %d; gas price: %s; access list: %d address(es)", address, *nonce, _tx.GasPrice.String(), len(accessList))
+	}
 
-			signedTx, err = _tx.WithSignature(signer, _sig)
-			if err != nil {
-				err = fmt.Errorf("failed to sign transaction payload using hardened account for HD wallet: %s; %s", txs.Wallet.ID, err.Error())
-				common.Log.Warning(err.Error())
-				return nil, nil, err
+	return _tx, _tx.Hash().Bytes(), nil
+}
+
+// signingIdentity resolves the address, vault key and signing options of the account or HD wallet signer
+func (txs *TransactionSigner) signingIdentity() (address, vaultID, keyID string, opts map[string]interface{}, err error) {
+	opts = map[string]interface{}{}
+
+	if txs.Account != nil && txs.Account.VaultID != nil && txs.Account.KeyID != nil {
+		return txs.Account.Address, txs.Account.VaultID.String(), txs.Account.KeyID.String(), opts, nil
+	}
+
+	if txs.Wallet != nil && txs.Wallet.VaultID != nil && txs.Wallet.KeyID != nil {
+		txAddress, txDerivationPath, err := txs.GetSignerDetails()
+		if err != nil {
+			return "", "", "", nil, err
+		}
+
+		if txDerivationPath != nil {
+			opts["hdwallet"] = map[string]interface{}{
+				"hd_derivation_path": txDerivationPath,
 			}
 		}
-	} else {
-		return nil, nil, fmt.Errorf("unable to generate signed tx for unsupported network: %s", *txs.Network.Name)
+
+		return *txAddress, txs.Wallet.VaultID.String(), txs.Wallet.KeyID.String(), opts, nil
 	}
 
-	return signedTx, hash, err
+	return "", "", "", nil, fmt.Errorf("no account or HD wallet signing identity configured for signer: %s", txs.String())
+}
+
+// allocateNonce allocates the next nonce for the given address using the nonce manager; when
+// allocation fails, the nonce is left unresolved so it is instead read from the network at signing
+func (txs *TransactionSigner) allocateNonce(address string) (*NonceManager, *uint64) {
+	nonceManager := nonceManagerFactory(txs.Network, address)
+	nonce, err := nonceManager.Allocate()
+	if err != nil {
+		common.Log.Warningf("failed to allocate managed nonce for signer %s; falling back to pending nonce; %s", address, err.Error())
+		return nil, nil
+	}
+	return nonceManager, nonce
 }
 
 // String prints a description of the transaction signer
@@ -404,16 +564,58 @@ func (t *Transaction) asEthereumCallMsg(address string, gasPrice, gasLimit uint6
 	if t.Data != nil {
 		data = ethcommon.FromHex(*t.Data)
 	}
+
+	// the gas price is left unset for a gas price of 0, so dynamic fee txs are not priced as legacy txs
+	var _gasPrice *big.Int
+	if gasPrice > 0 {
+		_gasPrice = new(big.Int).SetUint64(gasPrice)
+	}
+
 	return ethereum.CallMsg{
 		From:     ethcommon.HexToAddress(address),
 		To:       to,
 		Gas:      gasLimit,
-		GasPrice: big.NewInt(int64(gasPrice)),
+		GasPrice: _gasPrice,
 		Value:    t.Value.BigInt(),
 		Data:     data,
 	}
 }
 
+// estimateGas estimates the gas required to execute the tx via eth_estimateGas and applies the gas estimate
+// multiplier configured for the network; the raw and padded estimates are recorded on the tx params
+func (t *Transaction) estimateGas(ntwrk *network.Network, address string) (uint64, error) {
+	params := t.ParseParams()
+
+	gasPrice := uint64(0)
+	if gp, gpOk := params["gas_price"].(float64); gpOk {
+		gasPrice = uint64(gp)
+	}
+
+	client, err := providecrypto.EVMDialJsonRpc(ntwrk.ID.String(), ntwrk.RPCURL())
+	if err != nil {
+		return 0, fmt.Errorf("failed to dial JSON-RPC host; %s", err.Error())
+	}
+
+	estimate, err := client.EstimateGas(context.TODO(), t.asEthereumCallMsg(address, gasPrice, 0))
+	if err != nil {
+		if reason := t.revertReason(dbconf.DatabaseConnection(), err); reason != nil {
+			t.setRevertReason(reason)
+			return 0, fmt.Errorf("failed to estimate gas for tx; %s", reason.String())
+		}
+		return 0, fmt.Errorf("failed to estimate gas for tx; %s", err.Error())
+	}
+
+	multiplier := ntwrk.GasEstimateMultiplier()
+	gas := uint64(float64(estimate) * multiplier)
+
+	params["gas"] = gas
+	params["gas_estimate"] = estimate
+	t.setParams(params)
+
+	common.Log.Debugf("estimated gas for tx from %s on network: %s; estimate: %d; multiplier: %v; gas: %d", address, ntwrk.ID, estimate, multiplier, gas)
+	return gas, nil
+}
+
 func (t *Transaction) signerFactory(db *gorm.DB) (*TransactionSigner, error) {
 	var ntwrk *network.Network
 	if t.NetworkID != uuid.Nil {
@@ -453,8 +655,29 @@ func (t *Transaction) signerFactory(db *gorm.DB) (*TransactionSigner, error) {
 }
 
 // Create and persist a new transaction. Side effects include persistence of contract
-// and/or token instances when the tx represents a contract and/or token creation.
+// and/or token instances when the tx represents a contract and/or token creation. Creation
+// is idempotent by ref; if a tx having the same ref was previously created within the same
+// application, organization or user scope, the tx is populated with the existing tx.
 func (t *Transaction) Create(db *gorm.DB) bool {
+	if _, scopeID := t.refScope(); t.Ref == nil || scopeID == nil {
+		return t.create(db)
+	}
+
+	existing := t.findByRef(db)
+	if existing != nil {
+		common.Log.Debugf("tx with ref %s already exists; returning existing tx: %s", *t.Ref, existing.ID)
+		*t = *existing
+		return true
+	}
+
+	return t.create(db)
+}
+
+func (t *Transaction) create(db *gorm.DB) bool {
+	if t.Raw != nil {
+		return t.createRaw(db)
+	}
+
 	if !t.Validate() {
 		return false
 	}
@@ -467,6 +690,27 @@ func (t *Transaction) Create(db *gorm.DB) bool {
 		return false
 	}
 
+	// spending policies of the signing account and/or HD wallet are enforced before the tx is signed;
+	// a tx which requires approvals is persisted unsigned until quorum is reached
+	requirements, violation := t.enforcePolicies(db)
+	defer t.releasePolicyReservation()
+	if violation != nil {
+		return t.rejectByPolicy(db, signer, violation)
+	} else if len(requirements) > 0 {
+		return t.awaitApproval(db, requirements)
+	}
+
+	// relayed txs are signed by the account as forward requests and submitted using the relayer account
+	if t.shouldRelay() && t.RelayerID == nil {
+		signer, err = t.relay(db, signer)
+		if err != nil {
+			t.Errors = append(t.Errors, &provide.Error{
+				Message: common.StringOrNil(err.Error()),
+			})
+			return false
+		}
+	}
+
 	// xxx check what triggers a signingErr here...
 	signingErr := t.sign(db, signer)
 
@@ -484,7 +728,20 @@ func (t *Transaction) Create(db *gorm.DB) bool {
 		result := db.Create(&t)
 		rowsAffected := result.RowsAffected
 		errors := result.GetErrors()
+		t.releasePolicyReservation()
 		if len(errors) > 0 {
+			t.reclaimSignedNonce(signer)
+			t.releaseSignedCoins(signer)
+			t.settleRelayedGas(db, 0)
+
+			// the unique index on the ref rejects the tx when a concurrent request having the same ref
+			// was persisted first; the tx is populated with the existing tx, which alone is broadcast
+			if existing := t.findByRef(db); existing != nil {
+				common.Log.Debugf("tx with ref %s was concurrently created; returning existing tx: %s", *t.Ref, existing.ID)
+				*t = *existing
+				return true
+			}
+
 			for _, err := range errors {
 				t.Errors = append(t.Errors, &provide.Error{
 					Message: common.StringOrNil(err.Error()),
@@ -496,59 +753,79 @@ func (t *Transaction) Create(db *gorm.DB) bool {
 		if !db.NewRecord(t) {
 			if rowsAffected > 0 {
 
-				// if we have a signing error, which might be insufficient funds, try bookie
-				if signingErr != nil {
-					common.Log.Debugf("attepting broadcast to bookie...")
-					// network specified in t, so not required to be specifically passed to broadcast method
-					bookieBroadcastErr := t.broadcast(db, nil, nil)
-					// if bookie fails, we're out
-					if bookieBroadcastErr != nil {
-						common.Log.Warningf("attepted broadcast failed anyway! %s", signingErr.Error())
-
-						t.Errors = append(t.Errors, &provide.Error{
-							Message: common.StringOrNil(bookieBroadcastErr.Error()),
-						})
+				return t.broadcastSigned(db, signer, signingErr)
+			}
+		}
+	}
+	return false
+}
 
-						desc := bookieBroadcastErr.Error()
-						t.updateStatus(db, "failed", &desc)
-						return false
-					}
-					// if bookie succeeds, pop it onto nats
-					if bookieBroadcastErr == nil {
-						payload, _ := json.Marshal(map[string]interface{}{
-							"transaction_id": t.ID.String(),
-						})
-						natsutil.NatsJetstreamPublish(natsTxReceiptSubject, payload)
+// broadcastSigned broadcasts the persisted tx which was signed using the given signer; when signing failed,
+// the broadcast is attempted using bookie, unless the tx was relayed
+func (t *Transaction) broadcastSigned(db *gorm.DB, signer *TransactionSigner, signingErr error) bool {
+	if signingErr != nil && t.RelayerID != nil {
+		t.Errors = append(t.Errors, &provide.Error{
+			Message: common.StringOrNil(signingErr.Error()),
+		})
+		desc := signingErr.Error()
+		t.updateStatus(db, "failed", &desc)
+		t.settleRelayedGas(db, 0)
+		t.reclaimBatchNonce(signer)
+		return false
+	}
 
-						return true
-					}
-				}
+	// if we have a signing error, which might be insufficient funds, try bookie
+	if signingErr != nil {
+		common.Log.Debugf("attepting broadcast to bookie...")
+		// network specified in t, so not required to be specifically passed to broadcast method
+		bookieBroadcastErr := t.broadcast(db, nil, nil)
+		// if bookie fails, we're out
+		if bookieBroadcastErr != nil {
+			common.Log.Warningf("attepted broadcast failed anyway! %s", signingErr.Error())
 
-				if signingErr == nil {
-					// if no signing error, try regular broadcast
-					networkBroadcastErr := t.broadcast(db, signer.Network, signer)
-					// if regular fails, we're out
-					if networkBroadcastErr != nil {
-						payload, _ := json.Marshal(map[string]interface{}{
-							"transaction_id": t.ID.String(),
-						})
-						natsutil.NatsJetstreamPublish(natsTxReceiptSubject, payload)
+			t.Errors = append(t.Errors, &provide.Error{
+				Message: common.StringOrNil(bookieBroadcastErr.Error()),
+			})
 
-						return true
-					}
-					// if regular succeeds, pop it onto nats
-					if networkBroadcastErr == nil {
-						payload, _ := json.Marshal(map[string]interface{}{
-							"transaction_id": t.ID.String(),
-						})
-						natsutil.NatsJetstreamPublish(natsTxReceiptSubject, payload)
+			desc := bookieBroadcastErr.Error()
+			t.updateStatus(db, "failed", &desc)
+			t.reclaimBatchNonce(signer)
+			return false
+		}
+		// if bookie succeeds, pop it onto nats
+		if bookieBroadcastErr == nil {
+			payload, _ := json.Marshal(map[string]interface{}{
+				"transaction_id": t.ID.String(),
+			})
+			natsutil.NatsJetstreamPublish(natsTxReceiptSubject, payload)
 
-						return true
-					}
-				}
-			}
+			return true
 		}
 	}
+
+	if signingErr == nil {
+		// if no signing error, try regular broadcast
+		networkBroadcastErr := t.broadcast(db, signer.Network, signer)
+		// if regular fails, we're out
+		if networkBroadcastErr != nil {
+			payload, _ := json.Marshal(map[string]interface{}{
+				"transaction_id": t.ID.String(),
+			})
+			natsutil.NatsJetstreamPublish(natsTxReceiptSubject, payload)
+
+			return true
+		}
+		// if regular succeeds, pop it onto nats
+		if networkBroadcastErr == nil {
+			payload, _ := json.Marshal(map[string]interface{}{
+				"transaction_id": t.ID.String(),
+			})
+			natsutil.NatsJetstreamPublish(natsTxReceiptSubject, payload)
+
+			return true
+		}
+	}
+
 	return false
 }
 
@@ -690,15 +967,40 @@ func (t *Transaction) ParseParams() map[string]interface{} {
 	return params
 }
 
-// shouldSubsidize returns true if the transaction should be subsidized using the integration gas service
-func (t *Transaction) shouldSubsidize() bool {
-	if subsidize, subsidizeOk := t.ParseParams()["subsidize"].(bool); subsidizeOk {
-		return subsidize
+// managedNonce returns true if the tx nonce was allocated by the nonce manager, at signing or when its batch
+// was submitted, as opposed to having been explicitly provided in the tx params
+func (t *Transaction) managedNonce() bool {
+	if t.Nonce == nil || (t.AccountID == nil && t.WalletID == nil) {
+		return false
+	}
+	if t.BatchID != nil {
+		return true // batch nonces are allocated by the nonce manager when the batch is submitted
+	}
+	_, nonceOk := t.ParseParams()["nonce"].(float64)
+	return !nonceOk
+}
+
+// reclaimSignedNonce reclaims the managed nonce of the signed tx, which was never broadcast
+func (t *Transaction) reclaimSignedNonce(signer *TransactionSigner) {
+	if t.SignedTx == nil || signer == nil || !t.managedNonce() {
+		return
+	}
+	nonceManagerFactory(signer.Network, signer.Address()).Reclaim(*t.Nonce)
+}
+
+// releaseSignedCoins releases the coins spent by the signed UTXO tx, which was never broadcast
+func (t *Transaction) releaseSignedCoins(signer *TransactionSigner) {
+	if signer == nil {
+		return
+	}
+	if signedTx, ok := t.SignedTx.(*UTXOTransaction); ok {
+		releaseUTXOCoins(signer.Network, signer.Address(), signedTx)
 	}
-	return false
 }
 
 func (t *Transaction) updateStatus(db *gorm.DB, status string, description *string) {
+	statusChanged := t.Status == nil || *t.Status != status
+
 	t.Status = common.StringOrNil(status)
 	t.Description = description
 	result := db.Save(&t)
@@ -709,6 +1011,11 @@ func (t *Transaction) updateStatus(db *gorm.DB, status string, description *stri
 				Message: common.StringOrNil(err.Error()),
 			})
 		}
+		return
+	}
+
+	if event, eventOk := webhookEvents[status]; eventOk && statusChanged {
+		t.dispatchWebhook(db, event)
 	}
 }
 
@@ -769,8 +1076,34 @@ func (t *Transaction) broadcast(db *gorm.DB, ntwrk *network.Network, signer Sign
 					// so update the db with the received transaction hash
 					common.Log.Debugf("signed tx returned hash: %s", signedTx.Hash().String())
 					t.Hash = common.StringOrNil(signedTx.Hash().String())
+					t.Raw = rawSignedTx(signedTx)
 					db.Save(&t)
 					common.Log.Debugf("broadcast tx: %s", *t.Hash)
+				} else if t.managedNonce() && !isNonceConsumedErr(err) {
+					nonceManagerFactory(ntwrk, signer.Address()).Reclaim(signedTx.Nonce())
+				}
+			} else if signedTx, ok := t.SignedTx.(*TypedTransaction); ok {
+				err = broadcastTypedTx(ntwrk.ID.String(), ntwrk.RPCURL(), signedTx)
+				if err == nil {
+					t.Hash = common.StringOrNil(signedTx.Hash().String())
+					t.Raw = rawSignedTx(signedTx)
+					db.Save(&t)
+					common.Log.Debugf("broadcast type-%d tx: %s", signedTx.Type, *t.Hash)
+				} else if t.managedNonce() && !isNonceConsumedErr(err) {
+					nonceManagerFactory(ntwrk, signer.Address()).Reclaim(signedTx.Nonce)
+				}
+			} else {
+				err = fmt.Errorf("unable to broadcast signed tx; typecast failed for signed tx: %s", t.SignedTx)
+			}
+		} else if ntwrk.IsUTXONetwork() {
+			if signedTx, ok := t.SignedTx.(*UTXOTransaction); ok {
+				var txid string
+				txid, err = broadcastUTXOTx(ntwrk, signer.Address(), signedTx)
+				if err == nil {
+					t.Hash = common.StringOrNil(txid)
+					t.Raw = rawSignedTx(signedTx)
+					db.Save(&t)
+					common.Log.Debugf("broadcast %s tx: %s", signedTx.Network, *t.Hash)
 				}
 			} else {
 				err = fmt.Errorf("unable to broadcast signed tx; typecast failed for signed tx: %s", t.SignedTx)
@@ -787,9 +1120,11 @@ func (t *Transaction) broadcast(db *gorm.DB, ntwrk *network.Network, signer Sign
 		})
 		desc := err.Error()
 		t.updateStatus(db, "failed", &desc)
+		t.settleRelayedGas(db, 0)
 	} else {
 		broadcastAt := time.Now()
 		t.BroadcastAt = &broadcastAt
+		t.dispatchWebhook(db, webhook.EventTransactionBroadcast)
 	}
 
 	return err
@@ -814,6 +1149,7 @@ func (t *Transaction) sign(db *gorm.DB, signer Signer) error {
 	} else {
 		hashAsString := hex.EncodeToString(hash)
 		t.Hash = common.StringOrNil(hashAsString)
+		t.From = common.StringOrNil(signer.Address())
 
 		// ok, this looks wrong, for whatever reason as it's returning just Fe
 		//t.Hash = common.StringOrNil(string(ethcommon.FromHex(string(hash))))
@@ -823,6 +1159,10 @@ func (t *Transaction) sign(db *gorm.DB, signer Signer) error {
 }
 
 func (t *Transaction) fetchReceipt(db *gorm.DB, network *network.Network, signerAddress string) error {
+	if network.IsUTXONetwork() {
+		return t.fetchUTXOReceipt(network)
+	}
+
 	p2pAPI, err := network.P2PAPIClient()
 	if err != nil {
 		return err
@@ -849,6 +1189,21 @@ func (t *Transaction) fetchReceipt(db *gorm.DB, network *network.Network, signer
 		return err
 	}
 
+	err = t.recordGasSpend(db, network, receipt)
+	if err != nil {
+		common.Log.Warningf("failed to record gas spend for tx hash: %s; %s", *t.Hash, err.Error())
+	}
+
+	var block *uint64
+	if receipt.BlockNumber != nil {
+		receiptBlock := receipt.BlockNumber.Uint64()
+		block = &receiptBlock
+	}
+	err = t.persistInternalTransfers(db, network, block)
+	if err != nil {
+		common.Log.Warningf("failed to persist internal transfers for tx hash: %s; %s", *t.Hash, err.Error())
+	}
+
 	traces, traceErr := p2pAPI.FetchTxTraces(*t.Hash)
 	if traceErr != nil {
 		common.Log.Warningf("failed to fetch tx trace for tx hash: %s; %s", *t.Hash, traceErr.Error())
@@ -978,6 +1333,14 @@ func (t *Transaction) handleTxTraces(
 
 						rawParams := json.RawMessage(params)
 
+						existing := &contract.Contract{}
+						db.Where("network_id = ? AND address = ?", t.NetworkID, *contractAddr).Find(&existing)
+						if existing != nil && existing.ID != uuid.Nil {
+							// i.e., the receipt of the tx was redelivered; contracts created by txs in orphaned blocks are cleared
+							common.Log.Debugf("using previously created contract %s for %s contract-internal tx: %s", existing.ID, *network.Name, *t.Hash)
+							continue
+						}
+
 						internalContract := &contract.Contract{
 							ApplicationID:  t.ApplicationID,
 							OrganizationID: t.OrganizationID,
@@ -1037,13 +1400,21 @@ func (t *Transaction) RefreshDetails() error {
 		return nil
 	}
 
-	network, _ := t.GetNetwork()
+	network, err := t.GetNetwork()
+	if err != nil {
+		return err
+	}
+	t.setConfirmations(cachedLatestBlock(network.ID))
+	t.decode(dbconf.DatabaseConnection(), network)
+	if frame := t.traceCallTree(network); frame != nil {
+		t.CallTrace = t.buildCallTrace(dbconf.DatabaseConnection(), frame)
+	}
+
 	p2pAPI, clientErr := network.P2PAPIClient()
 	if clientErr != nil {
 		return clientErr
 	}
 
-	var err error
 	t.Traces, err = p2pAPI.FetchTxTraces(*t.Hash)
 	if err != nil {
 		common.Log.Warningf("failed to fetch tx trace for tx hash: %s; %s", *t.Hash, err.Error())
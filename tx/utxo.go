@@ -0,0 +1,541 @@
+package tx
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/btcsuite/btcd/btcec"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/btcsuite/btcutil"
+	"github.com/btcsuite/btcutil/psbt"
+	redisutil "github.com/kthomas/go-redisutil"
+	uuid "github.com/kthomas/go.uuid"
+	"github.com/provideplatform/nchain/common"
+	"github.com/provideplatform/nchain/contract"
+	"github.com/provideplatform/nchain/network"
+	provideapi "github.com/provideplatform/provide-go/api/nchain"
+)
+
+// utxoDefaultFeeRate is the fee rate, in base units per virtual byte, used when the node cannot estimate a fee rate
+const utxoDefaultFeeRate = int64(1)
+
+// utxoFeeEstimateBlocks is the confirmation target used to estimate the fee rate of UTXO txs
+const utxoFeeEstimateBlocks = 6
+
+// utxoDustThreshold is the minimum value of a change output; smaller change is added to the fee
+const utxoDustThreshold = int64(546)
+
+// utxoCoinbaseMaturity is the number of confirmations after which coinbase outputs are spendable
+const utxoCoinbaseMaturity = int64(100)
+
+// utxoCoinReservationTTL is the duration for which coins spent by a signed tx are excluded from coin selection;
+// by the time a reservation expires the coins of a broadcast tx are no longer returned by the coin index
+const utxoCoinReservationTTL = time.Minute * 10
+
+// UTXOTransaction is a signed bcoin or handshake tx
+type UTXOTransaction struct {
+	Hash    string   // txid
+	Raw     []byte   // serialized signed tx
+	Fee     int64    // fee paid, in base units
+	FeeRate int64    // fee rate, in base units per virtual byte
+	Inputs  int      // number of coins spent
+	PSBT    *string  // base64-encoded finalized PSBT, for bitcoin txs
+	Network string   // bitcoin or handshake
+	coins   []string // outpoints of the coins spent
+}
+
+// utxoSizes are the virtual sizes used to estimate the fee of a UTXO tx
+type utxoSizes struct {
+	overhead int64
+	input    int64
+}
+
+var (
+	bitcoinTxSizes   = utxoSizes{overhead: 11, input: 68} // version, counts, locktime, segwit marker; P2WPKH input
+	handshakeTxSizes = utxoSizes{overhead: 10, input: 66} // version, counts, locktime; witness pubkey hash input
+)
+
+// fee returns the fee of a tx spending the given number of inputs to outputs of the given sizes
+func (s utxoSizes) fee(feeRate int64, inputs int, outputSizes []int64) int64 {
+	size := s.overhead + s.input*int64(inputs)
+	for _, outputSize := range outputSizes {
+		size += outputSize
+	}
+	return size * feeRate
+}
+
+// signUTXOTx builds, funds and signs a tx on a bcoin or handshake network; coins of the signing account are
+// selected from the coin index of the node and signed using the Vault key of the account
+func (txs *TransactionSigner) signUTXOTx(tx *Transaction, signHash hashSignerFunc) (signedTx interface{}, hash []byte, err error) {
+	if txs.Account == nil || txs.Account.PublicKey == nil {
+		return nil, nil, fmt.Errorf("unable to sign %s tx without a signing account; HD wallets are not supported on UTXO networks", *txs.Network.Name)
+	}
+
+	if tx.To == nil || *tx.To == "" {
+		return nil, nil, fmt.Errorf("unable to sign %s tx without a recipient address", *txs.Network.Name)
+	}
+
+	if tx.Value == nil || tx.Value.BigInt().Sign() <= 0 || !tx.Value.BigInt().IsInt64() {
+		return nil, nil, fmt.Errorf("unable to sign %s tx with invalid value", *txs.Network.Name)
+	}
+
+	publicKey, err := hex.DecodeString(strings.TrimPrefix(*txs.Account.PublicKey, "0x"))
+	if err == nil {
+		var pubkey *btcec.PublicKey
+		pubkey, err = btcec.ParsePubKey(publicKey, btcec.S256())
+		if err == nil {
+			publicKey = pubkey.SerializeCompressed()
+		}
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid public key of signing account %s; %s", txs.Account.ID, err.Error())
+	}
+
+	feeRate, err := txs.utxoFeeRate(tx)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// coins are selected and reserved under the lock of the signing account so concurrent txs never spend
+	// the same coins; the reservation is released if the tx is not broadcast
+	var utxoTx *UTXOTransaction
+	err = redisutil.WithRedlock(utxoMutexKey(txs.Network.ID, txs.Account.Address), func() error {
+		now := time.Now()
+
+		coins, err := txs.spendableCoins(reservedCoins(txs.Network.ID, txs.Account.Address, now))
+		if err != nil {
+			return err
+		}
+
+		if txs.Network.IsHandshakeNetwork() {
+			utxoTx, err = txs.signHandshakeTx(tx, publicKey, coins, feeRate, signHash)
+		} else {
+			utxoTx, err = txs.signBitcoinTx(tx, publicKey, coins, feeRate, signHash)
+		}
+		if err != nil {
+			return err
+		}
+
+		return reserveCoins(txs.Network.ID, txs.Account.Address, utxoTx.coins, now)
+	})
+	if err != nil {
+		err = fmt.Errorf("failed to sign %s tx using signing account %s; %s", *txs.Network.Name, txs.Account.Address, err.Error())
+		common.Log.Warning(err.Error())
+		return nil, nil, err
+	}
+
+	params := tx.ParseParams()
+	params["fee"] = utxoTx.Fee
+	params["fee_rate"] = utxoTx.FeeRate
+	params["coins"] = utxoTx.coins
+	if utxoTx.PSBT != nil {
+		params["psbt"] = *utxoTx.PSBT
+	}
+	tx.setParams(params)
+
+	common.Log.Debugf("signed %s tx %s using signing account %s; %d input(s); fee: %d; fee rate: %d", utxoTx.Network, utxoTx.Hash, txs.Account.Address, utxoTx.Inputs, utxoTx.Fee, utxoTx.FeeRate)
+
+	hash, _ = hex.DecodeString(utxoTx.Hash)
+	return utxoTx, hash, nil
+}
+
+// utxoFeeRate resolves the fee rate of the tx, in base units per virtual byte; the fee_rate param takes precedence
+// over the fee rate estimated by the node
+func (txs *TransactionSigner) utxoFeeRate(tx *Transaction) (int64, error) {
+	if feeRate, feeRateOk := tx.ParseParams()["fee_rate"].(float64); feeRateOk {
+		if feeRate < 1 {
+			return 0, fmt.Errorf("invalid fee rate: %v", feeRate)
+		}
+		return int64(feeRate), nil
+	}
+
+	var estimate map[string]interface{}
+	err := txs.Network.InvokeBcoinJSONRPC("estimatesmartfee", []interface{}{utxoFeeEstimateBlocks}, &estimate)
+	if err != nil {
+		common.Log.Warningf("failed to estimate fee rate on network: %s; using default fee rate; %s", txs.Network.ID, err.Error())
+		return utxoDefaultFeeRate, nil
+	}
+
+	// bcoin and hsd return the fee per kB as "fee"; bitcoind-compatible nodes return it as "feerate"
+	feePerKB, feePerKBOk := estimate["fee"].(float64)
+	if !feePerKBOk {
+		feePerKB, feePerKBOk = estimate["feerate"].(float64)
+	}
+	if !feePerKBOk || feePerKB <= 0 {
+		return utxoDefaultFeeRate, nil
+	}
+
+	unit := float64(btcutil.SatoshiPerBitcoin)
+	if txs.Network.IsHandshakeNetwork() {
+		unit = 1e6 // dollarydoos per HNS
+	}
+
+	feeRate := int64(feePerKB * unit / 1000)
+	if feeRate < utxoDefaultFeeRate {
+		feeRate = utxoDefaultFeeRate
+	}
+	return feeRate, nil
+}
+
+// spendableCoins returns the coins of the signing account which can be spent, largest first; immature coinbase
+// outputs, handshake coins bound by a covenant and the given reserved outpoints are excluded
+func (txs *TransactionSigner) spendableCoins(reserved map[string]time.Time) ([]*network.BcoinCoin, error) {
+	coins, err := txs.Network.BcoinCoins(txs.Account.Address)
+	if err != nil {
+		return nil, err
+	}
+
+	var height int64
+	err = txs.Network.InvokeBcoinJSONRPC("getblockcount", []interface{}{}, &height)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve chain height; %s", err.Error())
+	}
+
+	spendable := make([]*network.BcoinCoin, 0)
+	for _, coin := range coins {
+		if coin.Coinbase && (coin.Height < 0 || height-coin.Height+1 < utxoCoinbaseMaturity) {
+			continue
+		}
+		if coin.Covenant != nil && coin.Covenant.Type != 0 {
+			continue
+		}
+		if _, reservedOk := reserved[fmt.Sprintf("%s:%d", coin.Hash, coin.Index)]; reservedOk {
+			continue
+		}
+		spendable = append(spendable, coin)
+	}
+
+	sort.SliceStable(spendable, func(i, j int) bool {
+		return spendable[i].Value > spendable[j].Value
+	})
+	return spendable, nil
+}
+
+// selectCoins selects the coins which fund the given value and the fee of a tx with the given outputs, and
+// returns the selected coins, the fee and the change; change below the dust threshold is added to the fee
+func selectCoins(coins []*network.BcoinCoin, value, feeRate, changeSize int64, sizes utxoSizes, outputSizes []int64) ([]*network.BcoinCoin, int64, int64, error) {
+	selected := make([]*network.BcoinCoin, 0)
+	funded := int64(0)
+
+	for _, coin := range coins {
+		selected = append(selected, coin)
+		funded += coin.Value
+
+		fee := sizes.fee(feeRate, len(selected), outputSizes)
+		if funded < value+fee {
+			continue
+		}
+
+		feeWithChange := sizes.fee(feeRate, len(selected), append(outputSizes, changeSize))
+		change := funded - value - feeWithChange
+		if change < utxoDustThreshold {
+			return selected, funded - value, 0, nil
+		}
+		return selected, feeWithChange, change, nil
+	}
+
+	return nil, 0, 0, fmt.Errorf("insufficient funds; %d coin(s) totaling %d do not cover value %d plus fee", len(coins), funded, value)
+}
+
+// signBitcoinTx builds a PSBT spending the given P2WPKH coins of the signing account, signs each of its inputs
+// using the given hash signer and returns the finalized tx
+func (txs *TransactionSigner) signBitcoinTx(tx *Transaction, publicKey []byte, coins []*network.BcoinCoin, feeRate int64, signHash hashSignerFunc) (*UTXOTransaction, error) {
+	params := txs.Network.BcoinChainParams()
+
+	to, err := btcutil.DecodeAddress(*tx.To, params)
+	if err != nil || !to.IsForNet(params) {
+		return nil, fmt.Errorf("invalid recipient address %s for chain %s", *tx.To, params.Name)
+	}
+	toScript, err := txscript.PayToAddrScript(to)
+	if err != nil {
+		return nil, err
+	}
+
+	from, err := btcutil.NewAddressWitnessPubKeyHash(btcutil.Hash160(publicKey), params)
+	if err != nil {
+		return nil, err
+	}
+	fromScript, _ := txscript.PayToAddrScript(from)
+
+	value := tx.Value.BigInt().Int64()
+	outputs := []*wire.TxOut{wire.NewTxOut(value, toScript)}
+
+	// calldata is committed to using a null data output
+	if tx.Data != nil && *tx.Data != "" && *tx.Data != "0x" {
+		data, err := hex.DecodeString(strings.TrimPrefix(*tx.Data, "0x"))
+		if err != nil {
+			return nil, fmt.Errorf("invalid data; %s", err.Error())
+		}
+		nullData, err := txscript.NullDataScript(data)
+		if err != nil {
+			return nil, err
+		}
+		outputs = append(outputs, wire.NewTxOut(0, nullData))
+	}
+
+	outputSizes := make([]int64, 0)
+	for _, output := range outputs {
+		outputSizes = append(outputSizes, int64(output.SerializeSize()))
+	}
+
+	// only coins locked to the P2WPKH script of the signing account are spent
+	ownCoins := make([]*network.BcoinCoin, 0)
+	for _, coin := range coins {
+		if coin.Script == hex.EncodeToString(fromScript) {
+			ownCoins = append(ownCoins, coin)
+		}
+	}
+
+	changeOutput := wire.NewTxOut(0, fromScript)
+	selected, fee, change, err := selectCoins(ownCoins, value, feeRate, int64(changeOutput.SerializeSize()), bitcoinTxSizes, outputSizes)
+	if err != nil {
+		return nil, err
+	}
+	if change > 0 {
+		changeOutput.Value = change
+		outputs = append(outputs, changeOutput)
+	}
+
+	outpoints := make([]*wire.OutPoint, 0)
+	sequences := make([]uint32, 0)
+	spent := make([]string, 0)
+	for _, coin := range selected {
+		hash, err := chainhash.NewHashFromStr(coin.Hash)
+		if err != nil {
+			return nil, fmt.Errorf("invalid coin hash %s; %s", coin.Hash, err.Error())
+		}
+		outpoints = append(outpoints, wire.NewOutPoint(hash, coin.Index))
+		sequences = append(sequences, wire.MaxTxInSequenceNum)
+		spent = append(spent, fmt.Sprintf("%s:%d", coin.Hash, coin.Index))
+	}
+
+	packet, err := psbt.New(outpoints, outputs, wire.TxVersion, 0, sequences)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create PSBT; %s", err.Error())
+	}
+
+	updater, err := psbt.NewUpdater(packet)
+	if err != nil {
+		return nil, err
+	}
+
+	for i, coin := range selected {
+		err = updater.AddInWitnessUtxo(wire.NewTxOut(coin.Value, fromScript), i)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	sigHashes := txscript.NewTxSigHashes(packet.UnsignedTx)
+	for i, coin := range selected {
+		sigHash, err := txscript.CalcWitnessSigHash(fromScript, sigHashes, txscript.SigHashAll, packet.UnsignedTx, i, coin.Value)
+		if err != nil {
+			return nil, err
+		}
+
+		sig, err := signHash(sigHash)
+		if err != nil {
+			return nil, err
+		}
+		r, s, err := signatureValues(sig)
+		if err != nil {
+			return nil, err
+		}
+
+		der := (&btcec.Signature{R: r, S: s}).Serialize()
+		outcome, err := updater.Sign(i, append(der, byte(txscript.SigHashAll)), publicKey, nil, nil)
+		if err != nil {
+			return nil, fmt.Errorf("failed to add signature of input %d to PSBT; %s", i, err.Error())
+		} else if outcome != psbt.SignSuccesful {
+			return nil, fmt.Errorf("failed to add signature of input %d to PSBT; outcome: %d", i, outcome)
+		}
+	}
+
+	err = psbt.MaybeFinalizeAll(packet)
+	if err != nil {
+		return nil, fmt.Errorf("failed to finalize PSBT; %s", err.Error())
+	}
+	encoded, _ := packet.B64Encode()
+
+	signed, err := psbt.Extract(packet)
+	if err != nil {
+		return nil, fmt.Errorf("failed to extract tx from PSBT; %s", err.Error())
+	}
+
+	buf := new(bytes.Buffer)
+	err = signed.Serialize(buf)
+	if err != nil {
+		return nil, err
+	}
+
+	return &UTXOTransaction{
+		Hash:    signed.TxHash().String(),
+		Raw:     buf.Bytes(),
+		Fee:     fee,
+		FeeRate: feeRate,
+		Inputs:  len(selected),
+		PSBT:    common.StringOrNil(encoded),
+		Network: "bitcoin",
+		coins:   spent,
+	}, nil
+}
+
+// signatureValues returns the R and S values of the given 64-byte [R || S] or 65-byte [R || S || V] signature;
+// S is normalized to the lower half of the curve order
+func signatureValues(sig []byte) (*big.Int, *big.Int, error) {
+	if len(sig) != 64 && len(sig) != 65 {
+		return nil, nil, fmt.Errorf("invalid %d-byte secp256k1 signature", len(sig))
+	}
+
+	r := new(big.Int).SetBytes(sig[:32])
+	s := new(big.Int).SetBytes(sig[32:64])
+
+	n := btcec.S256().N
+	if s.Cmp(new(big.Int).Rsh(n, 1)) > 0 {
+		s = new(big.Int).Sub(n, s)
+	}
+	return r, s, nil
+}
+
+// broadcastUTXOTx broadcasts the signed tx of the given address to the bcoin or hsd node and returns its txid;
+// the coins spent by the tx are released if the node rejects it
+func broadcastUTXOTx(ntwrk *network.Network, address string, signedTx *UTXOTransaction) (string, error) {
+	var txid string
+	var err error
+
+	lockErr := redisutil.WithRedlock(utxoMutexKey(ntwrk.ID, address), func() error {
+		err = ntwrk.InvokeBcoinJSONRPC("sendrawtransaction", []interface{}{hex.EncodeToString(signedTx.Raw)}, &txid)
+		if err != nil {
+			return releaseCoins(ntwrk.ID, address, signedTx.coins, time.Now())
+		}
+		return nil
+	})
+	if err != nil {
+		return "", err
+	} else if lockErr != nil {
+		return "", fmt.Errorf("failed to acquire lock for coins of %s; %s", address, lockErr.Error())
+	}
+
+	if txid == "" {
+		txid = signedTx.Hash
+	}
+	return txid, nil
+}
+
+// releaseUTXOCoins releases the coins spent by the given signed tx of the given address, which was not broadcast
+func releaseUTXOCoins(ntwrk *network.Network, address string, signedTx *UTXOTransaction) {
+	err := redisutil.WithRedlock(utxoMutexKey(ntwrk.ID, address), func() error {
+		return releaseCoins(ntwrk.ID, address, signedTx.coins, time.Now())
+	})
+	if err != nil {
+		common.Log.Warningf("failed to release coins of %s tx %s; %s", signedTx.Network, signedTx.Hash, err.Error())
+	}
+}
+
+// utxoMutexKey returns the key, unique-per-network-and-address, which represents the distributed lock for
+// selecting and broadcasting the coins of the address
+func utxoMutexKey(networkID uuid.UUID, address string) string {
+	return fmt.Sprintf("tx.utxo.%s.%s.mutex", networkID.String(), address)
+}
+
+func utxoReservedCoinsKey(networkID uuid.UUID, address string) string {
+	return fmt.Sprintf("tx.utxo.%s.%s.reserved", networkID.String(), address)
+}
+
+// reservedCoins returns the unexpired reservations of the coins of the given address, keyed by outpoint;
+// the caller must hold the lock of the address
+func reservedCoins(networkID uuid.UUID, address string, now time.Time) map[string]time.Time {
+	reserved := map[string]time.Time{}
+	raw, _ := redisutil.Get(utxoReservedCoinsKey(networkID, address))
+	if raw != nil {
+		json.Unmarshal([]byte(*raw), &reserved)
+	}
+
+	for outpoint, expiresAt := range reserved {
+		if !now.Before(expiresAt) {
+			delete(reserved, outpoint)
+		}
+	}
+	return reserved
+}
+
+// reserveCoins excludes the given outpoints from coin selection until the reservation expires; the caller
+// must hold the lock of the address
+func reserveCoins(networkID uuid.UUID, address string, outpoints []string, now time.Time) error {
+	reserved := reservedCoins(networkID, address, now)
+	for _, outpoint := range outpoints {
+		reserved[outpoint] = now.Add(utxoCoinReservationTTL)
+	}
+	return setReservedCoins(networkID, address, reserved)
+}
+
+// releaseCoins makes the given outpoints available for coin selection; the caller must hold the lock of the address
+func releaseCoins(networkID uuid.UUID, address string, outpoints []string, now time.Time) error {
+	reserved := reservedCoins(networkID, address, now)
+	for _, outpoint := range outpoints {
+		delete(reserved, outpoint)
+	}
+	return setReservedCoins(networkID, address, reserved)
+}
+
+func setReservedCoins(networkID uuid.UUID, address string, reserved map[string]time.Time) error {
+	raw, _ := json.Marshal(reserved)
+	ttl := utxoCoinReservationTTL
+	if len(reserved) == 0 {
+		ttl = time.Millisecond // expires the reservations, as keys cannot be deleted using redisutil
+	}
+	return redisutil.Set(utxoReservedCoinsKey(networkID, address), string(raw), &ttl)
+}
+
+// fetchUTXOReceipt resolves the receipt of the tx once it is included in a block on the bcoin or handshake network
+func (t *Transaction) fetchUTXOReceipt(ntwrk *network.Network) error {
+	if t.Hash == nil {
+		return fmt.Errorf("unable to fetch tx receipt for nil tx hash; tx id: %s", t.ID)
+	}
+
+	var raw map[string]interface{}
+	err := ntwrk.InvokeBcoinJSONRPC("getrawtransaction", []interface{}{*t.Hash, 1}, &raw)
+	if err != nil {
+		return err
+	}
+
+	blockHash, _ := raw["blockhash"].(string)
+	if blockHash == "" {
+		return fmt.Errorf("tx %s not yet included in a block", *t.Hash)
+	}
+
+	var header map[string]interface{}
+	err = ntwrk.InvokeBcoinJSONRPC("getblockheader", []interface{}{blockHash, true}, &header)
+	if err != nil {
+		return err
+	}
+
+	height, heightOk := header["height"].(float64)
+	if !heightOk {
+		return fmt.Errorf("failed to resolve height of block %s", blockHash)
+	}
+
+	txHash, _ := hex.DecodeString(*t.Hash)
+	_blockHash, _ := hex.DecodeString(blockHash)
+
+	common.Log.Debugf("fetched receipt of %s tx %s included in block %d", *ntwrk.Name, *t.Hash, uint64(height))
+	t.Response = &contract.ExecutionResponse{
+		Receipt: &provideapi.TxReceipt{
+			TxHash:      txHash,
+			BlockHash:   _blockHash,
+			BlockNumber: new(big.Int).SetUint64(uint64(height)),
+			Status:      1,
+		},
+		Transaction: t,
+	}
+
+	return nil
+}
@@ -0,0 +1,239 @@
+// +build unit
+
+package tx
+
+import (
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/btcsuite/btcd/btcec"
+	uuid "github.com/kthomas/go.uuid"
+	"github.com/provideplatform/nchain/network"
+	"github.com/provideplatform/nchain/wallet"
+)
+
+// bcoinStubFactory starts a bcoin node stub which returns the given coins from its coin index and rejects
+// broadcast txs, and returns a bcoin network configured to use it; the caller must close the returned server
+func bcoinStubFactory(t *testing.T, coins []*network.BcoinCoin) (*network.Network, *httptest.Server) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("content-type", "application/json")
+
+		if r.Method == http.MethodGet && strings.HasPrefix(r.URL.Path, "/coin/address/") {
+			json.NewEncoder(w).Encode(coins)
+			return
+		}
+
+		var req struct {
+			ID     json.RawMessage `json:"id"`
+			Method string          `json:"method"`
+		}
+		json.NewDecoder(r.Body).Decode(&req)
+
+		resp := map[string]interface{}{
+			"jsonrpc": "2.0",
+			"id":      req.ID,
+		}
+		switch req.Method {
+		case "getblockcount":
+			resp["result"] = 1000
+		case "sendrawtransaction":
+			resp["error"] = map[string]interface{}{"code": -25, "message": "bad-txns-inputs-missingorspent"}
+		default:
+			t.Logf("unexpected JSON-RPC call: %s", req.Method)
+			resp["error"] = map[string]interface{}{"code": -32601, "message": "method not found"}
+		}
+		json.NewEncoder(w).Encode(resp)
+	}))
+
+	networkID, _ := uuid.NewV4()
+	cfg, _ := json.Marshal(map[string]interface{}{
+		"is_bcoin_network": true,
+		"json_rpc_url":     srv.URL,
+	})
+	rawCfg := json.RawMessage(cfg)
+
+	ntwrk := &network.Network{
+		Config: &rawCfg,
+	}
+	ntwrk.ID = networkID
+
+	return ntwrk, srv
+}
+
+func utxoCoinsFixture() []*network.BcoinCoin {
+	return []*network.BcoinCoin{
+		{Hash: "9f2b1c3d4e5f60718293a4b5c6d7e8f90a1b2c3d4e5f60718293a4b5c6d7e8f9", Index: 0, Value: 50000, Height: 900},
+		{Hash: "1a2b3c4d5e6f708192a3b4c5d6e7f8091a2b3c4d5e6f708192a3b4c5d6e7f809", Index: 1, Value: 20000, Height: 950},
+	}
+}
+
+func TestSpendableCoinsExcludesReservedCoins(t *testing.T) {
+	coins := utxoCoinsFixture()
+	ntwrk, srv := bcoinStubFactory(t, coins)
+	defer srv.Close()
+
+	address := "bc1qtestaddress0000000000000000000000000"
+	txs := &TransactionSigner{
+		Network: ntwrk,
+		Account: &wallet.Account{Address: address},
+	}
+
+	now := time.Now()
+	inFlight := []string{"9f2b1c3d4e5f60718293a4b5c6d7e8f90a1b2c3d4e5f60718293a4b5c6d7e8f9:0"}
+	err := reserveCoins(ntwrk.ID, address, inFlight, now)
+	if err != nil {
+		t.Fatalf("failed to reserve coins; %s", err.Error())
+	}
+
+	spendable, err := txs.spendableCoins(reservedCoins(ntwrk.ID, address, now))
+	if err != nil {
+		t.Fatalf("failed to resolve spendable coins; %s", err.Error())
+	}
+	if len(spendable) != 1 || spendable[0].Hash != coins[1].Hash {
+		t.Errorf("expected only the coin not spent by the in-flight tx to be spendable; got %d coin(s)", len(spendable))
+	}
+
+	// reservations of txs which were never broadcast expire
+	spendable, _ = txs.spendableCoins(reservedCoins(ntwrk.ID, address, now.Add(utxoCoinReservationTTL)))
+	if len(spendable) != 2 {
+		t.Errorf("expected expired reservation to be ignored; got %d spendable coin(s)", len(spendable))
+	}
+}
+
+func TestRejectedUTXOTxReleasesCoins(t *testing.T) {
+	ntwrk, srv := bcoinStubFactory(t, utxoCoinsFixture())
+	defer srv.Close()
+
+	address := "bc1qtestaddress0000000000000000000000001"
+	signedTx := &UTXOTransaction{
+		Hash:    "5d6e7f8091a2b3c4d5e6f708192a3b4c5d6e7f8091a2b3c4d5e6f708192a3b4c",
+		Raw:     []byte{0x02, 0x00, 0x00, 0x00},
+		Network: "bitcoin",
+		coins: []string{
+			"9f2b1c3d4e5f60718293a4b5c6d7e8f90a1b2c3d4e5f60718293a4b5c6d7e8f9:0",
+			"1a2b3c4d5e6f708192a3b4c5d6e7f8091a2b3c4d5e6f708192a3b4c5d6e7f809:1",
+		},
+	}
+
+	now := time.Now()
+	reserveCoins(ntwrk.ID, address, signedTx.coins, now)
+	if reserved := reservedCoins(ntwrk.ID, address, now); len(reserved) != 2 {
+		t.Fatalf("expected 2 reserved coins; got %d", len(reserved))
+	}
+
+	_, err := broadcastUTXOTx(ntwrk, address, signedTx)
+	if err == nil {
+		t.Fatalf("expected broadcast of tx spending missing coins to fail")
+	}
+
+	if reserved := reservedCoins(ntwrk.ID, address, time.Now()); len(reserved) != 0 {
+		t.Errorf("expected coins of rejected tx to be released; %d coin(s) still reserved", len(reserved))
+	}
+}
+
+func TestUTXOFee(t *testing.T) {
+	// 11 vbytes overhead, 2 inputs of 68 vbytes and outputs of 31 and 43 vbytes at 3 per vbyte
+	fee := bitcoinTxSizes.fee(3, 2, []int64{31, 43})
+	if fee != (11+2*68+31+43)*3 {
+		t.Errorf("expected fee of 663; got %d", fee)
+	}
+}
+
+func TestSelectCoinsWithChange(t *testing.T) {
+	coins := utxoCoinsFixture() // 50000 and 20000, largest first
+	outputSizes := []int64{31}
+
+	selected, fee, change, err := selectCoins(coins, 30000, 1, 31, bitcoinTxSizes, outputSizes)
+	if err != nil {
+		t.Fatalf("failed to select coins; %s", err.Error())
+	}
+	if len(selected) != 1 || selected[0].Value != 50000 {
+		t.Fatalf("expected the largest coin alone to be selected; got %d coin(s)", len(selected))
+	}
+
+	expectedFee := bitcoinTxSizes.fee(1, 1, []int64{31, 31})
+	if fee != expectedFee {
+		t.Errorf("expected fee including the change output of %d; got %d", expectedFee, fee)
+	}
+	if change != 50000-30000-expectedFee {
+		t.Errorf("expected change of %d; got %d", 50000-30000-expectedFee, change)
+	}
+}
+
+func TestSelectCoinsAddsDustChangeToFee(t *testing.T) {
+	coins := utxoCoinsFixture()
+	outputSizes := []int64{31}
+
+	// the change would be below the dust threshold
+	value := 50000 - bitcoinTxSizes.fee(1, 1, []int64{31, 31}) - utxoDustThreshold + 1
+
+	selected, fee, change, err := selectCoins(coins, value, 1, 31, bitcoinTxSizes, outputSizes)
+	if err != nil {
+		t.Fatalf("failed to select coins; %s", err.Error())
+	}
+	if len(selected) != 1 {
+		t.Fatalf("expected 1 coin to be selected; got %d", len(selected))
+	}
+	if change != 0 {
+		t.Errorf("expected no change output; got change of %d", change)
+	}
+	if fee != 50000-value {
+		t.Errorf("expected dust change to be added to the fee of %d; got %d", 50000-value, fee)
+	}
+}
+
+func TestSelectCoinsSpendsAdditionalCoins(t *testing.T) {
+	coins := utxoCoinsFixture()
+	outputSizes := []int64{31}
+
+	selected, fee, change, err := selectCoins(coins, 60000, 2, 31, bitcoinTxSizes, outputSizes)
+	if err != nil {
+		t.Fatalf("failed to select coins; %s", err.Error())
+	}
+	if len(selected) != 2 {
+		t.Fatalf("expected both coins to be selected; got %d", len(selected))
+	}
+	if 60000+fee+change != 70000 {
+		t.Errorf("expected value, fee and change to total the selected coins; got %d", 60000+fee+change)
+	}
+}
+
+func TestSelectCoinsInsufficientFunds(t *testing.T) {
+	coins := utxoCoinsFixture()
+
+	_, _, _, err := selectCoins(coins, 70000, 1, 31, bitcoinTxSizes, []int64{31})
+	if err == nil {
+		t.Errorf("expected coins not covering the fee to be insufficient")
+	}
+
+	_, _, _, err = selectCoins([]*network.BcoinCoin{}, 1, 1, 31, bitcoinTxSizes, []int64{31})
+	if err == nil {
+		t.Errorf("expected no coins to be insufficient")
+	}
+}
+
+func TestSignatureValuesNormalizesS(t *testing.T) {
+	n := btcec.S256().N
+	highS := new(big.Int).Sub(n, big.NewInt(1))
+
+	sig := make([]byte, 65)
+	big.NewInt(7).FillBytes(sig[:32])
+	highS.FillBytes(sig[32:64])
+
+	r, s, err := signatureValues(sig)
+	if err != nil {
+		t.Fatalf("failed to parse signature; %s", err.Error())
+	}
+	if r.Int64() != 7 || s.Int64() != 1 {
+		t.Errorf("expected S in the upper half of the curve order to be normalized; got r: %s; s: %s", r.String(), s.String())
+	}
+
+	if _, _, err := signatureValues(sig[:63]); err == nil {
+		t.Errorf("expected 63-byte signature to be rejected")
+	}
+}
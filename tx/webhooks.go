@@ -0,0 +1,65 @@
+package tx
+
+import (
+	"time"
+
+	"github.com/jinzhu/gorm"
+	"github.com/provideplatform/nchain/common"
+	"github.com/provideplatform/nchain/webhook"
+)
+
+// webhookEvents maps the tx statuses which are delivered to webhook subscribers to their event type
+var webhookEvents = map[string]string{
+	"success":       webhook.EventTransactionSuccess,
+	"failed":        webhook.EventTransactionFailed,
+	txStatusDropped: webhook.EventTransactionDropped,
+}
+
+// webhookPayload returns the body of the webhook callback which notifies subscribers of the given tx event
+func (t *Transaction) webhookPayload(event string) map[string]interface{} {
+	payload := map[string]interface{}{
+		"id":              t.ID,
+		"network_id":      t.NetworkID,
+		"application_id":  t.ApplicationID,
+		"organization_id": t.OrganizationID,
+		"ref":             t.Ref,
+		"hash":            t.Hash,
+		"from":            t.From,
+		"to":              t.To,
+		"nonce":           t.Nonce,
+		"status":          t.Status,
+		"description":     t.Description,
+		"block":           t.Block,
+		"block_timestamp": t.BlockTimestamp,
+		"broadcast_at":    t.BroadcastAt,
+		"finalized_at":    t.FinalizedAt,
+		"queue_latency":   t.QueueLatency,
+		"network_latency": t.NetworkLatency,
+		"e2e_latency":     t.E2ELatency,
+		"failure_reason":  t.FailureReason,
+	}
+
+	// the decoded execution response, i.e. the return values of a contract call, when it was resolved
+	if t.Response != nil && t.Response.Response != nil {
+		payload["response"] = t.Response.Response
+	}
+
+	return map[string]interface{}{
+		"event":       event,
+		"transaction": payload,
+		"timestamp":   time.Now(),
+	}
+}
+
+// dispatchWebhook enqueues delivery of the given tx event to the webhooks of the application or
+// organization on whose behalf the tx was broadcast
+func (t *Transaction) dispatchWebhook(db *gorm.DB, event string) {
+	if t.ApplicationID == nil && t.OrganizationID == nil {
+		return
+	}
+
+	err := webhook.Dispatch(db, t.ApplicationID, t.OrganizationID, event, t.webhookPayload(event))
+	if err != nil {
+		common.Log.Warningf("failed to dispatch %s webhook event for tx: %s; %s", event, t.ID, err.Error())
+	}
+}
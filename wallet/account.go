@@ -1,9 +1,12 @@
 package wallet
 
 import (
+	"encoding/hex"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"math/big"
+	"strings"
 	"time"
 
 	"github.com/jinzhu/gorm"
@@ -32,6 +35,10 @@ type Account struct {
 
 	Type *string `json:"type,omitempty"`
 
+	// Signer config of an account which is signed outside of Vault; secrets are only persisted encrypted
+	Config          *json.RawMessage `sql:"type:json" json:"config,omitempty"`
+	EncryptedConfig *string          `sql:"type:bytea" json:"-"`
+
 	HDDerivationPath *string `json:"hd_derivation_path,omitempty"` // i.e. m/44'/60'/0'/0
 	PublicKey        *string `sql:"type:bytea" json:"public_key,omitempty"`
 	PrivateKey       *string `sql:"-" json:"private_key,omitempty"`
@@ -82,6 +89,24 @@ func (a *Account) generate(db *gorm.DB) error {
 	a.VaultID = key.VaultID
 	a.KeyID = &key.ID
 
+	// bcoin and handshake accounts are addressed by the witness pubkey hash of the key
+	if network.IsUTXONetwork() && key.PublicKey != nil {
+		publicKey, err := hex.DecodeString(strings.TrimPrefix(*key.PublicKey, "0x"))
+		if err != nil {
+			err := fmt.Errorf("unable to decode public key of account; %s", err.Error())
+			common.Log.Warning(err.Error())
+			return err
+		}
+
+		address, err := network.BcoinAddress(publicKey)
+		if err != nil {
+			err := fmt.Errorf("unable to derive address of account for network: %s; %s", network.ID, err.Error())
+			common.Log.Warning(err.Error())
+			return err
+		}
+		a.Address = address
+	}
+
 	// if network.IsEthereumNetwork() {
 	// 	// addr, privateKey, err := providecrypto.EVMGenerateKeyPair()
 	// 	// if err != nil {
@@ -146,9 +171,16 @@ func (a *Account) GetNetwork() (*network.Network, error) {
 func (a *Account) Create() bool {
 	db := dbconf.DatabaseConnection()
 
-	a.generate(db)
-	if !a.Validate() {
-		return false
+	if a.IsExternallySigned() {
+		a.resolveExternalAddress()
+		if !a.Validate() || !a.sanitizeConfig() {
+			return false
+		}
+	} else {
+		a.generate(db)
+		if !a.Validate() {
+			return false
+		}
 	}
 
 	if db.NewRecord(a) {
@@ -174,15 +206,19 @@ func (a *Account) Validate() bool {
 	a.Errors = make([]*provide.Error, 0)
 	var network = &network.Network{}
 	dbconf.DatabaseConnection().Model(a).Related(&network)
-	if a.VaultID == nil || *a.VaultID == uuid.Nil {
-		a.Errors = append(a.Errors, &provide.Error{
-			Message: common.StringOrNil("vault id required"),
-		})
-	}
-	if a.KeyID == nil || *a.KeyID == uuid.Nil {
-		a.Errors = append(a.Errors, &provide.Error{
-			Message: common.StringOrNil("vault key id required"),
-		})
+	if a.IsExternallySigned() {
+		a.validateExternalSigner()
+	} else {
+		if a.VaultID == nil || *a.VaultID == uuid.Nil {
+			a.Errors = append(a.Errors, &provide.Error{
+				Message: common.StringOrNil("vault id required"),
+			})
+		}
+		if a.KeyID == nil || *a.KeyID == uuid.Nil {
+			a.Errors = append(a.Errors, &provide.Error{
+				Message: common.StringOrNil("vault key id required"),
+			})
+		}
 	}
 	if a.NetworkID != nil && *a.NetworkID == uuid.Nil {
 		a.Errors = append(a.Errors, &provide.Error{
@@ -214,6 +250,15 @@ func (a *Account) NativeCurrencyBalance() (*big.Int, error) {
 		if err != nil {
 			return nil, err
 		}
+	} else if network.IsUTXONetwork() {
+		coins, err := network.BcoinCoins(a.Address)
+		if err != nil {
+			return nil, err
+		}
+		balance = big.NewInt(0)
+		for _, coin := range coins {
+			balance.Add(balance, big.NewInt(coin.Value))
+		}
 	} else {
 		common.Log.Warningf("unable to read native currency balance for network: %s", a.NetworkID)
 	}
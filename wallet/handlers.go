@@ -26,6 +26,8 @@ func InstallAccountsAPI(r *gin.Engine) {
 	r.POST("/api/v1/accounts", createAccountHandler)
 	r.GET("/api/v1/accounts/:id", accountDetailsHandler)
 	r.GET("/api/v1/accounts/:id/balances/:tokenId", accountBalanceHandler)
+
+	installPoliciesAPI(r, "accounts")
 }
 
 // InstallWalletsAPI installs the handlers using the given gin Engine
@@ -34,6 +36,8 @@ func InstallWalletsAPI(r *gin.Engine) {
 	r.POST("/api/v1/wallets", createWalletHandler)
 	r.GET("/api/v1/wallets/:id", walletDetailsHandler)
 	r.GET("/api/v1/wallets/:id/accounts", walletAccountsListHandler)
+
+	installPoliciesAPI(r, "wallets")
 }
 
 func createAccountHandler(c *gin.Context) {
@@ -0,0 +1,449 @@
+package wallet
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+
+	ethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/jinzhu/gorm"
+	dbconf "github.com/kthomas/go-db-config"
+	uuid "github.com/kthomas/go.uuid"
+	"github.com/provideplatform/nchain/common"
+	provide "github.com/provideplatform/provide-go/api"
+)
+
+// defaultPolicyApprovalTimeout is the duration for which a tx awaits approval when the policy does not specify a timeout
+const defaultPolicyApprovalTimeout = time.Hour * 24
+
+// policyWindowLayout is the layout of the time-of-day window boundaries of a policy, i.e. 09:00
+const policyWindowLayout = "15:04"
+
+// Policy is a spending policy which is enforced before a tx is custodially signed using the account
+// or HD wallet to which it belongs; every enabled policy of the signer must be satisfied
+type Policy struct {
+	provide.Model
+	AccountID *uuid.UUID `sql:"type:uuid" json:"account_id,omitempty"`
+	WalletID  *uuid.UUID `sql:"type:uuid" json:"wallet_id,omitempty"`
+	Name      *string    `json:"name,omitempty"`
+	Enabled   bool       `sql:"not null" json:"enabled"`
+
+	// Value limits, in the smallest denomination of the native currency (i.e., wei)
+	MaxValue   *string `json:"max_value,omitempty"`   // maximum value of a single tx
+	DailyLimit *string `json:"daily_limit,omitempty"` // maximum aggregate value signed per calendar day in the policy timezone
+
+	// Allowlists; when set, txs to other addresses, or calls of other methods, are rejected
+	AllowedAddresses *json.RawMessage `sql:"type:json" json:"allowed_addresses,omitempty"` // recipient addresses and contracts
+	AllowedMethods   *json.RawMessage `sql:"type:json" json:"allowed_methods,omitempty"`   // 4-byte method selectors, i.e. 0xa9059cbb
+
+	// Time-of-day window during which txs may be signed; the window wraps midnight when it ends before it starts
+	WindowStart *string `json:"window_start,omitempty"` // i.e. 09:00
+	WindowEnd   *string `json:"window_end,omitempty"`   // i.e. 17:30
+	Timezone    *string `json:"timezone,omitempty"`     // IANA time zone of the window and daily limit; defaults to UTC
+
+	// Approvals required to sign a tx having a value greater than the approval threshold; the tx awaits
+	// approval by the required number of designated approvers until the approval timeout elapses
+	ApprovalThreshold *string          `json:"approval_threshold,omitempty"`
+	RequiredApprovals *int             `json:"required_approvals,omitempty"`
+	Approvers         *json.RawMessage `sql:"type:json" json:"approvers,omitempty"` // ids of the users designated to approve
+	ApprovalTimeout   *int64           `json:"approval_timeout,omitempty"`          // in seconds; defaults to 24 hours
+}
+
+// PolicyRequest is a tx pending signature which is evaluated against the spending policies of its signer
+type PolicyRequest struct {
+	To        *string
+	Value     *big.Int
+	Data      *string
+	Timestamp time.Time
+
+	// Spent returns the aggregate value signed, on or after the given time, by the account or
+	// HD wallet to which the evaluated policy belongs; the daily limit is only enforced when the value
+	// spent by concurrently evaluated txs is included, and the evaluation serialized with their own
+	Spent func(policy *Policy, since time.Time) (*big.Int, error)
+}
+
+// PolicyViolation is the reason a tx was rejected by a spending policy
+type PolicyViolation struct {
+	PolicyID          uuid.UUID
+	Reason            string
+	RequiredApprovals int // non-zero when the tx is only rejected for lack of approvals
+}
+
+// Error implements the error interface
+func (v *PolicyViolation) Error() string {
+	return fmt.Sprintf("spending policy %s violated; %s", v.PolicyID, v.Reason)
+}
+
+// ResolvePolicies returns the enabled spending policies of the given account and/or HD wallet
+func ResolvePolicies(db *gorm.DB, accountID, walletID *uuid.UUID) []*Policy {
+	policies := make([]*Policy, 0)
+	if accountID == nil && walletID == nil {
+		return policies
+	}
+
+	query := db.Where("enabled = true")
+	if accountID != nil && walletID != nil {
+		query = query.Where("account_id = ? OR wallet_id = ?", accountID, walletID)
+	} else if accountID != nil {
+		query = query.Where("account_id = ?", accountID)
+	} else {
+		query = query.Where("wallet_id = ?", walletID)
+	}
+	query.Order("created_at ASC").Find(&policies)
+	return policies
+}
+
+// Create and persist a spending policy
+func (p *Policy) Create() bool {
+	db := dbconf.DatabaseConnection()
+
+	if !p.Validate() {
+		return false
+	}
+
+	if db.NewRecord(p) {
+		result := db.Create(&p)
+		rowsAffected := result.RowsAffected
+		errors := result.GetErrors()
+		if len(errors) > 0 {
+			for _, err := range errors {
+				p.Errors = append(p.Errors, &provide.Error{
+					Message: common.StringOrNil(err.Error()),
+				})
+			}
+		}
+		if !db.NewRecord(p) {
+			return rowsAffected > 0
+		}
+	}
+	return false
+}
+
+// Update an existing spending policy
+func (p *Policy) Update() bool {
+	db := dbconf.DatabaseConnection()
+
+	if !p.Validate() {
+		return false
+	}
+
+	result := db.Save(&p)
+	errors := result.GetErrors()
+	if len(errors) > 0 {
+		for _, err := range errors {
+			p.Errors = append(p.Errors, &provide.Error{
+				Message: common.StringOrNil(err.Error()),
+			})
+		}
+	}
+	return len(p.Errors) == 0
+}
+
+// Delete a spending policy
+func (p *Policy) Delete() bool {
+	db := dbconf.DatabaseConnection()
+	result := db.Delete(p)
+	errors := result.GetErrors()
+	if len(errors) > 0 {
+		for _, err := range errors {
+			p.Errors = append(p.Errors, &provide.Error{
+				Message: common.StringOrNil(err.Error()),
+			})
+		}
+	}
+	return len(p.Errors) == 0
+}
+
+// Validate a spending policy for persistence
+func (p *Policy) Validate() bool {
+	p.Errors = make([]*provide.Error, 0)
+
+	if (p.AccountID == nil) == (p.WalletID == nil) {
+		p.Errors = append(p.Errors, &provide.Error{
+			Message: common.StringOrNil("policy must be associated with exactly one account or HD wallet"),
+		})
+	}
+
+	fields := []string{"max_value", "daily_limit", "approval_threshold"}
+	for i, value := range []*string{p.MaxValue, p.DailyLimit, p.ApprovalThreshold} {
+		if value != nil && parsePolicyValue(value) == nil {
+			p.Errors = append(p.Errors, &provide.Error{
+				Message: common.StringOrNil(fmt.Sprintf("policy %s must be a non-negative integer value", fields[i])),
+			})
+		}
+	}
+
+	if p.AllowedAddresses != nil {
+		addresses, err := p.allowedAddresses()
+		if err != nil {
+			p.Errors = append(p.Errors, &provide.Error{
+				Message: common.StringOrNil("policy allowed_addresses must be a list of addresses"),
+			})
+		}
+		for _, address := range addresses {
+			if !ethcommon.IsHexAddress(address) {
+				p.Errors = append(p.Errors, &provide.Error{
+					Message: common.StringOrNil(fmt.Sprintf("policy allowed address is invalid: %s", address)),
+				})
+			}
+		}
+	}
+
+	if p.AllowedMethods != nil {
+		selectors, err := p.allowedMethods()
+		if err != nil {
+			p.Errors = append(p.Errors, &provide.Error{
+				Message: common.StringOrNil("policy allowed_methods must be a list of method selectors"),
+			})
+		}
+		for _, selector := range selectors {
+			if _, err := parseMethodSelector(selector); err != nil {
+				p.Errors = append(p.Errors, &provide.Error{
+					Message: common.StringOrNil(fmt.Sprintf("policy allowed method selector is invalid: %s", selector)),
+				})
+			}
+		}
+	}
+
+	if (p.WindowStart == nil) != (p.WindowEnd == nil) {
+		p.Errors = append(p.Errors, &provide.Error{
+			Message: common.StringOrNil("policy time-of-day window requires both window_start and window_end"),
+		})
+	} else if p.WindowStart != nil {
+		start, startErr := time.Parse(policyWindowLayout, *p.WindowStart)
+		end, endErr := time.Parse(policyWindowLayout, *p.WindowEnd)
+		if startErr != nil || endErr != nil {
+			p.Errors = append(p.Errors, &provide.Error{
+				Message: common.StringOrNil("policy window_start and window_end must be formatted as HH:MM"),
+			})
+		} else if start.Equal(end) {
+			p.Errors = append(p.Errors, &provide.Error{
+				Message: common.StringOrNil("policy time-of-day window must not be empty"),
+			})
+		}
+	}
+
+	if _, err := p.location(); err != nil {
+		p.Errors = append(p.Errors, &provide.Error{
+			Message: common.StringOrNil(fmt.Sprintf("policy timezone is invalid: %s", *p.Timezone)),
+		})
+	}
+
+	if (p.ApprovalThreshold == nil) != (p.RequiredApprovals == nil) {
+		p.Errors = append(p.Errors, &provide.Error{
+			Message: common.StringOrNil("policy approvals require both approval_threshold and required_approvals"),
+		})
+	} else if p.RequiredApprovals != nil && *p.RequiredApprovals < 1 {
+		p.Errors = append(p.Errors, &provide.Error{
+			Message: common.StringOrNil("policy required_approvals must be at least 1"),
+		})
+	} else if p.RequiredApprovals != nil {
+		approvers, err := p.ApproverIDs()
+		if err != nil {
+			p.Errors = append(p.Errors, &provide.Error{
+				Message: common.StringOrNil("policy approvers must be a list of user ids"),
+			})
+		} else if len(approvers) < *p.RequiredApprovals {
+			p.Errors = append(p.Errors, &provide.Error{
+				Message: common.StringOrNil(fmt.Sprintf("policy requires %d approval(s) but designates %d approver(s)", *p.RequiredApprovals, len(approvers))),
+			})
+		}
+	}
+
+	if p.ApprovalTimeout != nil && *p.ApprovalTimeout <= 0 {
+		p.Errors = append(p.Errors, &provide.Error{
+			Message: common.StringOrNil("policy approval_timeout must be a positive number of seconds"),
+		})
+	}
+
+	return len(p.Errors) == 0
+}
+
+// Evaluate the tx pending signature against the policy; a PolicyViolation is returned if the tx is rejected.
+// Hard limits are evaluated before the approval threshold, as approvals never override a hard limit.
+// Plain value transfers, having no calldata, are not subject to the method allowlist.
+func (p *Policy) Evaluate(req *PolicyRequest) error {
+	loc, _ := p.location()
+	at := req.Timestamp.In(loc)
+
+	value := req.Value
+	if value == nil {
+		value = big.NewInt(0)
+	}
+
+	if p.WindowStart != nil && p.WindowEnd != nil && !p.withinWindow(at) {
+		return p.violation(fmt.Sprintf("tx signed at %s is outside the permitted window %s-%s %s", at.Format(policyWindowLayout), *p.WindowStart, *p.WindowEnd, loc.String()))
+	}
+
+	if p.AllowedAddresses != nil {
+		if req.To == nil || *req.To == "" {
+			return p.violation("contract creation is not permitted by the address allowlist")
+		}
+
+		allowed := false
+		addresses, _ := p.allowedAddresses()
+		for _, address := range addresses {
+			if strings.EqualFold(address, *req.To) {
+				allowed = true
+				break
+			}
+		}
+		if !allowed {
+			return p.violation(fmt.Sprintf("recipient %s is not allowlisted", *req.To))
+		}
+	}
+
+	if p.AllowedMethods != nil && req.Data != nil {
+		data, _ := hex.DecodeString(strings.TrimPrefix(*req.Data, "0x"))
+		if len(data) >= 4 {
+			allowed := false
+			selectors, _ := p.allowedMethods()
+			for _, selector := range selectors {
+				if _selector, err := parseMethodSelector(selector); err == nil && string(_selector) == string(data[0:4]) {
+					allowed = true
+					break
+				}
+			}
+			if !allowed {
+				return p.violation(fmt.Sprintf("method selector 0x%x is not allowlisted", data[0:4]))
+			}
+		}
+	}
+
+	if maxValue := parsePolicyValue(p.MaxValue); maxValue != nil && value.Cmp(maxValue) > 0 {
+		return p.violation(fmt.Sprintf("tx value %s exceeds the maximum value per tx of %s", value.String(), maxValue.String()))
+	}
+
+	if dailyLimit := parsePolicyValue(p.DailyLimit); dailyLimit != nil {
+		dayStart := time.Date(at.Year(), at.Month(), at.Day(), 0, 0, 0, 0, loc)
+		spent, err := req.Spent(p, dayStart)
+		if err != nil {
+			return p.violation(fmt.Sprintf("failed to resolve value spent since %s; %s", dayStart.Format(time.RFC3339), err.Error()))
+		}
+
+		total := new(big.Int).Add(spent, value)
+		if total.Cmp(dailyLimit) > 0 {
+			return p.violation(fmt.Sprintf("tx value %s exceeds the remaining daily limit; %s of %s already spent", value.String(), spent.String(), dailyLimit.String()))
+		}
+	}
+
+	if threshold := parsePolicyValue(p.ApprovalThreshold); threshold != nil && p.RequiredApprovals != nil && value.Cmp(threshold) > 0 {
+		violation := p.violation(fmt.Sprintf("tx value %s exceeds the approval threshold of %s; %d approval(s) required", value.String(), threshold.String(), *p.RequiredApprovals))
+		violation.RequiredApprovals = *p.RequiredApprovals
+		return violation
+	}
+
+	return nil
+}
+
+// ApproverIDs returns the distinct ids of the users designated to approve txs exceeding the approval threshold
+func (p *Policy) ApproverIDs() ([]uuid.UUID, error) {
+	approvers := make([]uuid.UUID, 0)
+	if p.Approvers == nil {
+		return approvers, nil
+	}
+
+	var ids []uuid.UUID
+	err := json.Unmarshal(*p.Approvers, &ids)
+	if err != nil {
+		return nil, err
+	}
+
+	seen := map[uuid.UUID]bool{}
+	for _, id := range ids {
+		if id != uuid.Nil && !seen[id] {
+			approvers = append(approvers, id)
+			seen[id] = true
+		}
+	}
+	return approvers, nil
+}
+
+// ApprovalExpiry returns the duration for which a tx awaits approval under the policy
+func (p *Policy) ApprovalExpiry() time.Duration {
+	if p.ApprovalTimeout == nil || *p.ApprovalTimeout <= 0 {
+		return defaultPolicyApprovalTimeout
+	}
+	return time.Duration(*p.ApprovalTimeout) * time.Second
+}
+
+// allowedAddresses parses the recipient address allowlist
+func (p *Policy) allowedAddresses() ([]string, error) {
+	addresses := make([]string, 0)
+	if p.AllowedAddresses == nil {
+		return addresses, nil
+	}
+	err := json.Unmarshal(*p.AllowedAddresses, &addresses)
+	return addresses, err
+}
+
+// allowedMethods parses the method selector allowlist
+func (p *Policy) allowedMethods() ([]string, error) {
+	selectors := make([]string, 0)
+	if p.AllowedMethods == nil {
+		return selectors, nil
+	}
+	err := json.Unmarshal(*p.AllowedMethods, &selectors)
+	return selectors, err
+}
+
+// location returns the time zone of the policy window and daily limit
+func (p *Policy) location() (*time.Location, error) {
+	if p.Timezone == nil || *p.Timezone == "" {
+		return time.UTC, nil
+	}
+	loc, err := time.LoadLocation(*p.Timezone)
+	if err != nil {
+		return time.UTC, err
+	}
+	return loc, nil
+}
+
+// withinWindow returns true if the given time of day falls within the policy window
+func (p *Policy) withinWindow(at time.Time) bool {
+	start, _ := time.Parse(policyWindowLayout, *p.WindowStart)
+	end, _ := time.Parse(policyWindowLayout, *p.WindowEnd)
+
+	minute := at.Hour()*60 + at.Minute()
+	startMinute := start.Hour()*60 + start.Minute()
+	endMinute := end.Hour()*60 + end.Minute()
+
+	if startMinute < endMinute {
+		return minute >= startMinute && minute < endMinute
+	}
+	return minute >= startMinute || minute < endMinute
+}
+
+func (p *Policy) violation(reason string) *PolicyViolation {
+	return &PolicyViolation{
+		PolicyID: p.ID,
+		Reason:   reason,
+	}
+}
+
+// parsePolicyValue parses a non-negative integer policy value; nil is returned if the value is unset or invalid
+func parsePolicyValue(value *string) *big.Int {
+	if value == nil {
+		return nil
+	}
+	val, ok := new(big.Int).SetString(*value, 10)
+	if !ok || val.Sign() < 0 {
+		return nil
+	}
+	return val
+}
+
+// parseMethodSelector parses a hex-encoded 4-byte method selector
+func parseMethodSelector(selector string) ([]byte, error) {
+	_selector, err := hex.DecodeString(strings.TrimPrefix(selector, "0x"))
+	if err != nil {
+		return nil, err
+	}
+	if len(_selector) != 4 {
+		return nil, fmt.Errorf("method selector must be 4 bytes")
+	}
+	return _selector, nil
+}
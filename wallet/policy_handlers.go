@@ -0,0 +1,215 @@
+package wallet
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	dbconf "github.com/kthomas/go-db-config"
+	uuid "github.com/kthomas/go.uuid"
+	provide "github.com/provideplatform/provide-go/common"
+	util "github.com/provideplatform/provide-go/common/util"
+)
+
+// installPoliciesAPI installs the spending policy handlers of the given resource, i.e. accounts or HD wallets,
+// using the given gin Engine
+func installPoliciesAPI(r *gin.Engine, resource string) {
+	r.GET(fmt.Sprintf("/api/v1/%s/:id/policies", resource), policiesListHandler)
+	r.POST(fmt.Sprintf("/api/v1/%s/:id/policies", resource), createPolicyHandler)
+	r.GET(fmt.Sprintf("/api/v1/%s/:id/policies/:policyId", resource), policyDetailsHandler)
+	r.PUT(fmt.Sprintf("/api/v1/%s/:id/policies/:policyId", resource), updatePolicyHandler)
+	r.DELETE(fmt.Sprintf("/api/v1/%s/:id/policies/:policyId", resource), deletePolicyHandler)
+}
+
+// resolvePolicySubject returns a policy template associated with the account or HD wallet, owned by the
+// authorized subject, whose spending policies are being managed; an error is rendered and nil returned otherwise
+func resolvePolicySubject(c *gin.Context) *Policy {
+	appID := util.AuthorizedSubjectID(c, "application")
+	userID := util.AuthorizedSubjectID(c, "user")
+	organizationID := util.AuthorizedSubjectID(c, "organization")
+	if appID == nil && userID == nil && organizationID == nil {
+		provide.RenderError("unauthorized", 401, c)
+		return nil
+	}
+
+	db := dbconf.DatabaseConnection()
+
+	var subjectAppID, subjectUserID, subjectOrgID *uuid.UUID
+	policy := &Policy{}
+
+	if strings.HasPrefix(c.FullPath(), "/api/v1/accounts/") {
+		account := &Account{}
+		db.Where("id = ?", c.Param("id")).Find(&account)
+		if account == nil || account.ID == uuid.Nil {
+			provide.RenderError("account not found", 404, c)
+			return nil
+		}
+		subjectAppID = account.ApplicationID
+		subjectUserID = account.UserID
+		subjectOrgID = account.OrganizationID
+		policy.AccountID = &account.ID
+	} else {
+		wallet := &Wallet{}
+		db.Where("id = ?", c.Param("id")).Find(&wallet)
+		if wallet == nil || wallet.ID == uuid.Nil {
+			provide.RenderError("wallet not found", 404, c)
+			return nil
+		}
+		subjectAppID = wallet.ApplicationID
+		subjectUserID = wallet.UserID
+		subjectOrgID = wallet.OrganizationID
+		policy.WalletID = &wallet.ID
+	}
+
+	if appID != nil && (subjectAppID == nil || *subjectAppID != *appID) {
+		provide.RenderError("forbidden", 403, c)
+		return nil
+	} else if userID != nil && (subjectUserID == nil || *subjectUserID != *userID) {
+		provide.RenderError("forbidden", 403, c)
+		return nil
+	} else if organizationID != nil && (subjectOrgID == nil || *subjectOrgID != *organizationID) {
+		provide.RenderError("forbidden", 403, c)
+		return nil
+	}
+
+	return policy
+}
+
+// resolvePolicy returns the spending policy with the given id if it belongs to the account or HD wallet
+// owned by the authorized subject; an error is rendered and nil returned otherwise
+func resolvePolicy(c *gin.Context) *Policy {
+	subject := resolvePolicySubject(c)
+	if subject == nil {
+		return nil
+	}
+
+	query := dbconf.DatabaseConnection().Where("id = ?", c.Param("policyId"))
+	if subject.AccountID != nil {
+		query = query.Where("account_id = ?", subject.AccountID)
+	} else {
+		query = query.Where("wallet_id = ?", subject.WalletID)
+	}
+
+	policy := &Policy{}
+	query.Find(&policy)
+	if policy == nil || policy.ID == uuid.Nil {
+		provide.RenderError("policy not found", 404, c)
+		return nil
+	}
+
+	return policy
+}
+
+func policiesListHandler(c *gin.Context) {
+	subject := resolvePolicySubject(c)
+	if subject == nil {
+		return
+	}
+
+	query := dbconf.DatabaseConnection()
+	if subject.AccountID != nil {
+		query = query.Where("policies.account_id = ?", subject.AccountID)
+	} else {
+		query = query.Where("policies.wallet_id = ?", subject.WalletID)
+	}
+	query = query.Order("policies.created_at ASC")
+
+	var policies []*Policy
+	provide.Paginate(c, query, &Policy{}).Find(&policies)
+	provide.Render(policies, 200, c)
+}
+
+func policyDetailsHandler(c *gin.Context) {
+	policy := resolvePolicy(c)
+	if policy == nil {
+		return
+	}
+
+	provide.Render(policy, 200, c)
+}
+
+func createPolicyHandler(c *gin.Context) {
+	buf, err := c.GetRawData()
+	if err != nil {
+		provide.RenderError(err.Error(), 400, c)
+		return
+	}
+
+	subject := resolvePolicySubject(c)
+	if subject == nil {
+		return
+	}
+
+	policy := &Policy{}
+	err = json.Unmarshal(buf, policy)
+	if err != nil {
+		provide.RenderError(err.Error(), 422, c)
+		return
+	}
+	policy.AccountID = subject.AccountID
+	policy.WalletID = subject.WalletID
+
+	var params map[string]interface{}
+	json.Unmarshal(buf, &params)
+	if _, enabledOk := params["enabled"]; !enabledOk {
+		policy.Enabled = true
+	}
+
+	if policy.Create() {
+		provide.Render(policy, 201, c)
+	} else {
+		obj := map[string]interface{}{}
+		obj["errors"] = policy.Errors
+		provide.Render(obj, 422, c)
+	}
+}
+
+func updatePolicyHandler(c *gin.Context) {
+	buf, err := c.GetRawData()
+	if err != nil {
+		provide.RenderError(err.Error(), 400, c)
+		return
+	}
+
+	policy := resolvePolicy(c)
+	if policy == nil {
+		return
+	}
+
+	policyID := policy.ID
+	createdAt := policy.CreatedAt
+	accountID := policy.AccountID
+	walletID := policy.WalletID
+
+	err = json.Unmarshal(buf, policy)
+	if err != nil {
+		provide.RenderError(err.Error(), 422, c)
+		return
+	}
+	policy.ID = policyID
+	policy.CreatedAt = createdAt
+	policy.AccountID = accountID
+	policy.WalletID = walletID
+
+	if policy.Update() {
+		provide.Render(nil, 204, c)
+	} else {
+		obj := map[string]interface{}{}
+		obj["errors"] = policy.Errors
+		provide.Render(obj, 422, c)
+	}
+}
+
+func deletePolicyHandler(c *gin.Context) {
+	policy := resolvePolicy(c)
+	if policy == nil {
+		return
+	}
+
+	if !policy.Delete() {
+		provide.RenderError("policy not deleted", 500, c)
+		return
+	}
+	provide.Render(nil, 204, c)
+}
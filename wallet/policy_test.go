@@ -0,0 +1,248 @@
+// +build unit
+
+package wallet
+
+import (
+	"encoding/json"
+	"errors"
+	"math/big"
+	"testing"
+	"time"
+
+	uuid "github.com/kthomas/go.uuid"
+	"github.com/provideplatform/nchain/common"
+)
+
+const policyTestRecipient = "0x3535353535353535353535353535353535353535"
+
+// policyFactory returns an enabled policy of a random account
+func policyFactory() *Policy {
+	accountID, _ := uuid.NewV4()
+	policy := &Policy{
+		AccountID: &accountID,
+		Enabled:   true,
+	}
+	policy.ID, _ = uuid.NewV4()
+	return policy
+}
+
+// policyRequestFactory returns a request to sign a tx of the given value at noon UTC, having spent the given value
+func policyRequestFactory(value int64, spent int64) *PolicyRequest {
+	return &PolicyRequest{
+		To:        common.StringOrNil(policyTestRecipient),
+		Value:     big.NewInt(value),
+		Timestamp: time.Date(2021, 9, 14, 12, 0, 0, 0, time.UTC),
+		Spent: func(policy *Policy, since time.Time) (*big.Int, error) {
+			return big.NewInt(spent), nil
+		},
+	}
+}
+
+func rawJSON(val interface{}) *json.RawMessage {
+	raw, _ := json.Marshal(val)
+	msg := json.RawMessage(raw)
+	return &msg
+}
+
+func requireViolation(t *testing.T, err error, requiredApprovals int) {
+	t.Helper()
+	violation, ok := err.(*PolicyViolation)
+	if !ok {
+		t.Fatalf("expected policy violation; got %v", err)
+	}
+	if violation.RequiredApprovals != requiredApprovals {
+		t.Errorf("expected %d required approval(s); got %d", requiredApprovals, violation.RequiredApprovals)
+	}
+}
+
+func TestPolicyMaxValue(t *testing.T) {
+	policy := policyFactory()
+	policy.MaxValue = common.StringOrNil("1000")
+
+	if err := policy.Evaluate(policyRequestFactory(1000, 0)); err != nil {
+		t.Errorf("expected tx of the max value to be permitted; %s", err.Error())
+	}
+	requireViolation(t, policy.Evaluate(policyRequestFactory(1001, 0)), 0)
+}
+
+func TestPolicyDailyLimit(t *testing.T) {
+	policy := policyFactory()
+	policy.DailyLimit = common.StringOrNil("5000")
+
+	if err := policy.Evaluate(policyRequestFactory(1000, 4000)); err != nil {
+		t.Errorf("expected tx reaching the daily limit to be permitted; %s", err.Error())
+	}
+	requireViolation(t, policy.Evaluate(policyRequestFactory(1001, 4000)), 0)
+
+	req := policyRequestFactory(1, 0)
+	req.Spent = func(policy *Policy, since time.Time) (*big.Int, error) {
+		return nil, errors.New("connection refused")
+	}
+	requireViolation(t, policy.Evaluate(req), 0)
+}
+
+func TestPolicyDailyLimitStartsAtMidnightInPolicyTimezone(t *testing.T) {
+	policy := policyFactory()
+	policy.DailyLimit = common.StringOrNil("5000")
+	policy.Timezone = common.StringOrNil("America/New_York")
+
+	var since time.Time
+	req := policyRequestFactory(1, 0)
+	req.Timestamp = time.Date(2021, 9, 14, 2, 0, 0, 0, time.UTC) // 22:00 on September 13th in New York
+	req.Spent = func(policy *Policy, _since time.Time) (*big.Int, error) {
+		since = _since
+		return big.NewInt(0), nil
+	}
+
+	policy.Evaluate(req)
+	if !since.Equal(time.Date(2021, 9, 13, 4, 0, 0, 0, time.UTC)) {
+		t.Errorf("expected value spent since midnight in New York on September 13th; got %s", since.UTC().String())
+	}
+}
+
+func TestPolicyAddressAllowlist(t *testing.T) {
+	policy := policyFactory()
+	policy.AllowedAddresses = rawJSON([]string{"0x3535353535353535353535353535353535353535"})
+
+	req := policyRequestFactory(1, 0)
+	req.To = common.StringOrNil("0x3535353535353535353535353535353535353535")
+	if err := policy.Evaluate(req); err != nil {
+		t.Errorf("expected allowlisted recipient to be permitted; %s", err.Error())
+	}
+
+	req.To = common.StringOrNil("0x4242424242424242424242424242424242424242")
+	requireViolation(t, policy.Evaluate(req), 0)
+
+	req.To = nil
+	requireViolation(t, policy.Evaluate(req), 0)
+}
+
+func TestPolicyMethodAllowlist(t *testing.T) {
+	policy := policyFactory()
+	policy.AllowedMethods = rawJSON([]string{"0xa9059cbb"})
+
+	req := policyRequestFactory(0, 0)
+	req.Data = common.StringOrNil("0xa9059cbb0000000000000000000000003535353535353535353535353535353535353535")
+	if err := policy.Evaluate(req); err != nil {
+		t.Errorf("expected allowlisted method to be permitted; %s", err.Error())
+	}
+
+	req.Data = common.StringOrNil("0x095ea7b30000000000000000000000003535353535353535353535353535353535353535")
+	requireViolation(t, policy.Evaluate(req), 0)
+
+	// plain value transfers are not subject to the method allowlist
+	req.Data = nil
+	if err := policy.Evaluate(req); err != nil {
+		t.Errorf("expected value transfer to be permitted; %s", err.Error())
+	}
+}
+
+func TestPolicyWindow(t *testing.T) {
+	policy := policyFactory()
+	policy.WindowStart = common.StringOrNil("09:00")
+	policy.WindowEnd = common.StringOrNil("17:30")
+
+	for _, permitted := range []string{"09:00", "12:00", "17:29"} {
+		req := policyRequestFactory(1, 0)
+		at, _ := time.Parse(policyWindowLayout, permitted)
+		req.Timestamp = time.Date(2021, 9, 14, at.Hour(), at.Minute(), 0, 0, time.UTC)
+		if err := policy.Evaluate(req); err != nil {
+			t.Errorf("expected tx signed at %s to be permitted; %s", permitted, err.Error())
+		}
+	}
+
+	for _, rejected := range []string{"08:59", "17:30", "23:00"} {
+		req := policyRequestFactory(1, 0)
+		at, _ := time.Parse(policyWindowLayout, rejected)
+		req.Timestamp = time.Date(2021, 9, 14, at.Hour(), at.Minute(), 0, 0, time.UTC)
+		if policy.Evaluate(req) == nil {
+			t.Errorf("expected tx signed at %s to be rejected", rejected)
+		}
+	}
+}
+
+func TestPolicyWindowWrapsMidnight(t *testing.T) {
+	policy := policyFactory()
+	policy.WindowStart = common.StringOrNil("22:00")
+	policy.WindowEnd = common.StringOrNil("06:00")
+
+	req := policyRequestFactory(1, 0)
+	req.Timestamp = time.Date(2021, 9, 14, 23, 0, 0, 0, time.UTC)
+	if err := policy.Evaluate(req); err != nil {
+		t.Errorf("expected tx signed before midnight to be permitted; %s", err.Error())
+	}
+
+	req.Timestamp = time.Date(2021, 9, 14, 5, 59, 0, 0, time.UTC)
+	if err := policy.Evaluate(req); err != nil {
+		t.Errorf("expected tx signed after midnight to be permitted; %s", err.Error())
+	}
+
+	req.Timestamp = time.Date(2021, 9, 14, 12, 0, 0, 0, time.UTC)
+	requireViolation(t, policy.Evaluate(req), 0)
+}
+
+func TestPolicyApprovalThreshold(t *testing.T) {
+	approverID, _ := uuid.NewV4()
+	requiredApprovals := 1
+
+	policy := policyFactory()
+	policy.ApprovalThreshold = common.StringOrNil("1000")
+	policy.RequiredApprovals = &requiredApprovals
+	policy.Approvers = rawJSON([]uuid.UUID{approverID})
+
+	if err := policy.Evaluate(policyRequestFactory(1000, 0)); err != nil {
+		t.Errorf("expected tx at the approval threshold not to require approval; %s", err.Error())
+	}
+	requireViolation(t, policy.Evaluate(policyRequestFactory(1001, 0)), 1)
+
+	// approvals never override a hard limit
+	policy.MaxValue = common.StringOrNil("2000")
+	requireViolation(t, policy.Evaluate(policyRequestFactory(2001, 0)), 0)
+}
+
+func TestPolicyValidate(t *testing.T) {
+	requiredApprovals := 2
+	approverID, _ := uuid.NewV4()
+
+	invalid := map[string]func(p *Policy){
+		"no subject": func(p *Policy) {
+			p.AccountID = nil
+		},
+		"negative max value": func(p *Policy) {
+			p.MaxValue = common.StringOrNil("-1")
+		},
+		"invalid allowed address": func(p *Policy) {
+			p.AllowedAddresses = rawJSON([]string{"0x35"})
+		},
+		"invalid method selector": func(p *Policy) {
+			p.AllowedMethods = rawJSON([]string{"0xa9059c"})
+		},
+		"half-open window": func(p *Policy) {
+			p.WindowStart = common.StringOrNil("09:00")
+		},
+		"empty window": func(p *Policy) {
+			p.WindowStart = common.StringOrNil("09:00")
+			p.WindowEnd = common.StringOrNil("09:00")
+		},
+		"invalid timezone": func(p *Policy) {
+			p.Timezone = common.StringOrNil("Mars/Olympus_Mons")
+		},
+		"too few approvers": func(p *Policy) {
+			p.ApprovalThreshold = common.StringOrNil("1000")
+			p.RequiredApprovals = &requiredApprovals
+			p.Approvers = rawJSON([]uuid.UUID{approverID, approverID})
+		},
+	}
+
+	for name, invalidate := range invalid {
+		policy := policyFactory()
+		invalidate(policy)
+		if policy.Validate() {
+			t.Errorf("expected policy having %s to be invalid", name)
+		}
+	}
+
+	if policy := policyFactory(); !policy.Validate() {
+		t.Errorf("expected policy to be valid; %s", *policy.Errors[0].Message)
+	}
+}
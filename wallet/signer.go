@@ -0,0 +1,184 @@
+package wallet
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	ethcommon "github.com/ethereum/go-ethereum/common"
+	pgputil "github.com/kthomas/go-pgputil"
+	"github.com/provideplatform/nchain/common"
+	provide "github.com/provideplatform/provide-go/api"
+)
+
+// Account types which are signed using key material held outside of Vault
+const (
+	AccountTypeKeystore = "keystore" // geth JSON keystore decrypted using a passphrase held in the encrypted account config
+	AccountTypePKCS11   = "pkcs11"   // key held by a PKCS#11 token, i.e. an HSM or SoftHSM
+	AccountTypeRemote   = "remote"   // remote signer exposing account_signTransaction, i.e. Clef or Web3Signer
+)
+
+// accountSecretConfigKeys are the account config keys which are only persisted in the encrypted account config
+var accountSecretConfigKeys = []string{"authorization", "keystore", "passphrase", "pin"}
+
+// externalAccountTypes are the account types for which an alternative signer is registered
+var externalAccountTypes = map[string]bool{}
+
+// RegisterExternalAccountType registers the given account type as signed outside of Vault; accounts
+// of the type are created without generating Vault key material
+func RegisterExternalAccountType(accountType string) {
+	externalAccountTypes[accountType] = true
+}
+
+// IsExternallySigned returns true if the account is signed using key material held outside of Vault
+func (a *Account) IsExternallySigned() bool {
+	return a.Type != nil && externalAccountTypes[*a.Type]
+}
+
+// ParseConfig parses the unencrypted signer config of the account
+func (a *Account) ParseConfig() map[string]interface{} {
+	cfg := map[string]interface{}{}
+	if a.Config != nil {
+		err := json.Unmarshal(*a.Config, &cfg)
+		if err != nil {
+			common.Log.Warningf("failed to unmarshal account config; %s", err.Error())
+			return nil
+		}
+	}
+	return cfg
+}
+
+// SignerConfig returns the signer config of the account, including its decrypted secrets
+func (a *Account) SignerConfig() (map[string]interface{}, error) {
+	cfg := a.ParseConfig()
+	if cfg == nil {
+		cfg = map[string]interface{}{}
+	}
+
+	if a.EncryptedConfig != nil {
+		decrypted, err := pgputil.PGPPubDecrypt([]byte(*a.EncryptedConfig))
+		if err != nil {
+			return nil, fmt.Errorf("failed to decrypt encrypted config of account %s; %s", a.ID, err.Error())
+		}
+
+		secrets := map[string]interface{}{}
+		err = json.Unmarshal(decrypted, &secrets)
+		if err != nil {
+			return nil, fmt.Errorf("failed to unmarshal decrypted config of account %s; %s", a.ID, err.Error())
+		}
+
+		for k := range secrets {
+			cfg[k] = secrets[k]
+		}
+	}
+
+	return cfg, nil
+}
+
+// sanitizeConfig moves the secrets of the account config to its encrypted config
+func (a *Account) sanitizeConfig() bool {
+	cfg := a.ParseConfig()
+	if cfg == nil {
+		return false
+	}
+
+	secrets := map[string]interface{}{}
+	for _, key := range accountSecretConfigKeys {
+		if val, valOk := cfg[key]; valOk {
+			secrets[key] = val
+			delete(cfg, key)
+		}
+	}
+
+	cfgJSON, _ := json.Marshal(cfg)
+	_cfgJSON := json.RawMessage(cfgJSON)
+	a.Config = &_cfgJSON
+
+	if len(secrets) == 0 {
+		return true
+	}
+
+	secretsJSON, _ := json.Marshal(secrets)
+	encryptedConfig, err := pgputil.PGPPubEncrypt(secretsJSON)
+	if err != nil {
+		common.Log.Warningf("failed to encrypt account config; %s", err.Error())
+		a.Errors = append(a.Errors, &provide.Error{
+			Message: common.StringOrNil(err.Error()),
+		})
+		return false
+	}
+	a.EncryptedConfig = common.StringOrNil(string(encryptedConfig))
+	return true
+}
+
+// resolveExternalAddress resolves the address of an externally-signed account which was created without one
+func (a *Account) resolveExternalAddress() {
+	if a.Address != "" || a.Type == nil || *a.Type != AccountTypeKeystore {
+		return
+	}
+
+	// the address of a geth JSON keystore is stored in plaintext alongside the encrypted key
+	keystore := map[string]interface{}{}
+	switch ks := a.ParseConfig()["keystore"].(type) {
+	case string:
+		json.Unmarshal([]byte(ks), &keystore)
+	case map[string]interface{}:
+		keystore = ks
+	}
+
+	if address, addressOk := keystore["address"].(string); addressOk && ethcommon.IsHexAddress(address) {
+		a.Address = ethcommon.HexToAddress(address).Hex()
+	}
+}
+
+// validateExternalSigner validates the address and signer config of an externally-signed account
+func (a *Account) validateExternalSigner() {
+	if !ethcommon.IsHexAddress(a.Address) {
+		a.Errors = append(a.Errors, &provide.Error{
+			Message: common.StringOrNil(fmt.Sprintf("valid address required for %s account", *a.Type)),
+		})
+	}
+
+	cfg := a.ParseConfig()
+	if cfg == nil {
+		a.Errors = append(a.Errors, &provide.Error{
+			Message: common.StringOrNil(fmt.Sprintf("valid signer config required for %s account", *a.Type)),
+		})
+		return
+	}
+
+	required := map[string][]string{
+		AccountTypeKeystore: {"keystore|keystore_path", "passphrase"},
+		AccountTypePKCS11:   {"pin", "token_label|slot", "key_label|key_id"},
+		AccountTypeRemote:   {"url"},
+	}[*a.Type]
+
+	for _, keys := range required {
+		present := false
+		for _, key := range strings.Split(keys, "|") {
+			if _, keyOk := cfg[key]; keyOk {
+				present = true
+			}
+		}
+		if !present {
+			a.Errors = append(a.Errors, &provide.Error{
+				Message: common.StringOrNil(fmt.Sprintf("%s account config requires %s", *a.Type, strings.Replace(keys, "|", " or ", -1))),
+			})
+		}
+	}
+
+	// the remote signer is dialed on behalf of the account, so it must not target internal services
+	if *a.Type == AccountTypeRemote {
+		if url, urlOk := cfg["url"].(string); urlOk {
+			if err := common.ValidatePublicURL(url, common.AllowInsecureRemoteSigners); err != nil {
+				a.Errors = append(a.Errors, &provide.Error{
+					Message: common.StringOrNil(fmt.Sprintf("remote account config requires a valid https url resolving to a public address; %s", err.Error())),
+				})
+			}
+		} else if _, urlOk := cfg["url"]; urlOk {
+			a.Errors = append(a.Errors, &provide.Error{
+				Message: common.StringOrNil("remote account config requires a valid https url resolving to a public address"),
+			})
+		}
+	}
+}
@@ -0,0 +1,157 @@
+package webhook
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	dbconf "github.com/kthomas/go-db-config"
+	natsutil "github.com/kthomas/go-natsutil"
+	uuid "github.com/kthomas/go.uuid"
+	"github.com/nats-io/nats.go"
+	"github.com/provideplatform/nchain/common"
+)
+
+const defaultNatsStream = "nchain"
+
+const natsWebhookDeliverySubject = "nchain.webhook.delivery"
+const natsWebhookDeliveryMaxInFlight = 1024
+const natsWebhookDeliveryMsgMaxDeliveries = 256
+const webhookDeliveryAckWait = time.Second * 30
+
+// webhookDeliveryMaxAttempts is the number of delivery attempts made before a webhook event is abandoned
+const webhookDeliveryMaxAttempts = 8
+
+// webhookDeliveryInitialBackoff is the delay before the first retry of a failed delivery; the delay doubles
+// with each subsequent attempt
+const webhookDeliveryInitialBackoff = webhookDeliveryAckWait
+
+var waitGroup sync.WaitGroup
+
+// deliveryMessage is the NATS message which represents a pending delivery attempt of a webhook event
+type deliveryMessage struct {
+	WebhookID uuid.UUID       `json:"webhook_id"`
+	EventID   uuid.UUID       `json:"event_id"`
+	Event     string          `json:"event"`
+	Payload   json.RawMessage `json:"payload"`
+	Attempt   uint64          `json:"attempt"`
+	NotBefore *time.Time      `json:"not_before,omitempty"`
+}
+
+func init() {
+	if !common.ConsumeNATSStreamingSubscriptions {
+		common.Log.Debug("Webhook package consumer configured to skip NATS streaming subscription setup")
+		return
+	}
+
+	natsutil.EstablishSharedNatsConnection(nil)
+	natsutil.NatsCreateStream(defaultNatsStream, []string{
+		fmt.Sprintf("%s.>", defaultNatsStream),
+	})
+
+	createNatsWebhookDeliverySubscriptions(&waitGroup)
+}
+
+func createNatsWebhookDeliverySubscriptions(wg *sync.WaitGroup) {
+	for i := uint64(0); i < natsutil.GetNatsConsumerConcurrency(); i++ {
+		natsutil.RequireNatsJetstreamSubscription(wg,
+			webhookDeliveryAckWait,
+			natsWebhookDeliverySubject,
+			natsWebhookDeliverySubject,
+			natsWebhookDeliverySubject,
+			consumeWebhookDeliveryMsg,
+			webhookDeliveryAckWait,
+			natsWebhookDeliveryMaxInFlight,
+			natsWebhookDeliveryMsgMaxDeliveries,
+			nil,
+		)
+	}
+}
+
+func consumeWebhookDeliveryMsg(msg *nats.Msg) {
+	common.Log.Tracef("consuming %d-byte NATS webhook delivery message", len(msg.Data))
+
+	var params *deliveryMessage
+	err := json.Unmarshal(msg.Data, &params)
+	if err != nil {
+		common.Log.Warningf("failed to umarshal webhook delivery message; %s", err.Error())
+		msg.Term()
+		return
+	}
+
+	if params.NotBefore != nil && time.Now().Before(*params.NotBefore) {
+		holdUntilDue(msg, time.Until(*params.NotBefore))
+		return
+	}
+
+	db := dbconf.DatabaseConnection()
+
+	webhook := &Webhook{}
+	db.Where("id = ?", params.WebhookID).Find(&webhook)
+	if webhook == nil || webhook.ID == uuid.Nil {
+		common.Log.Debugf("dropping %s webhook event %s; webhook not resolved: %s", params.Event, params.EventID, params.WebhookID)
+		msg.Term()
+		return
+	}
+
+	if !webhook.Enabled {
+		common.Log.Debugf("dropping %s webhook event %s; webhook disabled: %s", params.Event, params.EventID, webhook.ID)
+		msg.Term()
+		return
+	}
+
+	_, err = webhook.deliver(db, params.EventID, params.Event, params.Payload, params.Attempt)
+	if err == nil {
+		common.Log.Debugf("delivered %s webhook event %s to webhook %s; attempt %d", params.Event, params.EventID, webhook.ID, params.Attempt)
+		msg.Ack()
+		return
+	}
+
+	if params.Attempt >= webhookDeliveryMaxAttempts {
+		common.Log.Warningf("abandoned %s webhook event %s for webhook %s after %d attempt(s); %s", params.Event, params.EventID, webhook.ID, params.Attempt, err.Error())
+		msg.Ack()
+		return
+	}
+
+	notBefore := time.Now().Add(webhookDeliveryInitialBackoff * time.Duration(1<<(params.Attempt-1)))
+	params.Attempt++
+	params.NotBefore = &notBefore
+
+	payload, _ := json.Marshal(params)
+	_, pubErr := natsutil.NatsJetstreamPublish(natsWebhookDeliverySubject, payload)
+	if pubErr != nil {
+		common.Log.Warningf("failed to requeue %s webhook event %s for webhook %s; %s", params.Event, params.EventID, webhook.ID, pubErr.Error())
+		msg.Nak()
+		return
+	}
+
+	common.Log.Debugf("failed to deliver %s webhook event %s to webhook %s; retrying after %s; %s", params.Event, params.EventID, webhook.ID, notBefore, err.Error())
+	msg.Ack()
+}
+
+// holdUntilDue keeps the given message, which is not yet due, in progress until the given delay elapses and
+// consumes it once it is due; the broker would otherwise redeliver the message each time the ack wait elapses.
+// A message held by a consumer which exits before it is due is redelivered after the ack wait
+func holdUntilDue(msg *nats.Msg, delay time.Duration) {
+	go func() {
+		due := time.NewTimer(delay)
+		defer due.Stop()
+
+		ticker := time.NewTicker(webhookDeliveryAckWait / 2)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-due.C:
+				consumeWebhookDeliveryMsg(msg)
+				return
+			case <-ticker.C:
+				err := msg.InProgress()
+				if err != nil {
+					common.Log.Warningf("failed to extend ack wait of webhook delivery message; %s", err.Error())
+				}
+			}
+		}
+	}()
+}
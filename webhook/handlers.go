@@ -0,0 +1,192 @@
+package webhook
+
+import (
+	"encoding/json"
+
+	"github.com/gin-gonic/gin"
+	dbconf "github.com/kthomas/go-db-config"
+	uuid "github.com/kthomas/go.uuid"
+	provide "github.com/provideplatform/provide-go/common"
+	util "github.com/provideplatform/provide-go/common/util"
+)
+
+// InstallWebhooksAPI installs the handlers using the given gin Engine
+func InstallWebhooksAPI(r *gin.Engine) {
+	r.GET("/api/v1/webhooks", webhooksListHandler)
+	r.POST("/api/v1/webhooks", createWebhookHandler)
+	r.GET("/api/v1/webhooks/:id", webhookDetailsHandler)
+	r.PUT("/api/v1/webhooks/:id", updateWebhookHandler)
+	r.DELETE("/api/v1/webhooks/:id", deleteWebhookHandler)
+
+	r.GET("/api/v1/webhooks/:id/deliveries", webhookDeliveriesListHandler)
+}
+
+// resolveWebhook returns the webhook with the given id if it belongs to the authorized application or organization
+func resolveWebhook(c *gin.Context) *Webhook {
+	appID := util.AuthorizedSubjectID(c, "application")
+	orgID := util.AuthorizedSubjectID(c, "organization")
+	if appID == nil && orgID == nil {
+		provide.RenderError("unauthorized", 401, c)
+		return nil
+	}
+
+	webhook := &Webhook{}
+	dbconf.DatabaseConnection().Where("id = ?", c.Param("id")).Find(&webhook)
+	if webhook == nil || webhook.ID == uuid.Nil {
+		provide.RenderError("webhook not found", 404, c)
+		return nil
+	}
+	if appID != nil && (webhook.ApplicationID == nil || *appID != *webhook.ApplicationID) {
+		provide.RenderError("forbidden", 403, c)
+		return nil
+	}
+	if appID == nil && orgID != nil && (webhook.OrganizationID == nil || *orgID != *webhook.OrganizationID) {
+		provide.RenderError("forbidden", 403, c)
+		return nil
+	}
+
+	return webhook
+}
+
+func webhooksListHandler(c *gin.Context) {
+	appID := util.AuthorizedSubjectID(c, "application")
+	orgID := util.AuthorizedSubjectID(c, "organization")
+	if appID == nil && orgID == nil {
+		provide.RenderError("unauthorized", 401, c)
+		return
+	}
+
+	query := dbconf.DatabaseConnection()
+
+	if appID != nil {
+		query = query.Where("webhooks.application_id = ?", appID)
+	} else {
+		query = query.Where("webhooks.organization_id = ?", orgID)
+	}
+
+	var webhooks []*Webhook
+	query = query.Order("webhooks.created_at ASC")
+	provide.Paginate(c, query, &Webhook{}).Find(&webhooks)
+	for _, webhook := range webhooks {
+		webhook.Secret = nil
+	}
+	provide.Render(webhooks, 200, c)
+}
+
+func webhookDetailsHandler(c *gin.Context) {
+	webhook := resolveWebhook(c)
+	if webhook == nil {
+		return
+	}
+
+	webhook.Secret = nil
+	provide.Render(webhook, 200, c)
+}
+
+func createWebhookHandler(c *gin.Context) {
+	appID := util.AuthorizedSubjectID(c, "application")
+	orgID := util.AuthorizedSubjectID(c, "organization")
+	if appID == nil && orgID == nil {
+		provide.RenderError("unauthorized", 401, c)
+		return
+	}
+
+	buf, err := c.GetRawData()
+	if err != nil {
+		provide.RenderError(err.Error(), 400, c)
+		return
+	}
+
+	webhook := &Webhook{}
+	err = json.Unmarshal(buf, webhook)
+	if err != nil {
+		provide.RenderError(err.Error(), 422, c)
+		return
+	}
+	webhook.ApplicationID = appID
+	webhook.OrganizationID = orgID
+
+	var params map[string]interface{}
+	json.Unmarshal(buf, &params)
+	if _, enabledOk := params["enabled"]; !enabledOk {
+		webhook.Enabled = true
+	}
+
+	// the signing secret is only rendered when the webhook is created
+	if webhook.Create() {
+		provide.Render(webhook, 201, c)
+	} else {
+		obj := map[string]interface{}{}
+		obj["errors"] = webhook.Errors
+		provide.Render(obj, 422, c)
+	}
+}
+
+func updateWebhookHandler(c *gin.Context) {
+	buf, err := c.GetRawData()
+	if err != nil {
+		provide.RenderError(err.Error(), 400, c)
+		return
+	}
+
+	webhook := resolveWebhook(c)
+	if webhook == nil {
+		return
+	}
+
+	webhookID := webhook.ID
+	applicationID := webhook.ApplicationID
+	organizationID := webhook.OrganizationID
+	secret := webhook.Secret
+
+	err = json.Unmarshal(buf, webhook)
+	if err != nil {
+		provide.RenderError(err.Error(), 422, c)
+		return
+	}
+	webhook.ID = webhookID
+	webhook.ApplicationID = applicationID
+	webhook.OrganizationID = organizationID
+	if webhook.Secret == nil {
+		webhook.Secret = secret
+	}
+
+	if webhook.Update() {
+		provide.Render(nil, 204, c)
+	} else {
+		obj := map[string]interface{}{}
+		obj["errors"] = webhook.Errors
+		provide.Render(obj, 422, c)
+	}
+}
+
+func deleteWebhookHandler(c *gin.Context) {
+	webhook := resolveWebhook(c)
+	if webhook == nil {
+		return
+	}
+
+	if !webhook.Delete() {
+		provide.RenderError("webhook not deleted", 500, c)
+		return
+	}
+	provide.Render(nil, 204, c)
+}
+
+func webhookDeliveriesListHandler(c *gin.Context) {
+	webhook := resolveWebhook(c)
+	if webhook == nil {
+		return
+	}
+
+	query := dbconf.DatabaseConnection().Where("webhook_deliveries.webhook_id = ?", webhook.ID)
+
+	if c.Query("event_id") != "" {
+		query = query.Where("webhook_deliveries.event_id = ?", c.Query("event_id"))
+	}
+
+	var deliveries []*Delivery
+	query = query.Order("webhook_deliveries.created_at DESC")
+	provide.Paginate(c, query, &Delivery{}).Find(&deliveries)
+	provide.Render(deliveries, 200, c)
+}
@@ -0,0 +1,350 @@
+package webhook
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/jinzhu/gorm"
+	dbconf "github.com/kthomas/go-db-config"
+	natsutil "github.com/kthomas/go-natsutil"
+	uuid "github.com/kthomas/go.uuid"
+	"github.com/provideplatform/nchain/common"
+	provide "github.com/provideplatform/provide-go/api"
+)
+
+// Webhook event types which are emitted over the lifecycle of a transaction
+const (
+	EventTransactionBroadcast = "broadcast"
+	EventTransactionSuccess   = "success"
+	EventTransactionFailed    = "failed"
+	EventTransactionDropped   = "dropped"
+)
+
+// Webhook request headers
+const (
+	webhookDeliveryHeader  = "X-Nchain-Delivery"
+	webhookEventHeader     = "X-Nchain-Event"
+	webhookSignatureHeader = "X-Nchain-Signature"
+	webhookTimestampHeader = "X-Nchain-Timestamp"
+)
+
+// webhookRequestTimeout is the timeout for a single delivery attempt
+const webhookRequestTimeout = time.Second * 10
+
+// webhookSecretLength is the number of random bytes in a generated webhook signing secret
+const webhookSecretLength = 32
+
+// Webhook is a subscription, owned by an application or organization, to signed HTTP callbacks which
+// are delivered whenever a transaction changes status
+type Webhook struct {
+	provide.Model
+	ApplicationID  *uuid.UUID       `sql:"type:uuid" json:"application_id,omitempty"`
+	OrganizationID *uuid.UUID       `sql:"type:uuid" json:"organization_id,omitempty"`
+	URL            *string          `sql:"not null" json:"url"`
+	Secret         *string          `sql:"not null" json:"secret,omitempty"`
+	Events         *json.RawMessage `sql:"type:json" json:"events,omitempty"` // event types to deliver; all events are delivered when omitted
+	Enabled        bool             `sql:"not null" json:"enabled"`
+}
+
+// Delivery is the record of a single attempt to deliver a webhook event
+type Delivery struct {
+	provide.Model
+	WebhookID   uuid.UUID        `sql:"not null;type:uuid" json:"webhook_id"`
+	EventID     uuid.UUID        `sql:"not null;type:uuid" json:"event_id"`
+	Event       *string          `sql:"not null" json:"event"`
+	Attempt     uint64           `sql:"not null" json:"attempt"`
+	Payload     *json.RawMessage `sql:"type:json" json:"payload,omitempty"`
+	StatusCode  *int             `json:"status_code,omitempty"`
+	Error       *string          `json:"error,omitempty"`
+	Duration    *uint64          `json:"duration,omitempty"` // in millis
+	DeliveredAt *time.Time       `json:"delivered_at,omitempty"`
+}
+
+// TableName returns the table in which webhook delivery attempts are persisted
+func (d *Delivery) TableName() string {
+	return "webhook_deliveries"
+}
+
+// Create and persist a webhook; a signing secret is generated when one is not provided
+func (w *Webhook) Create() bool {
+	db := dbconf.DatabaseConnection()
+
+	if w.Secret == nil {
+		secret, err := generateSecret()
+		if err != nil {
+			w.Errors = append(w.Errors, &provide.Error{
+				Message: common.StringOrNil(err.Error()),
+			})
+			return false
+		}
+		w.Secret = &secret
+	}
+
+	if !w.Validate() {
+		return false
+	}
+
+	if db.NewRecord(w) {
+		result := db.Create(&w)
+		rowsAffected := result.RowsAffected
+		errors := result.GetErrors()
+		if len(errors) > 0 {
+			for _, err := range errors {
+				w.Errors = append(w.Errors, &provide.Error{
+					Message: common.StringOrNil(err.Error()),
+				})
+			}
+		}
+		if !db.NewRecord(w) {
+			return rowsAffected > 0
+		}
+	}
+	return false
+}
+
+// Update an existing webhook
+func (w *Webhook) Update() bool {
+	db := dbconf.DatabaseConnection()
+
+	if !w.Validate() {
+		return false
+	}
+
+	result := db.Save(&w)
+	errors := result.GetErrors()
+	if len(errors) > 0 {
+		for _, err := range errors {
+			w.Errors = append(w.Errors, &provide.Error{
+				Message: common.StringOrNil(err.Error()),
+			})
+		}
+	}
+	return len(w.Errors) == 0
+}
+
+// Delete a webhook
+func (w *Webhook) Delete() bool {
+	db := dbconf.DatabaseConnection()
+	result := db.Delete(w)
+	errors := result.GetErrors()
+	if len(errors) > 0 {
+		for _, err := range errors {
+			w.Errors = append(w.Errors, &provide.Error{
+				Message: common.StringOrNil(err.Error()),
+			})
+		}
+	}
+	return len(w.Errors) == 0
+}
+
+// Validate a webhook for persistence
+func (w *Webhook) Validate() bool {
+	w.Errors = make([]*provide.Error, 0)
+
+	if w.ApplicationID == nil && w.OrganizationID == nil {
+		w.Errors = append(w.Errors, &provide.Error{
+			Message: common.StringOrNil("webhook must be associated with an application or organization"),
+		})
+	}
+
+	if w.URL == nil {
+		w.Errors = append(w.Errors, &provide.Error{
+			Message: common.StringOrNil("webhook requires a valid https url"),
+		})
+	} else if err := validateURL(*w.URL); err != nil {
+		w.Errors = append(w.Errors, &provide.Error{
+			Message: common.StringOrNil(err.Error()),
+		})
+	}
+
+	if w.Secret == nil || *w.Secret == "" {
+		w.Errors = append(w.Errors, &provide.Error{
+			Message: common.StringOrNil("webhook requires a signing secret"),
+		})
+	}
+
+	if w.Events != nil {
+		var events []string
+		err := json.Unmarshal(*w.Events, &events)
+		if err != nil {
+			w.Errors = append(w.Errors, &provide.Error{
+				Message: common.StringOrNil("webhook events must be a list of event types"),
+			})
+		}
+		for _, event := range events {
+			if event != EventTransactionBroadcast && event != EventTransactionSuccess && event != EventTransactionFailed && event != EventTransactionDropped {
+				w.Errors = append(w.Errors, &provide.Error{
+					Message: common.StringOrNil(fmt.Sprintf("webhook event type not supported: %s", event)),
+				})
+			}
+		}
+	}
+
+	return len(w.Errors) == 0
+}
+
+// Subscribes returns true if the webhook is enabled and subscribed to the given event type
+func (w *Webhook) Subscribes(event string) bool {
+	if !w.Enabled {
+		return false
+	}
+
+	if w.Events == nil {
+		return true
+	}
+
+	var events []string
+	json.Unmarshal(*w.Events, &events)
+	if len(events) == 0 {
+		return true
+	}
+
+	for _, evt := range events {
+		if evt == event {
+			return true
+		}
+	}
+	return false
+}
+
+// Sign returns the hex-encoded HMAC-SHA256 of the timestamp and payload using the webhook secret;
+// receivers verify the callback by computing the signature over "<timestamp>.<body>"
+func (w *Webhook) Sign(timestamp int64, payload []byte) string {
+	mac := hmac.New(sha256.New, []byte(*w.Secret))
+	mac.Write([]byte(fmt.Sprintf("%d.", timestamp)))
+	mac.Write(payload)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// Dispatch enqueues delivery of the given event to each webhook subscribed to it which belongs to
+// the given application or organization
+func Dispatch(db *gorm.DB, applicationID, organizationID *uuid.UUID, event string, payload interface{}) error {
+	if applicationID == nil && organizationID == nil {
+		return nil
+	}
+
+	query := db.Where("enabled = true")
+	if applicationID != nil && organizationID != nil {
+		query = query.Where("application_id = ? OR organization_id = ?", applicationID, organizationID)
+	} else if applicationID != nil {
+		query = query.Where("application_id = ?", applicationID)
+	} else {
+		query = query.Where("organization_id = ?", organizationID)
+	}
+
+	var webhooks []*Webhook
+	query.Find(&webhooks)
+	if len(webhooks) == 0 {
+		return nil
+	}
+
+	raw, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to marshal %s webhook payload; %s", event, err.Error())
+	}
+
+	eventID, _ := uuid.NewV4()
+	for _, webhook := range webhooks {
+		if !webhook.Subscribes(event) {
+			continue
+		}
+
+		msg, _ := json.Marshal(&deliveryMessage{
+			WebhookID: webhook.ID,
+			EventID:   eventID,
+			Event:     event,
+			Payload:   json.RawMessage(raw),
+			Attempt:   1,
+		})
+		_, err := natsutil.NatsJetstreamPublish(natsWebhookDeliverySubject, msg)
+		if err != nil {
+			return fmt.Errorf("failed to enqueue %s webhook delivery for webhook %s; %s", event, webhook.ID, err.Error())
+		}
+	}
+
+	return nil
+}
+
+// deliver attempts a single signed delivery of the event payload and records the attempt
+func (w *Webhook) deliver(db *gorm.DB, eventID uuid.UUID, event string, payload []byte, attempt uint64) (*Delivery, error) {
+	raw := json.RawMessage(payload)
+	delivery := &Delivery{
+		WebhookID: w.ID,
+		EventID:   eventID,
+		Event:     common.StringOrNil(event),
+		Attempt:   attempt,
+		Payload:   &raw,
+	}
+
+	timestamp := time.Now().Unix()
+	req, err := http.NewRequest(http.MethodPost, *w.URL, bytes.NewReader(payload))
+	if err != nil {
+		delivery.Error = common.StringOrNil(err.Error())
+		db.Create(&delivery)
+		return delivery, err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(webhookDeliveryHeader, eventID.String())
+	req.Header.Set(webhookEventHeader, event)
+	req.Header.Set(webhookSignatureHeader, w.Sign(timestamp, payload))
+	req.Header.Set(webhookTimestampHeader, fmt.Sprintf("%d", timestamp))
+
+	client := &http.Client{
+		Timeout: webhookRequestTimeout,
+		Transport: &http.Transport{
+			DialContext:       common.PublicDialContext(webhookRequestTimeout, common.AllowInsecureWebhooks),
+			DisableKeepAlives: true,
+		},
+	}
+
+	startedAt := time.Now()
+	resp, err := client.Do(req)
+	duration := uint64(time.Since(startedAt) / time.Millisecond)
+	delivery.Duration = &duration
+
+	if err == nil {
+		resp.Body.Close()
+		delivery.StatusCode = &resp.StatusCode
+		if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+			deliveredAt := time.Now()
+			delivery.DeliveredAt = &deliveredAt
+		} else {
+			err = fmt.Errorf("webhook receiver responded with status %d", resp.StatusCode)
+		}
+	}
+
+	if err != nil {
+		delivery.Error = common.StringOrNil(err.Error())
+	}
+
+	db.Create(&delivery)
+	return delivery, err
+}
+
+// validateURL returns an error if the given webhook url does not use https, or if its host does not resolve
+// or resolves to a restricted address
+func validateURL(rawURL string) error {
+	err := common.ValidatePublicURL(rawURL, common.AllowInsecureWebhooks)
+	if err != nil {
+		return fmt.Errorf("webhook requires a valid https url resolving to a public address; %s", err.Error())
+	}
+	return nil
+}
+
+// generateSecret returns a random hex-encoded webhook signing secret
+func generateSecret() (string, error) {
+	buf := make([]byte, webhookSecretLength)
+	_, err := rand.Read(buf)
+	if err != nil {
+		return "", fmt.Errorf("failed to generate webhook secret; %s", err.Error())
+	}
+	return hex.EncodeToString(buf), nil
+}